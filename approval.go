@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+)
+
+// statusAwaitingSecondApproval adalah status retur refund bernilai tinggi yang sudah disetujui satu approver
+// dan menunggu persetujuan approver kedua yang berbeda sebelum menjadi "Disetujui"
+const statusAwaitingSecondApproval = "Menunggu Persetujuan Kedua"
+
+// Error persetujuan kedua, dikirim ke client dengan status 409
+var (
+	errSameApprover          = errors.New("Second approval must be given by a different user than the first approver")
+	errSecondApprovalChanged = errors.New("Second approval must use the same pengembalian, refund_amount and currency as the first approval")
+)
+
+// requiresSecondApproval memeriksa apakah refund uang melewati RETUR_SECOND_APPROVAL_THRESHOLD (minor units); 0 berarti nonaktif
+func requiresSecondApproval(pengembalian string, refundAmount int64) bool {
+	return cfg.SecondApprovalThreshold > 0 && pengembalian == "uang" && refundAmount > cfg.SecondApprovalThreshold
+}
+
+// checkSecondApproval memastikan persetujuan kedua diberikan oleh pengguna lain dan tidak mengubah nilai yang disetujui pertama
+func checkSecondApproval(retur Retur, r *http.Request, pengembalian string, refundAmount int64, currency string) error {
+	if approverFromRequest(r) == retur.FirstApprovedBy {
+		return errSameApprover
+	}
+	if retur.Pengembalian != pengembalian || retur.RefundAmount != refundAmount || retur.Currency != currency {
+		return errSecondApprovalChanged
+	}
+	return nil
+}
+
+// decidableStatus memeriksa apakah retur masih boleh disetujui atau ditolak; retur yang sudah diputuskan hanya bisa
+// dibuka kembali lewat reset oleh admin
+func decidableStatus(status string) bool {
+	return status == "Dalam Proses" || status == statusAwaitingSecondApproval
+}
+
+// approverContextKey adalah key context untuk identitas approver yang sudah diautentikasi oleh requireApprover
+type approverContextKey struct{}
+
+// authenticatedApprover mencocokkan token request (Authorization: Bearer atau X-Admin-Token) dengan RETUR_APPROVER_TOKENS
+// Token admin juga diterima dengan identitas "admin"; header X-Actor tidak pernah dipakai sebagai identitas approver
+func authenticatedApprover(r *http.Request) (string, bool) {
+	token := adminTokenFromRequest(r)
+	if token == "" {
+		return "", false
+	}
+	for candidate, name := range cfg.ApproverTokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(candidate)) == 1 {
+			return name, true
+		}
+	}
+	if isAdmin(r) {
+		return "admin", true
+	}
+	return "", false
+}
+
+// requireApprover membungkus handler keputusan (approve/disapprove) sehingga hanya bisa dipanggil approver terautentikasi
+// Identitasnya disimpan di context dan dibaca lewat approverFromRequest, misalnya untuk aturan dua approver berbeda
+func requireApprover(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(cfg.ApproverTokens) == 0 && cfg.AdminToken == "" {
+			handleError(w, http.StatusForbidden, "Approval is not configured") // Tolak jika belum ada approver maupun admin
+			return
+		}
+		name, ok := authenticatedApprover(r)
+		if !ok {
+			handleError(w, http.StatusUnauthorized, "Approver token required") // Token tidak ada atau tidak valid
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), approverContextKey{}, name)))
+	}
+}
+
+// approverFromRequest mengembalikan identitas approver yang diautentikasi requireApprover, kosong jika tidak ada
+func approverFromRequest(r *http.Request) string {
+	name, _ := r.Context().Value(approverContextKey{}).(string)
+	return name
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// useApprovers mengatur token approver selama satu test
+func useApprovers(tb testing.TB, tokens map[string]string) {
+	tb.Helper()
+	withConfig(tb, func(c *Config) { c.ApproverTokens = tokens })
+}
+
+// asApprover menambahkan token approver ke request
+func asApprover(req *http.Request, token string) *http.Request {
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+func TestRequiresSecondApproval(t *testing.T) {
+	withConfig(t, func(c *Config) { c.SecondApprovalThreshold = 1000000 })
+	if requiresSecondApproval("uang", 1000000) || !requiresSecondApproval("uang", 1000001) || requiresSecondApproval("barang", 5000000) {
+		t.Fatal("unexpected threshold behaviour")
+	}
+	cfg.SecondApprovalThreshold = 0
+	if requiresSecondApproval("uang", 1<<40) {
+		t.Fatal("second approval required while disabled")
+	}
+}
+
+func TestRequireApproverAuthenticatesToken(t *testing.T) {
+	withConfig(t, func(c *Config) { c.AdminToken = "" })
+	useApprovers(t, nil)
+	req := jsonRequest(t, http.MethodPost, "/retur/1/disapprove", map[string]string{})
+	if rec := serve(req); rec.Code != http.StatusForbidden {
+		t.Fatalf("approval not configured: %d, want 403", rec.Code)
+	}
+
+	useApprovers(t, map[string]string{"tok-budi": "budi"})
+	tests := []struct {
+		token string
+		want  int
+	}{
+		{"", http.StatusUnauthorized},
+		{"tok-wrong", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := jsonRequest(t, http.MethodPost, "/retur/1/disapprove", map[string]string{})
+		req.Header.Set("X-Actor", "budi") // X-Actor tidak pernah menjadi identitas approver
+		if tt.token != "" {
+			asApprover(req, tt.token)
+		}
+		if rec := serve(req); rec.Code != tt.want {
+			t.Errorf("token %q: %d, want %d", tt.token, rec.Code, tt.want)
+		}
+	}
+
+	req = asApprover(httptest.NewRequest(http.MethodPost, "/", nil), "tok-budi")
+	if name, ok := authenticatedApprover(req); !ok || name != "budi" {
+		t.Fatalf("approver = %q, %v", name, ok)
+	}
+	if name, ok := authenticatedApprover(asAdmin(t, httptest.NewRequest(http.MethodPost, "/", nil))); !ok || name != "admin" {
+		t.Fatalf("admin approver = %q, %v", name, ok)
+	}
+}
+
+func TestSecondApprovalFlow(t *testing.T) {
+	testDB(t)
+	useApprovers(t, map[string]string{"tok-budi": "budi", "tok-siti": "siti"})
+	withConfig(t, func(c *Config) { c.SecondApprovalThreshold = 1000000 })
+	seeded := seedReturs(t, Retur{Barang: "Laptop", Alasan: "mati total"})
+	path := fmt.Sprintf("/retur/%d/approve", seeded[0].ID)
+	approve := func(token string, amount int64) (*httptest.ResponseRecorder, Retur) {
+		rec := serve(asApprover(jsonRequest(t, http.MethodPost, path, map[string]interface{}{"pengembalian": "uang", "refund_amount": amount, "currency": "IDR"}), token))
+		var retur Retur
+		if rec.Code == http.StatusOK {
+			decodeBody(t, rec, &retur)
+		}
+		return rec, retur
+	}
+
+	rec, retur := approve("tok-budi", 2000000)
+	if rec.Code != http.StatusOK || retur.Status != statusAwaitingSecondApproval || retur.FirstApprovedBy != "budi" || retur.DecidedBy != "" {
+		t.Fatalf("first approval: %d %+v", rec.Code, retur)
+	}
+	if rec, _ := approve("tok-budi", 2000000); rec.Code != http.StatusConflict {
+		t.Fatalf("same approver: %d, want 409", rec.Code)
+	}
+	if rec, _ := approve("tok-siti", 1500000); rec.Code != http.StatusConflict {
+		t.Fatalf("changed amount: %d, want 409", rec.Code)
+	}
+	rec, retur = approve("tok-siti", 2000000)
+	if rec.Code != http.StatusOK || retur.Status != "Disetujui" || retur.DecidedBy != "siti" {
+		t.Fatalf("second approval: %d %+v", rec.Code, retur)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// archivedRetur adalah satu baris arsip JSON lines: retur lengkap (beserta item) dan riwayat statusnya
+type archivedRetur struct {
+	ArchiveKey string         `json:"archive_key"` // "<id undo_entries>:<id retur>", stabil antar percobaan ulang untuk deduplikasi
+	Retur      Retur          `json:"retur"`
+	History    []ReturHistory `json:"history"`
+	DeletedAt  time.Time      `json:"deleted_at"`  // Waktu retur dihapus oleh pengguna
+	Reason     string         `json:"reason"`      // Alasan penghapusan dari pengguna
+	ArchivedAt time.Time      `json:"archived_at"` // Waktu retur diarsipkan sebelum dibuang permanen
+}
+
+// archiveUndoEntries menulis retur yang akan dibuang permanen ke file RETUR_ARCHIVE_PATH (JSON lines, append)
+// File di-sync sebelum fungsi kembali sehingga pemanggil hanya menghapus data jika arsip benar-benar tersimpan
+// Jika RETUR_ARCHIVE_PATH kosong, arsip dinonaktifkan dan fungsi langsung berhasil
+// Riwayat dibaca lewat tx agar arsip konsisten dengan transaksi purge yang memanggilnya (lihat purgeExpiredUndo)
+func archiveUndoEntries(tx *gorm.DB, entries []undoEntry) error {
+	if cfg.ArchivePath == "" || len(entries) == 0 {
+		return nil
+	}
+	now := clock.Now()
+	lines := make([]archivedRetur, 0, len(entries))
+	for _, entry := range entries {
+		for _, retur := range entry.Returs {
+			var history []ReturHistory
+			// Riwayat dibatasi sejak retur dibuat karena ID bisa pernah dipakai retur lain (ID reuse)
+			if err := tx.Where("retur_id = ? AND created_at >= ?", retur.ID, retur.CreatedAt).Order("id").Find(&history).Error; err != nil {
+				return fmt.Errorf("load history for retur %d: %w", retur.ID, err)
+			}
+			details, err := sealDetails(retur.Details) // Arsip adalah file di disk, details sensitif tidak boleh plaintext
+			if err != nil {
+				return fmt.Errorf("encrypt details for retur %d: %w", retur.ID, err)
+			}
+			retur.Details = details
+			key := fmt.Sprintf("%d:%d", entry.RecordID, retur.ID)
+			lines = append(lines, archivedRetur{ArchiveKey: key, Retur: retur, History: history, DeletedAt: entry.DeletedAt, Reason: entry.Reason, ArchivedAt: now})
+		}
+	}
+
+	file, err := os.OpenFile(cfg.ArchivePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, line := range lines {
+		if err := encoder.Encode(line); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
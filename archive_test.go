@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArchiveDisabledWithoutPath(t *testing.T) {
+	withConfig(t, func(c *Config) { c.ArchivePath = "" })
+	if err := archiveUndoEntries(nil, []undoEntry{{Returs: []Retur{{ID: 1}}}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPurgeExpiredUndoArchivesFirst(t *testing.T) {
+	testDB(t)
+	useDetailsKey(t)
+	fake := useFakeClock(t, time.Now())
+	archive := filepath.Join(t.TempDir(), "archive.jsonl")
+	withConfig(t, func(c *Config) {
+		c.MaxUndoAge = time.Hour
+		c.ArchivePath = filepath.Join(t.TempDir(), "missing", "archive.jsonl") // Direktori tidak ada, arsip gagal
+	})
+	useUndoState(t, nil, nil)
+	created := seedReturs(t, Retur{Barang: "Kulkas", Alasan: "bocor", Details: map[string]string{"account_number": "1234567890"}})[0]
+	serve(jsonRequest(t, http.MethodDelete, fmt.Sprintf("/retur/%d/delete", created.ID), map[string]string{"reason": "duplikat"}))
+	fake.Advance(2 * time.Hour)
+
+	if _, err := purgeExpiredUndo(); err == nil || deletedStack.Len() != 1 {
+		t.Fatalf("failed archive: err=%v, %d entries left", err, deletedStack.Len())
+	}
+	if wasPurged(context.Background(), created.ID) {
+		t.Fatal("tombstone written although the archive failed")
+	}
+
+	cfg.ArchivePath = archive
+	if purged, err := purgeExpiredUndo(); err != nil || purged != 1 || deletedStack.Len() != 0 {
+		t.Fatalf("purge: %d, %v, %d entries left", purged, err, deletedStack.Len())
+	}
+	file, err := os.Open(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var lines []archivedRetur
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var line archivedRetur
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 1 || lines[0].Retur.ID != created.ID || lines[0].Reason != "duplikat" || !strings.HasSuffix(lines[0].ArchiveKey, fmt.Sprintf(":%d", created.ID)) {
+		t.Fatalf("archive = %+v", lines)
+	}
+	if account := lines[0].Retur.Details["account_number"]; !strings.HasPrefix(account, encryptedValuePrefix) {
+		t.Fatalf("account number archived as %q", account)
+	}
+	if rec := serve(httptest.NewRequest(http.MethodGet, fmt.Sprintf("/retur/%d", created.ID), nil)); rec.Code != http.StatusGone {
+		t.Fatalf("GET purged id: %d, want 410", rec.Code)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// maxAgentLength membatasi panjang nama agent sesuai kolom assigned_to
+const maxAgentLength = 100
+
+// assignReturHandler adalah handler untuk menugaskan beberapa retur sekaligus ke satu agent dalam satu transaksi
+// Agent kosong menghapus penugasan; ID yang tidak ditemukan dilaporkan di not_found dan tidak menggagalkan request
+func assignReturHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		IDs   []int  `json:"ids"`   // Daftar ID retur yang ditugaskan
+		Agent string `json:"agent"` // Agent penerima tugas, kosong berarti hapus penugasan
+	}
+	if !decodeJSON(w, r, &input) {
+		return // Body kosong atau JSON tidak valid, error sudah dikirim
+	}
+	input.Agent = strings.TrimSpace(input.Agent)
+	if len(input.IDs) == 0 {
+		handleError(w, http.StatusBadRequest, "At least one id is required") // Daftar ID tidak boleh kosong
+		return
+	}
+	if len(input.IDs) > cfg.BatchMaxIDs {
+		handleError(w, http.StatusBadRequest, fmt.Sprintf("At most %d ids per request", cfg.BatchMaxIDs)) // Batasi jumlah ID per request
+		return
+	}
+	if utf8.RuneCountInString(input.Agent) > maxAgentLength {
+		handleError(w, http.StatusBadRequest, fmt.Sprintf("agent must be at most %d characters", maxAgentLength))
+		return
+	}
+
+	var returs []Retur
+	err := withTransaction(r.Context(), func(tx *gorm.DB) error {
+		if err := tx.Where("id IN ?", input.IDs).Order("id").Find(&returs).Error; err != nil {
+			return err
+		}
+		for i := range returs {
+			before := returs[i]
+			returs[i].AssignedTo = input.Agent
+			if err := tx.Model(&returs[i]).Update("assigned_to", input.Agent).Error; err != nil {
+				return err
+			}
+			if err := recordAudit(tx, "update", actorFromRequest(r), before, returs[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to assign returns")
+		return
+	}
+
+	found := make(map[int]bool, len(returs))
+	for _, retur := range returs {
+		found[retur.ID] = true
+	}
+	notFound := []int{}
+	for _, id := range input.IDs {
+		if !found[id] {
+			notFound = append(notFound, id)
+			found[id] = true // Hindari duplikat pada daftar not_found
+		}
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"agent": input.Agent, "assigned": len(returs), "not_found": notFound})
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAssignRejectsInvalidInput(t *testing.T) {
+	tests := []map[string]interface{}{
+		{"agent": "budi"},
+		{"ids": []int{}, "agent": "budi"},
+		{"ids": []int{1}, "agent": strings.Repeat("a", maxAgentLength+1)},
+	}
+	for _, payload := range tests {
+		if rec := serve(jsonRequest(t, http.MethodPost, "/retur/assign", payload)); rec.Code != http.StatusBadRequest {
+			t.Errorf("%v: %d, want 400", payload, rec.Code)
+		}
+	}
+}
+
+func TestAssignFilterAndClear(t *testing.T) {
+	testDB(t)
+	seeded := seedReturs(t, Retur{Barang: "A", Alasan: "x"}, Retur{Barang: "B", Alasan: "x"}, Retur{Barang: "C", Alasan: "x"})
+	queue := func(agent string) []int {
+		t.Helper()
+		var list []Retur
+		decodeBody(t, serve(httptest.NewRequest(http.MethodGet, "/retur?sort=id&assigned_to="+agent, nil)), &list)
+		var ids []int
+		for _, retur := range list {
+			ids = append(ids, retur.ID)
+		}
+		return ids
+	}
+	assign := func(ids []int, agent string) map[string]interface{} {
+		t.Helper()
+		rec := serve(jsonRequest(t, http.MethodPost, "/retur/assign", map[string]interface{}{"ids": ids, "agent": agent}))
+		var body map[string]interface{}
+		decodeBody(t, rec, &body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("assign %v to %q: %d %v", ids, agent, rec.Code, body)
+		}
+		return body
+	}
+
+	body := assign([]int{seeded[0].ID, seeded[2].ID, 999999}, " budi ")
+	if body["agent"] != "budi" || body["assigned"] != float64(2) || len(body["not_found"].([]interface{})) != 1 {
+		t.Fatalf("assign response = %v", body)
+	}
+	if got, want := queue("budi"), []int{seeded[0].ID, seeded[2].ID}; !slices.Equal(got, want) {
+		t.Fatalf("budi's queue = %v, want %v", got, want)
+	}
+	if got, want := queue(""), []int{seeded[1].ID}; !slices.Equal(got, want) {
+		t.Fatalf("unassigned = %v, want %v", got, want)
+	}
+
+	assign([]int{seeded[0].ID}, "")
+	if got, want := queue("budi"), []int{seeded[2].ID}; !slices.Equal(got, want) {
+		t.Fatalf("budi's queue after clearing = %v, want %v", got, want)
+	}
+	if got, want := queue(""), []int{seeded[0].ID, seeded[1].ID}; !slices.Equal(got, want) {
+		t.Fatalf("unassigned after clearing = %v, want %v", got, want)
+	}
+}
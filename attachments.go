@@ -0,0 +1,190 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// Attachment adalah file bukti (foto, video) yang dilampirkan pada retur
+// Isi file disimpan di RETUR_ATTACHMENT_DIR, database hanya menyimpan metadatanya
+// retur_id sengaja tanpa foreign key: lampiran retur yang dihapus harus tetap ada selama retur masih bisa di-undo,
+// dan baru dibuang saat undo kedaluwarsa (purgeAttachments) atau lewat /retur/admin/orphans/cleanup
+type Attachment struct {
+	ID          uint      `json:"id"`
+	ReturID     int       `json:"retur_id" gorm:"index"` // ID retur pemilik lampiran
+	Filename    string    `json:"filename"`              // Nama file asli dari header Content-Disposition
+	ContentType string    `json:"content_type"`          // Content type yang dikirim saat upload
+	Size        int64     `json:"size"`                  // Ukuran file dalam byte
+	StoragePath string    `json:"-" gorm:"type:text"`    // Path file di RETUR_ATTACHMENT_DIR
+	CreatedAt   time.Time `json:"created_at"`            // Waktu upload
+}
+
+// attachmentRoutes adalah route yang menerima body selain JSON sehingga dilewati oleh requireJSONContentType
+var attachmentRoutes = map[string]bool{"uploadAttachment": true}
+
+// uploadAttachmentHandler adalah handler untuk mengunggah lampiran retur sebagai body mentah
+// Nama file diambil dari header Content-Disposition (filename=...) dan ukuran dibatasi RETUR_ATTACHMENT_MAX_SIZE
+// Route ini dibungkus requireAuthenticated karena setiap upload memakai ruang disk
+func uploadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"]) // Convert ID dari string ke integer
+	if err != nil {
+		handleError(w, http.StatusBadRequest, "Invalid ID format") // Jika format ID salah, kirimkan error
+		return
+	}
+	var retur Retur
+	if err := db.WithContext(r.Context()).Select("id").First(&retur, id).Error; err != nil {
+		handleError(w, http.StatusNotFound, "Return not found") // Jika retur tidak ditemukan, kirimkan error
+		return
+	}
+
+	filename := "attachment"
+	if _, params, err := mime.ParseMediaType(r.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		filename = filepath.Base(params["filename"]) // Buang komponen direktori dari nama file client
+	}
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	if err := os.MkdirAll(cfg.AttachmentDir, 0o750); err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to store attachment")
+		return
+	}
+	file, err := os.CreateTemp(cfg.AttachmentDir, fmt.Sprintf("retur-%d-*", id))
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to store attachment")
+		return
+	}
+	size, err := io.Copy(file, http.MaxBytesReader(w, r.Body, cfg.AttachmentMaxSize))
+	closeErr := file.Close()
+	if err != nil || closeErr != nil {
+		os.Remove(file.Name())
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			handleError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Attachment must be at most %d bytes", cfg.AttachmentMaxSize))
+			return
+		}
+		handleError(w, http.StatusInternalServerError, "Failed to store attachment")
+		return
+	}
+
+	attachment := Attachment{ReturID: id, Filename: filename, ContentType: contentType, Size: size, StoragePath: file.Name()}
+	if err := db.WithContext(r.Context()).Create(&attachment).Error; err != nil {
+		os.Remove(file.Name()) // Jangan tinggalkan file tanpa metadata
+		handleError(w, http.StatusInternalServerError, "Failed to store attachment")
+		return
+	}
+	w.Header().Set("Location", urlFor(fmt.Sprintf("/retur/%d/attachments/%d", id, attachment.ID)))
+	respondJSON(w, http.StatusCreated, attachment)
+}
+
+// listAttachmentsHandler adalah handler untuk melihat metadata semua lampiran retur
+// Dengan ?envelope=true (selalu di bawah /v1), hasil dipaginasi dalam envelope data/meta (lihat findEnvelopePage)
+func listAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"]) // Convert ID dari string ke integer
+	if err != nil {
+		handleError(w, http.StatusBadRequest, "Invalid ID format") // Jika format ID salah, kirimkan error
+		return
+	}
+	attachments := []Attachment{}
+	query := db.WithContext(r.Context()).Model(&Attachment{}).Where("retur_id = ?", id).Order("id")
+	if wantsEnvelope(r) {
+		if meta, ok := findEnvelopePage(w, r, query, &attachments); ok {
+			respondEnvelope(w, attachments, meta)
+		}
+		return
+	}
+	if err := query.Find(&attachments).Error; err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to retrieve attachments")
+		return
+	}
+	respondJSON(w, http.StatusOK, attachments)
+}
+
+// downloadAttachmentHandler adalah handler untuk mengunduh lampiran retur
+// Memakai http.ServeContent sehingga header Range dilayani dengan 206 Partial Content (seek video, resume download)
+// dan range yang tidak bisa dipenuhi dijawab 416; If-Modified-Since juga didukung dari waktu upload
+func downloadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		handleError(w, http.StatusBadRequest, "Invalid ID format") // Jika format ID salah, kirimkan error
+		return
+	}
+	attID, err := strconv.Atoi(vars["attID"])
+	if err != nil {
+		handleError(w, http.StatusBadRequest, "Invalid attachment ID format") // Jika format ID lampiran salah, kirimkan error
+		return
+	}
+
+	var attachment Attachment
+	if err := db.WithContext(r.Context()).Where("retur_id = ?", id).First(&attachment, attID).Error; err != nil {
+		handleError(w, http.StatusNotFound, "Attachment not found")
+		return
+	}
+	file, err := os.Open(attachment.StoragePath)
+	if err != nil {
+		handleError(w, http.StatusNotFound, "Attachment content not found") // Metadata ada tetapi file sudah hilang
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, strings.ReplaceAll(attachment.Filename, "/", "_"), attachment.CreatedAt, file)
+}
+
+// attachmentPaths mengembalikan path file lampiran dengan ID yang diberikan, dipanggil sebelum barisnya dihapus
+func attachmentPaths(tx *gorm.DB, ids []uint) ([]string, error) {
+	var paths []string
+	err := tx.Model(&Attachment{}).Where("id IN ?", ids).Pluck("storage_path", &paths).Error
+	return paths, err
+}
+
+// purgeAttachments menghapus metadata lampiran milik retur yang dibuang permanen dan mengembalikan path file-nya
+// Lampiran dibatasi pada rentang hidup retur (dibuat sampai dihapus) karena ID bisa sudah dipakai retur lain (ID reuse)
+// File baru dihapus oleh removeAttachmentFiles setelah transaksi berhasil
+func purgeAttachments(tx *gorm.DB, entries []undoEntry) ([]string, error) {
+	var paths []string
+	for _, entry := range entries {
+		for _, retur := range entry.Returs {
+			var attachments []Attachment
+			if err := tx.Where("retur_id = ? AND created_at >= ? AND created_at <= ?", retur.ID, retur.CreatedAt, entry.DeletedAt).
+				Find(&attachments).Error; err != nil {
+				return nil, err
+			}
+			if len(attachments) == 0 {
+				continue
+			}
+			if err := tx.Delete(&attachments).Error; err != nil {
+				return nil, err
+			}
+			for _, attachment := range attachments {
+				paths = append(paths, attachment.StoragePath)
+			}
+		}
+	}
+	return paths, nil
+}
+
+// removeAttachmentFiles menghapus file lampiran dari RETUR_ATTACHMENT_DIR; file yang sudah tidak ada diabaikan
+func removeAttachmentFiles(paths []string) {
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("failed to remove attachment file %s: %v", path, err) // Metadata sudah hilang, file dibersihkan manual
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownloadAttachmentRange(t *testing.T) {
+	testDB(t)
+	retur := seedReturs(t, Retur{Barang: "Kamera", Alasan: "lensa buram"})[0]
+	content := []byte("0123456789abcdef")
+	path := filepath.Join(t.TempDir(), "bukti.mp4")
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	attachment := Attachment{ReturID: retur.ID, Filename: "bukti.mp4", ContentType: "video/mp4", Size: int64(len(content)), StoragePath: path}
+	if err := db.Create(&attachment).Error; err != nil {
+		t.Fatal(err)
+	}
+	download := func(byteRange string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/retur/%d/attachments/%d", retur.ID, attachment.ID), nil)
+		req.Header.Set("Range", byteRange)
+		return serve(req)
+	}
+
+	rec := download("bytes=4-9")
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "456789" {
+		t.Fatalf("range: %d %q, want 206 \"456789\"", rec.Code, rec.Body.String())
+	}
+	if got, want := rec.Header().Get("Content-Range"), fmt.Sprintf("bytes 4-9/%d", len(content)); got != want {
+		t.Fatalf("Content-Range = %q, want %q", got, want)
+	}
+
+	rec = download("bytes=100-200")
+	if rec.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Fatalf("unsatisfiable range: %d, want 416", rec.Code)
+	}
+	if got, want := rec.Header().Get("Content-Range"), fmt.Sprintf("bytes */%d", len(content)); got != want {
+		t.Fatalf("416 Content-Range = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// AuditLog mencatat siapa mengubah field apa menjadi nilai apa pada setiap perubahan data
+type AuditLog struct {
+	ID       uint                   `json:"id"`
+	Entity   string                 `json:"entity" gorm:"index:idx_audit_entity"`    // Jenis data, misalnya "retur"
+	EntityID int                    `json:"entity_id" gorm:"index:idx_audit_entity"` // ID data yang berubah
+	Actor    string                 `json:"actor"`                                   // Pengguna yang melakukan perubahan
+	Action   string                 `json:"action"`                                  // create, update, delete, restore, atau reconcile
+	Changes  map[string]fieldChange `json:"changes" gorm:"column:changes_json;serializer:json;type:text"`
+	At       time.Time              `json:"at"`
+}
+
+// fieldChange adalah nilai satu field sebelum dan sesudah perubahan
+type fieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// auditIgnoredFields adalah field JSON Retur yang tidak diaudit: dihitung saat dibaca atau dicatat terpisah
+var auditIgnoredFields = map[string]bool{
+	"items":        true, // Item memiliki status sendiri dan tercatat di riwayat
+	"sla_deadline": true,
+	"overdue":      true,
+	"created_at":   true,
+}
+
+// returFields mengubah retur menjadi map field JSON ke nilai untuk dibandingkan
+func returFields(retur Retur) map[string]interface{} {
+	fields := map[string]interface{}{}
+	data, err := json.Marshal(retur)
+	if err != nil {
+		return fields
+	}
+	json.Unmarshal(data, &fields)
+	for name := range auditIgnoredFields {
+		delete(fields, name)
+	}
+	if details, ok := fields["details"].(map[string]interface{}); ok && detailsAEAD != nil {
+		for key := range details {
+			if sensitiveDetail(key) {
+				details[key] = "[encrypted]" // Nilai sensitif tidak boleh tersimpan plaintext di audit log
+			}
+		}
+	}
+	return fields
+}
+
+// diffRetur mengembalikan hanya field yang nilainya berbeda antara before dan after
+func diffRetur(before, after Retur) map[string]fieldChange {
+	from, to := returFields(before), returFields(after)
+	changes := map[string]fieldChange{}
+	for name, value := range to {
+		if !reflect.DeepEqual(from[name], value) {
+			changes[name] = fieldChange{From: from[name], To: value}
+		}
+	}
+	for name, value := range from {
+		if _, ok := to[name]; !ok {
+			changes[name] = fieldChange{From: value, To: nil}
+		}
+	}
+	return changes
+}
+
+// recordAudit menyimpan entri audit untuk perubahan retur di dalam transaksi tx
+// Perubahan tanpa field yang berbeda tidak dicatat agar audit log tidak berisi entri kosong
+func recordAudit(tx *gorm.DB, action, actor string, before, after Retur) error {
+	changes := diffRetur(before, after)
+	if len(changes) == 0 {
+		return nil
+	}
+	id := after.ID
+	if action == "delete" {
+		id = before.ID
+	}
+	return tx.Create(&AuditLog{Entity: "retur", EntityID: id, Actor: actor, Action: action, Changes: changes, At: clock.Now()}).Error
+}
+
+// returAuditHandler adalah handler admin untuk melihat audit log satu retur, dari yang terlama
+// Entri tetap tersedia setelah retur dihapus agar jejak perubahan tidak hilang
+// Dengan ?envelope=true (selalu di bawah /v1), hasil dipaginasi dalam envelope data/meta (lihat findEnvelopePage)
+func returAuditHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"]) // Convert ID dari string ke integer
+	if err != nil {
+		handleError(w, http.StatusBadRequest, "Invalid ID format") // Jika format ID salah, kirimkan error
+		return
+	}
+	logs := []AuditLog{}
+	query := db.WithContext(r.Context()).Model(&AuditLog{}).Where("entity = ? AND entity_id = ?", "retur", id).Order("at, id")
+	if wantsEnvelope(r) {
+		if meta, ok := findEnvelopePage(w, r, query, &logs); ok {
+			respondEnvelope(w, logs, meta)
+		}
+		return
+	}
+	if err := query.Find(&logs).Error; err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to retrieve audit log")
+		return
+	}
+	respondJSON(w, http.StatusOK, logs)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiffReturOnlyChangedFields(t *testing.T) {
+	before := Retur{ID: 3, Barang: "Sepatu", Status: "Dalam Proses", CreatedAt: time.Now()}
+	after := before
+	after.Status = "Disetujui"
+	after.Pengembalian = "uang"
+	after.CreatedAt = before.CreatedAt.Add(time.Hour) // Diabaikan
+	changes := diffRetur(before, after)
+	if len(changes) != 2 || changes["status"].From != "Dalam Proses" || changes["status"].To != "Disetujui" || changes["pengembalian"].To != "uang" {
+		t.Fatalf("changes = %v", changes)
+	}
+	if len(diffRetur(before, before)) != 0 {
+		t.Fatal("identical returns produced changes")
+	}
+}
+
+func TestDiffReturHidesEncryptedDetails(t *testing.T) {
+	useDetailsKey(t)
+	before := Retur{Details: map[string]string{"account_number": "111"}}
+	after := Retur{Details: map[string]string{"account_number": "222", "bank": "BNI"}}
+	change := diffRetur(before, after)["details"]
+	to, _ := change.To.(map[string]interface{})
+	if to["account_number"] != "[encrypted]" || to["bank"] != "BNI" {
+		t.Fatalf("audit change = %v", change)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// autoApproveActor adalah pelaku yang dicatat untuk keputusan otomatis
+const autoApproveActor = "auto-approve"
+
+// parseAutoApproveRules membaca RETUR_AUTO_APPROVE_RULES berupa "reason_code=pengembalian", misalnya "WRONG_ITEM=barang"
+// Hanya pengembalian barang yang bisa disetujui otomatis karena refund uang membutuhkan nilai dari approver
+func parseAutoApproveRules(raw string) map[string]string {
+	rules := make(map[string]string)
+	for _, item := range strings.Split(raw, ",") {
+		code, pengembalian, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			continue
+		}
+		code, pengembalian = strings.TrimSpace(code), strings.TrimSpace(pengembalian)
+		if code == "" || pengembalian != "barang" {
+			log.Printf("ignoring auto-approve rule %q: only barang can be approved automatically", item)
+			continue
+		}
+		rules[code] = pengembalian
+	}
+	return rules
+}
+
+// autoApproveMatch memeriksa apakah retur "Dalam Proses" tanpa item cocok dengan aturan auto-approve yang berlaku
+// dan mengembalikan jenis pengembalian yang dipakai; details yang belum memenuhi RETUR_DETAILS_POLICY tidak cocok
+func autoApproveMatch(retur Retur) (string, bool) {
+	pengembalian, ok := cfg.AutoApproveRules[retur.ReasonCode]
+	if !ok || retur.Status != "Dalam Proses" || len(retur.Items) > 0 {
+		return "", false
+	}
+	if errs := validateDetails(pengembalian, retur.Details); len(errs) > 0 {
+		return "", false
+	}
+	return pengembalian, true
+}
+
+// applyAutoApprove mengisi data keputusan retur seperti persetujuan oleh autoApproveActor
+func applyAutoApprove(retur *Retur, pengembalian string) {
+	now := clock.Now()
+	retur.Status = "Disetujui"
+	retur.Pengembalian = pengembalian
+	retur.RefundAmount = 0
+	retur.Currency = ""
+	retur.DecidedBy = autoApproveActor
+	retur.DecidedAt = &now
+	retur.DecisionNote = "auto-approved by rule " + retur.ReasonCode
+}
+
+// applyAutoApproveHandler adalah handler admin untuk menerapkan aturan auto-approve (RETUR_AUTO_APPROVE_RULES)
+// pada retur "Dalam Proses" yang sudah ada; retur diproses per batch (RETUR_BACKFILL_BATCH_SIZE) dalam transaksi sendiri
+// dan setiap keputusan dicatat di riwayat dan audit log. Dengan ?dry_run=true hanya jumlah yang akan disetujui yang dilaporkan
+// Event dikirim setelah setiap batch commit; jika satu batch gagal, response 500 tetap berisi progres batch yang sudah
+// commit beserta resume_after_id yang bisa dikirim lewat ?after_id untuk melanjutkan
+func applyAutoApproveHandler(w http.ResponseWriter, r *http.Request) {
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	if len(cfg.AutoApproveRules) == 0 {
+		handleError(w, http.StatusBadRequest, "No auto-approve rules configured (RETUR_AUTO_APPROVE_RULES)")
+		return
+	}
+	codes := make([]string, 0, len(cfg.AutoApproveRules))
+	for code := range cfg.AutoApproveRules {
+		codes = append(codes, code)
+	}
+
+	lastID, err := queryInt(r, "after_id", 0)
+	if err != nil {
+		handleError(w, http.StatusBadRequest, "Invalid after_id")
+		return
+	}
+
+	byReason := make(map[string]int)
+	scanned, matched, approved, committedID := 0, 0, 0, lastID
+	progress := func() map[string]interface{} {
+		return map[string]interface{}{
+			"dry_run":         dryRun,
+			"scanned":         scanned,
+			"matched":         matched,
+			"approved":        approved, // Selalu 0 pada dry run
+			"by_reason":       byReason, // Jumlah yang (akan) disetujui per reason_code
+			"resume_after_id": committedID,
+		}
+	}
+	for {
+		var batch []Retur
+		if err := db.WithContext(r.Context()).
+			Where("id > ? AND status = ? AND reason_code IN ?", lastID, "Dalam Proses", codes).
+			Where("NOT EXISTS (SELECT 1 FROM retur_items WHERE retur_items.retur_id = returs.id)"). // Retur dengan item diputuskan per item
+			Order("id").Limit(cfg.BackfillBatchSize).Find(&batch).Error; err != nil {
+			handleError(w, http.StatusInternalServerError, "Failed to scan returns")
+			return
+		}
+		if len(batch) == 0 {
+			break
+		}
+		lastID = batch[len(batch)-1].ID
+		scanned += len(batch)
+
+		var decided, candidates []Retur
+		err := withTransaction(r.Context(), func(tx *gorm.DB) error {
+			for _, retur := range batch {
+				pengembalian, ok := autoApproveMatch(retur)
+				if !ok {
+					continue
+				}
+				candidates = append(candidates, retur)
+				if dryRun {
+					continue
+				}
+				before := retur
+				applyAutoApprove(&retur, pengembalian)
+				result := tx.Model(&retur).Select("*").Omit("Items").Where("status = ?", "Dalam Proses").Updates(&retur)
+				if result.Error != nil {
+					return result.Error
+				}
+				if result.RowsAffected == 0 {
+					continue // Sudah diputuskan oleh request lain sejak dibaca
+				}
+				if err := recordAudit(tx, "update", autoApproveActor, before, retur); err != nil {
+					return err
+				}
+				if err := tx.Create(&ReturHistory{ReturID: retur.ID, FromStatus: before.Status, ToStatus: retur.Status, Actor: autoApproveActor, Note: retur.DecisionNote}).Error; err != nil {
+					return err
+				}
+				decided = append(decided, retur)
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("auto-approve batch after id %d failed: %v", committedID, err)
+			result := progress()
+			result["error"] = fmt.Sprintf("Failed to auto-approve returns after id %d", committedID) // Batch sebelumnya sudah commit
+			respondJSON(w, http.StatusInternalServerError, result)
+			return
+		}
+		matched += len(candidates) // Dihitung setelah commit agar batch yang gagal tidak ikut dilaporkan
+		if dryRun {
+			decided = candidates
+		}
+		for _, retur := range decided {
+			byReason[retur.ReasonCode]++
+			if dryRun {
+				continue
+			}
+			publishEvent(ReturApproved, retur, r) // Batch ini sudah commit, event tidak menunggu batch berikutnya
+		}
+		if !dryRun {
+			approved += len(decided)
+		}
+		committedID = lastID
+	}
+	respondJSON(w, http.StatusOK, progress())
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseAutoApproveRulesOnlyAllowsBarang(t *testing.T) {
+	rules := parseAutoApproveRules(" WRONG_ITEM = barang ,DAMAGED=uang,broken,=barang")
+	if len(rules) != 1 || rules["WRONG_ITEM"] != "barang" {
+		t.Fatalf("rules = %v", rules)
+	}
+}
+
+func TestAutoApproveMatch(t *testing.T) {
+	withConfig(t, func(c *Config) {
+		c.AutoApproveRules = map[string]string{"WRONG_ITEM": "barang"}
+		c.DetailsPolicy = map[string][]string{"barang": {"alamat"}}
+	})
+	match := Retur{ReasonCode: "WRONG_ITEM", Status: "Dalam Proses", Details: map[string]string{"alamat": "Jl. Merdeka 1"}}
+	tests := []struct {
+		name  string
+		retur Retur
+		want  bool
+	}{
+		{"match", match, true},
+		{"other reason", Retur{ReasonCode: "DAMAGED", Status: "Dalam Proses", Details: match.Details}, false},
+		{"already decided", Retur{ReasonCode: "WRONG_ITEM", Status: "Disetujui", Details: match.Details}, false},
+		{"has items", Retur{ReasonCode: "WRONG_ITEM", Status: "Dalam Proses", Details: match.Details, Items: []ReturItem{{}}}, false},
+		{"missing details", Retur{ReasonCode: "WRONG_ITEM", Status: "Dalam Proses"}, false},
+	}
+	for _, tt := range tests {
+		if pengembalian, ok := autoApproveMatch(tt.retur); ok != tt.want || ok && pengembalian != "barang" {
+			t.Errorf("%s: got %q, %v", tt.name, pengembalian, ok)
+		}
+	}
+
+	applyAutoApprove(&match, "barang")
+	if match.Status != "Disetujui" || match.DecidedBy != autoApproveActor || match.DecidedAt == nil || match.DecisionNote != "auto-approved by rule WRONG_ITEM" {
+		t.Fatalf("applied = %+v", match)
+	}
+}
+
+func TestApplyAutoApproveHandlerDryRunThenApply(t *testing.T) {
+	testDB(t)
+	withConfig(t, func(c *Config) {
+		c.AutoApproveRules = map[string]string{"WRONG_ITEM": "barang"}
+		c.DetailsPolicy = nil
+		c.BackfillBatchSize = 1 // Setiap retur diproses di batch sendiri
+	})
+	seeded := seedReturs(t,
+		Retur{Barang: "Kaos", Alasan: "salah kirim", ReasonCode: "WRONG_ITEM"},
+		Retur{Barang: "Celana", Alasan: "rusak", ReasonCode: "DAMAGED"},
+		Retur{Barang: "Topi", Alasan: "salah kirim", ReasonCode: "WRONG_ITEM"},
+	)
+	var result struct {
+		Scanned  int            `json:"scanned"`
+		Matched  int            `json:"matched"`
+		Approved int            `json:"approved"`
+		ByReason map[string]int `json:"by_reason"`
+		Resume   int            `json:"resume_after_id"`
+	}
+	rec := serve(asAdmin(t, httptest.NewRequest(http.MethodPost, "/retur/admin/apply-autoapprove?dry_run=true", nil)))
+	decodeBody(t, rec, &result)
+	if rec.Code != http.StatusOK || result.Matched != 2 || result.Approved != 0 || result.ByReason["WRONG_ITEM"] != 2 {
+		t.Fatalf("dry run: %d %+v", rec.Code, result)
+	}
+	var pending int64
+	db.Model(&Retur{}).Where("status = ?", "Dalam Proses").Count(&pending)
+	if pending != 3 {
+		t.Fatalf("dry run changed %d returns", 3-pending)
+	}
+
+	rec = serve(asAdmin(t, httptest.NewRequest(http.MethodPost, "/retur/admin/apply-autoapprove", nil)))
+	decodeBody(t, rec, &result)
+	if rec.Code != http.StatusOK || result.Approved != 2 || result.Resume != seeded[2].ID {
+		t.Fatalf("apply: %d %+v", rec.Code, result)
+	}
+	var history int64
+	db.Model(&ReturHistory{}).Where("actor = ?", autoApproveActor).Count(&history)
+	if history != 2 {
+		t.Fatalf("history rows = %d, want 2", history)
+	}
+}
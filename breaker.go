@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// State circuit breaker database
+const (
+	breakerClosed   = "closed"    // Normal, semua request diteruskan ke database
+	breakerOpen     = "open"      // Database dianggap mati, request langsung dijawab 503
+	breakerHalfOpen = "half_open" // Cooldown selesai, satu request dicoba untuk menguji database
+)
+
+// circuitBreaker memutus akses ke database setelah sejumlah kegagalan koneksi berturut-turut
+// agar request tidak menumpuk menunggu timeout saat MySQL tidak tersedia
+type circuitBreaker struct {
+	mu            sync.Mutex
+	threshold     int           // Jumlah kegagalan berturut-turut sebelum breaker terbuka
+	cooldown      time.Duration // Lama breaker terbuka sebelum mencoba lagi
+	state         string
+	failures      int       // Kegagalan koneksi berturut-turut
+	openedAt      time.Time // Waktu breaker terakhir terbuka
+	probing       bool      // Ada request percobaan yang sedang berjalan saat half-open
+	trips         atomic.Int64
+	shortCircuits atomic.Int64
+}
+
+// newCircuitBreaker membuat circuit breaker dalam keadaan tertutup
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, state: breakerClosed}
+}
+
+// Allow melaporkan apakah request boleh diteruskan ke database, dan apakah request itu adalah percobaan half-open
+// Setelah cooldown, hanya satu request percobaan yang diteruskan sampai hasilnya diketahui
+func (b *circuitBreaker) Allow() (allowed, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if clock.Now().Sub(b.openedAt) < b.cooldown {
+			b.shortCircuits.Add(1)
+			return false, false
+		}
+		b.state = breakerHalfOpen // Cooldown selesai, izinkan satu percobaan
+		b.probing = true
+		return true, true
+	case breakerHalfOpen:
+		if b.probing {
+			b.shortCircuits.Add(1)
+			return false, false // Percobaan lain masih berjalan
+		}
+		b.probing = true
+		return true, true
+	}
+	return true, false
+}
+
+// Success mencatat operasi database yang berhasil dan menutup breaker
+func (b *circuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerOpen {
+		return // Hasil terlambat dari request sebelum breaker terbuka; hanya percobaan half-open yang boleh menutup breaker
+	}
+	b.failures = 0
+	b.probing = false
+	b.state = breakerClosed
+}
+
+// Failure mencatat kegagalan koneksi database; breaker terbuka jika ambang tercapai atau percobaan half-open gagal
+func (b *circuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerOpen {
+		return // Kegagalan terlambat dari request sebelum breaker terbuka tidak memperpanjang cooldown
+	}
+	b.failures++
+	b.probing = false
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.trips.Add(1)
+		b.state = breakerOpen
+		b.openedAt = clock.Now() // Hanya diatur saat transisi ke open
+	}
+}
+
+// Release melepas status percobaan half-open jika request percobaan selesai tanpa menyentuh database
+// Hanya dipanggil oleh request yang diizinkan Allow sebagai percobaan
+func (b *circuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// State mengembalikan state breaker saat ini
+func (b *circuitBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Snapshot mengembalikan kondisi breaker untuk endpoint /status
+func (b *circuitBreaker) Snapshot() map[string]interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	snapshot := map[string]interface{}{
+		"state":                b.state,
+		"consecutive_failures": b.failures,
+		"threshold":            b.threshold,
+		"cooldown":             b.cooldown.String(),
+		"trips":                b.trips.Load(),
+		"short_circuited":      b.shortCircuits.Load(),
+	}
+	if b.state != breakerClosed {
+		snapshot["opened_at"] = b.openedAt.UTC()
+	}
+	return snapshot
+}
+
+// dbBreaker adalah circuit breaker untuk semua operasi database, dibuat di main() dari konfigurasi
+var dbBreaker = newCircuitBreaker(5, 30*time.Second)
+
+// isConnectionError melaporkan apakah error berasal dari koneksi database yang terputus, bukan dari query
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false // Request dibatalkan klien atau kena timeout route, bukan tanda database mati
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) // Timeout, connection refused, dan sejenisnya
+}
+
+// registerBreakerCallbacks memasang callback GORM yang melaporkan hasil setiap operasi ke circuit breaker
+func registerBreakerCallbacks(db *gorm.DB) {
+	record := func(tx *gorm.DB) {
+		if errors.Is(tx.Error, context.Canceled) || errors.Is(tx.Error, context.DeadlineExceeded) {
+			return // Tidak memberi informasi tentang kondisi database, jangan dihitung sukses maupun gagal
+		}
+		if isConnectionError(tx.Error) {
+			dbBreaker.Failure()
+			return
+		}
+		dbBreaker.Success() // Error selain koneksi (misalnya record not found) berarti database hidup
+	}
+	callbacks := db.Callback()
+	callbacks.Create().After("gorm:create").Register("breaker:create", record)
+	callbacks.Query().After("gorm:query").Register("breaker:query", record)
+	callbacks.Update().After("gorm:update").Register("breaker:update", record)
+	callbacks.Delete().After("gorm:delete").Register("breaker:delete", record)
+	callbacks.Row().After("gorm:row").Register("breaker:row", record)
+	callbacks.Raw().After("gorm:raw").Register("breaker:raw", record)
+}
+
+// breakerExemptRoutes adalah route yang tetap dilayani saat breaker terbuka agar kondisi aplikasi tetap bisa dipantau
+var breakerExemptRoutes = map[string]bool{
+	"status":  true,
+	"metrics": true,
+	"postman": true,
+	"config":  true,
+
+	"adminTail": true, // Stream kejadian tetap bisa dipantau saat insiden database
+}
+
+// breakerMiddleware menjawab 503 dengan cepat selama circuit breaker database terbuka
+func breakerMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := mux.CurrentRoute(r); route != nil && breakerExemptRoutes[route.GetName()] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		allowed, probe := dbBreaker.Allow()
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(dbBreaker.cooldown.Seconds())))
+			handleError(w, http.StatusServiceUnavailable, "Database unavailable, try again later")
+			return
+		}
+		if probe {
+			defer dbBreaker.Release() // Percobaan selesai meskipun request tidak menyentuh database
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	fake := useFakeClock(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := newCircuitBreaker(2, time.Minute)
+
+	b.Failure()
+	if allowed, probe := b.Allow(); b.State() != breakerClosed || !allowed || probe {
+		t.Fatal("breaker opened before threshold")
+	}
+	b.Failure()
+	if allowed, _ := b.Allow(); b.State() != breakerOpen || allowed {
+		t.Fatal("breaker not open after threshold")
+	}
+
+	fake.Advance(time.Minute)
+	if allowed, probe := b.Allow(); !allowed || !probe || b.State() != breakerHalfOpen {
+		t.Fatal("breaker did not allow a probe after cooldown")
+	}
+	if allowed, _ := b.Allow(); allowed {
+		t.Fatal("second request allowed while probe is running")
+	}
+	b.Failure()
+	if allowed, _ := b.Allow(); b.State() != breakerOpen || allowed {
+		t.Fatal("failed probe did not reopen the breaker")
+	}
+
+	fake.Advance(time.Minute)
+	b.Allow()
+	b.Success()
+	if allowed, probe := b.Allow(); b.State() != breakerClosed || !allowed || probe {
+		t.Fatal("successful probe did not close the breaker")
+	}
+	if got := b.trips.Load(); got != 2 {
+		t.Fatalf("trips = %d, want 2", got)
+	}
+}
+
+func TestCircuitBreakerLateFailureKeepsCooldown(t *testing.T) {
+	fake := useFakeClock(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := newCircuitBreaker(1, time.Minute)
+	b.Failure()
+	fake.Advance(50 * time.Second)
+	b.Failure() // Request lama yang gagal setelah breaker terbuka
+	fake.Advance(10 * time.Second)
+	if allowed, _ := b.Allow(); !allowed {
+		t.Fatal("late failure extended the cooldown")
+	}
+}
+
+func TestCircuitBreakerIgnoresLateSuccessWhileOpen(t *testing.T) {
+	useFakeClock(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := newCircuitBreaker(1, time.Minute)
+	b.Failure()
+	b.Success() // Request lama yang berhasil setelah breaker terbuka
+	if allowed, _ := b.Allow(); b.State() != breakerOpen || allowed {
+		t.Fatal("late success closed an open breaker")
+	}
+}
+
+func TestBreakerMiddlewareReleasesOnlyTheProbe(t *testing.T) {
+	fake := useFakeClock(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	saved := dbBreaker
+	dbBreaker = newCircuitBreaker(1, time.Minute)
+	t.Cleanup(func() { dbBreaker = saved })
+
+	slowStarted, finishSlow := make(chan struct{}), make(chan struct{})
+	probeStarted, finishProbe := make(chan struct{}), make(chan struct{})
+	handler := breakerMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			close(slowStarted)
+			<-finishSlow
+		case "/probe":
+			close(probeStarted)
+			<-finishProbe
+		}
+	}))
+	slowDone := make(chan struct{})
+	go func() {
+		defer close(slowDone)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil)) // Diizinkan saat breaker masih tertutup
+	}()
+	<-slowStarted
+	dbBreaker.Failure()
+	fake.Advance(time.Minute)
+
+	probeDone := make(chan struct{})
+	go func() {
+		defer close(probeDone)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/probe", nil))
+	}()
+	<-probeStarted
+	close(finishSlow) // Request lama selesai saat percobaan masih berjalan dan tidak boleh melepas slot percobaan
+	<-slowDone
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/other", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("request during the probe: %d, want 503", rec.Code)
+	}
+	close(finishProbe)
+	<-probeDone
+	if dbBreaker.State() != breakerHalfOpen {
+		t.Fatalf("state after probe without database access: %s", dbBreaker.State())
+	}
+	if allowed, probe := dbBreaker.Allow(); !allowed || !probe {
+		t.Fatal("probe slot not released after the probe finished")
+	}
+}
+
+func TestIsConnectionError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{gorm.ErrRecordNotFound, false},
+		{context.Canceled, false},
+		{fmt.Errorf("query: %w", context.DeadlineExceeded), false},
+		{driver.ErrBadConn, true},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+	}
+	for _, tt := range tests {
+		if got := isConnectionError(tt.err); got != tt.want {
+			t.Errorf("isConnectionError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestBreakerMiddlewareShortCircuits(t *testing.T) {
+	saved := dbBreaker
+	dbBreaker = newCircuitBreaker(1, 30*time.Second)
+	t.Cleanup(func() { dbBreaker = saved })
+	dbBreaker.Failure()
+
+	rec := serve(httptest.NewRequest(http.MethodGet, "/retur/next-id", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "30" {
+		t.Fatalf("Retry-After = %q, want 30", got)
+	}
+}
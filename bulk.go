@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// bulkDeleteReturHandler adalah handler untuk menghapus semua retur yang cocok dengan filter status dan/atau before
+// Minimal satu filter wajib diisi, dan penghapusan hanya dijalankan jika confirm=true (selain itu hanya dry-run)
+// Alasan penghapusan bisa dikirim lewat ?reason dan ditampilkan di daftar retur yang dihapus
+// Jika ada retur yang baru disetujui (RETUR_DELETE_GRACE), seluruh penghapusan ditolak dengan 409
+// Retur di-soft delete (lihat softdelete.go) dan dipulihkan lewat POST /retur/undo selama entry undo belum dibuang
+// (RETUR_MAX_UNDO_AGE); setelah itu baris dibuang permanen, karena itu endpoint ini khusus admin
+func bulkDeleteReturHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	status := query.Get("status")
+	before := query.Get("before")
+	reason := strings.TrimSpace(query.Get("reason")) // Alasan penghapusan (opsional), disimpan di entry undo
+	if status == "" && before == "" {
+		handleError(w, http.StatusBadRequest, "At least one filter (status, before) is required") // Tolak penghapusan tanpa filter
+		return
+	}
+	if status != "" && !validStatus(status) {
+		handleError(w, http.StatusBadRequest, fmt.Sprintf("Unknown status '%s'", status)) // Salah ketik status tidak boleh dianggap "0 retur dihapus"
+		return
+	}
+
+	var beforeTime time.Time
+	if before != "" {
+		var err error
+		if beforeTime, err = parseTimeParam(before); err != nil {
+			handleError(w, http.StatusBadRequest, "Invalid before, use YYYY-MM-DD or RFC3339") // Format waktu tidak valid
+			return
+		}
+	}
+	filter := func(q *gorm.DB) *gorm.DB {
+		if status != "" {
+			q = q.Where("status = ?", status) // Filter berdasarkan status
+		}
+		if before != "" {
+			q = q.Where("created_at < ?", beforeTime) // Filter retur yang dibuat sebelum waktu tertentu
+		}
+		return q
+	}
+
+	if query.Get("confirm") != "true" {
+		var count int64
+		if err := db.WithContext(r.Context()).Model(&Retur{}).Scopes(filter).Count(&count).Error; err != nil {
+			handleError(w, http.StatusInternalServerError, "Failed to count returns") // Jika gagal menghitung, kirimkan error
+			return
+		}
+		respondJSON(w, http.StatusOK, map[string]interface{}{"dry_run": true, "count": count}) // Hanya laporkan jumlah yang akan dihapus
+		return
+	}
+
+	updateUndoShedding()
+	var returs []Retur
+	var entry undoEntry
+	var blocked []string
+	var skipped bool
+	err := withTransaction(r.Context(), func(tx *gorm.DB) error {
+		if err := tx.Preload("Items").Scopes(filter).Find(&returs).Error; err != nil {
+			return err
+		}
+		if len(returs) == 0 {
+			return nil // Tidak ada yang perlu dihapus
+		}
+		if blocked = deleteGraceBlocked(r, returs); len(blocked) > 0 {
+			return errDeleteGrace // Seluruh batch dibatalkan agar tidak ada retur yang baru disetujui ikut terhapus
+		}
+		entry = undoEntry{Returs: returs, DeletedAt: clock.Now(), Reason: reason}
+		for _, retur := range returs {
+			if err := recordAudit(tx, "delete", actorFromRequest(r), retur, Retur{}); err != nil {
+				return err
+			}
+		}
+		if err := softDeleteReturs(tx, entryReturIDs(entry), entry.DeletedAt); err != nil {
+			return err // Soft delete semua retur yang cocok dalam satu transaksi; item ikut tersembunyi bersama induknya
+		}
+		var err error
+		if skipped, err = skipUndo(tx, entry); err != nil || skipped {
+			return err // Load shedding (RETUR_UNDO_SHED_LOCK_WAIT): batch ini tidak bisa di-undo
+		}
+		return persistUndoEntry(tx, &entry) // Snapshot undo ikut tersimpan agar tetap bisa di-undo setelah restart
+	})
+	if errors.Is(err, errDeleteGrace) {
+		respondDeleteGrace(w, blocked)
+		return
+	}
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to delete returns") // Jika gagal menghapus, kirimkan error
+		return
+	}
+
+	if len(returs) > 0 {
+		if skipped {
+			logSkippedUndo(entry) // ID sudah ber-tombstone dan tidak masuk pool reuse
+		} else {
+			for _, retur := range returs {
+				addDeletedID(retur.ID) // Simpan ID yang dihapus untuk reuse
+			}
+			deletedStack.Push(entry) // Push sebagai satu grup agar bisa di-undo sekaligus
+		}
+		for _, retur := range returs {
+			publishEvent(ReturDeleted, retur, r)
+		}
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"dry_run": false, "count": len(returs)})
+}
+
+// parseIDList mengubah daftar ID dipisahkan koma (misalnya "1,2,3") menjadi slice integer
+func parseIDList(raw string) ([]int, error) {
+	var ids []int
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id '%s'", part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// batchGetReturHandler adalah handler untuk mengambil beberapa retur sekaligus berdasarkan daftar ID
+// ID bisa dikirim lewat query (GET ?ids=1,2,3) atau body JSON (POST {"ids":[1,2,3]})
+// Response berisi retur yang ditemukan serta daftar ID yang tidak ditemukan
+func batchGetReturHandler(w http.ResponseWriter, r *http.Request) {
+	var ids []int
+	if r.Method == http.MethodPost {
+		var input struct {
+			IDs []int `json:"ids"` // Daftar ID retur yang ingin diambil
+		}
+		if !decodeJSON(w, r, &input) {
+			return // Body kosong atau JSON tidak valid, error sudah dikirim
+		}
+		ids = input.IDs
+	} else {
+		var err error
+		if ids, err = parseIDList(r.URL.Query().Get("ids")); err != nil {
+			handleError(w, http.StatusBadRequest, err.Error()) // Format daftar ID tidak valid
+			return
+		}
+	}
+	if len(ids) == 0 {
+		handleError(w, http.StatusBadRequest, "At least one id is required") // Daftar ID tidak boleh kosong
+		return
+	}
+	if len(ids) > cfg.BatchMaxIDs {
+		handleError(w, http.StatusBadRequest, fmt.Sprintf("At most %d ids per request", cfg.BatchMaxIDs)) // Batasi jumlah ID per request
+		return
+	}
+
+	returs := []Retur{}
+	if err := db.WithContext(r.Context()).Where("id IN ?", ids).Order("id").Find(&returs).Error; err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to retrieve returns") // Jika query gagal, kirimkan error
+		return
+	}
+	found := make(map[int]bool, len(returs))
+	for i := range returs {
+		applySLA(&returs[i]) // Hitung sla_deadline dan overdue
+		found[returs[i].ID] = true
+	}
+	maskReturs(r, returs)
+	notFound := []int{}
+	for _, id := range ids {
+		if !found[id] {
+			notFound = append(notFound, id) // ID yang tidak ada di database
+			found[id] = true                // Hindari duplikat pada daftar not_found
+		}
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"returs": returs, "not_found": notFound})
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// errApprovalConflict menandai retur yang statusnya berubah di antara pembacaan dan penyimpanan
+var errApprovalConflict = errors.New("status changed concurrently")
+
+// skippedApproval adalah retur yang dilewati oleh bulk approve beserta alasannya
+type skippedApproval struct {
+	ID     int    `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// bulkApproveHandler adalah handler untuk menyetujui banyak retur sekaligus dengan keputusan yang sama
+// Setiap retur disimpan dalam transaksi sendiri dan hanya jika masih "Dalam Proses", sehingga satu retur yang
+// berubah bersamaan (atau sudah diputuskan, tidak ditemukan) tidak menggagalkan seluruh batch
+// Response berisi ID yang disetujui dan ID yang dilewati beserta alasannya
+func bulkApproveHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		IDs          []int  `json:"ids"`           // Daftar ID retur yang disetujui
+		Pengembalian string `json:"pengembalian"`  // Jenis pengembalian untuk semua retur (barang/uang)
+		RefundAmount int64  `json:"refund_amount"` // Jumlah refund per retur dalam minor units (khusus uang)
+		Currency     string `json:"currency"`      // Kode mata uang refund, default dari konfigurasi
+		Note         string `json:"note"`          // Catatan opsional untuk persetujuan
+	}
+	if !decodeJSON(w, r, &input) {
+		return // Body kosong atau JSON tidak valid, error sudah dikirim
+	}
+	if len(input.IDs) == 0 {
+		handleError(w, http.StatusBadRequest, "At least one id is required") // Daftar ID tidak boleh kosong
+		return
+	}
+	if len(input.IDs) > cfg.BatchMaxIDs {
+		handleError(w, http.StatusBadRequest, fmt.Sprintf("At most %d ids per request", cfg.BatchMaxIDs)) // Batasi jumlah ID per request
+		return
+	}
+	if !validPengembalian(input.Pengembalian) {
+		handleError(w, http.StatusBadRequest, "Pengembalian must be 'barang' or 'uang'") // Validasi nilai pengembalian
+		return
+	}
+	input.Note = strings.TrimSpace(input.Note)
+	if utf8.RuneCountInString(input.Note) > maxDecisionNoteLength {
+		handleError(w, http.StatusBadRequest, fmt.Sprintf("note must be at most %d characters", maxDecisionNoteLength)) // Catatan terlalu panjang
+		return
+	}
+	var currency string
+	if input.Pengembalian == "uang" {
+		if input.RefundAmount < 0 {
+			handleError(w, http.StatusBadRequest, "refund_amount must not be negative") // Jumlah refund tidak boleh negatif
+			return
+		}
+		var ok bool
+		if currency, ok = refundCurrency(input.Currency); !ok {
+			handleError(w, http.StatusBadRequest, fmt.Sprintf("Unknown currency code '%s'", input.Currency)) // Kode mata uang tidak dikenal
+			return
+		}
+	} else {
+		input.RefundAmount = 0 // Pengembalian barang tidak memiliki nilai refund
+	}
+
+	var returs []Retur
+	if err := db.WithContext(r.Context()).Where("id IN ?", input.IDs).Find(&returs).Error; err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to retrieve returns") // Jika query gagal, kirimkan error
+		return
+	}
+	byID := make(map[int]Retur, len(returs))
+	for _, retur := range returs {
+		byID[retur.ID] = retur
+	}
+
+	actor := approverFromRequest(r)
+	approved := []int{}
+	skipped := []skippedApproval{}
+	seen := make(map[int]bool, len(input.IDs))
+	for _, id := range input.IDs {
+		if seen[id] {
+			continue // ID duplikat hanya diproses sekali
+		}
+		seen[id] = true
+		retur, ok := byID[id]
+		switch {
+		case !ok:
+			skipped = append(skipped, skippedApproval{ID: id, Reason: "not found"})
+			continue
+		case retur.Status != "Dalam Proses":
+			skipped = append(skipped, skippedApproval{ID: id, Reason: "already decided (" + retur.Status + ")"})
+			continue
+		case requiresSecondApproval(input.Pengembalian, input.RefundAmount):
+			skipped = append(skipped, skippedApproval{ID: id, Reason: "requires second approval"}) // Refund bernilai tinggi harus disetujui satu per satu
+			continue
+		}
+		if errs := validateDetails(input.Pengembalian, retur.Details); len(errs) > 0 {
+			skipped = append(skipped, skippedApproval{ID: id, Reason: errs[0].Field + " " + errs[0].Message}) // Field sudah diawali "details."
+			continue
+		}
+
+		before := retur
+		retur.Pengembalian = input.Pengembalian
+		retur.RefundAmount = input.RefundAmount
+		retur.Currency = currency
+		retur.Status = "Disetujui"
+		retur.DecisionNote = input.Note
+		markDecided(&retur, r)
+		err := withTransaction(r.Context(), func(tx *gorm.DB) error {
+			result := tx.Model(&retur).Select("*").Omit("Items").Where("status = ?", "Dalam Proses").Updates(&retur)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errApprovalConflict // Retur sudah diputuskan oleh request lain setelah dibaca
+			}
+			if err := recordAudit(tx, "update", actor, before, retur); err != nil {
+				return err
+			}
+			return tx.Create(&ReturHistory{ReturID: retur.ID, FromStatus: before.Status, ToStatus: retur.Status, Actor: actor, Note: retur.DecisionNote}).Error
+		})
+		var invariant *invariantError
+		switch {
+		case errors.Is(err, errApprovalConflict):
+			skipped = append(skipped, skippedApproval{ID: id, Reason: err.Error()})
+			continue
+		case errors.As(err, &invariant):
+			skipped = append(skipped, skippedApproval{ID: id, Reason: invariant.Error()})
+			continue
+		case err != nil:
+			log.Printf("bulk approve retur=%d failed: %v", id, err)
+			skipped = append(skipped, skippedApproval{ID: id, Reason: "failed to save"})
+			continue
+		}
+		approved = append(approved, id)
+		publishEvent(ReturApproved, retur, r)
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"approved": approved, "skipped": skipped})
+}
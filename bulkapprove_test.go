@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBulkApproveValidatesInput(t *testing.T) {
+	useApprovers(t, map[string]string{"tok-budi": "budi"})
+	withConfig(t, func(c *Config) { c.BatchMaxIDs = 2 })
+	tests := []struct {
+		name    string
+		payload map[string]interface{}
+	}{
+		{"no ids", map[string]interface{}{"ids": []int{}, "pengembalian": "barang"}},
+		{"too many ids", map[string]interface{}{"ids": []int{1, 2, 3}, "pengembalian": "barang"}},
+		{"bad pengembalian", map[string]interface{}{"ids": []int{1}, "pengembalian": "voucher"}},
+		{"note too long", map[string]interface{}{"ids": []int{1}, "pengembalian": "barang", "note": strings.Repeat("é", maxDecisionNoteLength+1)}},
+		{"negative refund", map[string]interface{}{"ids": []int{1}, "pengembalian": "uang", "refund_amount": -1}},
+		{"unknown currency", map[string]interface{}{"ids": []int{1}, "pengembalian": "uang", "currency": "XXX"}},
+	}
+	for _, tt := range tests {
+		rec := serve(asApprover(jsonRequest(t, http.MethodPost, "/retur/approve", tt.payload), "tok-budi"))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: %d %s", tt.name, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestBulkApproveSkipsConflicts(t *testing.T) {
+	testDB(t)
+	useApprovers(t, map[string]string{"tok-budi": "budi"})
+	withConfig(t, func(c *Config) { c.DetailsPolicy = map[string][]string{"barang": {"alamat"}} })
+	seeded := seedReturs(t,
+		Retur{Barang: "A", Alasan: "x", Details: map[string]string{"alamat": "Jl. Sudirman"}},
+		Retur{Barang: "B", Alasan: "x", Status: "Tidak Disetujui"},
+		Retur{Barang: "C", Alasan: "x"}, // Details belum lengkap
+	)
+	ids := []int{seeded[0].ID, seeded[1].ID, seeded[2].ID, 9999, seeded[0].ID}
+	rec := serve(asApprover(jsonRequest(t, http.MethodPost, "/retur/approve", map[string]interface{}{"ids": ids, "pengembalian": "barang"}), "tok-budi"))
+	var body struct {
+		Approved []int             `json:"approved"`
+		Skipped  []skippedApproval `json:"skipped"`
+	}
+	decodeBody(t, rec, &body)
+	if rec.Code != http.StatusOK || !slices.Equal(body.Approved, []int{seeded[0].ID}) || len(body.Skipped) != 3 {
+		t.Fatalf("bulk approve: %d %+v", rec.Code, body)
+	}
+	reasons := map[int]string{}
+	for _, skip := range body.Skipped {
+		reasons[skip.ID] = skip.Reason
+	}
+	if reasons[seeded[1].ID] != "already decided (Tidak Disetujui)" || reasons[9999] != "not found" || reasons[seeded[2].ID] != "details.alamat is required when pengembalian is 'barang'" {
+		t.Fatalf("skip reasons = %v", reasons)
+	}
+	var stored Retur
+	db.First(&stored, seeded[0].ID)
+	if stored.Status != "Disetujui" || stored.DecidedBy != "budi" {
+		t.Fatalf("stored = %+v", stored)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// catalogKey menormalkan nama barang untuk dibandingkan: huruf kecil, hanya huruf dan angka ("iPhone 13" menjadi "iphone13")
+func catalogKey(barang string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(barang) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// levenshtein menghitung jumlah minimum sisip/hapus/ganti karakter untuk mengubah a menjadi b
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// catalogSimilarity mengembalikan kemiripan dua key katalog dalam persen (100 berarti sama persis)
+func catalogSimilarity(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 0
+	}
+	return 100 - levenshtein(ra, rb)*100/longest
+}
+
+// canonicalBarang mencocokkan Barang dengan katalog produk (RETUR_BARANG_CATALOG) dan mengembalikan nama kanonik
+// jika kemiripan terbaik mencapai RETUR_BARANG_MATCH_THRESHOLD persen; kosong jika katalog tidak diatur atau tidak ada yang cukup mirip
+func canonicalBarang(barang string) string {
+	key := catalogKey(barang)
+	if len(cfg.BarangCatalog) == 0 || key == "" {
+		return ""
+	}
+	best, bestScore := "", 0
+	for _, product := range cfg.BarangCatalog {
+		score := catalogSimilarity(key, catalogKey(product))
+		if score > bestScore {
+			best, bestScore = product, score
+		}
+	}
+	if bestScore < cfg.BarangMatchThreshold {
+		return ""
+	}
+	return best
+}
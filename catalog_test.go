@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestCatalogKeyAndSimilarity(t *testing.T) {
+	if got := catalogKey(" iPhone 13 - Pro!"); got != "iphone13pro" {
+		t.Fatalf("catalogKey = %q", got)
+	}
+	if got := levenshtein([]rune("kitten"), []rune("sitting")); got != 3 {
+		t.Fatalf("levenshtein = %d", got)
+	}
+	if catalogSimilarity("abc", "abc") != 100 || catalogSimilarity("", "") != 0 || catalogSimilarity("abcd", "abce") != 75 {
+		t.Fatal("unexpected similarity")
+	}
+}
+
+func TestCanonicalBarangMatchesCatalog(t *testing.T) {
+	withConfig(t, func(c *Config) {
+		c.BarangCatalog = []string{"iPhone 13", "Samsung Galaxy S21", "Kemeja Batik"}
+		c.BarangMatchThreshold = 80
+	})
+	tests := []struct {
+		barang, want string
+	}{
+		{"iphone13", "iPhone 13"},
+		{"IPHONE 13!", "iPhone 13"},
+		{"samsung galaxy s 21", "Samsung Galaxy S21"},
+		{"kemeja batk", "Kemeja Batik"}, // Salah ketik kecil masih cocok
+		{"Sepatu", ""},
+		{"!!!", ""},
+	}
+	for _, tt := range tests {
+		if got := canonicalBarang(tt.barang); got != tt.want {
+			t.Errorf("canonicalBarang(%q) = %q, want %q", tt.barang, got, tt.want)
+		}
+	}
+	cfg.BarangCatalog = nil
+	if got := canonicalBarang("iPhone 13"); got != "" {
+		t.Errorf("empty catalog matched %q", got)
+	}
+}
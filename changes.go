@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// returChange adalah satu entri change feed: retur terbaru untuk ID tersebut, atau tombstone jika retur sudah dihapus
+type returChange struct {
+	Seq     uint   `json:"seq"`             // Nomor urut perubahan (ID audit log), naik terus
+	ID      int    `json:"id"`              // ID retur yang berubah
+	Deleted bool   `json:"deleted"`         // Bernilai true jika retur sudah dihapus dan harus dibuang oleh client
+	Retur   *Retur `json:"retur,omitempty"` // Data retur saat ini, kosong untuk tombstone
+}
+
+// changesHandler adalah handler untuk mengambil retur yang berubah setelah seq tertentu (?since=<seq>)
+// Seq diambil dari audit log yang ditulis dalam transaksi yang sama dengan setiap perubahan, sehingga create, update,
+// delete, dan restore semuanya tercatat; beberapa perubahan pada retur yang sama dalam satu batch digabung menjadi satu entri
+// Client menyimpan next_since dari response dan mengirimkannya kembali pada polling berikutnya
+// ID audit log dialokasikan saat insert, bukan saat commit: transaksi dengan ID kecil bisa commit setelah ID yang lebih
+// besar sudah terlihat. Karena itu hanya entri yang lebih tua dari RETUR_CHANGES_SAFETY_LAG yang dikirim, agar
+// transaksi yang masih berjalan sempat commit sebelum client melompati ID-nya
+// Dengan ?envelope=true (selalu di bawah /v1), perubahan dikirim sebagai data dan next_since ada di meta; total dan
+// halaman dihitung dari entri audit setelah since, sehingga page selalu 1 dan has_prev berarti since bukan 0
+func changesHandler(w http.ResponseWriter, r *http.Request) {
+	since, err := queryInt(r, "since", 0)
+	if err != nil {
+		handleError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := queryInt(r, "limit", cfg.ChangesMaxBatch)
+	if err != nil {
+		handleError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if limit == 0 || limit > cfg.ChangesMaxBatch {
+		limit = cfg.ChangesMaxBatch // Batasi jumlah perubahan per polling
+	}
+
+	var logs []AuditLog
+	query := db.WithContext(r.Context()).Model(&AuditLog{}).Where("entity = ? AND id > ? AND at <= ?", "retur", since, clock.Now().Add(-cfg.ChangesSafetyLag))
+	if err := query.Session(&gorm.Session{}).Select("id", "entity_id", "action").Order("id").Limit(limit + 1).Find(&logs).Error; err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to retrieve changes")
+		return
+	}
+	hasMore := len(logs) > limit
+	if hasMore {
+		logs = logs[:limit]
+	}
+
+	latest := make(map[int]AuditLog) // Perubahan terakhir per retur di batch ini
+	for _, entry := range logs {
+		latest[entry.EntityID] = entry
+	}
+	ids := make([]int, 0, len(latest))
+	for id, entry := range latest {
+		if entry.Action != "delete" {
+			ids = append(ids, id)
+		}
+	}
+	current := make(map[int]*Retur, len(ids))
+	if len(ids) > 0 {
+		var returs []Retur
+		if err := db.WithContext(r.Context()).Where("id IN ?", ids).Find(&returs).Error; err != nil {
+			handleError(w, http.StatusInternalServerError, "Failed to retrieve changes")
+			return
+		}
+		maskReturs(r, returs)
+		for i := range returs {
+			applySLA(&returs[i])
+			current[returs[i].ID] = &returs[i]
+		}
+	}
+
+	changes := make([]returChange, 0, len(latest))
+	for id, entry := range latest {
+		change := returChange{Seq: entry.ID, ID: id, Retur: current[id]}
+		change.Deleted = change.Retur == nil // Retur yang tidak ditemukan sudah dihapus setelah perubahan ini
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Seq < changes[j].Seq }) // Seq terbaru di akhir
+
+	nextSince := uint(since)
+	if len(logs) > 0 {
+		nextSince = logs[len(logs)-1].ID
+	}
+	if wantsEnvelope(r) {
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			handleError(w, http.StatusInternalServerError, "Failed to count changes")
+			return
+		}
+		meta := newPageMeta(0, limit, total)
+		meta.HasNext, meta.HasPrev, meta.NextSince = hasMore, since > 0, &nextSince
+		respondEnvelope(w, changes, meta)
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"changes": changes, "next_since": nextSince, "has_more": hasMore})
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestChangeFeedFollowsCreateUpdateDelete(t *testing.T) {
+	testDB(t)
+	withConfig(t, func(c *Config) { c.ChangesSafetyLag = time.Second })
+	fake := useFakeClock(t, time.Now())
+	type feed struct {
+		Changes   []returChange `json:"changes"`
+		NextSince uint          `json:"next_since"`
+		HasMore   bool          `json:"has_more"`
+	}
+	poll := func(query string) feed {
+		t.Helper()
+		rec := serve(httptest.NewRequest(http.MethodGet, "/retur/changes?"+query, nil))
+		var body feed
+		decodeBody(t, rec, &body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("changes %s: %d %s", query, rec.Code, rec.Body)
+		}
+		return body
+	}
+
+	a := createViaAPI(t, map[string]interface{}{"barang": "Kipas", "alasan": "Mati"})
+	b := createViaAPI(t, map[string]interface{}{"barang": "Setrika", "alasan": "Tidak panas"})
+	if got := poll("since=0"); len(got.Changes) != 0 {
+		t.Fatalf("changes inside RETUR_CHANGES_SAFETY_LAG returned: %+v", got.Changes)
+	}
+	fake.Advance(time.Minute)
+	if got := poll("since=0&limit=1"); len(got.Changes) != 1 || got.Changes[0].ID != a.ID || !got.HasMore {
+		t.Fatalf("limited feed = %+v", got)
+	}
+	created := poll("since=0")
+	if len(created.Changes) != 2 || created.Changes[0].ID != a.ID || created.Changes[1].ID != b.ID || created.HasMore ||
+		created.Changes[0].Seq >= created.Changes[1].Seq || created.NextSince != created.Changes[1].Seq {
+		t.Fatalf("created feed = %+v", created)
+	}
+
+	if rec := serve(jsonRequest(t, http.MethodPost, "/retur/assign", map[string]interface{}{"ids": []int{a.ID}, "agent": "budi"})); rec.Code != http.StatusOK {
+		t.Fatalf("assign: %d %s", rec.Code, rec.Body)
+	}
+	fake.Advance(time.Minute)
+	updated := poll(fmt.Sprintf("since=%d", created.NextSince))
+	if len(updated.Changes) != 1 || updated.Changes[0].ID != a.ID || updated.Changes[0].Deleted || updated.Changes[0].Retur == nil ||
+		updated.Changes[0].Retur.AssignedTo != "budi" || updated.NextSince <= created.NextSince {
+		t.Fatalf("updated feed = %+v", updated)
+	}
+
+	if rec := serve(httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/retur/%d/delete", b.ID), nil)); rec.Code != http.StatusOK {
+		t.Fatalf("delete: %d %s", rec.Code, rec.Body)
+	}
+	fake.Advance(time.Minute)
+	deleted := poll(fmt.Sprintf("since=%d", updated.NextSince))
+	if len(deleted.Changes) != 1 || deleted.Changes[0].ID != b.ID || !deleted.Changes[0].Deleted || deleted.Changes[0].Retur != nil {
+		t.Fatalf("deleted feed = %+v", deleted)
+	}
+
+	if idle := poll(fmt.Sprintf("since=%d", deleted.NextSince)); len(idle.Changes) != 0 || idle.NextSince != deleted.NextSince {
+		t.Fatalf("idle feed = %+v, want no changes and the same next_since", idle)
+	}
+	if merged := poll("since=0"); len(merged.Changes) != 2 || merged.Changes[0].ID != a.ID || !merged.Changes[1].Deleted {
+		t.Fatalf("full feed = %+v, want the latest change per retur", merged)
+	}
+}
+
+func TestChangeFeedRejectsInvalidSince(t *testing.T) {
+	for _, query := range []string{"since=-1", "since=abc", "limit=x"} {
+		if rec := serve(httptest.NewRequest(http.MethodGet, "/retur/changes?"+query, nil)); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: %d, want 400", query, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// parseCIDRList membaca daftar CIDR dipisahkan koma; alamat IP tunggal dianggap /32 (IPv4) atau /128 (IPv6)
+func parseCIDRList(raw string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			if ip := net.ParseIP(item); ip != nil && ip.To4() != nil {
+				item += "/32"
+			} else {
+				item += "/128"
+			}
+		}
+		if _, network, err := net.ParseCIDR(item); err == nil {
+			nets = append(nets, network)
+		}
+	}
+	return nets
+}
+
+// ipInNets memeriksa apakah ip termasuk salah satu jaringan
+func ipInNets(ip net.IP, nets []*net.IPNet) bool {
+	for _, network := range nets {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP menentukan IP asli client
+// X-Forwarded-For hanya dipercaya jika koneksi datang dari proxy di RETUR_TRUSTED_PROXIES; daftar dibaca dari kanan
+// dan IP pertama yang bukan proxy tepercaya dianggap sebagai client
+func clientIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !ipInNets(ip, cfg.TrustedProxies) {
+		return ip // Koneksi langsung, header forwarding diabaikan
+	}
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break // Nilai rusak, berhenti di hop tepercaya terakhir
+		}
+		ip = hop
+		if !ipInNets(hop, cfg.TrustedProxies) {
+			break
+		}
+	}
+	return ip
+}
+
+// adminIPAllowed memeriksa IP client terhadap RETUR_ADMIN_ALLOWLIST; daftar kosong berarti semua IP diizinkan
+func adminIPAllowed(r *http.Request) bool {
+	if len(cfg.AdminAllowlist) == 0 {
+		return true
+	}
+	ip := clientIP(r)
+	return ip != nil && ipInNets(ip, cfg.AdminAllowlist)
+}
+
+// cidrStrings mengubah daftar jaringan menjadi teks CIDR untuk ditampilkan
+func cidrStrings(nets []*net.IPNet) []string {
+	result := make([]string, 0, len(nets))
+	for _, network := range nets {
+		result = append(result, network.String())
+	}
+	return result
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIPTrustsOnlyConfiguredProxies(t *testing.T) {
+	withConfig(t, func(c *Config) { c.TrustedProxies = parseCIDRList("10.0.0.0/8") })
+	tests := []struct {
+		remote, forwarded, want string
+	}{
+		{"203.0.113.9:4000", "198.51.100.1", "203.0.113.9"},                  // Bukan dari proxy, header diabaikan
+		{"10.0.0.1:4000", "198.51.100.1", "198.51.100.1"},                    // Satu hop proxy
+		{"10.0.0.1:4000", "6.6.6.6, 198.51.100.1, 10.0.0.2", "198.51.100.1"}, // Nilai paling kiri bisa dipalsukan client
+		{"10.0.0.1:4000", "garbage", "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tt.remote
+		req.Header.Set("X-Forwarded-For", tt.forwarded)
+		if got := clientIP(req); !got.Equal(net.ParseIP(tt.want)) {
+			t.Errorf("clientIP(%s, %q) = %s, want %s", tt.remote, tt.forwarded, got, tt.want)
+		}
+	}
+}
+
+func TestParseCIDRListAcceptsBareIPs(t *testing.T) {
+	nets := parseCIDRList(" 192.168.1.5, 2001:db8::1 ,bogus,10.0.0.0/8")
+	if got := cidrStrings(nets); len(got) != 3 || got[0] != "192.168.1.5/32" || got[1] != "2001:db8::1/128" || got[2] != "10.0.0.0/8" {
+		t.Fatalf("parseCIDRList = %v", got)
+	}
+}
+
+func TestAdminAllowlistIsCheckedBeforeToken(t *testing.T) {
+	withConfig(t, func(c *Config) {
+		c.AdminToken = "admin-secret"
+		c.AdminAllowlist = parseCIDRList("192.0.2.0/24")
+	})
+	req := httptest.NewRequest(http.MethodGet, "/config", nil)
+	req.RemoteAddr = "203.0.113.7:5000"
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	if rec := serve(req); rec.Code != http.StatusForbidden {
+		t.Fatalf("admin request from outside allowlist: status %d, want 403", rec.Code)
+	}
+	if isAdmin(req) {
+		t.Fatal("isAdmin must honour RETUR_ADMIN_ALLOWLIST")
+	}
+
+	req.RemoteAddr = "192.0.2.10:5000"
+	if !isAdmin(req) {
+		t.Fatal("isAdmin rejected an allowed address with a valid token")
+	}
+	req.Header.Set("Authorization", "Bearer wrong")
+	if rec := serve(req); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong token: status %d, want 401", rec.Code)
+	}
+}
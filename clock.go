@@ -0,0 +1,20 @@
+package main
+
+import "time"
+
+// Clock adalah sumber waktu aplikasi; semua timestamp dan batas waktu membaca waktu dari sini
+// sehingga logika berbasis waktu bisa diuji secara deterministik dengan fakeClock (clock_test.go)
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock adalah Clock yang membaca waktu sistem
+type realClock struct{}
+
+// Now mengembalikan waktu sistem saat ini
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// clock adalah sumber waktu aktif; default memakai waktu sistem
+var clock Clock = realClock{}
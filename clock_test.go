@@ -0,0 +1,71 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock adalah Clock dengan waktu yang diatur manual, untuk pengujian tanpa sleep
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// Now mengembalikan waktu yang sedang diatur pada fakeClock
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance memajukan waktu fakeClock sebesar d
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// useFakeClock memasang fakeClock sebagai clock aktif selama satu test
+func useFakeClock(t *testing.T, now time.Time) *fakeClock {
+	t.Helper()
+	fake := &fakeClock{now: now}
+	saved := clock
+	clock = fake
+	t.Cleanup(func() { clock = saved })
+	return fake
+}
+
+func TestUndoEntryExpiresAfterMaxUndoAge(t *testing.T) {
+	withConfig(t, func(c *Config) { c.MaxUndoAge = time.Hour })
+	fake := useFakeClock(t, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	entry := undoEntry{DeletedAt: fake.Now()}
+
+	fake.Advance(59 * time.Minute)
+	if entry.expired(fake.Now()) {
+		t.Fatal("entry expired before RETUR_MAX_UNDO_AGE")
+	}
+	fake.Advance(2 * time.Minute)
+	if !entry.expired(fake.Now()) {
+		t.Fatal("entry not expired after RETUR_MAX_UNDO_AGE")
+	}
+	if (undoEntry{}).expired(fake.Now()) {
+		t.Fatal("entry without deleted_at must never expire")
+	}
+}
+
+func TestApplySLAOverdueFollowsClock(t *testing.T) {
+	withConfig(t, func(c *Config) { c.SLA = map[string]time.Duration{"Dalam Proses": 48 * time.Hour} })
+	fake := useFakeClock(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	retur := Retur{Status: "Dalam Proses", CreatedAt: fake.Now()}
+
+	applySLA(&retur)
+	if retur.Overdue || retur.SLADeadline == nil || !retur.SLADeadline.Equal(fake.Now().Add(48*time.Hour)) {
+		t.Fatalf("fresh retur: overdue=%v deadline=%v", retur.Overdue, retur.SLADeadline)
+	}
+	fake.Advance(49 * time.Hour)
+	applySLA(&retur)
+	if !retur.Overdue {
+		t.Fatal("retur not overdue after SLA deadline")
+	}
+}
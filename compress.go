@@ -0,0 +1,149 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gorilla/mux"
+)
+
+// compressionEncoders adalah encoding response yang didukung beserta pembuat encoder-nya
+var compressionEncoders = map[string]func(io.Writer) io.WriteCloser{
+	"br":   func(w io.Writer) io.WriteCloser { return brotli.NewWriterLevel(w, brotli.DefaultCompression) },
+	"gzip": func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+}
+
+// compressionExemptRoutes adalah route yang tidak dikompres: download lampiran dan hasil ekspor memakai Range (206) pada byte asli
+var compressionExemptRoutes = map[string]bool{
+	"downloadAttachment": true,
+	"downloadExport":     true,
+}
+
+// parseCompressionEncodings membaca RETUR_COMPRESSION: encoding yang diaktifkan sesuai urutan preferensi server
+// Encoding tidak dikenal diabaikan; "identity" atau daftar kosong berarti kompresi nonaktif
+func parseCompressionEncodings(names []string) []string {
+	var result []string
+	for _, name := range names {
+		name = strings.ToLower(name)
+		if _, ok := compressionEncoders[name]; ok {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// negotiateEncoding memilih encoding dari Accept-Encoding berdasarkan nilai q tertinggi; jika q sama, urutan
+// RETUR_COMPRESSION yang menentukan. Encoding dengan q=0 ditolak, dan "*" berlaku untuk encoding yang tidak disebut
+// Mengembalikan string kosong jika tidak ada encoding yang cocok (response dikirim tanpa kompresi)
+func negotiateEncoding(header string, enabled []string) string {
+	if header == "" || len(enabled) == 0 {
+		return ""
+	}
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		if key, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(key) == "q" {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				continue // Nilai q tidak valid, abaikan encoding ini
+			}
+			q = parsed
+		}
+		qualities[name] = q
+	}
+	best, bestQ := "", 0.0
+	for _, name := range enabled {
+		q, ok := qualities[name]
+		if !ok {
+			q, ok = qualities["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// compressWriter mengompres body response dengan encoding yang dinegosiasikan
+// Encoder baru dibuat saat header dikirim, sehingga response tanpa body (204, 304) atau yang sudah
+// memiliki Content-Encoding dikirim apa adanya
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	encoder     io.WriteCloser
+	wroteHeader bool
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	header := cw.Header()
+	header.Add("Vary", "Accept-Encoding")
+	if status >= http.StatusOK && status != http.StatusNoContent && status != http.StatusNotModified && header.Get("Content-Encoding") == "" {
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length") // Panjang body berubah setelah dikompres
+		cw.encoder = compressionEncoders[cw.encoding](cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.encoder == nil {
+		return cw.ResponseWriter.Write(p)
+	}
+	return cw.encoder.Write(p)
+}
+
+// FlushError mengirim data yang masih tertahan di encoder ke client, dipakai oleh http.ResponseController (misalnya stream SSE)
+func (cw *compressWriter) FlushError() error {
+	if flusher, ok := cw.encoder.(interface{ Flush() error }); ok {
+		if err := flusher.Flush(); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap mengembalikan ResponseWriter asli agar opsi response dan http.ResponseController tetap bekerja
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// close menutup encoder agar footer kompresi ikut terkirim
+func (cw *compressWriter) close() {
+	if cw.encoder != nil {
+		cw.encoder.Close()
+	}
+}
+
+// compressionMiddleware mengompres response dengan br atau gzip sesuai Accept-Encoding dan RETUR_COMPRESSION
+// Request HEAD dan route pada compressionExemptRoutes tidak dikompres
+func compressionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"), cfg.CompressionEncodings)
+		if route := mux.CurrentRoute(r); route != nil && compressionExemptRoutes[route.GetName()] {
+			encoding = ""
+		}
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	enabled := []string{"br", "gzip"}
+	tests := []struct {
+		header, want string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"gzip, br", "br"},               // q sama, urutan RETUR_COMPRESSION menang
+		{"br;q=0.5, gzip;q=0.8", "gzip"}, // q tertinggi menang
+		{"br;q=0, gzip;q=0", ""},
+		{"*", "br"},
+		{"*;q=0.1, gzip", "gzip"},
+		{"br;q=abc", ""}, // q tidak valid diabaikan
+		{"deflate", ""},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header, enabled); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+	if got := negotiateEncoding("gzip", nil); got != "" {
+		t.Errorf("compression disabled but negotiated %q", got)
+	}
+	if got := parseCompressionEncodings([]string{"GZIP", "identity", "zstd", "br"}); strings.Join(got, ",") != "gzip,br" {
+		t.Errorf("parseCompressionEncodings = %v", got)
+	}
+}
+
+func TestCompressionMiddlewareEncodesBody(t *testing.T) {
+	withConfig(t, func(c *Config) { c.CompressionEncodings = []string{"br", "gzip"} })
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+	}
+	for encoding, decode := range decoders {
+		req := jsonRequest(t, http.MethodPost, "/retur/validate", map[string]string{"barang": "Sepatu", "alasan": "rusak"})
+		req.Header.Set("Accept-Encoding", encoding)
+		rec := serve(req)
+		if rec.Header().Get("Content-Encoding") != encoding || !strings.Contains(rec.Header().Get("Vary"), "Accept-Encoding") {
+			t.Fatalf("%s: headers %v", encoding, rec.Header())
+		}
+		reader, err := decode(rec.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(reader)
+		if err != nil || !strings.Contains(string(body), `"valid"`) {
+			t.Fatalf("%s: body %q, err %v", encoding, body, err)
+		}
+	}
+
+	req := jsonRequest(t, http.MethodPost, "/retur/validate", map[string]string{"barang": "Sepatu", "alasan": "rusak"})
+	if rec := serve(req); rec.Header().Get("Content-Encoding") != "" || !strings.Contains(rec.Body.String(), `"valid"`) {
+		t.Fatalf("uncompressed response: %v %q", rec.Header(), rec.Body.String())
+	}
+}
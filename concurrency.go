@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// heavySem membatasi berapa banyak request berat (ekspor, laporan) yang berjalan bersamaan; dibuat di main() dari konfigurasi
+var heavySem *semaphore.Weighted
+
+// heavyWeight membatasi bobot ke kapasitas heavySem agar request tidak menunggu slot yang tidak akan pernah tersedia
+func heavyWeight(weight int64) int64 {
+	return min(weight, int64(cfg.HeavyConcurrency))
+}
+
+// limitHeavy membungkus handler berat dengan semaphore berbobot: request menunggu hingga RETUR_HEAVY_WAIT,
+// lalu ditolak dengan 429 jika kapasitas masih penuh
+// weight menunjukkan seberapa berat satu request; bobot di atas kapasitas dibatasi ke kapasitas
+func limitHeavy(weight int64, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		held := heavyWeight(weight) // Variabel lokal: weight dibagi oleh semua request ke handler ini
+		ctx, cancel := context.WithTimeout(r.Context(), cfg.HeavyWait)
+		defer cancel()
+		if err := heavySem.Acquire(ctx, held); err != nil {
+			w.Header().Set("Retry-After", "1")
+			handleError(w, http.StatusTooManyRequests, "Too many concurrent report requests, try again later")
+			return
+		}
+		defer heavySem.Release(held)
+		next(w, r)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+func TestLimitHeavyRejectsWhenFull(t *testing.T) {
+	withConfig(t, func(c *Config) {
+		c.HeavyConcurrency = 2
+		c.HeavyWait = 10 * time.Millisecond
+	})
+	saved := heavySem
+	heavySem = semaphore.NewWeighted(2)
+	t.Cleanup(func() { heavySem = saved })
+
+	calls := 0
+	handler := limitHeavy(5, func(w http.ResponseWriter, r *http.Request) { calls++ }) // Bobot dibatasi ke kapasitas
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || calls != 1 {
+		t.Fatalf("request heavier than capacity never ran: status %d", rec.Code)
+	}
+
+	if err := heavySem.Acquire(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" || calls != 1 {
+		t.Fatalf("full semaphore: status %d, calls %d", rec.Code, calls)
+	}
+	heavySem.Release(1)
+	if !heavySem.TryAcquire(2) {
+		t.Fatal("slots leaked after rejected request")
+	}
+}
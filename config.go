@@ -0,0 +1,455 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Config menyimpan seluruh konfigurasi aplikasi yang dibaca dari environment variable
+type Config struct {
+	Addr           string            // Alamat server HTTP (RETUR_ADDR)
+	BasePath       string            // Prefix path saat aplikasi dipasang di belakang reverse proxy (RETUR_BASE_PATH), kosong berarti root
+	DSN            string            // Data Source Name untuk koneksi MySQL (RETUR_DSN)
+	AdminToken     string            // Token untuk mengakses endpoint admin (RETUR_ADMIN_TOKEN), kosong berarti admin nonaktif
+	ApproverTokens map[string]string // Token approver ke nama approver, dari "nama=token" dipisahkan koma (RETUR_APPROVER_TOKENS)
+
+	DBTLS           bool   // Koneksi database memakai TLS, wajib untuk MySQL managed di cloud (RETUR_DB_TLS)
+	DBTLSCA         string // Path file CA (PEM) untuk memverifikasi sertifikat database, kosong berarti CA sistem (RETUR_DB_TLS_CA)
+	DBTLSSkipVerify bool   // Lewati verifikasi sertifikat database, hanya untuk development (RETUR_DB_TLS_SKIP_VERIFY)
+
+	DefaultCurrency string // Mata uang default untuk refund jika tidak dikirim saat approve uang (RETUR_DEFAULT_CURRENCY)
+	PrettyJSON      bool   // Format semua response JSON dengan indentasi untuk debugging (RETUR_PRETTY_JSON)
+	FieldNaming     string // Profil penamaan field JSON default: default atau english_camel (RETUR_FIELD_NAMING)
+	ProblemJSON     bool   // Kirim semua error dalam format RFC 7807 application/problem+json (RETUR_PROBLEM_JSON)
+
+	StrictJSON       bool            // Tolak field JSON yang tidak dikenal secara global (RETUR_STRICT_JSON)
+	StrictJSONRoutes map[string]bool // Override per nama route, contoh "createRetur=true,approveRetur=false" (RETUR_STRICT_JSON_ROUTES)
+
+	ShutdownTimeout    time.Duration // Batas waktu menunggu request berjalan saat shutdown (RETUR_SHUTDOWN_TIMEOUT)
+	WebhookURL         string        // URL webhook untuk notifikasi perubahan status (RETUR_WEBHOOK_URL), kosong berarti nonaktif
+	SpikeThreshold     int           // Jumlah retur per barang dalam RETUR_SPIKE_WINDOW yang memicu alert, 0 berarti nonaktif (RETUR_SPIKE_THRESHOLD)
+	SpikeWindow        time.Duration // Jendela bergulir untuk menghitung lonjakan retur per barang (RETUR_SPIKE_WINDOW)
+	SpikeWebhookURL    string        // URL tujuan alert lonjakan retur, kosong berarti hanya dicatat di log (RETUR_SPIKE_WEBHOOK_URL)
+	SpikeWebhookSecret string        // Kunci HMAC header X-Retur-Signature untuk RETUR_SPIKE_WEBHOOK_URL, kosong berarti tanpa tanda tangan (RETUR_SPIKE_WEBHOOK_SECRET)
+	NotifyQueueSize    int           // Kapasitas antrian notifikasi (RETUR_NOTIFY_QUEUE_SIZE)
+	NotifyDrainTimeout time.Duration // Batas waktu menguras antrian notifikasi saat shutdown (RETUR_NOTIFY_DRAIN_TIMEOUT)
+
+	RequireRejectionNote      bool                     // Penolakan retur wajib disertai catatan (RETUR_REQUIRE_REJECTION_NOTE)
+	SLA                       map[string]time.Duration // Durasi SLA per status, contoh "Dalam Proses=48h" (RETUR_SLA)
+	PriorityWeights           priorityWeights          // Bobot skor prioritas, contoh "age=1,refund=0.001,reason=10,tier=10" (RETUR_PRIORITY_WEIGHTS)
+	ReasonSeverity            map[string]int           // Tingkat severity per reason_code untuk skor prioritas, contoh "DAMAGED=3" (RETUR_REASON_SEVERITY)
+	CustomerTiers             map[string]int           // Tingkat tier per customer_id untuk skor prioritas, contoh "CUST-001=2" (RETUR_CUSTOMER_TIERS)
+	DetailsKey                string                   // Key AES-GCM (base64) untuk mengenkripsi field details sensitif, kosong berarti nonaktif (RETUR_DETAILS_KEY)
+	EncryptedDetails          []string                 // Key details yang dienkripsi saat disimpan, dipisahkan koma (RETUR_ENCRYPTED_DETAILS)
+	PIIFields                 []string                 // Field yang disamarkan di log dan ekspor non-admin, dipisahkan koma (RETUR_PII_FIELDS)
+	PIIMode                   string                   // Cara penyamaran PII: hash, mask, atau off (RETUR_PII_MODE)
+	PIIHashKey                string                   // Key HMAC untuk mode hash (RETUR_PII_HASH_KEY)
+	DetailsPolicy             map[string][]string      // Field details wajib saat approve per pengembalian, contoh "uang=bank_name|account_number" (RETUR_DETAILS_POLICY)
+	SecondApprovalThreshold   int64                    // Refund uang di atas nilai ini (minor units) butuh dua approver berbeda, 0 berarti nonaktif (RETUR_SECOND_APPROVAL_THRESHOLD)
+	AutoApproveRules          map[string]string        // Aturan auto-approve "reason_code=barang" untuk /retur/admin/apply-autoapprove (RETUR_AUTO_APPROVE_RULES)
+	AutoApproveOnCreate       bool                     // Terapkan aturan auto-approve juga saat create, default nonaktif (RETUR_AUTO_APPROVE_ON_CREATE)
+	AllowImportStatus         bool                     // Admin boleh mengirim status saat create dengan ?import=true untuk migrasi data historis (RETUR_ALLOW_IMPORT_STATUS)
+	ValidateInvariants        bool                     // Tolak penyimpanan retur dengan kombinasi status/pengembalian yang tidak mungkin (RETUR_VALIDATE_INVARIANTS)
+	BarangCatalog             []string                 // Nama produk kanonik untuk normalisasi Barang, dipisahkan koma, kosong berarti nonaktif (RETUR_BARANG_CATALOG)
+	BarangMatchThreshold      int                      // Kemiripan minimum (persen) agar Barang dipetakan ke produk katalog (RETUR_BARANG_MATCH_THRESHOLD)
+	ReturnWindow              time.Duration            // Batas umur pembelian (order_date) saat retur dibuat, 0 berarti nonaktif dan order_date opsional (RETUR_RETURN_WINDOW)
+	DeleteGrace               time.Duration            // Retur yang disetujui kurang dari durasi ini tidak bisa dihapus (409), 0 berarti nonaktif (RETUR_DELETE_GRACE)
+	DeleteGraceOverrideHeader string                   // Header yang bernilai true dari admin untuk melewati RETUR_DELETE_GRACE, kosong berarti tanpa override (RETUR_DELETE_GRACE_OVERRIDE_HEADER)
+
+	IDPoolCap        int           // Jumlah maksimum ID di pool deletedIDs, 0 berarti tanpa batas (RETUR_ID_POOL_CAP)
+	BatchMaxIDs      int           // Jumlah maksimum ID per request pada /retur/batch (RETUR_BATCH_MAX_IDS)
+	SampleMax        int           // Ukuran sampel maksimum pada /retur/sample (RETUR_SAMPLE_MAX)
+	LatencyWindow    time.Duration // Rentang riwayat yang dihitung pada /retur/stats/latency (RETUR_LATENCY_WINDOW)
+	OverviewRecent   int           // Jumlah retur terbaru per status pada /retur/overview (RETUR_OVERVIEW_RECENT)
+	DefaultSort      string        // Urutan default daftar retur, contoh "-created_at"; id selalu ditambahkan sebagai tie-breaker (RETUR_DEFAULT_SORT)
+	ChangesMaxBatch  int           // Jumlah maksimum perubahan per polling /retur/changes (RETUR_CHANGES_MAX_BATCH)
+	ChangesSafetyLag time.Duration // Umur minimum entri audit sebelum muncul di /retur/changes, harus di atas transaksi tulis terlama (RETUR_CHANGES_SAFETY_LAG)
+
+	AttachmentDir     string // Direktori penyimpanan file lampiran retur (RETUR_ATTACHMENT_DIR)
+	AttachmentMaxSize int64  // Ukuran maksimum satu lampiran dalam byte (RETUR_ATTACHMENT_MAX_SIZE)
+
+	ExportDir           string        // Direktori lokal hasil job ekspor async, bukan blob store; pada beberapa instance harus berupa volume bersama (RETUR_EXPORT_DIR)
+	ExportLinkTTL       time.Duration // Masa berlaku link unduhan hasil ekspor (RETUR_EXPORT_LINK_TTL)
+	ExportSigningKey    string        // Kunci HMAC link unduhan; kosong berarti kunci acak per proses (RETUR_EXPORT_SIGNING_KEY)
+	ExportRetention     time.Duration // Lama job dan file ekspor disimpan, 0 berarti selamanya (RETUR_EXPORT_RETENTION)
+	ExportPurgeInterval time.Duration // Jeda job pembersih ekspor lama, 0 berarti nonaktif (RETUR_EXPORT_PURGE_INTERVAL)
+	ExportMaxQueued     int           // Jumlah job ekspor yang boleh antre atau berjalan bersamaan (RETUR_EXPORT_MAX_QUEUED)
+	ExportMaxPerActor   int           // Jumlah job ekspor antre per kredensial (RETUR_EXPORT_MAX_PER_ACTOR)
+	ExportJobTimeout    time.Duration // Batas waktu satu job ekspor, termasuk menunggu slot heavySem (RETUR_EXPORT_JOB_TIMEOUT)
+
+	MaxUndoAge         time.Duration // Umur maksimum retur terhapus yang masih bisa di-undo, 0 berarti tanpa batas (RETUR_MAX_UNDO_AGE)
+	UndoShedLockWait   time.Duration // Jika rata-rata waktu tunggu lock stack undo melewati batas ini, delete tidak masuk stack undo; 0 berarti nonaktif (RETUR_UNDO_SHED_LOCK_WAIT)
+	UndoPurgeInterval  time.Duration // Jeda job pembersih undo kedaluwarsa, 0 berarti nonaktif (RETUR_UNDO_PURGE_INTERVAL)
+	TombstoneRetention time.Duration // Lama tombstone ID retur yang dibuang permanen disimpan untuk jawaban 410, 0 berarti selamanya (RETUR_TOMBSTONE_RETENTION)
+	ArchivePath        string        // File JSON lines untuk mengarsipkan retur sebelum dibuang permanen, kosong berarti nonaktif (RETUR_ARCHIVE_PATH)
+
+	AutoMigrate       bool          // Jalankan AutoMigrate saat startup; false berarti hanya verifikasi skema (RETUR_AUTO_MIGRATE)
+	BackfillBatchSize int           // Jumlah baris per batch saat backfill kolom baru (RETUR_BACKFILL_BATCH_SIZE)
+	BackfillPause     time.Duration // Jeda antar batch backfill (RETUR_BACKFILL_PAUSE)
+
+	BreakerThreshold int           // Jumlah kegagalan koneksi berturut-turut sebelum circuit breaker terbuka (RETUR_BREAKER_THRESHOLD)
+	BreakerCooldown  time.Duration // Lama circuit breaker terbuka sebelum mencoba database lagi (RETUR_BREAKER_COOLDOWN)
+
+	StatsCacheTTL time.Duration // Lama hasil /retur/stats disimpan di cache (RETUR_STATS_CACHE_TTL)
+
+	TrustedProxies []*net.IPNet // Proxy/load balancer yang header X-Forwarded-For-nya dipercaya (RETUR_TRUSTED_PROXIES)
+
+	SecurityHeaders map[string]string // Header keamanan pada setiap response, override "Header=nilai" atau "Header=" untuk menonaktifkan (RETUR_SECURITY_HEADERS)
+	HSTS            string            // Nilai Strict-Transport-Security untuk request HTTPS, kosong berarti nonaktif (RETUR_HSTS)
+	AdminAllowlist  []*net.IPNet      // Jaringan yang boleh mengakses endpoint admin, kosong berarti semua (RETUR_ADMIN_ALLOWLIST)
+
+	DedupWindow        time.Duration // Jendela deduplikasi approve/disapprove per Idempotency-Key, 0 berarti nonaktif (RETUR_DEDUP_WINDOW)
+	UndoIdempotencyTTL time.Duration // Lama hasil undo disimpan per Idempotency-Key untuk retry, 0 berarti nonaktif (RETUR_UNDO_IDEMPOTENCY_TTL)
+	RenotifyInterval   time.Duration // Jeda minimum kirim ulang notifikasi per retur dan jenis (RETUR_RENOTIFY_INTERVAL)
+
+	HeavyConcurrency int           // Kapasitas semaphore untuk endpoint ekspor/laporan (RETUR_HEAVY_CONCURRENCY)
+	HeavyWait        time.Duration // Lama request berat menunggu slot sebelum ditolak 429 (RETUR_HEAVY_WAIT)
+
+	TenantHeader       string            // Header yang berisi API key tenant, kosong berarti batas per tenant nonaktif (RETUR_TENANT_HEADER)
+	TenantKeys         map[string]string // API key ke nama tenant, dari "nama=key" dipisahkan koma (RETUR_TENANT_KEYS)
+	TenantRateLimit    int               // Request per menit untuk setiap tenant, 0 berarti tanpa batas (RETUR_TENANT_RATE_LIMIT)
+	TenantRateLimits   map[string]int    // Override request per menit per tenant, contoh "acme=600" (RETUR_TENANT_RATE_LIMITS)
+	TenantCreateQuota  int               // Retur yang boleh dibuat setiap tenant per bulan, 0 berarti tanpa kuota (RETUR_TENANT_CREATE_QUOTA)
+	TenantCreateQuotas map[string]int    // Override kuota create bulanan per tenant, contoh "acme=10000" (RETUR_TENANT_CREATE_QUOTAS)
+
+	WriteStaleAfter time.Duration // Batas umur tulis sukses terakhir sebelum /status melaporkan stale, 0 berarti nonaktif (RETUR_WRITE_STALE_AFTER)
+
+	JSONSchema bool // Validasi body createRetur dan approveRetur dengan JSON Schema (RETUR_JSON_SCHEMA)
+
+	RequestTimeout time.Duration            // Batas waktu default setiap request, 0 berarti tanpa batas (RETUR_REQUEST_TIMEOUT)
+	RouteTimeouts  map[string]time.Duration // Batas waktu per nama route, contoh "exportByCustomer=2m" (RETUR_ROUTE_TIMEOUTS)
+
+	SlowRequestThreshold time.Duration // Request lebih lama dari ini dicatat sebagai peringatan, 0 berarti nonaktif (RETUR_SLOW_REQUEST_THRESHOLD)
+	CompressionEncodings []string      // Encoding response yang diaktifkan sesuai urutan preferensi, "identity" berarti nonaktif (RETUR_COMPRESSION)
+	MaxTagsPerRetur      int           // Jumlah maksimum tag per retur (RETUR_MAX_TAGS)
+	TailBuffer           int           // Jumlah kejadian yang ditampung per koneksi /retur/admin/tail sebelum dibuang (RETUR_TAIL_BUFFER)
+}
+
+// cfg adalah konfigurasi aktif yang dimuat saat aplikasi dijalankan
+var cfg Config
+
+// envOr membaca environment variable, mengembalikan def jika variabel tidak diisi
+func envOr(key, def string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return def
+}
+
+// envInt membaca environment variable bertipe integer positif, mengembalikan def jika kosong atau tidak valid
+func envInt(key string, def int) int {
+	value, err := strconv.Atoi(envOr(key, ""))
+	if err != nil || value <= 0 {
+		return def
+	}
+	return value
+}
+
+// envDuration membaca environment variable bertipe durasi (misalnya "500ms", "2s"), mengembalikan def jika tidak valid
+func envDuration(key string, def time.Duration) time.Duration {
+	value, err := time.ParseDuration(envOr(key, ""))
+	if err != nil || value < 0 {
+		return def
+	}
+	return value
+}
+
+// envBoolMap membaca daftar "nama=true,nama=false" dari environment variable; nama tanpa nilai dianggap true
+func envBoolMap(key string) map[string]bool {
+	result := make(map[string]bool)
+	for _, item := range strings.Split(envOr(key, ""), ",") {
+		name, value, hasValue := strings.Cut(strings.TrimSpace(item), "=")
+		if name == "" {
+			continue
+		}
+		result[name] = !hasValue || value == "true"
+	}
+	return result
+}
+
+// envList membaca daftar nilai dipisahkan koma dari environment variable; item kosong diabaikan
+func envList(key, def string) []string {
+	var result []string
+	for _, item := range strings.Split(envOr(key, def), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// loadConfig membaca konfigurasi dari environment variable dengan nilai default untuk pengembangan lokal
+func loadConfig() Config {
+	return Config{
+		Addr:           envOr("RETUR_ADDR", ":8080"),
+		BasePath:       normalizeBasePath(envOr("RETUR_BASE_PATH", "")),
+		DSN:            envOr("RETUR_DSN", "root:@tcp(127.0.0.1:3306)/retur_db?charset=utf8mb4&parseTime=True&loc=Local"),
+		AdminToken:     envOr("RETUR_ADMIN_TOKEN", ""),
+		ApproverTokens: parseNamedTokens(envOr("RETUR_APPROVER_TOKENS", "")),
+
+		DBTLS:           envOr("RETUR_DB_TLS", "false") == "true",
+		DBTLSCA:         envOr("RETUR_DB_TLS_CA", ""),
+		DBTLSSkipVerify: envOr("RETUR_DB_TLS_SKIP_VERIFY", "false") == "true",
+
+		DefaultCurrency: envOr("RETUR_DEFAULT_CURRENCY", "IDR"),
+		PrettyJSON:      envOr("RETUR_PRETTY_JSON", "false") == "true",
+		FieldNaming:     envOr("RETUR_FIELD_NAMING", namingDefault),
+		ProblemJSON:     envOr("RETUR_PROBLEM_JSON", "false") == "true",
+
+		StrictJSON:       envOr("RETUR_STRICT_JSON", "false") == "true",
+		StrictJSONRoutes: envBoolMap("RETUR_STRICT_JSON_ROUTES"),
+
+		ShutdownTimeout:    envDuration("RETUR_SHUTDOWN_TIMEOUT", 10*time.Second),
+		WebhookURL:         envOr("RETUR_WEBHOOK_URL", ""),
+		SpikeThreshold:     envInt("RETUR_SPIKE_THRESHOLD", 0),
+		SpikeWindow:        envDuration("RETUR_SPIKE_WINDOW", time.Hour),
+		SpikeWebhookURL:    envOr("RETUR_SPIKE_WEBHOOK_URL", ""),
+		SpikeWebhookSecret: envOr("RETUR_SPIKE_WEBHOOK_SECRET", ""),
+		NotifyQueueSize:    envInt("RETUR_NOTIFY_QUEUE_SIZE", 100),
+		NotifyDrainTimeout: envDuration("RETUR_NOTIFY_DRAIN_TIMEOUT", 5*time.Second),
+
+		RequireRejectionNote:      envOr("RETUR_REQUIRE_REJECTION_NOTE", "false") == "true",
+		SLA:                       parseSLAConfig(envOr("RETUR_SLA", "Dalam Proses=48h")),
+		PriorityWeights:           parsePriorityWeights(envOr("RETUR_PRIORITY_WEIGHTS", "age=1,refund=0.001,reason=10,tier=10")),
+		ReasonSeverity:            parseIntMap(envOr("RETUR_REASON_SEVERITY", "")),
+		CustomerTiers:             parseIntMap(envOr("RETUR_CUSTOMER_TIERS", "")),
+		DetailsKey:                envOr("RETUR_DETAILS_KEY", ""),
+		EncryptedDetails:          envList("RETUR_ENCRYPTED_DETAILS", "account_number"),
+		PIIFields:                 envList("RETUR_PII_FIELDS", "customer_id,actor"),
+		PIIMode:                   envOr("RETUR_PII_MODE", piiHash),
+		PIIHashKey:                envOr("RETUR_PII_HASH_KEY", ""),
+		DetailsPolicy:             parseDetailsPolicy(envOr("RETUR_DETAILS_POLICY", "")),
+		SecondApprovalThreshold:   int64(envInt("RETUR_SECOND_APPROVAL_THRESHOLD", 0)),
+		AutoApproveRules:          parseAutoApproveRules(envOr("RETUR_AUTO_APPROVE_RULES", "")),
+		AutoApproveOnCreate:       envOr("RETUR_AUTO_APPROVE_ON_CREATE", "false") == "true",
+		AllowImportStatus:         envOr("RETUR_ALLOW_IMPORT_STATUS", "false") == "true",
+		ValidateInvariants:        envOr("RETUR_VALIDATE_INVARIANTS", "true") == "true",
+		BarangCatalog:             envList("RETUR_BARANG_CATALOG", ""),
+		BarangMatchThreshold:      envInt("RETUR_BARANG_MATCH_THRESHOLD", 80),
+		ReturnWindow:              envDuration("RETUR_RETURN_WINDOW", 0),
+		DeleteGrace:               envDuration("RETUR_DELETE_GRACE", 0),
+		DeleteGraceOverrideHeader: envOr("RETUR_DELETE_GRACE_OVERRIDE_HEADER", "X-Override-Delete-Grace"),
+
+		IDPoolCap:        envInt("RETUR_ID_POOL_CAP", 0),
+		BatchMaxIDs:      envInt("RETUR_BATCH_MAX_IDS", 100),
+		SampleMax:        envInt("RETUR_SAMPLE_MAX", 100),
+		LatencyWindow:    envDuration("RETUR_LATENCY_WINDOW", 30*24*time.Hour),
+		OverviewRecent:   envInt("RETUR_OVERVIEW_RECENT", 3),
+		DefaultSort:      envOr("RETUR_DEFAULT_SORT", "id"),
+		ChangesMaxBatch:  envInt("RETUR_CHANGES_MAX_BATCH", 500),
+		ChangesSafetyLag: envDuration("RETUR_CHANGES_SAFETY_LAG", 5*time.Second),
+
+		AttachmentDir:     envOr("RETUR_ATTACHMENT_DIR", "attachments"),
+		AttachmentMaxSize: int64(envInt("RETUR_ATTACHMENT_MAX_SIZE", 100<<20)),
+
+		ExportDir:           envOr("RETUR_EXPORT_DIR", "exports"),
+		ExportLinkTTL:       envDuration("RETUR_EXPORT_LINK_TTL", 15*time.Minute),
+		ExportSigningKey:    envOr("RETUR_EXPORT_SIGNING_KEY", ""),
+		ExportRetention:     envDuration("RETUR_EXPORT_RETENTION", 24*time.Hour),
+		ExportPurgeInterval: envDuration("RETUR_EXPORT_PURGE_INTERVAL", 10*time.Minute),
+		ExportMaxQueued:     envInt("RETUR_EXPORT_MAX_QUEUED", 8),
+		ExportMaxPerActor:   envInt("RETUR_EXPORT_MAX_PER_ACTOR", 2),
+		ExportJobTimeout:    envDuration("RETUR_EXPORT_JOB_TIMEOUT", 30*time.Minute),
+
+		MaxUndoAge:         envDuration("RETUR_MAX_UNDO_AGE", 0),
+		UndoShedLockWait:   envDuration("RETUR_UNDO_SHED_LOCK_WAIT", 0),
+		UndoPurgeInterval:  envDuration("RETUR_UNDO_PURGE_INTERVAL", time.Minute),
+		TombstoneRetention: envDuration("RETUR_TOMBSTONE_RETENTION", 90*24*time.Hour),
+		ArchivePath:        envOr("RETUR_ARCHIVE_PATH", ""),
+
+		AutoMigrate:       envOr("RETUR_AUTO_MIGRATE", "true") == "true",
+		BackfillBatchSize: envInt("RETUR_BACKFILL_BATCH_SIZE", 1000),
+		BackfillPause:     envDuration("RETUR_BACKFILL_PAUSE", 100*time.Millisecond),
+
+		BreakerThreshold: envInt("RETUR_BREAKER_THRESHOLD", 5),
+		BreakerCooldown:  envDuration("RETUR_BREAKER_COOLDOWN", 30*time.Second),
+
+		StatsCacheTTL: envDuration("RETUR_STATS_CACHE_TTL", 10*time.Second),
+
+		TrustedProxies: parseCIDRList(envOr("RETUR_TRUSTED_PROXIES", "")),
+
+		SecurityHeaders: parseSecurityHeaders(envOr("RETUR_SECURITY_HEADERS", "")),
+		HSTS:            envOr("RETUR_HSTS", "max-age=31536000; includeSubDomains"),
+		AdminAllowlist:  parseCIDRList(envOr("RETUR_ADMIN_ALLOWLIST", "")),
+
+		DedupWindow:        envDuration("RETUR_DEDUP_WINDOW", 0),
+		UndoIdempotencyTTL: envDuration("RETUR_UNDO_IDEMPOTENCY_TTL", 10*time.Minute),
+		RenotifyInterval:   envDuration("RETUR_RENOTIFY_INTERVAL", time.Minute),
+
+		HeavyConcurrency: envInt("RETUR_HEAVY_CONCURRENCY", 4),
+		HeavyWait:        envDuration("RETUR_HEAVY_WAIT", 2*time.Second),
+
+		TenantHeader:       envOr("RETUR_TENANT_HEADER", "X-Tenant-ID"),
+		TenantKeys:         parseNamedTokens(envOr("RETUR_TENANT_KEYS", "")),
+		TenantRateLimit:    envInt("RETUR_TENANT_RATE_LIMIT", 0),
+		TenantRateLimits:   parseIntMap(envOr("RETUR_TENANT_RATE_LIMITS", "")),
+		TenantCreateQuota:  envInt("RETUR_TENANT_CREATE_QUOTA", 0),
+		TenantCreateQuotas: parseIntMap(envOr("RETUR_TENANT_CREATE_QUOTAS", "")),
+
+		WriteStaleAfter: envDuration("RETUR_WRITE_STALE_AFTER", 5*time.Minute),
+
+		JSONSchema: envOr("RETUR_JSON_SCHEMA", "false") == "true",
+
+		RequestTimeout: envDuration("RETUR_REQUEST_TIMEOUT", 10*time.Second),
+		RouteTimeouts:  parseDurationMap(envOr("RETUR_ROUTE_TIMEOUTS", "listReturs=1m,exportReturs=2m,exportByCustomer=2m,statsTimeseries=30s,uploadAttachment=5m,downloadAttachment=30m,downloadExport=30m,adminTail=0")),
+
+		SlowRequestThreshold: envDuration("RETUR_SLOW_REQUEST_THRESHOLD", time.Second),
+		CompressionEncodings: parseCompressionEncodings(envList("RETUR_COMPRESSION", "br,gzip")),
+		MaxTagsPerRetur:      envInt("RETUR_MAX_TAGS", 20),
+		TailBuffer:           envInt("RETUR_TAIL_BUFFER", 256),
+	}
+}
+
+// redactDSN menyamarkan password di dalam DSN agar aman ditampilkan
+func redactDSN(dsn string) string {
+	parsed, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "[invalid dsn]" // Jangan tampilkan DSN mentah jika tidak bisa diparse
+	}
+	if parsed.Passwd != "" {
+		parsed.Passwd = "****" // Samarkan password
+	}
+	return parsed.FormatDSN()
+}
+
+// redactSecret menyamarkan nilai rahasia, hanya menunjukkan apakah nilainya diisi atau tidak
+func redactSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "****"
+}
+
+// Redacted mengembalikan konfigurasi dalam bentuk map dengan semua nilai rahasia disamarkan
+func (c Config) Redacted() map[string]interface{} {
+	return map[string]interface{}{
+		"addr":        c.Addr,
+		"base_path":   c.BasePath,
+		"dsn":         redactDSN(c.DSN),
+		"admin_token": redactSecret(c.AdminToken),
+		"approvers":   tokenNames(c.ApproverTokens), // Hanya nama, token tidak pernah ditampilkan
+
+		"db_tls":             c.DBTLS,
+		"db_tls_ca":          c.DBTLSCA,
+		"db_tls_skip_verify": c.DBTLSSkipVerify,
+
+		"default_currency": c.DefaultCurrency,
+		"pretty_json":      c.PrettyJSON,
+		"field_naming":     c.FieldNaming,
+		"problem_json":     c.ProblemJSON,
+
+		"strict_json":        c.StrictJSON,
+		"strict_json_routes": c.StrictJSONRoutes,
+
+		"shutdown_timeout":     c.ShutdownTimeout.String(),
+		"webhook_url":          redactSecret(c.WebhookURL),
+		"spike_threshold":      c.SpikeThreshold,
+		"spike_window":         c.SpikeWindow.String(),
+		"spike_webhook_url":    redactSecret(c.SpikeWebhookURL),
+		"spike_webhook_secret": redactSecret(c.SpikeWebhookSecret),
+		"notify_queue_size":    c.NotifyQueueSize,
+		"notify_drain_timeout": c.NotifyDrainTimeout.String(),
+
+		"require_rejection_note":       c.RequireRejectionNote,
+		"sla":                          durationStrings(c.SLA),
+		"priority_weights":             c.PriorityWeights,
+		"reason_severity":              c.ReasonSeverity,
+		"customer_tiers":               c.CustomerTiers,
+		"details_key":                  redactSecret(c.DetailsKey),
+		"encrypted_details":            c.EncryptedDetails,
+		"pii_fields":                   c.PIIFields,
+		"pii_mode":                     c.PIIMode,
+		"pii_hash_key":                 redactSecret(c.PIIHashKey),
+		"details_policy":               detailsPolicyStrings(c.DetailsPolicy),
+		"auto_approve_rules":           c.AutoApproveRules,
+		"auto_approve_on_create":       c.AutoApproveOnCreate,
+		"allow_import_status":          c.AllowImportStatus,
+		"validate_invariants":          c.ValidateInvariants,
+		"barang_catalog":               c.BarangCatalog,
+		"barang_match_threshold":       c.BarangMatchThreshold,
+		"return_window":                c.ReturnWindow.String(),
+		"delete_grace":                 c.DeleteGrace.String(),
+		"delete_grace_override_header": c.DeleteGraceOverrideHeader,
+		"second_approval_threshold":    c.SecondApprovalThreshold,
+
+		"id_pool_cap":        c.IDPoolCap,
+		"batch_max_ids":      c.BatchMaxIDs,
+		"sample_max":         c.SampleMax,
+		"latency_window":     c.LatencyWindow.String(),
+		"overview_recent":    c.OverviewRecent,
+		"default_sort":       c.DefaultSort,
+		"changes_max_batch":  c.ChangesMaxBatch,
+		"changes_safety_lag": c.ChangesSafetyLag.String(),
+
+		"attachment_dir":      c.AttachmentDir,
+		"attachment_max_size": c.AttachmentMaxSize,
+
+		"export_dir":            c.ExportDir,
+		"export_link_ttl":       c.ExportLinkTTL.String(),
+		"export_signing_key":    redactSecret(c.ExportSigningKey),
+		"export_retention":      c.ExportRetention.String(),
+		"export_purge_interval": c.ExportPurgeInterval.String(),
+		"export_max_queued":     c.ExportMaxQueued,
+		"export_max_per_actor":  c.ExportMaxPerActor,
+		"export_job_timeout":    c.ExportJobTimeout.String(),
+
+		"max_undo_age":        c.MaxUndoAge.String(),
+		"undo_shed_lock_wait": c.UndoShedLockWait.String(),
+		"undo_purge_interval": c.UndoPurgeInterval.String(),
+		"tombstone_retention": c.TombstoneRetention.String(),
+		"archive_path":        c.ArchivePath,
+
+		"auto_migrate":        c.AutoMigrate,
+		"backfill_batch_size": c.BackfillBatchSize,
+		"backfill_pause":      c.BackfillPause.String(),
+
+		"breaker_threshold": c.BreakerThreshold,
+		"breaker_cooldown":  c.BreakerCooldown.String(),
+
+		"stats_cache_ttl": c.StatsCacheTTL.String(),
+
+		"trusted_proxies": cidrStrings(c.TrustedProxies),
+		"admin_allowlist": cidrStrings(c.AdminAllowlist),
+
+		"security_headers": headerStrings(c.SecurityHeaders),
+		"hsts":             c.HSTS,
+
+		"dedup_window":         c.DedupWindow.String(),
+		"undo_idempotency_ttl": c.UndoIdempotencyTTL.String(),
+		"renotify_interval":    c.RenotifyInterval.String(),
+
+		"heavy_concurrency": c.HeavyConcurrency,
+		"heavy_wait":        c.HeavyWait.String(),
+
+		"tenant_header":        c.TenantHeader,
+		"tenants":              tokenNames(c.TenantKeys), // Hanya nama tenant, API key tidak pernah ditampilkan
+		"tenant_rate_limit":    c.TenantRateLimit,
+		"tenant_rate_limits":   c.TenantRateLimits,
+		"tenant_create_quota":  c.TenantCreateQuota,
+		"tenant_create_quotas": c.TenantCreateQuotas,
+
+		"write_stale_after": c.WriteStaleAfter.String(),
+
+		"json_schema": c.JSONSchema,
+
+		"request_timeout": c.RequestTimeout.String(),
+		"route_timeouts":  durationStrings(c.RouteTimeouts),
+
+		"slow_request_threshold": c.SlowRequestThreshold.String(),
+		"compression":            c.CompressionEncodings,
+		"max_tags_per_retur":     c.MaxTagsPerRetur,
+		"tail_buffer":            c.TailBuffer,
+	}
+}
+
+// configHandler adalah handler admin untuk menampilkan konfigurasi aktif setelah env dibaca, dengan nilai rahasia disamarkan
+// Setiap field Config harus punya entri di Redacted agar endpoint ini lengkap
+func configHandler(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, cfg.Redacted())
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// nonZeroValue membuat nilai bukan nol untuk tipe field Config, termasuk map, slice, dan pointer bersarang
+func nonZeroValue(tb testing.TB, t reflect.Type) reflect.Value {
+	tb.Helper()
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(1) // Termasuk time.Duration
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(1)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(1)
+	case reflect.Slice:
+		v.Set(reflect.Append(v, nonZeroValue(tb, t.Elem())))
+	case reflect.Map:
+		v.Set(reflect.MakeMap(t))
+		v.SetMapIndex(nonZeroValue(tb, t.Key()), nonZeroValue(tb, t.Elem()))
+	case reflect.Pointer:
+		v.Set(reflect.New(t.Elem()))
+		v.Elem().Set(nonZeroValue(tb, t.Elem()))
+	case reflect.Struct:
+		for i := range t.NumField() {
+			if t.Field(i).IsExported() {
+				v.Field(i).Set(nonZeroValue(tb, t.Field(i).Type))
+			}
+		}
+	default:
+		tb.Fatalf("no non-zero value for %s", t)
+	}
+	return v
+}
+
+func TestRedactedCoversEveryConfigField(t *testing.T) {
+	base := Config{}.Redacted()
+	typ := reflect.TypeOf(Config{})
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		var c Config
+		reflect.ValueOf(&c).Elem().Field(i).Set(nonZeroValue(t, field.Type))
+		if reflect.DeepEqual(c.Redacted(), base) {
+			t.Errorf("Config.%s is missing from Redacted", field.Name) // Setiap field Config harus tampil di /retur/admin/config
+		}
+	}
+}
+
+func TestRedactedHidesSecrets(t *testing.T) {
+	c := Config{
+		DSN:                "retur:dsn-password@tcp(db:3306)/retur",
+		AdminToken:         "admin-token-secret",
+		ApproverTokens:     map[string]string{"approver-token-secret": "budi"},
+		TenantKeys:         map[string]string{"tenant-key-secret": "toko-a"},
+		WebhookURL:         "https://hooks.example.com/webhook-url-secret",
+		SpikeWebhookURL:    "https://hooks.example.com/spike-url-secret",
+		SpikeWebhookSecret: "spike-secret",
+		DetailsKey:         "details-key-secret",
+		PIIHashKey:         "pii-hash-key-secret",
+		ExportSigningKey:   "export-signing-key-secret",
+	}
+	redacted := c.Redacted()
+	dump, err := json.Marshal(redacted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"dsn-password", "admin-token-secret", "approver-token-secret", "tenant-key-secret",
+		"webhook-url-secret", "spike-url-secret", "spike-secret", "details-key-secret", "pii-hash-key-secret", "export-signing-key-secret"} {
+		if strings.Contains(string(dump), secret) {
+			t.Errorf("config dump leaks %q: %s", secret, dump)
+		}
+	}
+	for _, key := range []string{"admin_token", "details_key", "pii_hash_key", "export_signing_key", "spike_webhook_secret"} {
+		if redacted[key] != "****" {
+			t.Errorf("%s = %v, want ****", key, redacted[key])
+		}
+	}
+	if names := redacted["approvers"]; !reflect.DeepEqual(names, []string{"budi"}) {
+		t.Errorf("approvers = %v, want only names", names)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// csvColumn adalah satu kolom yang bisa dipilih pada /retur/export
+type csvColumn struct {
+	Name  string             // Nama kolom di header CSV dan di parameter ?columns
+	Value func(Retur) string // Nilai kolom untuk satu retur
+}
+
+// csvTime memformat waktu opsional sebagai RFC3339, kosong jika nil
+func csvTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// csvColumns adalah kolom yang dikenal sesuai urutan default; details tidak diekspor karena bisa berisi data sensitif
+var csvColumns = []csvColumn{
+	{"id", func(r Retur) string { return strconv.Itoa(r.ID) }},
+	{"barang", func(r Retur) string { return r.Barang }},
+	{"canonical_barang", func(r Retur) string { return r.CanonicalBarang }},
+	{"alasan", func(r Retur) string { return r.Alasan }},
+	{"reason_code", func(r Retur) string { return r.ReasonCode }},
+	{"status", func(r Retur) string { return r.Status }},
+	{"pengembalian", func(r Retur) string { return r.Pengembalian }},
+	{"customer_id", func(r Retur) string { return r.CustomerID }},
+	{"order_id", func(r Retur) string { return r.OrderID }},
+	{"order_date", func(r Retur) string { return csvTime(r.OrderDate) }},
+	{"refund_amount", func(r Retur) string { return strconv.FormatInt(r.RefundAmount, 10) }}, // Dalam minor units sesuai mata uang
+	{"currency", func(r Retur) string { return r.Currency }},
+	{"decided_by", func(r Retur) string { return r.DecidedBy }},
+	{"decided_at", func(r Retur) string { return csvTime(r.DecidedAt) }},
+	{"decision_note", func(r Retur) string { return r.DecisionNote }},
+	{"assigned_to", func(r Retur) string { return r.AssignedTo }},
+	{"tags", func(r Retur) string { return strings.Join(r.Tags, ";") }},
+	{"created_at", func(r Retur) string { return r.CreatedAt.Format(time.RFC3339) }},
+	{"sla_deadline", func(r Retur) string { return csvTime(r.SLADeadline) }},
+	{"overdue", func(r Retur) string { return strconv.FormatBool(r.Overdue) }},
+}
+
+// deletedCSVColumns adalah kolom penanda retur yang dihapus, ikut di urutan default hanya pada ?include_deleted=true
+var deletedCSVColumns = []csvColumn{
+	{"deleted", func(r Retur) string { return strconv.FormatBool(r.Deleted) }},
+	{"deleted_at", func(r Retur) string { return csvTime(r.DeletedAt) }},
+}
+
+// parseCSVColumns membaca ?columns=id,barang,refund_amount menjadi daftar kolom sesuai urutan yang diminta
+// Tanpa parameter, semua kolom dikembalikan dalam urutan default, ditambah deletedCSVColumns jika withDeleted
+func parseCSVColumns(raw string, withDeleted bool) ([]csvColumn, error) {
+	if strings.TrimSpace(raw) == "" {
+		if withDeleted {
+			return append(slices.Clip(csvColumns), deletedCSVColumns...), nil
+		}
+		return csvColumns, nil
+	}
+	known := make(map[string]csvColumn, len(csvColumns)+len(deletedCSVColumns))
+	for _, column := range append(slices.Clip(csvColumns), deletedCSVColumns...) {
+		known[column.Name] = column
+	}
+	var columns []csvColumn
+	seen := map[string]bool{}
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue // Kolom duplikat hanya ditulis sekali
+		}
+		column, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown column '%s'", name)
+		}
+		seen[name] = true
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// exportRetursHandler adalah handler untuk mengekspor daftar retur sebagai CSV datar secara streaming
+// Filter dan urutan sama dengan GET /retur (lihat listFilters dan listOrder); ?columns memilih dan mengurutkan kolom
+// Field PII (RETUR_PII_FIELDS) disamarkan kecuali request membawa token admin
+// Dengan ?include_deleted=true, retur di stack undo yang cocok dengan filter ikut diekspor sesuai ?sort (lihat deletedReturs)
+func exportRetursHandler(w http.ResponseWriter, r *http.Request) {
+	columns, err := parseCSVColumns(r.URL.Query().Get("columns"), includeDeleted(r))
+	if err != nil {
+		handleError(w, http.StatusBadRequest, err.Error()) // Kolom tidak dikenal
+		return
+	}
+	filters, err := listFilters(r)
+	if err != nil {
+		handleError(w, http.StatusBadRequest, err.Error()) // Parameter filter tidak valid
+		return
+	}
+	order, err := listOrder(r)
+	if err != nil {
+		handleError(w, http.StatusBadRequest, err.Error()) // Kolom sort tidak dikenal
+		return
+	}
+	var deleted *deletedMerge
+	if includeDeleted(r) {
+		if deleted, err = deletedReturs(r); err != nil {
+			handleError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	query := db.WithContext(r.Context()).Model(&Retur{}).Scopes(filters).Order(order)
+	rows, err := query.Rows()
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to export returns") // Jika query gagal, kirimkan error
+		return
+	}
+	defer rows.Close()
+
+	filename := "retur-" + clock.Now().Format("20060102") + ".csv"
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	if _, err := writeReturCSV(w, query, rows, columns, isAdmin(r), deleted); err != nil {
+		log.Printf("export returns failed: %v", err) // Header sudah terkirim, stream hanya bisa dihentikan
+	}
+}
+
+// csvSafe menambahkan tanda kutip tunggal di depan sel yang diawali =, +, -, @, tab, atau carriage return
+// Excel dan LibreOffice menganggap sel seperti itu sebagai formula (CSV injection), misalnya alasan "=HYPERLINK(...)"
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// writeReturCSV menulis header dan semua baris dari rows sebagai CSV dengan kolom yang dipilih
+// Dipakai oleh ekspor streaming dan job ekspor async; mengembalikan jumlah baris data yang ditulis
+// Field PII (RETUR_PII_FIELDS) disamarkan kecuali raw bernilai true (ekspor oleh admin)
+// Setiap sel dilewatkan ke csvSafe agar teks dari pengguna tidak dijalankan sebagai formula oleh spreadsheet
+// deleted (boleh nil) disisipkan di antara baris query sesuai urutan ?sort, dipakai untuk ?include_deleted=true
+func writeReturCSV(w io.Writer, query *gorm.DB, rows *sql.Rows, columns []csvColumn, raw bool, deleted *deletedMerge) (int, error) {
+	writer := csv.NewWriter(w)
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Name
+	}
+	writer.Write(header) // Header CSV sesuai kolom yang dipilih
+	record := make([]string, len(columns))
+	count := 0
+	write := func(retur Retur) error {
+		applySLA(&retur) // Hitung sla_deadline dan overdue
+		for i, column := range columns {
+			record[i] = column.Value(retur)
+			if !raw {
+				record[i] = redactPII(column.Name, record[i])
+			}
+			record[i] = csvSafe(record[i])
+		}
+		count++
+		return writer.Write(record)
+	}
+	for rows.Next() {
+		var retur Retur
+		if err := query.ScanRows(rows, &retur); err != nil {
+			writer.Flush()
+			return count, err // Hentikan jika baris gagal dibaca
+		}
+		deleted.emitBefore(retur, write)
+		write(retur)
+	}
+	if err := rows.Err(); err != nil {
+		writer.Flush()
+		return count, err
+	}
+	deleted.emitRest(write)
+	writer.Flush() // Pastikan semua baris tertulis
+	return count, writer.Error()
+}
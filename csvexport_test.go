@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCSVSafeEscapesFormulaPrefixes(t *testing.T) {
+	for _, value := range []string{"=HYPERLINK(\"x\")", "+1", "-2", "@SUM(A1)", "\tcmd", "\rcmd"} {
+		if got := csvSafe(value); got != "'"+value {
+			t.Errorf("csvSafe(%q) = %q", value, got)
+		}
+	}
+	for _, value := range []string{"", "Sepatu", "1=1", "a-b"} {
+		if got := csvSafe(value); got != value {
+			t.Errorf("csvSafe(%q) = %q, want unchanged", value, got)
+		}
+	}
+}
+
+func TestParseCSVColumns(t *testing.T) {
+	columns, err := parseCSVColumns(" refund_amount, id ,id,deleted", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, column := range columns {
+		names = append(names, column.Name)
+	}
+	if strings.Join(names, ",") != "refund_amount,id,deleted" {
+		t.Fatalf("columns = %v", names)
+	}
+	if _, err := parseCSVColumns("id,password", false); err == nil || !strings.Contains(err.Error(), "password") {
+		t.Fatalf("unknown column: %v", err)
+	}
+	all, _ := parseCSVColumns("", false)
+	withDeleted, _ := parseCSVColumns("", true)
+	if len(all) != len(csvColumns) || len(withDeleted) != len(csvColumns)+len(deletedCSVColumns) {
+		t.Fatalf("default columns: %d / %d", len(all), len(withDeleted))
+	}
+	if withDeleted[len(csvColumns)].Name != "deleted" || len(csvColumns) != len(all) {
+		t.Fatal("appending deleted columns changed the default list")
+	}
+}
+
+func TestExportEscapesCellsAndRedactsForNonAdmin(t *testing.T) {
+	testDB(t)
+	withConfig(t, func(c *Config) {
+		c.PIIFields = []string{"customer_id"}
+		c.PIIMode = piiMask
+	})
+	seedReturs(t, Retur{Barang: "Kabel", Alasan: "=HYPERLINK(\"http://evil\")", CustomerID: "CUST-0042"})
+
+	read := func(req *http.Request) []string {
+		rec := serve(req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("export: %d %s", rec.Code, rec.Body.String())
+		}
+		records, err := csv.NewReader(rec.Body).ReadAll()
+		if err != nil || len(records) != 2 {
+			t.Fatalf("csv: %v, %d records", err, len(records))
+		}
+		return records[1]
+	}
+	target := "/retur/export?columns=alasan,customer_id"
+	masked := read(httptest.NewRequest(http.MethodGet, target, nil))
+	if masked[0] != "'=HYPERLINK(\"http://evil\")" || masked[1] != "*******42" {
+		t.Fatalf("non-admin row = %q", masked)
+	}
+	raw := read(asAdmin(t, httptest.NewRequest(http.MethodGet, target, nil)))
+	if raw[0] != "'=HYPERLINK(\"http://evil\")" || raw[1] != "CUST-0042" {
+		t.Fatalf("admin row = %q", raw)
+	}
+}
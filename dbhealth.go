@@ -0,0 +1,49 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Waktu (unix nano) tulis database terakhir yang berhasil dan gagal, diperbarui oleh callback GORM
+var (
+	lastWriteSuccess atomic.Int64
+	lastWriteFailure atomic.Int64
+)
+
+// registerWriteCallbacks memasang callback GORM yang mencatat waktu tulis (create/update/delete) terakhir
+func registerWriteCallbacks(db *gorm.DB) {
+	record := func(tx *gorm.DB) {
+		if tx.Error != nil {
+			lastWriteFailure.Store(clock.Now().UnixNano())
+			return
+		}
+		lastWriteSuccess.Store(clock.Now().UnixNano())
+	}
+	callbacks := db.Callback()
+	callbacks.Create().After("gorm:create").Register("health:create", record)
+	callbacks.Update().After("gorm:update").Register("health:update", record)
+	callbacks.Delete().After("gorm:delete").Register("health:delete", record)
+}
+
+// writeHealth merangkum kondisi tulis database untuk endpoint /status
+// Tulis dianggap stale jika ada kegagalan setelah tulis sukses terakhir dan tulis sukses terakhir lebih lama dari
+// RETUR_WRITE_STALE_AFTER; kondisi ini menandakan tulis gagal meskipun ping dan query baca berhasil
+func writeHealth() map[string]interface{} {
+	health := map[string]interface{}{"stale": false}
+	success, failure := lastWriteSuccess.Load(), lastWriteFailure.Load()
+	if success > 0 {
+		at := time.Unix(0, success)
+		health["last_success_at"] = at.UTC()
+		health["seconds_since_success"] = int64(clock.Now().Sub(at).Seconds())
+	}
+	if failure > 0 {
+		health["last_failure_at"] = time.Unix(0, failure).UTC()
+	}
+	if failure > success && cfg.WriteStaleAfter > 0 {
+		health["stale"] = success == 0 || clock.Now().Sub(time.Unix(0, success)) > cfg.WriteStaleAfter
+	}
+	return health
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWriteHealthStaleAfterFailures(t *testing.T) {
+	fake := useFakeClock(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	withConfig(t, func(c *Config) { c.WriteStaleAfter = time.Minute })
+	savedSuccess, savedFailure := lastWriteSuccess.Load(), lastWriteFailure.Load()
+	t.Cleanup(func() {
+		lastWriteSuccess.Store(savedSuccess)
+		lastWriteFailure.Store(savedFailure)
+	})
+
+	lastWriteSuccess.Store(fake.Now().UnixNano())
+	lastWriteFailure.Store(0)
+	if writeHealth()["stale"] != false {
+		t.Fatal("stale without failures")
+	}
+	fake.Advance(30 * time.Second)
+	lastWriteFailure.Store(fake.Now().UnixNano())
+	if writeHealth()["stale"] != false {
+		t.Fatal("stale before RETUR_WRITE_STALE_AFTER")
+	}
+	fake.Advance(time.Minute)
+	lastWriteFailure.Store(fake.Now().UnixNano())
+	health := writeHealth()
+	if health["stale"] != true || health["seconds_since_success"] != int64(90) {
+		t.Fatalf("health = %v", health)
+	}
+	fake.Advance(time.Second)
+	lastWriteSuccess.Store(fake.Now().UnixNano())
+	if writeHealth()["stale"] != false {
+		t.Fatal("still stale after a successful write")
+	}
+}
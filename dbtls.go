@@ -0,0 +1,48 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// dbTLSConfigName adalah nama konfigurasi TLS yang didaftarkan ke driver MySQL dan dipakai lewat parameter tls pada DSN
+const dbTLSConfigName = "custom"
+
+// databaseDSN mengembalikan DSN untuk koneksi database; jika RETUR_DB_TLS aktif, konfigurasi TLS didaftarkan
+// ke driver MySQL dan DSN diberi parameter tls=custom (menggantikan parameter tls yang sudah ada)
+// CA dari RETUR_DB_TLS_CA wajib bisa dibaca sehingga aplikasi gagal cepat jika file tidak ada atau tidak valid
+func databaseDSN() (string, error) {
+	if !cfg.DBTLS {
+		return cfg.DSN, nil // Tanpa TLS untuk database lokal
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.DBTLSSkipVerify, // Hanya untuk development dengan sertifikat self-signed
+	}
+	if cfg.DBTLSCA != "" {
+		pem, err := os.ReadFile(cfg.DBTLSCA)
+		if err != nil {
+			return "", fmt.Errorf("read database CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return "", errors.New("database CA file contains no valid PEM certificates")
+		}
+		tlsConfig.RootCAs = pool // Tanpa RETUR_DB_TLS_CA, CA sistem yang dipakai
+	}
+	if err := mysql.RegisterTLSConfig(dbTLSConfigName, tlsConfig); err != nil {
+		return "", err
+	}
+
+	parsed, err := mysql.ParseDSN(cfg.DSN)
+	if err != nil {
+		return "", fmt.Errorf("parse DSN: %w", err)
+	}
+	parsed.TLSConfig = dbTLSConfigName
+	return parsed.FormatDSN(), nil
+}
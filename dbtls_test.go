@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDatabaseDSNWithoutTLS(t *testing.T) {
+	withConfig(t, func(c *Config) { c.DBTLS = false; c.DSN = "u:p@tcp(db:3306)/retur" })
+	if dsn, err := databaseDSN(); err != nil || dsn != "u:p@tcp(db:3306)/retur" {
+		t.Fatalf("dsn = %q, %v", dsn, err)
+	}
+}
+
+func TestDatabaseDSNWithCustomCA(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	withConfig(t, func(c *Config) {
+		c.DBTLS = true
+		c.DBTLSCA = caFile
+		c.DSN = "u:p@tcp(db:3306)/retur?tls=false&parseTime=true"
+	})
+	dsn, err := databaseDSN()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(dsn, "tls="+dbTLSConfigName) || strings.Contains(dsn, "tls=false") || !strings.Contains(dsn, "parseTime=true") {
+		t.Fatalf("dsn = %q", dsn)
+	}
+}
+
+func TestDatabaseDSNRejectsBadCA(t *testing.T) {
+	dir := t.TempDir()
+	garbage := filepath.Join(dir, "garbage.pem")
+	os.WriteFile(garbage, []byte("not a certificate"), 0o600)
+	for _, ca := range []string{filepath.Join(dir, "missing.pem"), garbage} {
+		withConfig(t, func(c *Config) { c.DBTLS = true; c.DBTLSCA = ca })
+		if _, err := databaseDSN(); err == nil {
+			t.Errorf("CA %s accepted", ca)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// recordedResponse adalah response yang disimpan untuk dikirim ulang ke request duplikat
+type recordedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// dedupEntry adalah satu request yang sedang atau sudah diproses di dalam jendela deduplikasi
+type dedupEntry struct {
+	done     chan struct{} // Ditutup setelah response pertama selesai direkam
+	response recordedResponse
+	expires  time.Time
+}
+
+// dedupCache menyimpan response per kunci (route + ID retur + idempotency key) selama jendela deduplikasi
+type dedupCache struct {
+	mu      sync.Mutex
+	entries map[string]*dedupEntry
+	window  func() time.Duration // Lama response disimpan; 0 berarti deduplikasi nonaktif
+}
+
+// decisionDedup adalah cache deduplikasi untuk endpoint keputusan (approve/disapprove) selama RETUR_DEDUP_WINDOW
+var decisionDedup = &dedupCache{entries: make(map[string]*dedupEntry), window: func() time.Duration { return cfg.DedupWindow }}
+
+// undoDedup adalah cache deduplikasi untuk undo selama RETUR_UNDO_IDEMPOTENCY_TTL, sehingga retry undo dengan
+// Idempotency-Key yang sama menerima hasil restore pertama alih-alih mengembalikan entry berikutnya dari stack
+var undoDedup = &dedupCache{entries: make(map[string]*dedupEntry), window: func() time.Duration { return cfg.UndoIdempotencyTTL }}
+
+// responseRecorder merekam response handler sambil tetap meneruskannya ke client
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(p []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(p)
+	return rec.ResponseWriter.Write(p)
+}
+
+// Unwrap mengembalikan ResponseWriter asli agar opsi response (pretty, naming) tetap ditemukan
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// idempotencyKeyFromRequest mengambil kunci dari header Idempotency-Key, atau X-Request-ID jika tidak ada
+func idempotencyKeyFromRequest(r *http.Request) string {
+	if key := strings.TrimSpace(r.Header.Get("Idempotency-Key")); key != "" {
+		return key
+	}
+	return strings.TrimSpace(r.Header.Get("X-Request-ID"))
+}
+
+// dedupRequests membungkus handler sehingga request duplikat (kunci sama di dalam jendela cache) menerima
+// response pertama alih-alih diproses ulang; duplikat yang datang saat request pertama masih berjalan menunggu hasilnya
+// Nonaktif jika jendela cache 0 atau request tidak membawa Idempotency-Key/X-Request-ID
+func dedupRequests(cache *dedupCache, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := idempotencyKeyFromRequest(r)
+		if cache.window() <= 0 || key == "" {
+			next(w, r)
+			return
+		}
+		routeName := ""
+		if route := mux.CurrentRoute(r); route != nil {
+			routeName = route.GetName()
+		}
+		cacheKey := routeName + "|" + mux.Vars(r)["id"] + "|" + key
+
+		entry, owner := cache.acquire(cacheKey)
+		if !owner {
+			<-entry.done // Tunggu request pertama selesai
+			replayResponse(w, entry.response)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w}
+		defer func() {
+			entry.response = recordedResponse{status: rec.status, header: w.Header().Clone(), body: rec.body.Bytes()}
+			if entry.response.status == 0 {
+				entry.response.status = http.StatusOK
+			}
+			close(entry.done)
+		}()
+		next(rec, r)
+	}
+}
+
+// acquire mengembalikan entri untuk kunci; owner bernilai true jika pemanggil adalah request pertama yang harus diproses
+func (c *dedupCache) acquire(key string) (entry *dedupEntry, owner bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := clock.Now()
+	for k, e := range c.entries {
+		if now.After(e.expires) {
+			delete(c.entries, k) // Buang entri yang sudah keluar dari jendela deduplikasi
+		}
+	}
+	if existing, ok := c.entries[key]; ok {
+		return existing, false
+	}
+	entry = &dedupEntry{done: make(chan struct{}), expires: now.Add(c.window())}
+	c.entries[key] = entry
+	return entry, true
+}
+
+// replayResponse mengirim ulang response yang direkam ke request duplikat
+func replayResponse(w http.ResponseWriter, response recordedResponse) {
+	for name, values := range response.header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true") // Tandai bahwa response ini adalah hasil request sebelumnya
+	w.WriteHeader(response.status)
+	w.Write(response.body)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// dedupRouter memasang handler penghitung di belakang dedupRequests pada route bernama seperti di aplikasi
+func dedupRouter(cache *dedupCache, calls *atomic.Int64, release <-chan struct{}) http.Handler {
+	r := mux.NewRouter()
+	r.HandleFunc("/retur/{id}/approve", dedupRequests(cache, func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		if release != nil {
+			<-release
+		}
+		respondJSON(w, http.StatusOK, map[string]int64{"call": n})
+	})).Methods("POST").Name("approveRetur")
+	return r
+}
+
+func dedupPost(h http.Handler, id, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/retur/"+id+"/approve", nil)
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestDedupReplaysWithinWindow(t *testing.T) {
+	fake := useFakeClock(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	cache := &dedupCache{entries: map[string]*dedupEntry{}, window: func() time.Duration { return time.Minute }}
+	var calls atomic.Int64
+	h := dedupRouter(cache, &calls, nil)
+
+	first := dedupPost(h, "1", "k1")
+	second := dedupPost(h, "1", "k1")
+	if calls.Load() != 1 || second.Body.String() != first.Body.String() || second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("duplicate processed again: calls=%d replayed=%q", calls.Load(), second.Header().Get("Idempotent-Replayed"))
+	}
+	dedupPost(h, "2", "k1") // Kunci sama, retur lain
+	dedupPost(h, "1", "")   // Tanpa kunci tidak pernah dideduplikasi
+	if calls.Load() != 3 {
+		t.Fatalf("calls = %d, want 3", calls.Load())
+	}
+
+	fake.Advance(2 * time.Minute)
+	dedupPost(h, "1", "k1")
+	if calls.Load() != 4 {
+		t.Fatal("entry outside the window was replayed")
+	}
+}
+
+func TestDedupConcurrentDuplicateWaitsForFirst(t *testing.T) {
+	cache := &dedupCache{entries: map[string]*dedupEntry{}, window: func() time.Duration { return time.Minute }}
+	var calls atomic.Int64
+	release := make(chan struct{})
+	h := dedupRouter(cache, &calls, release)
+
+	results := make([]*httptest.ResponseRecorder, 5)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = dedupPost(h, "7", "same")
+		}()
+	}
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	if calls.Load() != 1 {
+		t.Fatalf("handler ran %d times", calls.Load())
+	}
+	for _, rec := range results {
+		if rec.Code != http.StatusOK || rec.Body.String() != results[0].Body.String() {
+			t.Fatalf("response %d %s differs from first %s", rec.Code, rec.Body.String(), results[0].Body.String())
+		}
+	}
+}
+
+func TestDedupDisabledWithZeroWindow(t *testing.T) {
+	cache := &dedupCache{entries: map[string]*dedupEntry{}, window: func() time.Duration { return 0 }}
+	var calls atomic.Int64
+	h := dedupRouter(cache, &calls, nil)
+	dedupPost(h, "1", "k")
+	dedupPost(h, "1", "k")
+	if calls.Load() != 2 {
+		t.Fatalf("calls = %d, want 2 with deduplication disabled", calls.Load())
+	}
+}
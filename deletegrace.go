@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// recentlyApproved memeriksa apakah retur disetujui kurang dari RETUR_DELETE_GRACE yang lalu; 0 berarti nonaktif
+// Retur seperti ini kemungkinan sudah memicu refund di sistem lain sehingga tidak boleh terhapus tanpa sengaja
+func recentlyApproved(retur Retur) bool {
+	return cfg.DeleteGrace > 0 && retur.Status == "Disetujui" && retur.DecidedAt != nil &&
+		clock.Now().Sub(*retur.DecidedAt) < cfg.DeleteGrace
+}
+
+// deleteGraceOverridden memeriksa apakah admin mengirim header override (RETUR_DELETE_GRACE_OVERRIDE_HEADER) bernilai true
+func deleteGraceOverridden(r *http.Request) bool {
+	return cfg.DeleteGraceOverrideHeader != "" && r.Header.Get(cfg.DeleteGraceOverrideHeader) == "true" && isAdmin(r)
+}
+
+// errDeleteGrace menandai penghapusan massal yang dibatalkan karena ada retur dalam masa tenggang
+var errDeleteGrace = errors.New("delete blocked by grace period")
+
+// deleteGraceBlocked mengembalikan ID retur yang masih dalam masa tenggang setelah disetujui, kosong jika admin mengirim override
+func deleteGraceBlocked(r *http.Request, returs []Retur) []string {
+	if deleteGraceOverridden(r) {
+		return nil
+	}
+	var blocked []string
+	for _, retur := range returs {
+		if recentlyApproved(retur) {
+			blocked = append(blocked, strconv.Itoa(retur.ID))
+		}
+	}
+	return blocked
+}
+
+// respondDeleteGrace mengirimkan 409 beserta ID retur yang tidak boleh dihapus
+func respondDeleteGrace(w http.ResponseWriter, blocked []string) {
+	handleError(w, http.StatusConflict, fmt.Sprintf("Returns approved less than %s ago cannot be deleted: %s", cfg.DeleteGrace, strings.Join(blocked, ",")))
+}
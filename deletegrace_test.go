@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDeleteGraceBlocksRecentApprovals(t *testing.T) {
+	fake := useFakeClock(t, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	withConfig(t, func(c *Config) { c.DeleteGrace = time.Hour })
+	recent := fake.Now().Add(-10 * time.Minute)
+	old := fake.Now().Add(-2 * time.Hour)
+	returs := []Retur{
+		{ID: 1, Status: "Disetujui", DecidedAt: &recent},
+		{ID: 2, Status: "Disetujui", DecidedAt: &old},
+		{ID: 3, Status: "Tidak Disetujui", DecidedAt: &recent}, // Hanya retur yang disetujui yang dilindungi
+		{ID: 4, Status: "Disetujui"},
+	}
+	req := httptest.NewRequest(http.MethodDelete, "/retur/1/delete", nil)
+	if blocked := deleteGraceBlocked(req, returs); !slices.Equal(blocked, []string{"1"}) {
+		t.Fatalf("blocked = %v", blocked)
+	}
+
+	// Header override hanya berlaku untuk admin
+	req.Header.Set(cfg.DeleteGraceOverrideHeader, "true")
+	if blocked := deleteGraceBlocked(req, returs); len(blocked) != 1 {
+		t.Fatalf("non-admin override accepted: %v", blocked)
+	}
+	if blocked := deleteGraceBlocked(asAdmin(t, req), returs); blocked != nil {
+		t.Fatalf("admin override ignored: %v", blocked)
+	}
+
+	fake.Advance(time.Hour)
+	cfg.DeleteGrace = 0
+	if recentlyApproved(returs[0]) {
+		t.Fatal("grace period applied while disabled")
+	}
+}
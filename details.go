@@ -0,0 +1,61 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// parseDetailsPolicy membaca kebijakan field details wajib per jenis pengembalian,
+// contoh "uang=bank_name|account_number,barang=shipping_address"
+func parseDetailsPolicy(raw string) map[string][]string {
+	policy := make(map[string][]string)
+	for _, item := range strings.Split(raw, ",") {
+		pengembalian, fields, ok := strings.Cut(item, "=")
+		if !ok {
+			continue
+		}
+		for _, field := range strings.Split(fields, "|") {
+			if field = strings.TrimSpace(field); field != "" {
+				policy[strings.TrimSpace(pengembalian)] = append(policy[strings.TrimSpace(pengembalian)], field)
+			}
+		}
+	}
+	return policy
+}
+
+// mergeDetails menggabungkan details baru ke details lama dalam map baru agar salinan retur sebelum perubahan tidak ikut berubah
+func mergeDetails(current, incoming map[string]string) map[string]string {
+	if len(incoming) == 0 {
+		return current
+	}
+	merged := make(map[string]string, len(current)+len(incoming))
+	for key, value := range current {
+		merged[key] = value
+	}
+	for key, value := range incoming {
+		merged[key] = strings.TrimSpace(value)
+	}
+	return merged
+}
+
+// validateDetails memeriksa field details yang diwajibkan RETUR_DETAILS_POLICY untuk jenis pengembalian tertentu
+func validateDetails(pengembalian string, details map[string]string) []fieldError {
+	var errs []fieldError
+	for _, field := range cfg.DetailsPolicy[pengembalian] {
+		if details[field] == "" {
+			errs = append(errs, fieldError{Field: "details." + field, Message: "is required when pengembalian is '" + pengembalian + "'"})
+		}
+	}
+	return errs
+}
+
+// detailsPolicyStrings mengubah kebijakan details menjadi teks untuk ditampilkan
+func detailsPolicyStrings(policy map[string][]string) map[string]string {
+	result := make(map[string]string, len(policy))
+	for pengembalian, fields := range policy {
+		sorted := append([]string{}, fields...)
+		sort.Strings(sorted)
+		result[pengembalian] = strings.Join(sorted, "|")
+	}
+	return result
+}
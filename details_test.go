@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestParseDetailsPolicy(t *testing.T) {
+	got := parseDetailsPolicy(" uang = bank_name| account_number ,barang=shipping_address,invalid,kosong=")
+	want := map[string][]string{"uang": {"bank_name", "account_number"}, "barang": {"shipping_address"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("policy = %v, want %v", got, want)
+	}
+}
+
+func TestValidateDetailsPerPengembalian(t *testing.T) {
+	withConfig(t, func(c *Config) {
+		c.DetailsPolicy = map[string][]string{"uang": {"bank_name", "account_number"}, "barang": {"shipping_address"}}
+	})
+	tests := []struct {
+		pengembalian string
+		details      map[string]string
+		want         []string
+	}{
+		{"uang", nil, []string{"details.bank_name", "details.account_number"}},
+		{"uang", map[string]string{"bank_name": "BCA", "shipping_address": "Jl. Merdeka 1"}, []string{"details.account_number"}},
+		{"uang", map[string]string{"bank_name": "BCA", "account_number": "1234567890"}, nil},
+		{"barang", map[string]string{"bank_name": "BCA", "account_number": "1234567890"}, []string{"details.shipping_address"}},
+		{"barang", map[string]string{"shipping_address": "Jl. Merdeka 1"}, nil},
+	}
+	for _, tt := range tests {
+		var fields []string
+		for _, err := range validateDetails(tt.pengembalian, tt.details) {
+			fields = append(fields, err.Field)
+		}
+		if !reflect.DeepEqual(fields, tt.want) {
+			t.Errorf("%s with %v: missing %v, want %v", tt.pengembalian, tt.details, fields, tt.want)
+		}
+	}
+}
+
+func TestApproveRequiresPolicyDetails(t *testing.T) {
+	testDB(t)
+	useApprovers(t, map[string]string{"tok-budi": "budi"})
+	withConfig(t, func(c *Config) {
+		c.DetailsPolicy = map[string][]string{"uang": {"bank_name", "account_number"}, "barang": {"shipping_address"}}
+	})
+	tests := []struct {
+		pengembalian string
+		missing      []string
+		complete     map[string]string
+	}{
+		{"uang", []string{"details.bank_name", "details.account_number"}, map[string]string{"bank_name": "BCA", "account_number": "1234567890"}},
+		{"barang", []string{"details.shipping_address"}, map[string]string{"shipping_address": "Jl. Merdeka 1"}},
+	}
+	for _, tt := range tests {
+		retur := seedReturs(t, Retur{Barang: "Blender", Alasan: "Rusak"})[0]
+		path := fmt.Sprintf("/retur/%d/approve", retur.ID)
+		payload := map[string]interface{}{"pengembalian": tt.pengembalian, "refund_amount": 150000, "currency": "IDR"}
+
+		rec := serve(asApprover(jsonRequest(t, http.MethodPost, path, payload), "tok-budi"))
+		var body struct {
+			Errors []fieldError `json:"errors"`
+		}
+		decodeBody(t, rec, &body)
+		var fields []string
+		for _, err := range body.Errors {
+			fields = append(fields, err.Field)
+		}
+		if rec.Code != http.StatusUnprocessableEntity || !reflect.DeepEqual(fields, tt.missing) {
+			t.Fatalf("%s without details: %d, errors %v, want 422 listing %v", tt.pengembalian, rec.Code, fields, tt.missing)
+		}
+
+		payload["details"] = tt.complete
+		if rec := serve(asApprover(jsonRequest(t, http.MethodPost, path, payload), "tok-budi")); rec.Code != http.StatusOK {
+			t.Fatalf("%s with details: %d %s", tt.pengembalian, rec.Code, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm/schema"
+)
+
+// encryptedValuePrefix menandai nilai details yang tersimpan sebagai ciphertext AES-GCM (base64 dari nonce+ciphertext)
+const encryptedValuePrefix = "enc:v1:"
+
+// detailsAEAD adalah cipher AES-GCM untuk field details sensitif; nil berarti enkripsi nonaktif (RETUR_DETAILS_KEY kosong)
+var detailsAEAD cipher.AEAD
+
+// initDetailsCipher menyiapkan cipher dari RETUR_DETAILS_KEY (base64, 16/24/32 byte) dan mendaftarkan serializer GORM "details"
+// Key yang tidak valid membuat aplikasi gagal cepat agar data tidak tersimpan tanpa enkripsi
+func initDetailsCipher() error {
+	schema.RegisterSerializer("details", detailsSerializer{})
+	schema.RegisterSerializer("snapshot", snapshotSerializer{})
+	schema.RegisterSerializer("secret", secretSerializer{})
+	if cfg.DetailsKey == "" {
+		return nil
+	}
+	key, err := base64.StdEncoding.DecodeString(cfg.DetailsKey)
+	if err != nil {
+		return fmt.Errorf("RETUR_DETAILS_KEY must be base64: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	detailsAEAD, err = cipher.NewGCM(block)
+	return err
+}
+
+// sensitiveDetail memeriksa apakah key details termasuk RETUR_ENCRYPTED_DETAILS
+func sensitiveDetail(key string) bool {
+	for _, name := range cfg.EncryptedDetails {
+		if name == key {
+			return true
+		}
+	}
+	return false
+}
+
+// encryptDetail mengenkripsi satu nilai details dengan nonce acak
+func encryptDetail(plaintext string) (string, error) {
+	nonce := make([]byte, detailsAEAD.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := detailsAEAD.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedValuePrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptDetail membuka nilai details terenkripsi; nilai tanpa prefix (data lama atau field non-sensitif) dikembalikan apa adanya
+func decryptDetail(value string) (string, error) {
+	if !strings.HasPrefix(value, encryptedValuePrefix) {
+		return value, nil
+	}
+	if detailsAEAD == nil {
+		return "", errors.New("encrypted detail found but RETUR_DETAILS_KEY is not configured")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedValuePrefix))
+	if err != nil || len(sealed) < detailsAEAD.NonceSize() {
+		return "", errors.New("malformed encrypted detail")
+	}
+	nonce, ciphertext := sealed[:detailsAEAD.NonceSize()], sealed[detailsAEAD.NonceSize():]
+	plaintext, err := detailsAEAD.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// detailsSerializer menyimpan Details sebagai JSON seperti serializer:json, tetapi field sensitif
+// dienkripsi saat ditulis dan didekripsi saat dibaca sehingga handler selalu melihat plaintext
+type detailsSerializer struct{}
+
+// Scan membaca JSON details dari database dan mendekripsi nilai yang terenkripsi
+func (detailsSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var details map[string]string
+	var raw []byte
+	switch value := dbValue.(type) {
+	case nil:
+	case []byte:
+		raw = value
+	case string:
+		raw = []byte(value)
+	default:
+		return fmt.Errorf("unsupported details value %T", dbValue)
+	}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &details); err != nil {
+			return err
+		}
+	}
+	if err := openDetails(details); err != nil {
+		return err
+	}
+	field.ReflectValueOf(ctx, dst).Set(reflect.ValueOf(details))
+	return nil
+}
+
+// Value mengubah Details menjadi JSON untuk disimpan; field sensitif dienkripsi jika RETUR_DETAILS_KEY diatur
+func (detailsSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	details, _ := fieldValue.(map[string]string)
+	if details == nil {
+		return "null", nil // Sama dengan serializer:json untuk map kosong
+	}
+	stored, err := sealDetails(details)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(stored)
+	return string(data), err
+}
+
+// sealDetails mengembalikan salinan details dengan field sensitif terenkripsi; nilai yang sudah terenkripsi tidak dienkripsi ulang
+func sealDetails(details map[string]string) (map[string]string, error) {
+	if details == nil {
+		return nil, nil
+	}
+	stored := make(map[string]string, len(details))
+	for key, value := range details {
+		if detailsAEAD != nil && sensitiveDetail(key) && !strings.HasPrefix(value, encryptedValuePrefix) {
+			encrypted, err := encryptDetail(value)
+			if err != nil {
+				return nil, err
+			}
+			value = encrypted
+		}
+		stored[key] = value
+	}
+	return stored, nil
+}
+
+// openDetails mendekripsi nilai details yang terenkripsi langsung di map yang diberikan
+func openDetails(details map[string]string) error {
+	for key, value := range details {
+		plaintext, err := decryptDetail(value)
+		if err != nil {
+			return fmt.Errorf("decrypt details.%s: %w", key, err)
+		}
+		details[key] = plaintext
+	}
+	return nil
+}
+
+// sealReturs mengembalikan salinan snapshot retur dengan details sensitif terenkripsi, untuk data yang keluar dari
+// tabel returs (undo_entries, arsip, ekspor undo state) sehingga nomor rekening tidak pernah tersimpan sebagai plaintext
+func sealReturs(returs []Retur) ([]Retur, error) {
+	sealed := make([]Retur, len(returs))
+	for i, retur := range returs {
+		details, err := sealDetails(retur.Details)
+		if err != nil {
+			return nil, err
+		}
+		retur.Details = details
+		sealed[i] = retur
+	}
+	return sealed, nil
+}
+
+// openReturs mendekripsi details pada snapshot retur hasil sealReturs langsung di slice yang diberikan
+func openReturs(returs []Retur) error {
+	for i := range returs {
+		if err := openDetails(returs[i].Details); err != nil {
+			return fmt.Errorf("retur %d: %w", returs[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// maskSensitiveDetails mengembalikan salinan details dengan field RETUR_ENCRYPTED_DETAILS disamarkan (empat karakter
+// terakhir tetap terlihat), dipakai untuk retur yang dilihat non-admin dan payload webhook
+func maskSensitiveDetails(details map[string]string) map[string]string {
+	if details == nil {
+		return nil
+	}
+	masked := make(map[string]string, len(details))
+	for key, value := range details {
+		if sensitiveDetail(key) {
+			value = maskTail(value, 4)
+		}
+		masked[key] = value
+	}
+	return masked
+}
+
+// concealRetur menyamarkan data sensitif retur untuk pembaca yang bukan admin: details RETUR_ENCRYPTED_DETAILS
+// dan field PII (lihat redactRetur)
+func concealRetur(retur *Retur) {
+	retur.Details = maskSensitiveDetails(retur.Details)
+	redactRetur(retur)
+}
+
+// maskRetur menyamarkan retur sebelum dikirim ke client kecuali request membawa token admin
+// Plaintext details sensitif dan PII hanya untuk admin; aturan yang sama dipakai setiap handler yang mengirim retur dan daftar undo
+func maskRetur(r *http.Request, retur *Retur) {
+	if !isAdmin(r) {
+		concealRetur(retur)
+	}
+}
+
+// maskReturs adalah maskRetur untuk setiap retur di slice, langsung di slice yang diberikan
+func maskReturs(r *http.Request, returs []Retur) {
+	if isAdmin(r) {
+		return
+	}
+	for i := range returs {
+		concealRetur(&returs[i])
+	}
+}
+
+// maskEntries mengembalikan salinan entri undo dengan data sensitif disamarkan untuk non-admin
+func maskEntries(r *http.Request, entries []undoEntry) []undoEntry {
+	if isAdmin(r) {
+		return entries
+	}
+	masked := make([]undoEntry, len(entries))
+	for i, entry := range entries {
+		returs := make([]Retur, len(entry.Returs))
+		for j, retur := range entry.Returs {
+			concealRetur(&retur)
+			returs[j] = retur
+		}
+		entry.Returs = returs
+		masked[i] = entry
+	}
+	return masked
+}
+
+// snapshotSerializer menyimpan []Retur sebagai JSON seperti serializer:json, tetapi details sensitif setiap retur
+// dienkripsi lewat sealReturs; dipakai UndoRecord.Returs
+type snapshotSerializer struct{}
+
+// Scan membaca JSON snapshot dari database dan mendekripsi details yang terenkripsi
+func (snapshotSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var returs []Retur
+	var raw []byte
+	switch value := dbValue.(type) {
+	case nil:
+	case []byte:
+		raw = value
+	case string:
+		raw = []byte(value)
+	default:
+		return fmt.Errorf("unsupported snapshot value %T", dbValue)
+	}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &returs); err != nil {
+			return err
+		}
+	}
+	if err := openReturs(returs); err != nil {
+		return err
+	}
+	field.ReflectValueOf(ctx, dst).Set(reflect.ValueOf(returs))
+	return nil
+}
+
+// Value mengubah snapshot menjadi JSON untuk disimpan dengan details sensitif terenkripsi
+func (snapshotSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	returs, _ := fieldValue.([]Retur)
+	sealed, err := sealReturs(returs)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(sealed)
+	return string(data), err
+}
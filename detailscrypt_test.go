@@ -0,0 +1,162 @@
+package main
+
+import (
+	"crypto/cipher"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// useDetailsKey mengaktifkan enkripsi details dengan key acak tetap selama satu test
+func useDetailsKey(tb testing.TB) {
+	tb.Helper()
+	saved := detailsAEAD
+	withConfig(tb, func(c *Config) {
+		c.DetailsKey = base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+		c.EncryptedDetails = []string{"account_number"}
+	})
+	if err := initDetailsCipher(); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { detailsAEAD = saved })
+}
+
+// withoutDetailsKey menonaktifkan enkripsi details selama satu test
+func withoutDetailsKey(tb testing.TB) {
+	tb.Helper()
+	saved := detailsAEAD
+	detailsAEAD = nil
+	tb.Cleanup(func() { detailsAEAD = saved })
+}
+
+func TestSealDetailsEncryptsOnlySensitiveKeys(t *testing.T) {
+	useDetailsKey(t)
+	details := map[string]string{"account_number": "1234567890", "bank": "BCA"}
+	sealed, err := sealDetails(details)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sealed["account_number"], encryptedValuePrefix) || sealed["bank"] != "BCA" {
+		t.Fatalf("sealed = %v", sealed)
+	}
+	if details["account_number"] != "1234567890" {
+		t.Fatal("sealDetails modified its input")
+	}
+	again, _ := sealDetails(sealed)
+	if again["account_number"] != sealed["account_number"] {
+		t.Fatal("already encrypted value was encrypted twice")
+	}
+	if err := openDetails(sealed); err != nil || sealed["account_number"] != "1234567890" {
+		t.Fatalf("openDetails = %v, %v", sealed, err)
+	}
+}
+
+func TestDecryptDetailFailures(t *testing.T) {
+	useDetailsKey(t)
+	if plain, err := decryptDetail("plaintext"); err != nil || plain != "plaintext" {
+		t.Fatalf("legacy plaintext: %q, %v", plain, err)
+	}
+	sealed, _ := encryptDetail("secret")
+	raw, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, encryptedValuePrefix))
+	raw[len(raw)-1] ^= 0xff // Ubah satu byte tag GCM
+	tampered := encryptedValuePrefix + base64.StdEncoding.EncodeToString(raw)
+	if _, err := decryptDetail(tampered); err == nil {
+		t.Fatal("tampered ciphertext accepted")
+	}
+	if _, err := decryptDetail(encryptedValuePrefix + "!!"); err == nil {
+		t.Fatal("malformed ciphertext accepted")
+	}
+
+	var saved cipher.AEAD
+	saved, detailsAEAD = detailsAEAD, nil
+	defer func() { detailsAEAD = saved }()
+	if _, err := decryptDetail(sealed); err == nil {
+		t.Fatal("encrypted value opened without RETUR_DETAILS_KEY")
+	}
+}
+
+func TestMaskSensitiveDetailsKeepsLastFour(t *testing.T) {
+	withConfig(t, func(c *Config) { c.EncryptedDetails = []string{"account_number"} })
+	masked := maskSensitiveDetails(map[string]string{"account_number": "1234567890", "bank": "BCA"})
+	if masked["account_number"] != "******7890" || masked["bank"] != "BCA" {
+		t.Fatalf("masked = %v", masked)
+	}
+}
+
+func TestSealRetursLeavesSourceUntouched(t *testing.T) {
+	useDetailsKey(t)
+	returs := []Retur{{ID: 1, Details: map[string]string{"account_number": "111122223333"}}}
+	sealed, err := sealReturs(returs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if returs[0].Details["account_number"] != "111122223333" || !strings.HasPrefix(sealed[0].Details["account_number"], encryptedValuePrefix) {
+		t.Fatalf("source %v sealed %v", returs[0].Details, sealed[0].Details)
+	}
+	if err := openReturs(sealed); err != nil || sealed[0].Details["account_number"] != "111122223333" {
+		t.Fatalf("openReturs = %v, %v", sealed[0].Details, err)
+	}
+}
+
+func TestMaskReturGivesPlaintextOnlyToAdmin(t *testing.T) {
+	withConfig(t, func(c *Config) { c.EncryptedDetails = []string{"account_number"} })
+	details := map[string]string{"account_number": "1234567890", "bank": "BCA"}
+
+	retur := Retur{Details: details}
+	maskRetur(asAdmin(t, httptest.NewRequest(http.MethodGet, "/retur/1", nil)), &retur)
+	if retur.Details["account_number"] != "1234567890" {
+		t.Fatalf("admin reader got %v", retur.Details)
+	}
+	retur = Retur{Details: details}
+	maskRetur(httptest.NewRequest(http.MethodGet, "/retur/1", nil), &retur)
+	if retur.Details["account_number"] != "******7890" || retur.Details["bank"] != "BCA" {
+		t.Fatalf("non-admin reader got %v", retur.Details)
+	}
+	if details["account_number"] != "1234567890" {
+		t.Fatal("maskRetur modified the stored details")
+	}
+}
+
+func TestNotifierMasksSensitiveDetails(t *testing.T) {
+	withConfig(t, func(c *Config) { c.EncryptedDetails = []string{"account_number"} })
+	n := &notifier{url: "http://hooks.example.com/retur", queue: make(chan notification, 1)} // Tanpa worker, notifikasi dibaca langsung dari antrian
+	if queued, _ := n.Enqueue("retur.approved", Retur{ID: 1, Details: map[string]string{"account_number": "1234567890"}}); queued != 1 {
+		t.Fatalf("queued = %d, want 1", queued)
+	}
+	if account := (<-n.queue).Retur.Details["account_number"]; account != "******7890" {
+		t.Fatalf("webhook payload carries account number %q", account)
+	}
+}
+
+func TestDetailsStoredEncryptedAndReadByRole(t *testing.T) {
+	testDB(t)
+	useDetailsKey(t)
+	seeded := seedReturs(t, Retur{Barang: "Kulkas", Alasan: "bocor", Details: map[string]string{"account_number": "1234567890", "bank": "BCA"}})[0]
+
+	var stored string
+	if err := db.Raw("SELECT details FROM returs WHERE id = ?", seeded.ID).Scan(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(stored, "1234567890") || !strings.Contains(stored, encryptedValuePrefix) {
+		t.Fatalf("details stored as %s", stored)
+	}
+
+	target := fmt.Sprintf("/retur/%d", seeded.ID)
+	var admin, reader Retur
+	decodeBody(t, serve(asAdmin(t, httptest.NewRequest(http.MethodGet, target, nil))), &admin)
+	if admin.Details["account_number"] != "1234567890" {
+		t.Fatalf("admin reader got %v", admin.Details)
+	}
+	decodeBody(t, serve(httptest.NewRequest(http.MethodGet, target, nil)), &reader)
+	if reader.Details["account_number"] != "******7890" || reader.Details["bank"] != "BCA" {
+		t.Fatalf("non-admin reader got %v", reader.Details)
+	}
+	for _, path := range []string{"/retur", target + "/full", "/retur/overview", "/retur/sample"} {
+		if body := serve(httptest.NewRequest(http.MethodGet, path, nil)).Body.String(); strings.Contains(body, "1234567890") {
+			t.Errorf("%s leaks the account number: %s", path, body)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+// defaultEnvelopeLimit adalah jumlah item per halaman pada ?envelope=true jika ?limit tidak dikirim
+const defaultEnvelopeLimit = 50
+
+// pageMeta adalah metadata paginasi pada response envelope {"data": [...], "meta": {...}}
+type pageMeta struct {
+	Page       int   `json:"page"`                 // Halaman saat ini, dimulai dari 1
+	Limit      int   `json:"limit"`                // Jumlah item per halaman
+	Total      int64 `json:"total"`                // Jumlah seluruh item yang cocok dengan filter
+	TotalPages int   `json:"total_pages"`          // Jumlah halaman, 0 jika tidak ada item
+	HasNext    bool  `json:"has_next"`             // Masih ada item setelah halaman ini
+	HasPrev    bool  `json:"has_prev"`             // Ada item sebelum halaman ini
+	NextSince  *uint `json:"next_since,omitempty"` // Cursor polling berikutnya, hanya untuk GET /retur/changes
+}
+
+// envelopeContextKey adalah key context yang menandai request di bawah /v1, yang daftarnya selalu memakai envelope
+type envelopeContextKey struct{}
+
+// envelopeMiddleware memaksa response envelope untuk semua route daftar di bawah /v1
+func envelopeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), envelopeContextKey{}, true)))
+	})
+}
+
+// wantsEnvelope memeriksa apakah client meminta response daftar dalam envelope data/meta (?envelope=true atau path /v1)
+// Tanpa keduanya response tetap berupa array (atau bentuk lama endpoint) demi kompatibilitas
+// Envelope adalah satu dokumen JSON sehingga tidak bisa digabung dengan NDJSON; GET /retur menolak kombinasi itu dengan 400
+func wantsEnvelope(r *http.Request) bool {
+	forced, _ := r.Context().Value(envelopeContextKey{}).(bool)
+	return forced || r.URL.Query().Get("envelope") == "true"
+}
+
+// pageOffset membaca posisi halaman dari ?page (dimulai dari 1) atau, jika tidak dikirim, dari ?offset
+func pageOffset(r *http.Request, limit int) (int, error) {
+	if r.URL.Query().Get("page") == "" {
+		return queryInt(r, "offset", 0)
+	}
+	page, err := queryInt(r, "page", 1)
+	if err != nil || page == 0 || page-1 > math.MaxInt/limit {
+		return 0, errInvalidParam("page") // Halaman terlalu besar akan overflow saat dikalikan limit
+	}
+	return (page - 1) * limit, nil
+}
+
+// envelopePage membaca ?limit (default defaultEnvelopeLimit, maksimal maxListLimit) serta ?page atau ?offset
+// untuk endpoint daftar yang hanya dipaginasi dalam mode envelope
+func envelopePage(r *http.Request) (int, int, error) {
+	limit, err := queryInt(r, "limit", defaultEnvelopeLimit)
+	if err != nil || limit == 0 {
+		return 0, 0, errInvalidParam("limit")
+	}
+	limit = min(limit, maxListLimit)
+	offset, err := pageOffset(r, limit)
+	if err != nil {
+		return 0, 0, errInvalidParam("offset or page")
+	}
+	return offset, limit, nil
+}
+
+// findEnvelopePage mengisi dest dengan satu halaman hasil query (lihat envelopePage) dan mengembalikan metadatanya
+// Jika parameter tidak valid atau query gagal, error sudah dikirim ke client dan hasilnya false
+func findEnvelopePage(w http.ResponseWriter, r *http.Request, query *gorm.DB, dest interface{}) (pageMeta, bool) {
+	offset, limit, err := envelopePage(r)
+	if err != nil {
+		handleError(w, http.StatusBadRequest, err.Error())
+		return pageMeta{}, false
+	}
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to count items")
+		return pageMeta{}, false
+	}
+	if err := query.Offset(offset).Limit(limit).Find(dest).Error; err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to retrieve items")
+		return pageMeta{}, false
+	}
+	return newPageMeta(offset, limit, total), true
+}
+
+// newPageMeta menghitung metadata paginasi dari offset, limit, dan total item
+// Offset yang tidak kelipatan limit dibulatkan ke bawah ke halaman yang memuat item pertamanya
+func newPageMeta(offset, limit int, total int64) pageMeta {
+	return pageMeta{
+		Page:       offset/limit + 1,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: int((total + int64(limit) - 1) / int64(limit)),
+		HasNext:    int64(offset) < total-int64(limit), // Limit dikurangkan dari total agar offset sebesar MaxInt tidak overflow
+		HasPrev:    offset > 0,
+	}
+}
+
+// respondEnvelope mengirim satu halaman daftar beserta metadata paginasinya
+func respondEnvelope(w http.ResponseWriter, data interface{}, meta pageMeta) {
+	respondJSON(w, http.StatusOK, map[string]interface{}{"data": data, "meta": meta})
+}
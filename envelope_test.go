@@ -0,0 +1,108 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestNewPageMeta(t *testing.T) {
+	tests := []struct {
+		name          string
+		offset, limit int
+		total         int64
+		want          pageMeta
+	}{
+		{"first", 0, 10, 25, pageMeta{Page: 1, Limit: 10, Total: 25, TotalPages: 3, HasNext: true}},
+		{"middle", 10, 10, 25, pageMeta{Page: 2, Limit: 10, Total: 25, TotalPages: 3, HasNext: true, HasPrev: true}},
+		{"last", 20, 10, 25, pageMeta{Page: 3, Limit: 10, Total: 25, TotalPages: 3, HasPrev: true}},
+		{"exact last", 20, 10, 30, pageMeta{Page: 3, Limit: 10, Total: 30, TotalPages: 3, HasPrev: true}},
+		{"empty", 0, 10, 0, pageMeta{Page: 1, Limit: 10}},
+		{"unaligned offset", 15, 10, 25, pageMeta{Page: 2, Limit: 10, Total: 25, TotalPages: 3, HasPrev: true}},
+		{"huge offset", math.MaxInt, 10, 25, pageMeta{Page: math.MaxInt/10 + 1, Limit: 10, Total: 25, TotalPages: 3, HasPrev: true}},
+	}
+	for _, tt := range tests {
+		if got := newPageMeta(tt.offset, tt.limit, tt.total); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEnvelopePageParams(t *testing.T) {
+	tests := []struct {
+		query         string
+		offset, limit int
+		ok            bool
+	}{
+		{"", 0, defaultEnvelopeLimit, true},
+		{"?limit=10&page=3", 20, 10, true},
+		{"?limit=10&offset=5", 5, 10, true},
+		{"?limit=100000", 0, maxListLimit, true},
+		{"?page=0", 0, 0, false},
+		{"?limit=0", 0, 0, false},
+		{"?limit=10&page=9223372036854775807", 0, 0, false}, // Akan overflow saat dikalikan limit
+	}
+	for _, tt := range tests {
+		offset, limit, err := envelopePage(httptest.NewRequest(http.MethodGet, "/retur"+tt.query, nil))
+		if (err == nil) != tt.ok || tt.ok && (offset != tt.offset || limit != tt.limit) {
+			t.Errorf("%s: offset %d limit %d err %v", tt.query, offset, limit, err)
+		}
+	}
+}
+
+func TestUndoListEnvelopePages(t *testing.T) {
+	entries := make([]undoEntry, 5)
+	for i := range entries {
+		entries[i] = undoEntry{Returs: []Retur{{ID: i + 1}}}
+	}
+	useUndoState(t, entries, nil)
+	tests := []struct {
+		page    string
+		ids     []int
+		hasNext bool
+		hasPrev bool
+	}{
+		{"1", []int{5, 4}, true, false},
+		{"2", []int{3, 2}, true, true},
+		{"3", []int{1}, false, true},
+	}
+	for _, tt := range tests {
+		rec := serve(httptest.NewRequest(http.MethodGet, "/v1/retur/undo?limit=2&page="+tt.page, nil))
+		var body struct {
+			Data []undoEntry `json:"data"`
+			Meta pageMeta    `json:"meta"`
+		}
+		decodeBody(t, rec, &body)
+		var ids []int
+		for _, entry := range body.Data {
+			ids = append(ids, entry.Returs[0].ID)
+		}
+		if rec.Code != http.StatusOK || !slices.Equal(ids, tt.ids) || body.Meta.HasNext != tt.hasNext || body.Meta.HasPrev != tt.hasPrev || body.Meta.TotalPages != 3 || body.Meta.Total != 5 {
+			t.Errorf("page %s: %d ids %v meta %+v", tt.page, rec.Code, ids, body.Meta)
+		}
+	}
+}
+
+func TestListEnvelopeOnV1(t *testing.T) {
+	testDB(t)
+	for range 5 {
+		seedReturs(t, Retur{Barang: "Buku", Alasan: "rusak"})
+	}
+	for page, want := range map[string]pageMeta{
+		"1": {Page: 1, Limit: 2, Total: 5, TotalPages: 3, HasNext: true},
+		"2": {Page: 2, Limit: 2, Total: 5, TotalPages: 3, HasNext: true, HasPrev: true},
+		"3": {Page: 3, Limit: 2, Total: 5, TotalPages: 3, HasPrev: true},
+	} {
+		var body struct {
+			Data []Retur  `json:"data"`
+			Meta pageMeta `json:"meta"`
+		}
+		rec := serve(httptest.NewRequest(http.MethodGet, "/v1/retur?limit=2&page="+page, nil))
+		decodeBody(t, rec, &body)
+		if rec.Code != http.StatusOK || body.Meta != want || len(body.Data) != min(2, 5-(want.Page-1)*2) {
+			t.Errorf("page %s: %d %d items, meta %+v", page, rec.Code, len(body.Data), body.Meta)
+		}
+	}
+}
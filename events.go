@@ -0,0 +1,155 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// EventType adalah jenis kejadian pada retur yang dipublikasikan ke event bus
+type EventType string
+
+// Daftar kejadian yang dipublikasikan oleh handler
+const (
+	ReturCreated       EventType = "retur.created"
+	ReturApproved      EventType = "retur.approved"
+	ReturFirstApproved EventType = "retur.first_approved"
+	ReturDisapproved   EventType = "retur.disapproved"
+	ReturReset         EventType = "retur.reset"
+	ReturUpdated       EventType = "retur.updated"
+	ReturDeleted       EventType = "retur.deleted"
+	ReturRestored      EventType = "retur.restored"
+	ReturSpike         EventType = "retur.spike" // Alert lonjakan retur per barang, hanya dikirim ke webhook (lihat spikeMonitor)
+)
+
+// Event adalah satu kejadian pada retur beserta pelaku dan waktunya
+type Event struct {
+	Type  EventType `json:"type"`  // Jenis kejadian
+	Retur Retur     `json:"retur"` // Data retur setelah kejadian
+	Actor string    `json:"actor"` // Pengguna yang memicu kejadian
+	At    time.Time `json:"at"`    // Waktu kejadian
+
+	Redelivery bool           `json:"redelivery,omitempty"` // Kirim ulang manual lewat POST /retur/{id}/notify, bukan perubahan baru
+	delivery   *eventDelivery // Diisi subscriber webhook jika pemanggil ingin tahu hasil antrean, boleh nil
+}
+
+// eventDelivery adalah hasil antrean webhook untuk satu Event; Publish synchronous sehingga terisi saat Publish kembali
+type eventDelivery struct {
+	Queued  int // Jumlah tujuan webhook yang masuk antrian
+	Dropped int // Jumlah tujuan yang dibuang (antrian penuh atau sedang shutdown)
+}
+
+// eventBus adalah event bus sederhana di dalam proses: handler mempublikasikan Event,
+// dan setiap subscriber (webhook, audit log, metrik, dan seterusnya) mendaftar secara terpisah
+type eventBus struct {
+	mu          sync.RWMutex
+	subscribers []*eventSubscriber
+}
+
+// eventSubscriber membungkus fungsi subscriber agar bisa dilepas kembali lewat pointer-nya
+type eventSubscriber struct {
+	fn func(Event)
+}
+
+// bus adalah event bus yang dipakai seluruh aplikasi
+var bus = &eventBus{}
+
+// Subscribe mendaftarkan fungsi yang dipanggil untuk setiap Event yang dipublikasikan
+// Subscriber dipanggil secara synchronous sehingga harus cepat dan tidak blocking
+// Fungsi yang dikembalikan melepas subscriber, dipakai oleh subscriber sementara seperti stream tail admin
+func (b *eventBus) Subscribe(fn func(Event)) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sub := &eventSubscriber{fn: fn}
+	b.subscribers = append(b.subscribers, sub)
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		for i, s := range b.subscribers {
+			if s == sub {
+				b.subscribers = append(b.subscribers[:i:i], b.subscribers[i+1:]...) // Salin agar slice yang sedang di-Publish tidak berubah
+				return
+			}
+		}
+	}
+}
+
+// Publish mengirim Event ke semua subscriber sesuai urutan pendaftaran
+// Subscriber yang panic dicatat dan dilewati sehingga subscriber lain tetap menerima Event
+func (b *eventBus) Publish(event Event) {
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+	for _, sub := range subscribers {
+		sub.call(event)
+	}
+}
+
+// call menjalankan subscriber untuk satu Event dan menangkap panic-nya
+func (s *eventSubscriber) call(event Event) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Printf("event subscriber panicked on %s retur=%d: %v", event.Type, event.Retur.ID, err)
+		}
+	}()
+	s.fn(event)
+}
+
+// publishEvent membuat Event dari request dan mempublikasikannya ke bus
+func publishEvent(eventType EventType, retur Retur, r *http.Request) {
+	bus.Publish(Event{Type: eventType, Retur: retur, Actor: actorFromRequest(r), At: clock.Now()})
+}
+
+// eventCounts menghitung jumlah Event per jenis untuk metrik
+var eventCounts = struct {
+	sync.Mutex
+	byType map[EventType]int64
+}{byType: make(map[EventType]int64)}
+
+// logEvent menulis satu baris audit log untuk Event; actor dan customer disamarkan sesuai RETUR_PII_FIELDS
+func logEvent(e Event) {
+	log.Printf("event %s retur=%d customer=%s actor=%s status=%s redelivery=%t", e.Type, e.Retur.ID, redactPII("customer_id", e.Retur.CustomerID), redactPII("actor", e.Actor), e.Retur.Status, e.Redelivery)
+}
+
+// invalidateStats mengosongkan cache /retur/stats sehingga ETag dihitung ulang setelah setiap perubahan retur
+func invalidateStats(Event) {
+	cachedStats.Invalidate()
+}
+
+// registerEventSubscribers mendaftarkan semua efek samping bawaan aplikasi ke event bus
+func registerEventSubscribers() {
+	// Webhook hanya menerima perubahan status
+	bus.Subscribe(func(e Event) {
+		switch e.Type {
+		case ReturApproved, ReturFirstApproved, ReturDisapproved, ReturReset:
+			queued, dropped := notify.Enqueue(string(e.Type), e.Retur)
+			if e.delivery != nil {
+				e.delivery.Queued += queued
+				e.delivery.Dropped += dropped
+			}
+		}
+	})
+	// Audit log sederhana untuk semua kejadian
+	bus.Subscribe(logEvent)
+	// Statistik /retur/stats dihitung ulang setelah setiap perubahan retur
+	bus.Subscribe(invalidateStats)
+	// Alert lonjakan retur per barang (RETUR_SPIKE_THRESHOLD)
+	bus.Subscribe(func(e Event) {
+		if e.Type != ReturCreated {
+			return
+		}
+		if alert, ok := spikes.Observe(e.Retur, e.At); ok {
+			spikes.Send(alert)
+		}
+	})
+	// Metrik jumlah kejadian per jenis; kirim ulang tidak dihitung sebagai kejadian baru
+	bus.Subscribe(func(e Event) {
+		if e.Redelivery {
+			return
+		}
+		eventCounts.Lock()
+		eventCounts.byType[e.Type]++
+		eventCounts.Unlock()
+	})
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEventBusFanOut(t *testing.T) {
+	b := &eventBus{}
+	var got []string
+	for _, name := range []string{"webhook", "audit", "metrics"} {
+		b.Subscribe(func(e Event) { got = append(got, name+":"+string(e.Type)) })
+	}
+	b.Publish(Event{Type: ReturApproved, Retur: Retur{ID: 7}})
+	if want := []string{"webhook:" + string(ReturApproved), "audit:" + string(ReturApproved), "metrics:" + string(ReturApproved)}; !slices.Equal(got, want) {
+		t.Fatalf("received %v, want %v", got, want)
+	}
+}
+
+func TestEventBusSurvivesFailingSubscriber(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	b := &eventBus{}
+	var before, after int
+	b.Subscribe(func(Event) { before++ })
+	b.Subscribe(func(Event) { panic("webhook down") })
+	unsubscribe := b.Subscribe(func(Event) { after++ })
+	b.Publish(Event{Type: ReturCreated, Retur: Retur{ID: 3}})
+	if before != 1 || after != 1 {
+		t.Fatalf("subscribers around the failing one received %d and %d events, want 1 each", before, after)
+	}
+	if !strings.Contains(logs.String(), "webhook down") {
+		t.Fatalf("panic not logged: %q", logs.String())
+	}
+
+	unsubscribe()
+	b.Publish(Event{Type: ReturCreated})
+	if before != 2 || after != 1 {
+		t.Fatalf("after unsubscribe: %d and %d events, want 2 and 1", before, after)
+	}
+}
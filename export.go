@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// customerExportRow adalah satu baris rekap retur yang disetujui per customer dan mata uang
+type customerExportRow struct {
+	CustomerID      string `json:"customer_id"`
+	Currency        string `json:"currency"`
+	ApprovedReturns int64  `json:"approved_returns"`
+	RefundTotal     int64  `json:"refund_total"` // Dalam minor units sesuai mata uang
+	BarangCount     int64  `json:"barang_count"`
+	UangCount       int64  `json:"uang_count"`
+}
+
+// exportByCustomerHandler adalah handler untuk mengekspor rekap retur yang disetujui per customer dalam format CSV
+// Rekap dihitung dengan query agregat (GROUP BY customer_id, currency) pada rentang waktu keputusan from..to
+// Dengan ?format=ndjson atau Accept: application/x-ndjson, setiap baris rekap dikirim sebagai satu objek JSON per baris
+// customer_id disamarkan sesuai RETUR_PII_MODE kecuali request membawa token admin
+func exportByCustomerHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if format := query.Get("format"); format != "" && format != "csv" && format != "ndjson" {
+		handleError(w, http.StatusBadRequest, "Unsupported format, use csv or ndjson") // Saat ini hanya mendukung CSV dan NDJSON
+		return
+	}
+	ndjson := wantsNDJSON(r) && query.Get("format") != "csv" // ?format=csv diutamakan di atas header Accept
+	raw := isAdmin(r)                                        // customer_id hanya dikirim mentah untuk admin (lihat RETUR_PII_FIELDS)
+
+	scope := db.WithContext(r.Context()).Model(&Retur{}).
+		Select(`customer_id, currency,
+			COUNT(*) AS approved_returns,
+			COALESCE(SUM(refund_amount), 0) AS refund_total,
+			SUM(CASE WHEN pengembalian = 'barang' THEN 1 ELSE 0 END) AS barang_count,
+			SUM(CASE WHEN pengembalian = 'uang' THEN 1 ELSE 0 END) AS uang_count`).
+		Where("status = ? AND customer_id <> ''", "Disetujui"). // Hanya retur yang disetujui dan memiliki customer
+		Group("customer_id, currency").
+		Order("customer_id, currency")
+	for _, param := range []struct {
+		name, op string
+	}{{"from", ">="}, {"to", "<"}} {
+		raw := query.Get(param.name)
+		if raw == "" {
+			continue
+		}
+		t, err := parseTimeParam(raw)
+		if err != nil {
+			handleError(w, http.StatusBadRequest, "Invalid "+param.name+", use YYYY-MM-DD or RFC3339") // Format waktu tidak valid
+			return
+		}
+		scope = scope.Where("decided_at "+param.op+" ?", t) // Batasi berdasarkan waktu keputusan
+	}
+
+	rows, err := scope.Rows()
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to export returns") // Jika query gagal, kirimkan error
+		return
+	}
+	defer rows.Close()
+
+	if ndjson {
+		w.Header().Set("Content-Type", ndjsonContentType)
+		w.WriteHeader(http.StatusOK)
+		encoder := json.NewEncoder(w)
+		for rows.Next() {
+			var row customerExportRow
+			if err := db.ScanRows(rows, &row); err != nil {
+				log.Printf("export by customer failed: %v", err)
+				break // Hentikan stream jika baris gagal dibaca
+			}
+			if !raw {
+				row.CustomerID = redactPII("customer_id", row.CustomerID)
+			}
+			if err := encoder.Encode(row); err != nil {
+				break // Client kemungkinan sudah memutus koneksi
+			}
+		}
+		return
+	}
+
+	filename := "retur-by-customer-" + clock.Now().Format("20060102") + ".csv"
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"customer_id", "currency", "approved_returns", "refund_total", "barang_count", "uang_count"}) // Header CSV
+	for rows.Next() {
+		var row customerExportRow
+		if err := db.ScanRows(rows, &row); err != nil {
+			log.Printf("export by customer failed: %v", err)
+			break // Hentikan stream jika baris gagal dibaca
+		}
+		if !raw {
+			row.CustomerID = redactPII("customer_id", row.CustomerID)
+		}
+		writer.Write([]string{
+			row.CustomerID,
+			row.Currency,
+			strconv.FormatInt(row.ApprovedReturns, 10),
+			strconv.FormatInt(row.RefundTotal, 10), // Dalam minor units sesuai mata uang
+			strconv.FormatInt(row.BarangCount, 10),
+			strconv.FormatInt(row.UangCount, 10),
+		})
+	}
+	writer.Flush() // Pastikan semua baris terkirim ke client
+}
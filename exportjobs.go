@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// Status job ekspor async
+const (
+	exportPending = "pending"
+	exportRunning = "running"
+	exportDone    = "done"
+	exportFailed  = "failed"
+)
+
+// ExportJob adalah job ekspor CSV yang dijalankan di background lewat POST /retur/export
+// Hasilnya ditulis sebagai file di direktori lokal RETUR_EXPORT_DIR (bukan blob store) dan diunduh lewat link bertanda
+// tangan (lihat signedExportURL); jika aplikasi berjalan di beberapa instance, direktori tersebut harus dibagi bersama
+// karena link bisa dilayani instance lain. Job berjalan di goroutine proses yang membuatnya dan ditandai gagal setelah restart
+type ExportJob struct {
+	ID          string     `json:"id" gorm:"primaryKey;size:32"`     // ID acak, juga dipakai sebagai bagian link unduhan
+	Status      string     `json:"status"`                           // pending, running, done, atau failed
+	Query       string     `json:"query" gorm:"type:text"`           // Query string filter saat job dibuat
+	Raw         bool       `json:"raw"`                              // Field PII tidak disamarkan karena job dibuat oleh admin
+	Rows        int        `json:"rows"`                             // Jumlah retur yang diekspor
+	Error       string     `json:"error,omitempty" gorm:"type:text"` // Penyebab kegagalan job
+	StoragePath string     `json:"-" gorm:"type:text"`               // Path file hasil di RETUR_EXPORT_DIR
+	CreatedAt   time.Time  `json:"created_at"`                       // Waktu job dibuat
+	CompletedAt *time.Time `json:"completed_at,omitempty"`           // Waktu job selesai atau gagal
+}
+
+// exportEphemeralKey dipakai menandatangani link jika RETUR_EXPORT_SIGNING_KEY kosong; link tidak berlaku lagi setelah restart
+var exportEphemeralKey = func() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+// exportSignature menghitung HMAC-SHA256 atas ID job dan waktu kedaluwarsa link
+func exportSignature(jobID string, expires int64) string {
+	key := []byte(cfg.ExportSigningKey)
+	if len(key) == 0 {
+		key = exportEphemeralKey
+	}
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s:%d", jobID, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signedExportURL membuat link unduhan hasil job yang berlaku selama RETUR_EXPORT_LINK_TTL
+func signedExportURL(jobID string) (string, time.Time) {
+	expiresAt := clock.Now().Add(cfg.ExportLinkTTL)
+	expires := expiresAt.Unix()
+	return urlFor(fmt.Sprintf("/retur/export/jobs/%s/download?expires=%d&signature=%s", jobID, expires, exportSignature(jobID, expires))), expiresAt
+}
+
+// exportJobResponse menambahkan URL status dan, jika job selesai, link unduhan baru pada data job
+func exportJobResponse(job ExportJob) map[string]interface{} {
+	response := map[string]interface{}{"job": job, "status_url": urlFor("/retur/export/jobs/" + job.ID)}
+	if job.Status == exportDone {
+		url, expiresAt := signedExportURL(job.ID)
+		response["download_url"] = url
+		response["expires_at"] = expiresAt.UTC()
+	}
+	return response
+}
+
+// exportWeight adalah bobot job ekspor di heavySem, sama dengan GET /retur/export
+const exportWeight = 2
+
+// exportQueue menghitung job ekspor yang antre atau berjalan di proses ini, total dan per kredensial
+var exportQueue = struct {
+	sync.Mutex
+	total   int
+	byActor map[string]int
+}{byActor: make(map[string]int)}
+
+// reserveExportSlot mengambil satu slot antrean ekspor untuk actor; false jika RETUR_EXPORT_MAX_QUEUED atau
+// RETUR_EXPORT_MAX_PER_ACTOR sudah tercapai
+func reserveExportSlot(actor string) bool {
+	exportQueue.Lock()
+	defer exportQueue.Unlock()
+	if exportQueue.total >= cfg.ExportMaxQueued || exportQueue.byActor[actor] >= cfg.ExportMaxPerActor {
+		return false
+	}
+	exportQueue.total++
+	exportQueue.byActor[actor]++
+	return true
+}
+
+// releaseExportSlot mengembalikan slot hasil reserveExportSlot setelah job selesai atau gagal dibuat
+func releaseExportSlot(actor string) {
+	exportQueue.Lock()
+	defer exportQueue.Unlock()
+	exportQueue.total--
+	if exportQueue.byActor[actor]--; exportQueue.byActor[actor] <= 0 {
+		delete(exportQueue.byActor, actor) // Jangan simpan actor yang tidak punya job lagi
+	}
+}
+
+// createExportJobHandler adalah handler untuk membuat job ekspor CSV async dengan filter, urutan, dan ?columns yang sama
+// seperti GET /retur/export; response 202 berisi URL status yang bisa dipoll sampai job selesai dan link unduhan tersedia
+// Route ini membutuhkan kredensial (requireAuthenticated), dan antrean dibatasi RETUR_EXPORT_MAX_QUEUED serta
+// RETUR_EXPORT_MAX_PER_ACTOR; request yang melewati batas dijawab 429
+// Dengan ?include_deleted=true, isi stack undo diambil saat job dibuat, bukan saat job berjalan
+func createExportJobHandler(w http.ResponseWriter, r *http.Request) {
+	columns, err := parseCSVColumns(r.URL.Query().Get("columns"), includeDeleted(r))
+	if err != nil {
+		handleError(w, http.StatusBadRequest, err.Error()) // Kolom tidak dikenal
+		return
+	}
+	filters, err := listFilters(r)
+	if err != nil {
+		handleError(w, http.StatusBadRequest, err.Error()) // Parameter filter tidak valid
+		return
+	}
+	order, err := listOrder(r)
+	if err != nil {
+		handleError(w, http.StatusBadRequest, err.Error()) // Kolom sort tidak dikenal
+		return
+	}
+	var deleted *deletedMerge
+	if includeDeleted(r) {
+		if deleted, err = deletedReturs(r); err != nil {
+			handleError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	actor, _ := authenticatedActor(r)
+	if !reserveExportSlot(actor) {
+		w.Header().Set("Retry-After", "30")
+		handleError(w, http.StatusTooManyRequests, "Too many export jobs queued, try again later")
+		return
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		releaseExportSlot(actor)
+		handleError(w, http.StatusInternalServerError, "Failed to create export job")
+		return
+	}
+	job := ExportJob{ID: hex.EncodeToString(id), Status: exportPending, Query: r.URL.RawQuery, Raw: isAdmin(r)} // Link unduhan tanpa auth, jadi hak admin dicatat saat job dibuat
+	if err := db.WithContext(r.Context()).Create(&job).Error; err != nil {
+		releaseExportSlot(actor)
+		handleError(w, http.StatusInternalServerError, "Failed to create export job")
+		return
+	}
+	query := db.Model(&Retur{}).Scopes(filters).Order(order) // Tanpa context request agar job tetap berjalan setelah response dikirim
+	go func() {
+		defer releaseExportSlot(actor)
+		runExportJob(job, query, columns, deleted)
+	}()
+
+	w.Header().Set("Location", urlFor("/retur/export/jobs/"+job.ID))
+	respondJSON(w, http.StatusAccepted, exportJobResponse(job))
+}
+
+// runExportJob menjalankan satu job ekspor: menunggu slot heavySem, menulis CSV ke RETUR_EXPORT_DIR, lalu mencatat hasilnya
+// Menunggu slot dan menjalankan query dibatasi RETUR_EXPORT_JOB_TIMEOUT; job yang melewati batas ditandai gagal
+func runExportJob(job ExportJob, query *gorm.DB, columns []csvColumn, deleted *deletedMerge) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ExportJobTimeout)
+	defer cancel()
+	weight := heavyWeight(exportWeight)
+	var path string
+	var rows int
+	err := heavySem.Acquire(ctx, weight)
+	if err == nil {
+		db.Model(&job).Update("status", exportRunning)
+		path, rows, err = writeExportFile(job, query.WithContext(ctx), columns, deleted)
+		heavySem.Release(weight)
+	} else {
+		err = fmt.Errorf("timed out waiting for export capacity: %w", err)
+	}
+	now := clock.Now()
+	updates := map[string]interface{}{"status": exportDone, "rows": rows, "storage_path": path, "completed_at": now}
+	if err != nil {
+		log.Printf("export job %s failed: %v", job.ID, err)
+		updates = map[string]interface{}{"status": exportFailed, "error": err.Error(), "completed_at": now}
+	}
+	if err := db.Model(&job).Updates(updates).Error; err != nil {
+		log.Printf("export job %s: failed to record result: %v", job.ID, err)
+	}
+}
+
+// writeExportFile menulis hasil query ke file CSV baru dan menghapusnya lagi jika penulisan gagal
+func writeExportFile(job ExportJob, query *gorm.DB, columns []csvColumn, deleted *deletedMerge) (string, int, error) {
+	if err := os.MkdirAll(cfg.ExportDir, 0o750); err != nil {
+		return "", 0, err
+	}
+	path := filepath.Join(cfg.ExportDir, "retur-export-"+job.ID+".csv")
+	file, err := os.Create(path)
+	if err != nil {
+		return "", 0, err
+	}
+	rows, err := query.Rows()
+	if err != nil {
+		file.Close()
+		os.Remove(path)
+		return "", 0, err
+	}
+	defer rows.Close()
+	count, err := writeReturCSV(file, query, rows, columns, job.Raw, deleted)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", 0, err
+	}
+	return path, count, nil
+}
+
+// listExportJobsHandler adalah handler admin untuk melihat job ekspor, dari yang terbaru (tanpa envelope maksimal maxListLimit job)
+// Dengan ?envelope=true (selalu di bawah /v1), hasil dipaginasi dalam envelope data/meta (lihat findEnvelopePage)
+func listExportJobsHandler(w http.ResponseWriter, r *http.Request) {
+	jobs := []ExportJob{}
+	query := db.WithContext(r.Context()).Model(&ExportJob{}).Order("created_at desc, id")
+	var meta pageMeta
+	if wantsEnvelope(r) {
+		var ok bool
+		if meta, ok = findEnvelopePage(w, r, query, &jobs); !ok {
+			return
+		}
+	} else if err := query.Limit(maxListLimit).Find(&jobs).Error; err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to list export jobs")
+		return
+	}
+	items := make([]map[string]interface{}, len(jobs))
+	for i, job := range jobs {
+		items[i] = exportJobResponse(job) // Link unduhan baru untuk job yang sudah selesai
+	}
+	if wantsEnvelope(r) {
+		respondEnvelope(w, items, meta)
+		return
+	}
+	respondJSON(w, http.StatusOK, items)
+}
+
+// exportJobStatusHandler adalah handler untuk melihat status job ekspor; link unduhan baru diberikan setiap kali job selesai dipoll
+func exportJobStatusHandler(w http.ResponseWriter, r *http.Request) {
+	var job ExportJob
+	if err := db.WithContext(r.Context()).First(&job, "id = ?", mux.Vars(r)["job"]).Error; err != nil {
+		handleError(w, http.StatusNotFound, "Export job not found")
+		return
+	}
+	respondJSON(w, http.StatusOK, exportJobResponse(job))
+}
+
+// downloadExportHandler adalah handler untuk mengunduh hasil job ekspor lewat link bertanda tangan
+// Link yang kedaluwarsa ditolak dengan 410 dan tanda tangan yang salah dengan 403
+func downloadExportHandler(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["job"]
+	expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	if err != nil || !hmac.Equal([]byte(r.URL.Query().Get("signature")), []byte(exportSignature(jobID, expires))) {
+		handleError(w, http.StatusForbidden, "Invalid download signature")
+		return
+	}
+	if clock.Now().Unix() > expires {
+		handleError(w, http.StatusGone, "Download link expired, request a new one from the job status endpoint")
+		return
+	}
+	var job ExportJob
+	if err := db.WithContext(r.Context()).First(&job, "id = ?", jobID).Error; err != nil {
+		handleError(w, http.StatusNotFound, "Export job not found")
+		return
+	}
+	if job.Status != exportDone {
+		handleError(w, http.StatusConflict, "Export job is "+job.Status)
+		return
+	}
+	file, err := os.Open(job.StoragePath)
+	if err != nil {
+		handleError(w, http.StatusNotFound, "Export file no longer available")
+		return
+	}
+	defer file.Close()
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="retur-export-`+job.ID+`.csv"`)
+	http.ServeContent(w, r, "", *job.CompletedAt, file)
+}
+
+// purgeExportJobs menghapus job ekspor beserta filenya setelah RETUR_EXPORT_RETENTION, dan menandai gagal job
+// yang masih pending/running dari proses sebelum restart karena goroutine-nya sudah tidak ada
+func purgeExportJobs() (int, error) {
+	if err := db.Model(&ExportJob{}).Where("status IN ? AND created_at < ?", []string{exportPending, exportRunning}, startTime).
+		Updates(map[string]interface{}{"status": exportFailed, "error": "interrupted by restart", "completed_at": clock.Now()}).Error; err != nil {
+		return 0, err
+	}
+	if cfg.ExportRetention <= 0 {
+		return 0, nil
+	}
+	var jobs []ExportJob
+	if err := db.Where("created_at < ?", clock.Now().Add(-cfg.ExportRetention)).Find(&jobs).Error; err != nil {
+		return 0, err
+	}
+	for _, job := range jobs {
+		if job.StoragePath != "" {
+			if err := os.Remove(job.StoragePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return 0, err // Metadata dipertahankan agar file dicoba dihapus lagi pada eksekusi berikutnya
+			}
+		}
+		if err := db.Delete(&job).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(jobs), nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReserveExportSlotLimits(t *testing.T) {
+	withConfig(t, func(c *Config) {
+		c.ExportMaxQueued = 3
+		c.ExportMaxPerActor = 2
+	})
+	if !reserveExportSlot("budi") || !reserveExportSlot("budi") {
+		t.Fatal("slots within per-actor limit rejected")
+	}
+	if reserveExportSlot("budi") {
+		t.Fatal("per-actor limit exceeded")
+	}
+	if !reserveExportSlot("siti") || reserveExportSlot("andi") {
+		t.Fatal("total queue limit not enforced")
+	}
+	releaseExportSlot("budi")
+	releaseExportSlot("budi")
+	releaseExportSlot("siti")
+	exportQueue.Lock()
+	defer exportQueue.Unlock()
+	if exportQueue.total != 0 || len(exportQueue.byActor) != 0 {
+		t.Fatalf("queue not empty after release: %d %v", exportQueue.total, exportQueue.byActor)
+	}
+}
+
+func TestDownloadExportChecksSignatureAndExpiry(t *testing.T) {
+	fake := useFakeClock(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	withConfig(t, func(c *Config) {
+		c.ExportSigningKey = "test-signing-key"
+		c.ExportLinkTTL = 15 * time.Minute
+	})
+	link, expiresAt := signedExportURL("job1")
+	if !expiresAt.Equal(fake.Now().Add(15 * time.Minute)) {
+		t.Fatalf("expires at %v", expiresAt)
+	}
+	parsed, _ := url.Parse(link)
+	query := parsed.Query()
+
+	tampered := strings.Replace(link, "job1", "job2", 1) // Tanda tangan hanya berlaku untuk job yang sama
+	if rec := serve(httptest.NewRequest(http.MethodGet, tampered, nil)); rec.Code != http.StatusForbidden {
+		t.Fatalf("other job id: %d, want 403", rec.Code)
+	}
+	later := fmt.Sprintf("/retur/export/jobs/job1/download?expires=%d&signature=%s", expiresAt.Unix()+3600, query.Get("signature"))
+	if rec := serve(httptest.NewRequest(http.MethodGet, later, nil)); rec.Code != http.StatusForbidden {
+		t.Fatalf("extended expiry: %d, want 403", rec.Code)
+	}
+
+	fake.Advance(16 * time.Minute)
+	if rec := serve(httptest.NewRequest(http.MethodGet, link, nil)); rec.Code != http.StatusGone {
+		t.Fatalf("expired link: %d, want 410", rec.Code)
+	}
+}
+
+func TestExportJobLifecycle(t *testing.T) {
+	testDB(t)
+	fake := useFakeClock(t, time.Now())
+	withConfig(t, func(c *Config) {
+		c.ExportDir = t.TempDir()
+		c.ExportLinkTTL = time.Minute
+	})
+	seedReturs(t, Retur{Barang: "Sepatu", Alasan: "rusak"}, Retur{Barang: "Tas", Alasan: "robek"})
+
+	rec := serve(asAdmin(t, httptest.NewRequest(http.MethodPost, "/retur/export?columns=id,barang", nil)))
+	var created struct {
+		Job       ExportJob `json:"job"`
+		StatusURL string    `json:"status_url"`
+	}
+	decodeBody(t, rec, &created)
+	if rec.Code != http.StatusAccepted || created.Job.Status != exportPending || rec.Header().Get("Location") != created.StatusURL {
+		t.Fatalf("create: %d %s", rec.Code, rec.Body.String())
+	}
+
+	var status struct {
+		Job         ExportJob `json:"job"`
+		DownloadURL string    `json:"download_url"`
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for status.Job.Status != exportDone {
+		if time.Now().After(deadline) || status.Job.Status == exportFailed {
+			t.Fatalf("job did not complete: %+v", status.Job)
+		}
+		time.Sleep(20 * time.Millisecond)
+		decodeBody(t, serve(httptest.NewRequest(http.MethodGet, created.StatusURL, nil)), &status)
+	}
+	if status.Job.Rows != 2 || status.DownloadURL == "" {
+		t.Fatalf("done job = %+v, link %q", status.Job, status.DownloadURL)
+	}
+
+	download := serve(httptest.NewRequest(http.MethodGet, status.DownloadURL, nil))
+	if download.Code != http.StatusOK || !strings.HasPrefix(download.Body.String(), "id,barang\n") || strings.Count(download.Body.String(), "\n") != 3 {
+		t.Fatalf("download: %d %q", download.Code, download.Body.String())
+	}
+	fake.Advance(2 * time.Minute)
+	if rec := serve(httptest.NewRequest(http.MethodGet, status.DownloadURL, nil)); rec.Code != http.StatusGone {
+		t.Fatalf("expired download: %d, want 410", rec.Code)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// listParams adalah parameter filter daftar yang sudah divalidasi oleh parseListParams
+// Dipakai bersama oleh listFilters (query SQL) dan listPredicate (snapshot di stack undo) agar keduanya menerima
+// dan menolak nilai yang sama
+type listParams struct {
+	Status       string
+	AssignedTo   *string // nil jika ?assigned_to tidak dikirim; nilai kosong memilih retur yang belum ditugaskan
+	Tag          string  // Sudah dinormalisasi ke huruf kecil
+	Pengembalian string
+	Search       string // ?q tanpa spasi di awal/akhir
+	From, To     *time.Time
+	Overdue      *bool
+}
+
+// parseListParams membaca dan memvalidasi parameter filter daftar; nilai yang tidak valid dijawab errInvalidParam
+func parseListParams(r *http.Request) (listParams, error) {
+	query := r.URL.Query()
+	params := listParams{
+		Status:       query.Get("status"),
+		Tag:          strings.ToLower(strings.TrimSpace(query.Get("tag"))),
+		Pengembalian: query.Get("pengembalian"),
+		Search:       strings.TrimSpace(query.Get("q")),
+	}
+	if query.Has("assigned_to") {
+		agent := query.Get("assigned_to")
+		params.AssignedTo = &agent
+	}
+	if params.Tag != "" && !tagPattern.MatchString(params.Tag) {
+		return listParams{}, errInvalidParam("tag")
+	}
+	if params.Pengembalian != "" && !validPengembalian(params.Pengembalian) {
+		return listParams{}, errInvalidParam("pengembalian")
+	}
+	for _, bound := range []struct {
+		param string
+		dest  **time.Time
+	}{{"from", &params.From}, {"to", &params.To}} {
+		raw := query.Get(bound.param)
+		if raw == "" {
+			continue
+		}
+		t, err := parseTimeParam(raw)
+		if err != nil {
+			return listParams{}, errInvalidParam(bound.param)
+		}
+		*bound.dest = &t
+	}
+	switch query.Get("overdue") {
+	case "":
+	case "true", "false":
+		overdue := query.Get("overdue") == "true"
+		params.Overdue = &overdue
+	default:
+		return listParams{}, errInvalidParam("overdue")
+	}
+	return params, nil
+}
+
+// listFilters membaca parameter filter pada endpoint daftar dan hitung retur lalu mengubahnya menjadi scope GORM
+// Filter yang didukung: status, assigned_to, tag, pengembalian, q (pencarian di barang/alasan), from/to (created_at), dan overdue
+func listFilters(r *http.Request) (func(*gorm.DB) *gorm.DB, error) {
+	params, err := parseListParams(r)
+	if err != nil {
+		return nil, err
+	}
+	var scopes []func(*gorm.DB) *gorm.DB
+	if params.Status != "" {
+		scopes = append(scopes, func(q *gorm.DB) *gorm.DB { return q.Where("status = ?", params.Status) })
+	}
+	if params.AssignedTo != nil {
+		scopes = append(scopes, func(q *gorm.DB) *gorm.DB { return q.Where("assigned_to = ?", *params.AssignedTo) })
+	}
+	if params.Tag != "" {
+		scopes = append(scopes, func(q *gorm.DB) *gorm.DB { return q.Where("JSON_CONTAINS(tags, JSON_QUOTE(?))", params.Tag) })
+	}
+	if params.Pengembalian != "" {
+		scopes = append(scopes, func(q *gorm.DB) *gorm.DB { return q.Where("pengembalian = ?", params.Pengembalian) })
+	}
+	if params.Search != "" {
+		pattern := "%" + likeEscaper.Replace(params.Search) + "%" // Karakter wildcard dari client dicari apa adanya
+		scopes = append(scopes, func(q *gorm.DB) *gorm.DB {
+			return q.Where("barang LIKE ? OR alasan LIKE ?", pattern, pattern)
+		})
+	}
+	if params.From != nil {
+		scopes = append(scopes, func(q *gorm.DB) *gorm.DB { return q.Where("created_at >= ?", *params.From) })
+	}
+	if params.To != nil {
+		scopes = append(scopes, func(q *gorm.DB) *gorm.DB { return q.Where("created_at < ?", *params.To) })
+	}
+	if params.Overdue != nil {
+		scopes = append(scopes, overdueScope(*params.Overdue)) // true: hanya yang melewati SLA, false: yang belum/tidak melewati SLA
+	}
+	return func(q *gorm.DB) *gorm.DB {
+		return q.Scopes(scopes...)
+	}, nil
+}
+
+// sortableColumns adalah kolom yang boleh dipakai pada ?sort di endpoint daftar; "priority" ditangani priorityOrder
+var sortableColumns = map[string]bool{"id": true, "created_at": true, "decided_at": true, "refund_amount": true, "status": true}
+
+// listOrder membaca ?sort (default RETUR_DEFAULT_SORT), misalnya "created_at" atau "-created_at" untuk urutan menurun,
+// lalu selalu menambahkan id dengan arah yang sama sebagai tie-breaker sehingga urutan halaman deterministik
+// meskipun banyak retur memiliki nilai kolom sort yang sama (misalnya created_at yang sama persis)
+// Aturannya sama untuk semua kolom termasuk priority: "-priority" berarti skor tertinggi lebih dulu (lihat priorityOrder)
+// Tanpa ?sort, daftar antrean agent (?assigned_to) diurutkan "-priority"; selain itu RETUR_DEFAULT_SORT
+func listOrder(r *http.Request) (interface{}, error) {
+	column, descending, err := sortParam(r)
+	if err != nil {
+		return "", err
+	}
+	if column == "priority" {
+		return priorityOrder(descending), nil
+	}
+	direction := "asc"
+	if descending {
+		direction = "desc"
+	}
+	if column == "id" {
+		return "id " + direction, nil
+	}
+	return column + " " + direction + ", id " + direction, nil
+}
+
+// sortParam membaca kolom dan arah ?sort (lihat listOrder) dan menolak kolom yang tidak dikenal
+func sortParam(r *http.Request) (string, bool, error) {
+	sort := r.URL.Query().Get("sort")
+	if sort == "" {
+		sort = cfg.DefaultSort
+		if r.URL.Query().Has("assigned_to") {
+			sort = "-priority" // Antrean kerja agent: retur terpenting dikerjakan lebih dulu
+		}
+	}
+	column, descending, ok := parseSort(sort)
+	if !ok {
+		return "", false, errInvalidParam("sort")
+	}
+	return column, descending, nil
+}
+
+// parseSort memecah nilai sort seperti "-created_at" menjadi kolom dan arah; ok false jika kolom tidak dikenal
+// Dipakai juga saat startup untuk memvalidasi RETUR_DEFAULT_SORT
+func parseSort(sort string) (column string, descending bool, ok bool) {
+	column = strings.TrimPrefix(sort, "-")
+	if column != "priority" && !sortableColumns[column] {
+		return "", false, false
+	}
+	return column, strings.HasPrefix(sort, "-"), true
+}
+
+// likeEscaper meng-escape karakter wildcard LIKE agar pencarian q dicocokkan secara literal
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// countRetursHandler adalah handler untuk menghitung retur yang cocok dengan filter daftar tanpa mengambil datanya
+// Dengan ?include_deleted=true, retur di stack undo yang cocok dengan filter ikut dihitung
+func countRetursHandler(w http.ResponseWriter, r *http.Request) {
+	filters, err := listFilters(r)
+	if err != nil {
+		handleError(w, http.StatusBadRequest, err.Error()) // Parameter filter tidak valid
+		return
+	}
+	var deleted *deletedMerge
+	if includeDeleted(r) {
+		if deleted, err = deletedReturs(r); err != nil {
+			handleError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	var count int64
+	if err := db.WithContext(r.Context()).Model(&Retur{}).Scopes(filters).Count(&count).Error; err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to count returns")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]int64{"count": count + int64(deleted.Len())})
+}
+
+// invalidParamError menandakan parameter query yang tidak valid
+type invalidParamError struct {
+	Name string
+}
+
+func (e *invalidParamError) Error() string {
+	return "Invalid " + e.Name
+}
+
+// errInvalidParam membuat error untuk parameter query yang tidak valid
+func errInvalidParam(name string) error {
+	return &invalidParamError{Name: name}
+}
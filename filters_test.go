@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		sort       string
+		column     string
+		descending bool
+		ok         bool
+	}{
+		{"created_at", "created_at", false, true},
+		{"-refund_amount", "refund_amount", true, true},
+		{"-priority", "priority", true, true},
+		{"password", "", false, false},
+		{"--id", "", false, false},
+	}
+	for _, tt := range tests {
+		column, descending, ok := parseSort(tt.sort)
+		if column != tt.column || descending != tt.descending || ok != tt.ok {
+			t.Errorf("parseSort(%q) = %q, %v, %v", tt.sort, column, descending, ok)
+		}
+	}
+}
+
+func TestCountRejectsInvalidFilters(t *testing.T) {
+	for _, query := range []string{"?tag=Bad%20Tag", "?pengembalian=voucher", "?from=kemarin", "?overdue=maybe"} {
+		if rec := serve(httptest.NewRequest(http.MethodGet, "/retur/count"+query, nil)); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+func TestCountMatchesFilters(t *testing.T) {
+	testDB(t)
+	seedReturs(t,
+		Retur{Barang: "Sepatu", Alasan: "sobek 100%"},
+		Retur{Barang: "Sepatu", Alasan: "kekecilan", Status: "Disetujui"},
+		Retur{Barang: "Tas", Alasan: "warna"},
+	)
+	useUndoState(t, []undoEntry{{Returs: []Retur{{ID: 900, Barang: "Sepatu", Status: "Dalam Proses"}}}}, nil)
+	tests := map[string]int64{
+		"":                                       3,
+		"?status=Dalam+Proses":                   2,
+		"?q=sepatu":                              2,
+		"?q=100%25":                              1, // % dicocokkan secara literal, bukan wildcard LIKE
+		"?q=sepatu&include_deleted=true":         3,
+		"?status=Disetujui&include_deleted=true": 1,
+	}
+	for query, want := range tests {
+		var body struct {
+			Count int64 `json:"count"`
+		}
+		rec := serve(httptest.NewRequest(http.MethodGet, "/retur/count"+query, nil))
+		decodeBody(t, rec, &body)
+		if rec.Code != http.StatusOK || body.Count != want {
+			t.Errorf("%q: %d count %d, want %d", query, rec.Code, body.Count, want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// ReturHistory mencatat setiap perubahan status retur beserta pelaku dan catatannya
+// retur_id sengaja tanpa foreign key: riwayat retur yang dihapus dibutuhkan lagi saat undo dan saat diarsipkan (archiveUndoEntries)
+type ReturHistory struct {
+	ID         uint      `json:"id"`                    // ID unik entri riwayat
+	ReturID    int       `json:"retur_id" gorm:"index"` // ID retur yang berubah
+	FromStatus string    `json:"from_status"`           // Status sebelum perubahan
+	ToStatus   string    `json:"to_status"`             // Status setelah perubahan
+	Actor      string    `json:"actor"`                 // Pengguna yang melakukan perubahan
+	Note       string    `json:"note"`                  // Catatan tambahan untuk perubahan
+	CreatedAt  time.Time `json:"created_at"`            // Waktu perubahan terjadi
+}
+
+// saveWithHistory menyimpan retur dan mencatat perubahan status (riwayat) serta field yang berubah (audit log)
+// di dalam tx; before adalah salinan retur sebelum diubah
+func saveWithHistory(tx *gorm.DB, before Retur, retur *Retur, actor, note string) error {
+	if err := tx.Save(retur).Error; err != nil {
+		return err
+	}
+	if err := recordAudit(tx, "update", actor, before, *retur); err != nil {
+		return err
+	}
+	return tx.Create(&ReturHistory{
+		ReturID:    retur.ID,
+		FromStatus: before.Status,
+		ToStatus:   retur.Status,
+		Actor:      actor,
+		Note:       note,
+	}).Error
+}
+
+// saveDecision menyimpan keputusan seperti saveWithHistory, tetapi hanya jika status di database masih before.Status
+// Keputusan yang bersamaan pada retur yang sama menghasilkan errApprovalConflict alih-alih saling menimpa
+func saveDecision(ctx context.Context, before Retur, retur *Retur, actor, note string) error {
+	return withTransaction(ctx, func(tx *gorm.DB) error {
+		if err := updateDecision(tx, before, retur); err != nil {
+			return err
+		}
+		if err := recordAudit(tx, "update", actor, before, *retur); err != nil {
+			return err
+		}
+		return tx.Create(&ReturHistory{ReturID: retur.ID, FromStatus: before.Status, ToStatus: retur.Status, Actor: actor, Note: note}).Error
+	})
+}
+
+// updateDecision menulis retur (tanpa item) di dalam tx hanya jika status di database masih before.Status
+func updateDecision(tx *gorm.DB, before Retur, retur *Retur) error {
+	result := tx.Model(retur).Select("*").Omit("Items").Where("status = ?", before.Status).Updates(retur)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errApprovalConflict
+	}
+	return nil
+}
+
+// resetDecisionHandler adalah handler admin untuk membuka kembali retur dan menghapus seluruh data keputusannya
+// Status kembali menjadi "Dalam Proses", keputusan item ikut dihapus, dan reset dicatat di riwayat retur
+func resetDecisionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"]) // Convert ID dari string ke integer
+	if err != nil {
+		handleError(w, http.StatusBadRequest, "Invalid ID format") // Jika format ID salah, kirimkan error
+		return
+	}
+
+	var retur Retur
+	if err := db.WithContext(r.Context()).First(&retur, id).Error; err != nil {
+		handleError(w, http.StatusNotFound, "Return not found") // Jika retur tidak ditemukan, kirimkan error
+		return
+	}
+
+	before := retur
+	retur.Status = "Dalam Proses" // Kembalikan ke status awal
+	retur.Pengembalian = ""       // Hapus jenis pengembalian
+	retur.RefundAmount = 0        // Hapus nilai refund
+	retur.Currency = ""
+	retur.DecidedBy = "" // Hapus data pemberi keputusan
+	retur.DecidedAt = nil
+	retur.DecisionNote = ""
+	retur.FirstApprovedBy = "" // Persetujuan pertama ikut dibatalkan
+	err = withTransaction(r.Context(), func(tx *gorm.DB) error {
+		if err := resetItems(tx, retur.ID); err != nil {
+			return err
+		}
+		return saveWithHistory(tx, before, &retur, actorFromRequest(r), "decision reset")
+	})
+	if err != nil {
+		respondSaveError(w, err, "Failed to reset return") // Jika gagal menyimpan, kirimkan error
+		return
+	}
+	publishEvent(ReturReset, retur, r) // Kabarkan perubahan status ke subscriber (webhook, audit, metrik)
+	maskRetur(r, &retur)
+	respondJSON(w, http.StatusOK, retur) // Kirimkan retur yang sudah di-reset
+}
+
+// returHistoryHandler adalah handler untuk melihat riwayat status satu retur, dari yang terlama
+// Riwayat dibatasi sejak retur dibuat agar riwayat milik retur lama dengan ID yang sama (ID reuse) tidak ikut tampil
+// Dengan ?envelope=true (selalu di bawah /v1), hasil dipaginasi dalam envelope data/meta (lihat findEnvelopePage)
+func returHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"]) // Convert ID dari string ke integer
+	if err != nil {
+		handleError(w, http.StatusBadRequest, "Invalid ID format") // Jika format ID salah, kirimkan error
+		return
+	}
+	var retur Retur
+	if err := db.WithContext(r.Context()).Select("id", "created_at").First(&retur, id).Error; err != nil {
+		handleError(w, http.StatusNotFound, "Return not found") // Jika retur tidak ditemukan, kirimkan error
+		return
+	}
+	history := []ReturHistory{}
+	query := db.WithContext(r.Context()).Model(&ReturHistory{}).Where("retur_id = ? AND created_at >= ?", retur.ID, retur.CreatedAt).Order("created_at, id")
+	if wantsEnvelope(r) {
+		if meta, ok := findEnvelopePage(w, r, query, &history); ok {
+			respondEnvelope(w, history, meta)
+		}
+		return
+	}
+	if err := query.Find(&history).Error; err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to retrieve return history")
+		return
+	}
+	respondJSON(w, http.StatusOK, history)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestResetDecisionClearsFieldsAndRecordsHistory(t *testing.T) {
+	testDB(t)
+	decidedAt := time.Now().Add(-time.Hour)
+	seeded := seedReturs(t, Retur{Barang: "Paket", Alasan: "rusak", Status: "Disetujui", Pengembalian: "uang", RefundAmount: 15000, Currency: "IDR",
+		DecidedBy: "budi", DecidedAt: &decidedAt, DecisionNote: "sesuai kebijakan",
+		Items: []ReturItem{{SKU: "A-1", Quantity: 1, Status: "Disetujui", Pengembalian: "uang"}}})[0]
+
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/retur/%d/reset", seeded.ID), nil)
+	req.Header.Set("X-Actor", "ana")
+	rec := serve(asAdmin(t, req))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reset: %d %s", rec.Code, rec.Body.String())
+	}
+	var stored Retur
+	if err := db.Preload("Items").First(&stored, seeded.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != "Dalam Proses" || stored.Pengembalian != "" || stored.RefundAmount != 0 || stored.Currency != "" ||
+		stored.DecidedBy != "" || stored.DecidedAt != nil || stored.DecisionNote != "" {
+		t.Fatalf("decision fields after reset: %+v", stored)
+	}
+	if item := stored.Items[0]; item.Status != "Dalam Proses" || item.Pengembalian != "" {
+		t.Fatalf("item after reset: %+v", item)
+	}
+
+	var history []ReturHistory
+	decodeBody(t, serve(httptest.NewRequest(http.MethodGet, fmt.Sprintf("/retur/%d/history", seeded.ID), nil)), &history)
+	if len(history) != 1 {
+		t.Fatalf("history = %+v, want one reset entry", history)
+	}
+	if last := history[0]; last.FromStatus != "Disetujui" || last.ToStatus != "Dalam Proses" || last.Note != "decision reset" || last.Actor != "ana" {
+		t.Fatalf("history = %+v", history)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// addDeletedID menyimpan ID yang dihapus ke pool agar bisa digunakan ulang
+// Jika RETUR_ID_POOL_CAP diatur, ID terlama dibuang (FIFO) saat pool melewati batas; ID yang dibuang
+// tidak akan digunakan ulang sehingga muncul celah kecil pada urutan ID, sebagai ganti memori yang terbatas
+func addDeletedID(id int) {
+	idMu.Lock()
+	defer idMu.Unlock()
+	deletedIDs = append(deletedIDs, id)
+	idPoolDeletes.Add(1)
+	if cfg.IDPoolCap > 0 && len(deletedIDs) > cfg.IDPoolCap {
+		evicted := len(deletedIDs) - cfg.IDPoolCap
+		deletedIDs = append(deletedIDs[:0], deletedIDs[evicted:]...) // Buang ID terlama di awal slice
+		idPoolEvictions.Add(int64(evicted))
+	}
+}
+
+// idPoolReport menghitung ringkasan isi deletedIDs: jumlah, duplikat, dan ID yang sudah dipakai retur aktif
+func idPoolReport() (map[string]interface{}, error) {
+	idMu.Lock()
+	ids := append([]int{}, deletedIDs...) // Salinan agar query database tidak berjalan sambil memegang lock
+	idMu.Unlock()
+	occupied, err := occupiedPoolIDs(db, ids)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[int]bool, len(ids))
+	duplicates := 0
+	for _, id := range ids {
+		if seen[id] {
+			duplicates++ // ID yang sama tercatat lebih dari sekali
+		}
+		seen[id] = true
+	}
+	return map[string]interface{}{
+		"size":       len(ids),
+		"ids":        ids, // Isi pool sesuai urutan reuse (terakhir dipakai lebih dulu)
+		"duplicates": duplicates,
+		"occupied":   len(occupied), // ID yang ternyata sudah dimiliki retur aktif
+		"cap":        cfg.IDPoolCap, // 0 berarti tanpa batas
+		"evictions":  idPoolEvictions.Load(),
+	}, nil
+}
+
+// occupiedPoolIDs mengembalikan ID dari pool yang saat ini sudah dimiliki oleh retur aktif di database
+func occupiedPoolIDs(tx *gorm.DB, pool []int) (map[int]bool, error) {
+	occupied := make(map[int]bool)
+	if len(pool) == 0 {
+		return occupied, nil
+	}
+	var ids []int
+	if err := tx.Model(&Retur{}).Where("id IN ?", pool).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		occupied[id] = true
+	}
+	return occupied, nil
+}
+
+// compactDeletedIDs membuang ID duplikat dan ID yang sudah dimiliki retur aktif dari deletedIDs
+// Urutan ID yang tersisa tetap dipertahankan; fungsi mengembalikan daftar ID yang dibuang
+// Query berjalan pada salinan pool tanpa memegang idMu; ID yang masuk pool setelah salinan dibuat dibiarkan
+func compactDeletedIDs() ([]int, error) {
+	idMu.Lock()
+	ids := append([]int{}, deletedIDs...)
+	idMu.Unlock()
+	occupied, err := occupiedPoolIDs(db, ids)
+	if err != nil {
+		return nil, err
+	}
+	idMu.Lock()
+	defer idMu.Unlock()
+	return compactDeletedIDsLocked(occupied), nil
+}
+
+// compactDeletedIDsLocked membuang ID di occupied dan ID duplikat dari deletedIDs; pemanggil wajib memegang idMu
+func compactDeletedIDsLocked(occupied map[int]bool) []int {
+	seen := make(map[int]bool, len(deletedIDs))
+	kept := make([]int, 0, len(deletedIDs))
+	var dropped []int
+	for _, id := range deletedIDs {
+		if occupied[id] || seen[id] {
+			dropped = append(dropped, id) // ID ini tidak aman untuk digunakan ulang
+			continue
+		}
+		seen[id] = true
+		kept = append(kept, id)
+	}
+	deletedIDs = kept
+	sort.Ints(dropped)
+	return dropped
+}
+
+// idPoolReportHandler adalah handler admin untuk melihat isi dan kondisi pool deletedIDs
+func idPoolReportHandler(w http.ResponseWriter, r *http.Request) {
+	report, err := idPoolReport()
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to inspect ID pool") // Jika query gagal, kirimkan error
+		return
+	}
+	respondJSON(w, http.StatusOK, report)
+}
+
+// compactIDPoolHandler adalah handler admin untuk membersihkan pool deletedIDs dari ID yang tidak valid
+func compactIDPoolHandler(w http.ResponseWriter, r *http.Request) {
+	before := deletedIDCount()
+	dropped, err := compactDeletedIDs()
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to compact ID pool") // Jika query gagal, kirimkan error
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"before":  before,
+		"after":   deletedIDCount(),
+		"dropped": dropped,
+	})
+}
+
+// idPoolStatsHandler adalah handler admin untuk mengukur efektivitas reuse ID: jumlah delete dan reuse sejak aplikasi
+// berjalan, ukuran pool, serta celah antara max(id) dan jumlah retur aktif (ID yang "hilang" walaupun ada reuse)
+func idPoolStatsHandler(w http.ResponseWriter, r *http.Request) {
+	var row struct {
+		MaxID  int64
+		Active int64
+	}
+	if err := db.WithContext(r.Context()).Model(&Retur{}).Select("COALESCE(MAX(id), 0) AS max_id, COUNT(*) AS active").Scan(&row).Error; err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to inspect ID pool") // Jika query gagal, kirimkan error
+		return
+	}
+	deletes, reuses := idPoolDeletes.Load(), idPoolReuses.Load()
+	reuseRatio := 0.0
+	if deletes > 0 {
+		reuseRatio = float64(reuses) / float64(deletes) // Porsi ID yang dihapus dan benar-benar dipakai lagi
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"total_deletes": deletes,
+		"total_reuses":  reuses,
+		"reuse_ratio":   reuseRatio,
+		"pool_size":     deletedIDCount(),
+		"evictions":     idPoolEvictions.Load(),
+		"max_id":        row.MaxID,
+		"active_rows":   row.Active,
+		"id_gap":        row.MaxID - row.Active, // ID di bawah max(id) yang tidak dimiliki retur aktif
+	})
+}
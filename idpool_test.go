@@ -0,0 +1,129 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// useIDState mengosongkan pool ID dan mengganti maxReturID selama satu test
+func useIDState(tb testing.TB, dbMax func() int) {
+	tb.Helper()
+	savedMax, savedIDs, savedLast := maxReturID, deletedIDs, lastAllocatedID
+	maxReturID, deletedIDs, lastAllocatedID = dbMax, nil, 0
+	tb.Cleanup(func() { maxReturID, deletedIDs, lastAllocatedID = savedMax, savedIDs, savedLast })
+}
+
+func TestAllocateReturIDConcurrentIsUnique(t *testing.T) {
+	useIDState(t, func() int {
+		time.Sleep(time.Millisecond) // Round-trip database yang basi selama create lain berjalan
+		return 10
+	})
+	addDeletedID(3)
+	addDeletedID(7)
+
+	const workers = 64
+	ids := make(chan int, workers)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ids <- allocateReturID()
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[int]bool, workers)
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("id %d allocated twice", id)
+		}
+		if id <= 10 && id != 3 && id != 7 {
+			t.Fatalf("id %d collides with existing rows (max id 10)", id)
+		}
+		seen[id] = true
+	}
+	if !seen[3] || !seen[7] {
+		t.Fatal("pooled ids 3 and 7 were not reused")
+	}
+	if deletedIDCount() != 0 {
+		t.Fatalf("pool not drained: %v", deletedIDs)
+	}
+}
+
+func TestPeekNextReturIDMatchesAllocation(t *testing.T) {
+	useIDState(t, func() int { return 5 })
+	if id, reused := peekNextReturID(); id != 6 || reused {
+		t.Fatalf("peek = %d, %v; want 6, false", id, reused)
+	}
+	if id := allocateReturID(); id != 6 {
+		t.Fatalf("allocate = %d; want 6", id)
+	}
+	if id, _ := peekNextReturID(); id != 7 {
+		t.Fatalf("peek after allocate = %d; want 7 (lastAllocatedID ahead of database)", id)
+	}
+	addDeletedID(2)
+	if id, reused := peekNextReturID(); id != 2 || !reused {
+		t.Fatalf("peek with pool = %d, %v; want 2, true", id, reused)
+	}
+}
+
+func TestIDPoolCapEvictsOldestAndCountsReuse(t *testing.T) {
+	useIDState(t, func() int { return 20 })
+	withConfig(t, func(c *Config) { c.IDPoolCap = 2 })
+	deletes, reuses, evictions := idPoolDeletes.Load(), idPoolReuses.Load(), idPoolEvictions.Load()
+	for _, id := range []int{4, 5, 6} {
+		addDeletedID(id)
+	}
+	if !slices.Equal(deletedIDs, []int{5, 6}) {
+		t.Fatalf("pool = %v, want oldest id evicted", deletedIDs)
+	}
+	allocateReturID()
+	allocateReturID()
+	allocateReturID()
+	if got := idPoolDeletes.Load() - deletes; got != 3 {
+		t.Errorf("deletes = %d, want 3", got)
+	}
+	if got := idPoolReuses.Load() - reuses; got != 2 {
+		t.Errorf("reuses = %d, want 2 (third allocation is new)", got)
+	}
+	if got := idPoolEvictions.Load() - evictions; got != 1 {
+		t.Errorf("evictions = %d, want 1", got)
+	}
+}
+
+func TestIDPoolStatsReportsGap(t *testing.T) {
+	testDB(t)
+	seedReturs(t, Retur{ID: 1, Barang: "A", Alasan: "x"}, Retur{ID: 5, Barang: "B", Alasan: "y"})
+	addDeletedID(3)
+	var stats struct {
+		PoolSize   int   `json:"pool_size"`
+		MaxID      int64 `json:"max_id"`
+		ActiveRows int64 `json:"active_rows"`
+		IDGap      int64 `json:"id_gap"`
+	}
+	rec := serve(asAdmin(t, httptest.NewRequest(http.MethodGet, "/retur/admin/id-pool/stats", nil)))
+	decodeBody(t, rec, &stats)
+	if rec.Code != http.StatusOK || stats.PoolSize != 1 || stats.MaxID != 5 || stats.ActiveRows != 2 || stats.IDGap != 3 {
+		t.Fatalf("stats: %d %+v", rec.Code, stats)
+	}
+}
+
+// BenchmarkAllocateReturID mengukur alokasi ID bersamaan dengan latensi database tiruan di luar idMu
+func BenchmarkAllocateReturID(b *testing.B) {
+	useIDState(b, func() int {
+		time.Sleep(50 * time.Microsecond)
+		return 0
+	})
+	b.SetParallelism(16) // Latensi tiruan berupa sleep, bukan CPU, sehingga create bersamaan tetap tumpang tindih
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			allocateReturID()
+		}
+	})
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// includeDeleted memeriksa apakah daftar retur juga harus memuat retur yang dihapus (?include_deleted=true)
+func includeDeleted(r *http.Request) bool {
+	return r.URL.Query().Get("include_deleted") == "true"
+}
+
+// listPredicate adalah padanan listFilters untuk retur yang dihapus (snapshot di stack undo, bukan baris soft delete di tabel returs)
+// Validasi parameter sama dengan listFilters (lihat parseListParams); q dicocokkan tanpa peka huruf besar/kecil seperti LIKE
+func listPredicate(r *http.Request) (func(Retur) bool, error) {
+	params, err := parseListParams(r)
+	if err != nil {
+		return nil, err
+	}
+	var checks []func(Retur) bool
+	if params.Status != "" {
+		checks = append(checks, func(retur Retur) bool { return retur.Status == params.Status })
+	}
+	if params.AssignedTo != nil {
+		checks = append(checks, func(retur Retur) bool { return retur.AssignedTo == *params.AssignedTo })
+	}
+	if params.Tag != "" {
+		checks = append(checks, func(retur Retur) bool { return slices.Contains(retur.Tags, params.Tag) })
+	}
+	if params.Pengembalian != "" {
+		checks = append(checks, func(retur Retur) bool { return retur.Pengembalian == params.Pengembalian })
+	}
+	if search := strings.ToLower(params.Search); search != "" {
+		checks = append(checks, func(retur Retur) bool {
+			return strings.Contains(strings.ToLower(retur.Barang), search) || strings.Contains(strings.ToLower(retur.Alasan), search)
+		})
+	}
+	if params.From != nil {
+		checks = append(checks, func(retur Retur) bool { return !retur.CreatedAt.Before(*params.From) })
+	}
+	if params.To != nil {
+		checks = append(checks, func(retur Retur) bool { return retur.CreatedAt.Before(*params.To) })
+	}
+	if params.Overdue != nil {
+		checks = append(checks, func(retur Retur) bool {
+			applySLA(&retur)
+			return retur.Overdue == *params.Overdue
+		})
+	}
+	return func(retur Retur) bool {
+		for _, check := range checks {
+			if !check(retur) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// listCompare adalah padanan listOrder untuk retur di luar database: kolom ?sort yang sama dengan id sebagai tie-breaker
+// decided_at kosong diurutkan lebih dulu seperti NULL pada MySQL
+func listCompare(r *http.Request) (func(a, b Retur) int, error) {
+	column, descending, err := sortParam(r)
+	if err != nil {
+		return nil, err
+	}
+	compareColumn := func(a, b Retur) int {
+		switch column {
+		case "created_at":
+			return a.CreatedAt.Compare(b.CreatedAt)
+		case "decided_at":
+			return cmp.Compare(timeKey(a.DecidedAt), timeKey(b.DecidedAt))
+		case "refund_amount":
+			return cmp.Compare(a.RefundAmount, b.RefundAmount)
+		case "status":
+			return strings.Compare(a.Status, b.Status)
+		case "priority":
+			return cmp.Compare(priorityScore(a), priorityScore(b))
+		}
+		return 0 // id, dibandingkan di bawah
+	}
+	return func(a, b Retur) int {
+		result := cmp.Or(compareColumn(a, b), cmp.Compare(a.ID, b.ID))
+		if descending {
+			return -result
+		}
+		return result
+	}, nil
+}
+
+// timeKey mengubah waktu opsional menjadi angka yang bisa dibandingkan; nil menjadi yang terkecil
+func timeKey(t *time.Time) int64 {
+	if t == nil {
+		return -1 << 63
+	}
+	return t.UnixNano()
+}
+
+// deletedMerge menyisipkan retur yang dihapus ke stream hasil query sesuai urutan ?sort
+// Hasil query sudah terurut oleh database, jadi retur yang dihapus cukup diurutkan sekali lalu digabung seperti merge sort
+type deletedMerge struct {
+	rows    []Retur // Retur yang dihapus, sudah terurut dengan compare
+	compare func(a, b Retur) int
+}
+
+// Len mengembalikan jumlah retur yang dihapus yang belum ditulis; aman dipanggil pada nil
+func (m *deletedMerge) Len() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.rows)
+}
+
+// emitBefore menulis retur yang dihapus yang urutannya sebelum retur dari database
+func (m *deletedMerge) emitBefore(retur Retur, emit func(Retur) error) error {
+	for m.Len() > 0 && m.compare(m.rows[0], retur) < 0 {
+		next := m.rows[0]
+		m.rows = m.rows[1:]
+		if err := emit(next); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// emitRest menulis semua retur yang dihapus yang tersisa setelah baris database terakhir
+func (m *deletedMerge) emitRest(emit func(Retur) error) error {
+	for m.Len() > 0 {
+		next := m.rows[0]
+		m.rows = m.rows[1:]
+		if err := emit(next); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deletedReturs mengembalikan retur di stack undo yang cocok dengan filter daftar, terurut sesuai ?sort (lihat listCompare)
+// Retur yang sudah dibuang permanen dari stack undo (RETUR_MAX_UNDO_AGE) tidak lagi bisa ditemukan
+// Hasilnya belum disamarkan: setiap format output menyamarkan baris database dan baris terhapus dengan cara yang sama
+func deletedReturs(r *http.Request) (*deletedMerge, error) {
+	match, err := listPredicate(r)
+	if err != nil {
+		return nil, err
+	}
+	compare, err := listCompare(r)
+	if err != nil {
+		return nil, err
+	}
+	var result []Retur
+	for _, entry := range deletedStack.SnapshotRange(0, deletedStack.Len()) {
+		for _, retur := range entry.Returs {
+			if !match(retur) {
+				continue
+			}
+			deletedAt := entry.DeletedAt
+			retur.Deleted = true
+			retur.DeletedAt = &deletedAt
+			result = append(result, retur)
+		}
+	}
+	slices.SortStableFunc(result, compare)
+	return &deletedMerge{rows: result, compare: compare}, nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestListPredicateMatchesListFilters(t *testing.T) {
+	created := time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)
+	retur := Retur{Status: "Dalam Proses", Barang: "Sepatu Lari", Alasan: "Sol lepas", Tags: []string{"vip"}, AssignedTo: "budi", CreatedAt: created}
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", true},
+		{"?status=Dalam+Proses&tag=VIP&assigned_to=budi", true},
+		{"?status=Disetujui", false},
+		{"?assigned_to=", false}, // assigned_to kosong berarti belum ditugaskan
+		{"?q=SOL", true},         // Pencarian tidak peka huruf besar/kecil seperti LIKE
+		{"?q=kemeja", false},
+		{"?from=2024-05-10T00:00:00Z&to=2024-05-11T00:00:00Z", true},
+		{"?to=2024-05-10T00:00:00Z", false}, // to eksklusif
+	}
+	for _, tt := range tests {
+		match, err := listPredicate(httptest.NewRequest(http.MethodGet, "/retur"+tt.query, nil))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		if got := match(retur); got != tt.want {
+			t.Errorf("%s: match = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+	if _, err := listPredicate(httptest.NewRequest(http.MethodGet, "/retur?overdue=maybe", nil)); err == nil {
+		t.Error("invalid overdue accepted")
+	}
+}
+
+func TestListCompareSortsNilDecidedAtFirst(t *testing.T) {
+	decided := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	returs := []Retur{{ID: 3, DecidedAt: &decided}, {ID: 2}, {ID: 1}}
+	ids := func(sort string) []int {
+		compare, err := listCompare(httptest.NewRequest(http.MethodGet, "/retur?sort="+sort, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sorted := slices.Clone(returs)
+		slices.SortFunc(sorted, compare)
+		var result []int
+		for _, retur := range sorted {
+			result = append(result, retur.ID)
+		}
+		return result
+	}
+	if got := ids("decided_at"); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("decided_at = %v", got)
+	}
+	if got := ids("-decided_at"); !slices.Equal(got, []int{3, 2, 1}) {
+		t.Errorf("-decided_at = %v", got)
+	}
+	if _, err := listCompare(httptest.NewRequest(http.MethodGet, "/retur?sort=password", nil)); err == nil {
+		t.Error("unknown sort column accepted")
+	}
+}
+
+func TestDeletedMergeInterleavesRows(t *testing.T) {
+	deletedAt := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	useUndoState(t, []undoEntry{
+		{Returs: []Retur{{ID: 4, Status: "Dalam Proses"}, {ID: 9, Status: "Disetujui"}}, DeletedAt: deletedAt},
+		{Returs: []Retur{{ID: 1, Status: "Dalam Proses"}}, DeletedAt: deletedAt},
+	}, nil)
+	merge, err := deletedReturs(httptest.NewRequest(http.MethodGet, "/retur?status=Dalam+Proses&sort=id", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merge.Len() != 2 || !merge.rows[0].Deleted || merge.rows[0].DeletedAt == nil || !merge.rows[0].DeletedAt.Equal(deletedAt) {
+		t.Fatalf("deleted rows = %+v", merge.rows)
+	}
+
+	var ids []int
+	emit := func(retur Retur) error {
+		ids = append(ids, retur.ID)
+		return nil
+	}
+	for _, row := range []Retur{{ID: 2}, {ID: 3}, {ID: 5}} { // Baris dari database, sudah terurut
+		merge.emitBefore(row, emit)
+		emit(row)
+	}
+	merge.emitRest(emit)
+	if !slices.Equal(ids, []int{1, 2, 3, 4, 5}) {
+		t.Fatalf("merged = %v", ids)
+	}
+	var empty *deletedMerge
+	if empty.Len() != 0 || empty.emitRest(emit) != nil {
+		t.Fatal("nil merge is not empty")
+	}
+}
+
+func TestListIncludeDeleted(t *testing.T) {
+	testDB(t)
+	seeded := seedReturs(t, Retur{Barang: "A", Alasan: "x"}, Retur{Barang: "B", Alasan: "x"}, Retur{Barang: "C", Alasan: "x"})
+	serve(httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/retur/%d/delete", seeded[1].ID), nil))
+
+	var list []Retur
+	decodeBody(t, serve(httptest.NewRequest(http.MethodGet, "/retur?sort=id&include_deleted=true", nil)), &list)
+	if len(list) != 3 || list[1].ID != seeded[1].ID || !list[1].Deleted || list[0].Deleted {
+		t.Fatalf("list = %+v", list)
+	}
+	decodeBody(t, serve(httptest.NewRequest(http.MethodGet, "/retur?sort=id", nil)), &list)
+	if len(list) != 2 {
+		t.Fatalf("deleted return listed without include_deleted: %+v", list)
+	}
+}
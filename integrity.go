@@ -0,0 +1,167 @@
+package main
+
+import (
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+// integrityIssue adalah satu pelanggaran invariant pada data retur
+// Fix berisi perbaikan otomatis untuk inkonsistensi yang jelas; nil berarti harus diperbaiki manual
+type integrityIssue struct {
+	Problem string
+	Fix     func(retur *Retur)
+}
+
+// integrityViolation adalah laporan pelanggaran untuk satu retur
+type integrityViolation struct {
+	ID       int      `json:"id"`
+	Status   string   `json:"status"`
+	Problems []string `json:"problems"`
+	Fixable  bool     `json:"fixable"` // Semua masalah bisa diperbaiki otomatis lewat /retur/admin/integrity/fix
+}
+
+// checkIntegrity memeriksa konsistensi status dengan field keputusan dan nilai enum pada satu retur
+func checkIntegrity(retur Retur) []integrityIssue {
+	var issues []integrityIssue
+	clearDecision := func(retur *Retur) {
+		retur.DecidedBy = ""
+		retur.DecidedAt = nil
+		retur.FirstApprovedBy = ""
+	}
+	clearRefund := func(retur *Retur) {
+		retur.RefundAmount = 0
+		retur.Currency = ""
+	}
+
+	if !validStatus(retur.Status) {
+		issues = append(issues, integrityIssue{Problem: "unknown status"})
+	}
+	if retur.Pengembalian != "" && !validPengembalian(retur.Pengembalian) {
+		issues = append(issues, integrityIssue{Problem: "unknown pengembalian"})
+	}
+	switch retur.Status {
+	case "Dalam Proses":
+		if retur.DecidedBy != "" || retur.DecidedAt != nil || retur.FirstApprovedBy != "" {
+			issues = append(issues, integrityIssue{Problem: "undecided return has decision metadata", Fix: clearDecision})
+		}
+	case statusAwaitingSecondApproval:
+		if retur.FirstApprovedBy == "" {
+			issues = append(issues, integrityIssue{Problem: "awaiting second approval without a first approver"})
+		}
+	case "Disetujui":
+		if retur.Pengembalian == "" {
+			issues = append(issues, integrityIssue{Problem: "approved return has no pengembalian"})
+		}
+		if retur.DecidedAt == nil {
+			issues = append(issues, integrityIssue{Problem: "approved return has no decided_at"})
+		}
+	case statusPartiallyApproved:
+		if retur.DecidedAt == nil {
+			issues = append(issues, integrityIssue{Problem: "partially approved return has no decided_at"})
+		}
+	case "Tidak Disetujui":
+		if retur.RefundAmount != 0 || retur.Currency != "" {
+			issues = append(issues, integrityIssue{Problem: "rejected return has refund data", Fix: clearRefund})
+		}
+		if retur.DecidedAt == nil {
+			issues = append(issues, integrityIssue{Problem: "rejected return has no decided_at"})
+		}
+	}
+	if retur.Status == "Disetujui" || retur.Status == statusAwaitingSecondApproval {
+		switch retur.Pengembalian {
+		case "barang":
+			if retur.RefundAmount != 0 || retur.Currency != "" {
+				issues = append(issues, integrityIssue{Problem: "barang return has refund data", Fix: clearRefund})
+			}
+		case "uang":
+			if retur.RefundAmount < 0 {
+				issues = append(issues, integrityIssue{Problem: "negative refund_amount"})
+			}
+			if _, ok := normalizeCurrency(retur.Currency); !ok {
+				issues = append(issues, integrityIssue{Problem: "uang return has an unknown currency"})
+			}
+		}
+	}
+	return issues
+}
+
+// scanIntegrity memeriksa semua retur per batch (berdasarkan ID) dan memanggil fn untuk setiap retur yang melanggar invariant
+func scanIntegrity(r *http.Request, fn func(retur Retur, issues []integrityIssue) error) (int, error) {
+	scanned, lastID := 0, 0
+	for {
+		var batch []Retur
+		if err := db.WithContext(r.Context()).Where("id > ?", lastID).Order("id").Limit(cfg.BackfillBatchSize).Find(&batch).Error; err != nil {
+			return scanned, err
+		}
+		if len(batch) == 0 {
+			return scanned, nil
+		}
+		lastID = batch[len(batch)-1].ID
+		scanned += len(batch)
+		for _, retur := range batch {
+			if issues := checkIntegrity(retur); len(issues) > 0 {
+				if err := fn(retur, issues); err != nil {
+					return scanned, err
+				}
+			}
+		}
+	}
+}
+
+// integrityReportHandler adalah handler admin untuk melaporkan retur dengan status yang tidak konsisten dengan field-nya
+func integrityReportHandler(w http.ResponseWriter, r *http.Request) {
+	violations := []integrityViolation{}
+	scanned, err := scanIntegrity(r, func(retur Retur, issues []integrityIssue) error {
+		violation := integrityViolation{ID: retur.ID, Status: retur.Status, Fixable: true}
+		for _, issue := range issues {
+			violation.Problems = append(violation.Problems, issue.Problem)
+			violation.Fixable = violation.Fixable && issue.Fix != nil
+		}
+		violations = append(violations, violation)
+		return nil
+	})
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to scan returns")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"scanned": scanned, "violations": violations})
+}
+
+// fixIntegrityHandler adalah handler admin untuk memperbaiki inkonsistensi yang jelas (misalnya data keputusan pada retur
+// yang belum diputuskan); setiap retur diperbaiki dalam transaksi sendiri dan perubahan dicatat di audit log
+// Masalah tanpa perbaikan otomatis dilewati dan tetap dilaporkan oleh GET /retur/admin/integrity
+func fixIntegrityHandler(w http.ResponseWriter, r *http.Request) {
+	fixed := []int{}
+	_, err := scanIntegrity(r, func(retur Retur, issues []integrityIssue) error {
+		before := retur
+		changed := false
+		for _, issue := range issues {
+			if issue.Fix != nil {
+				issue.Fix(&retur)
+				changed = true
+			}
+		}
+		if !changed || retur.Validate() != nil {
+			return nil // Retur yang masih melanggar Validate setelah diperbaiki dibiarkan untuk ditangani manual
+		}
+		if err := withTransaction(r.Context(), func(tx *gorm.DB) error {
+			if err := tx.Save(&retur).Error; err != nil {
+				return err
+			}
+			return recordAudit(tx, "update", actorFromRequest(r), before, retur)
+		}); err != nil {
+			return err
+		}
+		fixed = append(fixed, retur.ID)
+		return nil
+	})
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to fix returns")
+		return
+	}
+	if len(fixed) > 0 {
+		cachedStats.Invalidate() // Statistik bisa berubah karena data refund dibersihkan
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"fixed": fixed, "count": len(fixed)})
+}
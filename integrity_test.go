@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// problems mengambil teks masalah dari hasil checkIntegrity
+func problems(issues []integrityIssue) []string {
+	result := make([]string, len(issues))
+	for i, issue := range issues {
+		result[i] = issue.Problem
+	}
+	return result
+}
+
+func TestCheckIntegrityFindsViolations(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		retur   Retur
+		want    int
+		fixable bool
+	}{
+		{"consistent pending", Retur{Status: "Dalam Proses"}, 0, false},
+		{"consistent approval", Retur{Status: "Disetujui", Pengembalian: "uang", RefundAmount: 5000, Currency: "IDR", DecidedAt: &now}, 0, false},
+		{"pending with decision", Retur{Status: "Dalam Proses", DecidedBy: "ana", DecidedAt: &now}, 1, true},
+		{"rejected with refund", Retur{Status: "Tidak Disetujui", RefundAmount: 10, DecidedAt: &now}, 1, true},
+		{"approved without pengembalian", Retur{Status: "Disetujui", DecidedAt: &now}, 1, false},
+		{"consistent partial approval", Retur{Status: statusPartiallyApproved, Pengembalian: "barang", DecidedAt: &now}, 0, false},
+		{"partial approval without decided_at", Retur{Status: statusPartiallyApproved}, 1, false},
+		{"unknown status", Retur{Status: "Selesai"}, 1, false},
+		{"second approval without first approver", Retur{Status: statusAwaitingSecondApproval, Pengembalian: "uang", Currency: "IDR"}, 1, false},
+	}
+	for _, tt := range tests {
+		issues := checkIntegrity(tt.retur)
+		if len(issues) != tt.want {
+			t.Errorf("%s: problems %v", tt.name, problems(issues))
+			continue
+		}
+		for _, issue := range issues {
+			if (issue.Fix != nil) != tt.fixable {
+				t.Errorf("%s: %q fixable = %v", tt.name, issue.Problem, issue.Fix != nil)
+			}
+			if issue.Fix != nil {
+				fixed := tt.retur
+				issue.Fix(&fixed)
+				if remaining := checkIntegrity(fixed); len(remaining) != 0 {
+					t.Errorf("%s: still inconsistent after fix: %v", tt.name, problems(remaining))
+				}
+			}
+		}
+	}
+}
+
+func TestReturValidateInvariants(t *testing.T) {
+	now := time.Now()
+	var invariant *invariantError
+	for _, retur := range []Retur{
+		{Status: "Disetujui", Pengembalian: ""},
+		{Status: "Disetujui", Pengembalian: "barang", RefundAmount: 1},
+		{Status: "Tidak Disetujui", Currency: "IDR"},
+		{Status: "Dalam Proses", DecidedAt: &now},
+	} {
+		if err := retur.Validate(); !errors.As(err, &invariant) {
+			t.Errorf("%+v accepted: %v", retur, err)
+		}
+	}
+	if err := (&Retur{Status: "Disetujui", Pengembalian: "uang", RefundAmount: 100, Currency: "IDR"}).Validate(); err != nil {
+		t.Fatalf("valid approval rejected: %v", err)
+	}
+}
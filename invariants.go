@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+// invariantError adalah pelanggaran aturan lintas field pada retur yang akan disimpan
+// Aturan ini berlaku terlepas dari aturan transisi status, sehingga tidak ada handler yang bisa menyimpan baris yang tidak konsisten
+type invariantError struct {
+	fieldError
+}
+
+func (e *invariantError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+// Validate memeriksa kombinasi status dan data keputusan retur:
+// "Disetujui" wajib memiliki pengembalian yang valid, sedangkan "Dalam Proses" dan "Tidak Disetujui" tidak boleh memiliki data refund
+// "Dalam Proses" juga belum boleh memiliki pemberi/waktu keputusan; status kosong (misalnya model untuk update massal) dilewati
+func (r *Retur) Validate() error {
+	invalid := func(field, message string) error {
+		return &invariantError{fieldError{Field: field, Message: message}}
+	}
+	switch r.Status {
+	case "Disetujui":
+		if !validPengembalian(r.Pengembalian) {
+			return invalid("pengembalian", "must be 'barang' or 'uang' when status is 'Disetujui'")
+		}
+		if r.Pengembalian == "barang" && (r.RefundAmount != 0 || r.Currency != "") {
+			return invalid("refund_amount", "must be empty when pengembalian is 'barang'")
+		}
+	case "Dalam Proses", "Tidak Disetujui":
+		if r.RefundAmount != 0 || r.Currency != "" {
+			return invalid("refund_amount", "must be empty when status is '"+r.Status+"'")
+		}
+		if r.Status == "Dalam Proses" && (r.DecidedBy != "" || r.DecidedAt != nil) {
+			return invalid("decided_at", "must be empty when status is 'Dalam Proses'")
+		}
+	}
+	return nil
+}
+
+// BeforeSave adalah hook GORM yang menjalankan Validate sebelum setiap Save/Create retur (RETUR_VALIDATE_INVARIANTS)
+// Update per kolom (Update/Updates dengan map) tidak menulis ulang seluruh baris sehingga tidak diperiksa
+// Hook ini juga mengisi natural_key dari order_id dan barang agar unique index selalu mengikuti nilai terbaru
+func (r *Retur) BeforeSave(tx *gorm.DB) error {
+	r.NaturalKey = naturalKey(r.OrderID, r.Barang)
+	if !cfg.ValidateInvariants {
+		return nil
+	}
+	if _, ok := tx.Statement.Dest.(map[string]interface{}); ok {
+		return nil
+	}
+	return r.Validate()
+}
+
+// respondSaveError mengirimkan 422 jika penyimpanan ditolak oleh Validate, selain itu 500 dengan pesan yang diberikan
+func respondSaveError(w http.ResponseWriter, err error, message string) {
+	var invariant *invariantError
+	if errors.As(err, &invariant) {
+		respondValidationErrors(w, []fieldError{invariant.fieldError})
+		return
+	}
+	handleError(w, http.StatusInternalServerError, message)
+}
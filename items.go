@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReturItem adalah satu barang di dalam retur yang bisa disetujui atau ditolak secara terpisah
+// Retur tanpa item tetap berjalan seperti biasa; keputusan diberikan langsung pada retur
+// retur_id dilindungi foreign key ON DELETE CASCADE (relasi Retur.Items) karena item selalu ikut tersimpan di snapshot undo
+type ReturItem struct {
+	ID           int    `json:"id"`                    // ID unik item
+	ReturID      int    `json:"retur_id" gorm:"index"` // ID retur induk
+	SKU          string `json:"sku"`                   // Kode barang
+	Quantity     int    `json:"quantity"`              // Jumlah barang yang diretur
+	Status       string `json:"status"`                // Status item (Dalam Proses, Disetujui, Tidak Disetujui)
+	Pengembalian string `json:"pengembalian"`          // Jenis pengembalian item (barang atau uang)
+}
+
+// statusPartiallyApproved adalah status retur induk yang sebagian item-nya disetujui dan sisanya ditolak
+const statusPartiallyApproved = "Disetujui Sebagian"
+
+// derivedParentStatus menentukan status retur induk dari status semua item-nya
+// Semua disetujui → "Disetujui", semua ditolak → "Tidak Disetujui", ada yang belum diputuskan → "Dalam Proses",
+// campuran disetujui dan ditolak → "Disetujui Sebagian". Nilai kedua adalah pengembalian jika seragam
+func derivedParentStatus(items []ReturItem) (string, string) {
+	approved, rejected := 0, 0
+	pengembalian := ""
+	uniform := true
+	for _, item := range items {
+		switch item.Status {
+		case "Disetujui":
+			approved++
+			if pengembalian == "" {
+				pengembalian = item.Pengembalian
+			} else if pengembalian != item.Pengembalian {
+				uniform = false // Item disetujui dengan jenis pengembalian berbeda
+			}
+		case "Tidak Disetujui":
+			rejected++
+		default:
+			return "Dalam Proses", "" // Masih ada item yang belum diputuskan
+		}
+	}
+	if !uniform {
+		pengembalian = ""
+	}
+	switch {
+	case rejected == len(items):
+		return "Tidak Disetujui", ""
+	case approved == len(items):
+		return "Disetujui", pengembalian
+	}
+	return statusPartiallyApproved, pengembalian
+}
+
+// Kesalahan keputusan item yang dijawab handler dengan status selain 500
+var (
+	errItemNotFound  = errors.New("item not found")
+	errParentDecided = errors.New("return has already been decided")
+)
+
+// resetItems mengembalikan semua item retur ke "Dalam Proses" tanpa pengembalian, dipakai saat keputusan retur di-reset
+// Tanpa ini keputusan item berikutnya menurunkan ulang status induk dari keputusan item yang lama
+func resetItems(tx *gorm.DB, returID int) error {
+	return tx.Model(&ReturItem{}).Where("retur_id = ?", returID).Updates(map[string]interface{}{"status": "Dalam Proses", "pengembalian": ""}).Error
+}
+
+// itemDecisionHandler membuat handler untuk menyetujui (approve=true) atau menolak satu item retur
+// Status retur induk diturunkan ulang dari status semua item dan perubahannya dicatat di riwayat
+// Item hanya bisa diputuskan selama retur induk "Dalam Proses"; retur yang sudah final atau menunggu approver kedua dijawab 409
+func itemDecisionHandler(approve bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			handleError(w, http.StatusBadRequest, "Invalid ID format") // Jika format ID salah, kirimkan error
+			return
+		}
+		itemID, err := strconv.Atoi(vars["itemID"])
+		if err != nil {
+			handleError(w, http.StatusBadRequest, "Invalid item ID format") // Jika format ID item salah, kirimkan error
+			return
+		}
+
+		var input struct {
+			Pengembalian string `json:"pengembalian"` // Jenis pengembalian item, wajib saat approve
+		}
+		if approve {
+			if !decodeJSON(w, r, &input) {
+				return // Body kosong atau JSON tidak valid, error sudah dikirim
+			}
+			if !validPengembalian(input.Pengembalian) {
+				handleError(w, http.StatusBadRequest, "Pengembalian must be 'barang' or 'uang'") // Validasi nilai pengembalian
+				return
+			}
+		}
+
+		var retur Retur
+		err = withTransaction(r.Context(), func(tx *gorm.DB) error {
+			// Dibaca ulang dengan FOR UPDATE agar keputusan item bersamaan pada retur yang sama menurunkan status induk dari item terbaru
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Items").First(&retur, id).Error; err != nil {
+				return err
+			}
+			if retur.Status != "Dalam Proses" {
+				return errParentDecided // Termasuk retur yang menunggu approver kedua, yang hanya boleh diputuskan lewat approve
+			}
+			index := -1
+			for i, item := range retur.Items {
+				if item.ID == itemID {
+					index = i
+				}
+			}
+			if index < 0 {
+				return errItemNotFound
+			}
+
+			before := retur
+			item := &retur.Items[index]
+			if approve {
+				item.Status = "Disetujui"
+				item.Pengembalian = input.Pengembalian
+			} else {
+				item.Status = "Tidak Disetujui"
+				item.Pengembalian = ""
+			}
+			retur.Status, retur.Pengembalian = derivedParentStatus(retur.Items)
+			if retur.Status != "Dalam Proses" {
+				markDecided(&retur, r) // Catat pemberi keputusan terakhir pada retur induk
+			}
+			if err := tx.Save(item).Error; err != nil {
+				return err
+			}
+			if err := updateDecision(tx, before, &retur); err != nil {
+				return err // Sama seperti approve/disapprove, status induk hanya ditulis jika belum berubah
+			}
+			if err := recordAudit(tx, "update", approverFromRequest(r), before, retur); err != nil {
+				return err
+			}
+			note := fmt.Sprintf("item %d %s", item.ID, item.Status)
+			return tx.Create(&ReturHistory{ReturID: retur.ID, FromStatus: before.Status, ToStatus: retur.Status, Actor: approverFromRequest(r), Note: note}).Error
+		})
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			handleError(w, http.StatusNotFound, "Return not found") // Jika retur tidak ditemukan, kirimkan error
+			return
+		case errors.Is(err, errItemNotFound):
+			handleError(w, http.StatusNotFound, "Item not found") // Item tidak ada di retur ini
+			return
+		case errors.Is(err, errParentDecided):
+			handleError(w, http.StatusConflict, "Return has already been decided ("+retur.Status+")") // Gunakan reset admin untuk membuka kembali
+			return
+		case errors.Is(err, errApprovalConflict):
+			handleError(w, http.StatusConflict, "Return was decided concurrently") // Status berubah setelah dibaca
+			return
+		case err != nil:
+			respondSaveError(w, err, "Failed to update item") // Jika gagal memperbarui, kirimkan error
+			return
+		}
+
+		eventType := ReturDisapproved
+		if approve {
+			eventType = ReturApproved
+		}
+		publishEvent(eventType, retur, r)
+		maskRetur(r, &retur)
+		respondJSON(w, http.StatusOK, retur) // Kirimkan retur beserta semua item-nya
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestDerivedParentStatus(t *testing.T) {
+	tests := []struct {
+		items                []ReturItem
+		status, pengembalian string
+	}{
+		{[]ReturItem{{Status: "Disetujui", Pengembalian: "uang"}, {Status: "Dalam Proses"}}, "Dalam Proses", ""},
+		{[]ReturItem{{Status: "Disetujui", Pengembalian: "uang"}, {Status: "Disetujui", Pengembalian: "uang"}}, "Disetujui", "uang"},
+		{[]ReturItem{{Status: "Disetujui", Pengembalian: "uang"}, {Status: "Disetujui", Pengembalian: "barang"}}, "Disetujui", ""},
+		{[]ReturItem{{Status: "Tidak Disetujui"}, {Status: "Tidak Disetujui"}}, "Tidak Disetujui", ""},
+		{[]ReturItem{{Status: "Disetujui", Pengembalian: "barang"}, {Status: "Tidak Disetujui"}}, statusPartiallyApproved, "barang"},
+	}
+	for _, tt := range tests {
+		status, pengembalian := derivedParentStatus(tt.items)
+		if status != tt.status || pengembalian != tt.pengembalian {
+			t.Errorf("%+v: got %q/%q, want %q/%q", tt.items, status, pengembalian, tt.status, tt.pengembalian)
+		}
+	}
+}
+
+// itemDecision memutuskan satu item lewat API sebagai approver "budi"
+func itemDecision(tb testing.TB, returID, itemID int, approve bool) (int, Retur) {
+	tb.Helper()
+	action := "disapprove"
+	if approve {
+		action = "approve"
+	}
+	path := fmt.Sprintf("/retur/%d/items/%d/%s", returID, itemID, action)
+	rec := serve(asApprover(jsonRequest(tb, http.MethodPost, path, map[string]string{"pengembalian": "barang"}), "tok-budi"))
+	var retur Retur
+	if rec.Code == http.StatusOK {
+		decodeBody(tb, rec, &retur)
+	}
+	return rec.Code, retur
+}
+
+func TestItemDecisionsDeriveParentAndRejectDecidedParent(t *testing.T) {
+	testDB(t)
+	useApprovers(t, map[string]string{"tok-budi": "budi"})
+	seeded := seedReturs(t, Retur{Barang: "Paket", Alasan: "rusak", Items: []ReturItem{
+		{SKU: "A-1", Quantity: 1, Status: "Dalam Proses"},
+		{SKU: "B-2", Quantity: 2, Status: "Dalam Proses"},
+	}})[0]
+	first, second := seeded.Items[0].ID, seeded.Items[1].ID
+
+	if code, retur := itemDecision(t, seeded.ID, first, true); code != http.StatusOK || retur.Status != "Dalam Proses" {
+		t.Fatalf("first item: %d %+v", code, retur)
+	}
+	if code, retur := itemDecision(t, seeded.ID, second, false); code != http.StatusOK || retur.Status != statusPartiallyApproved || retur.DecidedBy != "budi" {
+		t.Fatalf("second item: %d %+v", code, retur)
+	}
+	if code, _ := itemDecision(t, seeded.ID, second, true); code != http.StatusConflict {
+		t.Fatalf("deciding an item of a decided return: %d, want 409", code)
+	}
+	if code, _ := itemDecision(t, seeded.ID, 999999, true); code != http.StatusConflict {
+		t.Fatalf("unknown item on a decided return: %d, want 409", code)
+	}
+}
+
+func TestItemDecisionCannotSkipSecondApproval(t *testing.T) {
+	testDB(t)
+	useApprovers(t, map[string]string{"tok-budi": "budi"})
+	seeded := seedReturs(t, Retur{Barang: "Laptop", Alasan: "mati", Status: statusAwaitingSecondApproval, Pengembalian: "uang", RefundAmount: 2000000, Currency: "IDR", FirstApprovedBy: "siti",
+		Items: []ReturItem{{SKU: "L-1", Quantity: 1, Status: "Dalam Proses"}}})[0]
+
+	if code, _ := itemDecision(t, seeded.ID, seeded.Items[0].ID, true); code != http.StatusConflict {
+		t.Fatalf("item decision while awaiting second approval: %d, want 409", code)
+	}
+	var stored Retur
+	if err := db.First(&stored, seeded.ID).Error; err != nil || stored.Status != statusAwaitingSecondApproval {
+		t.Fatalf("parent after rejected item decision: %+v, %v", stored, err)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// backgroundJob adalah pekerjaan yang dijalankan berkala di background selama aplikasi hidup
+type backgroundJob struct {
+	Name     string                          // Nama job untuk log dan /status
+	Interval func() time.Duration            // Jeda antar eksekusi; 0 atau negatif berarti job nonaktif
+	Run      func(ctx context.Context) error // Satu kali eksekusi job
+
+	runs    atomic.Int64 // Jumlah eksekusi yang sudah selesai
+	lastRun atomic.Int64 // Waktu (unix nano) eksekusi terakhir
+	paused  atomic.Bool  // Job dijeda oleh admin; diperiksa di awal setiap iterasi
+}
+
+// backgroundJobs adalah semua job background yang dijalankan oleh startBackgroundJobs
+var backgroundJobs = []*backgroundJob{
+	{Name: "undo-purge", Interval: func() time.Duration { return cfg.UndoPurgeInterval }, Run: func(context.Context) error {
+		_, err := purgeExpiredUndo()
+		return err
+	}},
+	{Name: "tombstone-purge", Interval: func() time.Duration { return cfg.UndoPurgeInterval }, Run: func(context.Context) error {
+		_, err := purgeOldTombstones()
+		return err
+	}},
+	{Name: "export-purge", Interval: func() time.Duration { return cfg.ExportPurgeInterval }, Run: func(context.Context) error {
+		_, err := purgeExportJobs()
+		return err
+	}},
+}
+
+// startBackgroundJobs menjalankan setiap job aktif di goroutine sendiri sampai ctx dibatalkan
+func startBackgroundJobs(ctx context.Context) {
+	for _, job := range backgroundJobs {
+		interval := job.Interval()
+		if interval <= 0 {
+			continue // Job dinonaktifkan lewat konfigurasi
+		}
+		go job.loop(ctx, interval)
+	}
+}
+
+// loop menjalankan job setiap interval sampai ctx dibatalkan
+func (job *backgroundJob) loop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if job.paused.Load() {
+			continue // Dijeda lewat /retur/admin/jobs/{name}/pause, ticker tetap berjalan agar bisa dilanjutkan kapan saja
+		}
+		if err := job.Run(ctx); err != nil {
+			log.Printf("job %s failed: %v", job.Name, err)
+		}
+		job.runs.Add(1)
+		job.lastRun.Store(clock.Now().UnixNano())
+	}
+}
+
+// Snapshot mengembalikan kondisi job untuk endpoint /status
+func (job *backgroundJob) Snapshot() map[string]interface{} {
+	snapshot := map[string]interface{}{
+		"interval": job.Interval().String(),
+		"enabled":  job.Interval() > 0,
+		"runs":     job.runs.Load(),
+		"paused":   job.paused.Load(),
+	}
+	if last := job.lastRun.Load(); last > 0 {
+		snapshot["last_run_at"] = time.Unix(0, last).UTC()
+	}
+	return snapshot
+}
+
+// jobsStatus merangkum semua job background untuk endpoint /status
+func jobsStatus() map[string]interface{} {
+	status := make(map[string]interface{}, len(backgroundJobs))
+	for _, job := range backgroundJobs {
+		status[job.Name] = job.Snapshot()
+	}
+	return status
+}
+
+// findJob mencari job background berdasarkan nama
+func findJob(name string) (*backgroundJob, bool) {
+	for _, job := range backgroundJobs {
+		if job.Name == name {
+			return job, true
+		}
+	}
+	return nil, false
+}
+
+// setJobPausedHandler membuat handler admin untuk menjeda (paused=true) atau melanjutkan job background tanpa redeploy
+func setJobPausedHandler(paused bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, ok := findJob(mux.Vars(r)["name"])
+		if !ok {
+			handleError(w, http.StatusNotFound, "Job not found")
+			return
+		}
+		if job.paused.Swap(paused) != paused {
+			log.Printf("job %s paused=%t by %s", job.Name, paused, logActor(r))
+		}
+		respondJSON(w, http.StatusOK, map[string]interface{}{"name": job.Name, "job": job.Snapshot()})
+	}
+}
+
+// purgeExpiredUndo membuang entry undo yang melewati RETUR_MAX_UNDO_AGE dari stack
+// Jika arsip aktif (RETUR_ARCHIVE_PATH), entry diarsipkan lebih dulu dan tidak ada yang dibuang jika arsip gagal ditulis
+// Arsip ditulis di dalam transaksi yang mengunci lalu menghapus baris undo_entries, baris retur yang di-soft delete, tombstone, dan lampirannya, sehingga
+// entry yang barisnya sudah dibuang (purge sebelumnya atau instance lain) tidak diarsipkan dua kali. Jika commit gagal
+// setelah arsip ditulis, entry dicoba lagi dan barisnya bisa muncul dua kali; archive_key dipakai untuk deduplikasi
+func purgeExpiredUndo() (int, error) {
+	now := clock.Now()
+	var expired []undoEntry
+	for _, entry := range deletedStack.Items() {
+		if entry.expired(now) {
+			expired = append(expired, entry)
+		}
+	}
+	if len(expired) == 0 {
+		return 0, nil
+	}
+	var purged []undoEntry
+	var files []string
+	err := withTransaction(context.Background(), func(tx *gorm.DB) error {
+		live, err := lockUndoRecords(tx, expired)
+		if err != nil || len(live) == 0 {
+			return err
+		}
+		if err := archiveUndoEntries(tx, live); err != nil {
+			return fmt.Errorf("archive expired undo entries: %w", err) // Entry tetap di stack dan dicoba lagi pada eksekusi berikutnya
+		}
+		if err := deleteUndoRecords(tx, live...); err != nil {
+			return err
+		}
+		if err := recordTombstones(tx, live); err != nil {
+			return err // GET untuk ID ini harus menjawab 410, jadi tombstone ikut commit bersama penghapusan
+		}
+		if err := purgeSoftDeleted(tx, entryReturIDs(live...)); err != nil {
+			return err // Undo tidak lagi mungkin, baris soft delete dibuang permanen
+		}
+		if files, err = purgeAttachments(tx, live); err != nil {
+			return err
+		}
+		purged = live
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	// Entry yang barisnya sudah tidak ada juga dikeluarkan dari stack karena sudah dibuang di tempat lain
+	deletedStack.RemoveFunc(func(entry undoEntry) bool { return entry.expired(now) })
+	removeAttachmentFiles(files)
+	if len(purged) > 0 {
+		log.Printf("purged %d expired undo entries", len(purged))
+	}
+	return len(purged), nil
+}
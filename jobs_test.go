@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// useTestJob mengganti daftar job background dengan satu job yang melapor ke ran setiap kali dijalankan
+func useTestJob(t *testing.T, ran chan<- struct{}) *backgroundJob {
+	t.Helper()
+	job := &backgroundJob{Name: "test-job", Interval: func() time.Duration { return 5 * time.Millisecond }, Run: func(ctx context.Context) error {
+		select {
+		case ran <- struct{}{}:
+		case <-ctx.Done():
+		}
+		return nil
+	}}
+	saved := backgroundJobs
+	backgroundJobs = []*backgroundJob{job}
+	t.Cleanup(func() { backgroundJobs = saved })
+	return job
+}
+
+func TestPauseAndResumeJob(t *testing.T) {
+	ran := make(chan struct{})
+	job := useTestJob(t, ran)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	startBackgroundJobs(ctx)
+
+	waitRun := func(within time.Duration) bool {
+		select {
+		case <-ran:
+			return true
+		case <-time.After(within):
+			return false
+		}
+	}
+	if !waitRun(time.Second) {
+		t.Fatal("job never ran")
+	}
+
+	rec := serve(asAdmin(t, jsonRequest(t, http.MethodPost, "/retur/admin/jobs/test-job/pause", nil)))
+	if rec.Code != http.StatusOK || !job.paused.Load() {
+		t.Fatalf("pause: %d %s", rec.Code, rec.Body)
+	}
+	if status := jobsStatus()["test-job"].(map[string]interface{}); status["paused"] != true {
+		t.Fatalf("status after pause = %v", status)
+	}
+	waitRun(20 * time.Millisecond) // Eksekusi yang sudah lewat pemeriksaan paused sebelum pause boleh selesai
+	if waitRun(50 * time.Millisecond) {
+		t.Fatal("paused job kept running")
+	}
+	runs := job.runs.Load()
+
+	if rec := serve(asAdmin(t, jsonRequest(t, http.MethodPost, "/retur/admin/jobs/test-job/resume", nil))); rec.Code != http.StatusOK {
+		t.Fatalf("resume: %d %s", rec.Code, rec.Body)
+	}
+	if !waitRun(time.Second) {
+		t.Fatal("resumed job did not run")
+	}
+	if status := jobsStatus()["test-job"].(map[string]interface{}); status["paused"] != false {
+		t.Fatalf("status after resume = %v", status)
+	}
+	waitRun(time.Second) // Eksekusi sebelumnya tercatat setelah Run kembali
+	if job.runs.Load() <= runs {
+		t.Fatalf("runs = %d after resume, want more than %d", job.runs.Load(), runs)
+	}
+
+	if rec := serve(asAdmin(t, jsonRequest(t, http.MethodPost, "/retur/admin/jobs/nope/pause", nil))); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown job: %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"sort"
+)
+
+// latencyStats adalah ringkasan lama waktu (detik) dari retur dibuat sampai keputusan
+type latencyStats struct {
+	Count      int     `json:"count"`
+	AvgSeconds float64 `json:"avg_seconds"`
+	P50Seconds float64 `json:"p50_seconds"`
+	P90Seconds float64 `json:"p90_seconds"`
+	P99Seconds float64 `json:"p99_seconds"`
+}
+
+// percentile menghitung persentil p (0-100) dengan metode nearest-rank dari durasi yang sudah terurut
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// summarizeLatency menghitung rata-rata dan persentil dari daftar durasi
+func summarizeLatency(durations []float64) latencyStats {
+	sort.Float64s(durations)
+	var total float64
+	for _, d := range durations {
+		total += d
+	}
+	stats := latencyStats{Count: len(durations)}
+	if len(durations) > 0 {
+		stats.AvgSeconds = total / float64(len(durations))
+		stats.P50Seconds = percentile(durations, 50)
+		stats.P90Seconds = percentile(durations, 90)
+		stats.P99Seconds = percentile(durations, 99)
+	}
+	return stats
+}
+
+// latencyStatsHandler adalah handler untuk lama waktu dari retur dibuat sampai disetujui/ditolak per status tujuan
+// Durasi dihitung dari riwayat status (retur_histories) yang tercatat dalam RETUR_LATENCY_WINDOW terakhir,
+// lalu rata-rata dan persentil p50/p90/p99 dihitung di aplikasi
+func latencyStatsHandler(w http.ResponseWriter, r *http.Request) {
+	since := clock.Now().Add(-cfg.LatencyWindow)
+	var rows []struct {
+		ToStatus string
+		Seconds  float64
+	}
+	// Riwayat dibatasi sejak retur dibuat agar riwayat milik retur lama dengan ID yang sama (ID reuse) tidak ikut dihitung
+	err := db.WithContext(r.Context()).Table("retur_histories AS h").
+		Select("h.to_status, TIMESTAMPDIFF(SECOND, r.created_at, h.created_at) AS seconds").
+		Joins("JOIN returs AS r ON r.id = h.retur_id AND h.created_at >= r.created_at").
+		Where("h.to_status IN ? AND h.created_at >= ?", []string{"Disetujui", "Tidak Disetujui"}, since).
+		Scan(&rows).Error
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to compute latency stats")
+		return
+	}
+
+	durations := map[string][]float64{"Disetujui": {}, "Tidak Disetujui": {}}
+	for _, row := range rows {
+		durations[row.ToStatus] = append(durations[row.ToStatus], row.Seconds)
+	}
+	transitions := make(map[string]latencyStats, len(durations))
+	for status, values := range durations {
+		transitions[status] = summarizeLatency(values)
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"window":      cfg.LatencyWindow.String(),
+		"since":       since,
+		"transitions": transitions, // Per status tujuan: Disetujui atau Tidak Disetujui
+	})
+}
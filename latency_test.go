@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSummarizeLatency(t *testing.T) {
+	durations := []float64{600, 60, 540, 120, 480, 180, 420, 240, 360, 300} // 1 sampai 10 menit, tidak terurut
+	want := latencyStats{Count: 10, AvgSeconds: 330, P50Seconds: 300, P90Seconds: 540, P99Seconds: 600}
+	if got := summarizeLatency(durations); got != want {
+		t.Fatalf("stats = %+v, want %+v", got, want)
+	}
+	if got := summarizeLatency(nil); got != (latencyStats{}) {
+		t.Fatalf("empty stats = %+v", got)
+	}
+}
+
+func TestLatencyStatsFromHistory(t *testing.T) {
+	testDB(t)
+	withConfig(t, func(c *Config) { c.LatencyWindow = 24 * time.Hour })
+	created := clock.Now().Add(-12 * time.Hour).Truncate(time.Second)
+	decide := func(status string, after time.Duration) {
+		retur := seedReturs(t, Retur{Barang: "Barang", Alasan: "Rusak", Status: status, CreatedAt: created})[0]
+		history := []ReturHistory{
+			{ReturID: retur.ID, FromStatus: "", ToStatus: "Dalam Proses", CreatedAt: created}, // Bukan keputusan, tidak dihitung
+			{ReturID: retur.ID, FromStatus: "Dalam Proses", ToStatus: status, CreatedAt: created.Add(after)},
+		}
+		if err := db.Create(&history).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	for minutes := 1; minutes <= 10; minutes++ {
+		decide("Disetujui", time.Duration(minutes)*time.Minute)
+	}
+	decide("Tidak Disetujui", time.Hour)
+	decide("Tidak Disetujui", 2*time.Hour)
+	old := seedReturs(t, Retur{Barang: "Lama", Alasan: "Rusak", Status: "Disetujui", CreatedAt: created.Add(-72 * time.Hour)})[0]
+	if err := db.Create(&ReturHistory{ReturID: old.ID, FromStatus: "Dalam Proses", ToStatus: "Disetujui", CreatedAt: created.Add(-48 * time.Hour)}).Error; err != nil {
+		t.Fatal(err) // Di luar RETUR_LATENCY_WINDOW
+	}
+
+	rec := serve(httptest.NewRequest(http.MethodGet, "/retur/stats/latency", nil))
+	var body struct {
+		Transitions map[string]latencyStats `json:"transitions"`
+	}
+	decodeBody(t, rec, &body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("latency: %d %s", rec.Code, rec.Body)
+	}
+	want := map[string]latencyStats{
+		"Disetujui":       {Count: 10, AvgSeconds: 330, P50Seconds: 300, P90Seconds: 540, P99Seconds: 600},
+		"Tidak Disetujui": {Count: 2, AvgSeconds: 5400, P50Seconds: 3600, P90Seconds: 7200, P99Seconds: 7200},
+	}
+	for status, stats := range want {
+		if got := body.Transitions[status]; got != stats {
+			t.Errorf("%s: %+v, want %+v", status, got, stats)
+		}
+	}
+}
@@ -1,12 +1,26 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gorilla/mux"
+	"golang.org/x/sync/semaphore"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
@@ -17,25 +31,91 @@ import (
 type Retur struct {
 	ID          int    `json:"id"`         // ID unik untuk setiap retur
 	Barang      string `json:"barang"`     // Nama barang yang diretur
+	CanonicalBarang string `json:"canonical_barang,omitempty" gorm:"index"` // Nama barang di katalog (RETUR_BARANG_CATALOG) jika Barang cukup mirip, kosong jika tidak ada yang cocok
 	Alasan      string `json:"alasan"`     // Alasan pengembalian barang
-	Status      string `json:"status"`     // Status retur (Dalam Proses, Disetujui, Tidak Disetujui)
+	ReasonCode  string `json:"reason_code" gorm:"index"` // Kode alasan terstruktur (lihat reasonCodes), kosong untuk retur lama
+	Status      string `json:"status"`     // Status retur (Dalam Proses, Menunggu Persetujuan Kedua, Disetujui, Tidak Disetujui)
 	Pengembalian string `json:"pengembalian"` // Jenis pengembalian (barang atau uang)
+	CustomerID  string `json:"customer_id" gorm:"index"` // ID customer yang mengajukan retur
+	OrderID     string `json:"order_id,omitempty" gorm:"index"` // ID pesanan, bersama Barang menjadi natural key untuk create dengan ?upsert=true
+	NaturalKey  *string `json:"-" gorm:"uniqueIndex;size:64"` // Hash (order_id, barang) yang diisi BeforeSave, NULL jika order_id kosong
+	OrderDate   *time.Time `json:"order_date,omitempty"` // Waktu pembelian, wajib jika RETUR_RETURN_WINDOW diatur
+	RefundAmount int64  `json:"refund_amount"` // Jumlah refund dalam satuan terkecil mata uang (minor units), hanya untuk pengembalian uang
+	Currency    string `json:"currency"`     // Kode mata uang ISO 4217 untuk refund
+	DecidedBy   string     `json:"decided_by"` // Pengguna yang terakhir menyetujui/menolak retur
+	DecidedAt   *time.Time `json:"decided_at"` // Waktu keputusan terakhir, null jika belum diputuskan
+	DecisionNote string    `json:"decision_note"` // Catatan keputusan, wajib untuk penolakan jika dikonfigurasi
+	FirstApprovedBy string `json:"first_approved_by,omitempty"` // Approver pertama untuk refund yang membutuhkan persetujuan kedua
+	AssignedTo   string    `json:"assigned_to" gorm:"index"` // Agent yang menangani retur, kosong berarti belum ditugaskan
+	Tags         []string  `json:"tags,omitempty" gorm:"serializer:json;type:text"` // Label bebas, misalnya fraud-review; diubah lewat /retur/{id}/tags
+	Details      map[string]string `json:"details,omitempty" gorm:"serializer:details;type:text"` // Data tambahan per jenis pengembalian (rekening, alamat kirim), lihat RETUR_DETAILS_POLICY dan RETUR_ENCRYPTED_DETAILS
+	CreatedAt   time.Time `json:"created_at"` // Waktu retur dibuat, diisi otomatis oleh GORM
+	Items       []ReturItem `json:"items,omitempty" gorm:"foreignKey:ReturID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"` // Item retur untuk keputusan per barang (opsional)
+	Removed     gorm.DeletedAt `json:"-" gorm:"column:deleted_at;index"` // Soft delete: terisi selama retur yang dihapus menunggu undo (lihat softdelete.go)
+
+	SLADeadline *time.Time `json:"sla_deadline,omitempty" gorm:"-"` // Batas waktu SLA untuk status saat ini (dihitung, tidak disimpan)
+	Overdue     bool       `json:"overdue" gorm:"-"`                // Bernilai true jika retur melewati SLA (dihitung, tidak disimpan)
+	Priority    float64    `json:"priority" gorm:"-"`               // Skor prioritas antrean kerja, lihat priorityWeights (dihitung, tidak disimpan)
+	Deleted     bool       `json:"deleted,omitempty" gorm:"-"`      // Bernilai true untuk retur dari stack undo pada ?include_deleted=true
+	DeletedAt   *time.Time `json:"deleted_at,omitempty" gorm:"-"`   // Waktu penghapusan, hanya untuk retur yang dihapus
+}
+
+// undoEntry adalah satu langkah undo yang berisi satu atau beberapa retur yang dihapus bersamaan
+// Penghapusan massal disimpan sebagai satu entry agar bisa dikembalikan sekaligus dengan satu undo
+type undoEntry struct {
+	Returs    []Retur   `json:"returs"`           // Data retur yang dihapus pada langkah ini
+	DeletedAt time.Time `json:"deleted_at"`       // Waktu penghapusan, dipakai untuk batas umur undo (RETUR_MAX_UNDO_AGE)
+	Reason    string    `json:"reason,omitempty"` // Alasan penghapusan dari pengguna (opsional)
+	RecordID  uint      `json:"-"`                // ID baris di tabel undo_entries, 0 jika belum tersimpan
+}
+
+// expired memeriksa apakah entry sudah melewati batas umur undo; batas 0 berarti tidak pernah kedaluwarsa
+// Entry tanpa waktu penghapusan (misalnya hasil impor format lama) dianggap belum kedaluwarsa
+func (e undoEntry) expired(now time.Time) bool {
+	return cfg.MaxUndoAge > 0 && !e.DeletedAt.IsZero() && now.Sub(e.DeletedAt) > cfg.MaxUndoAge
 }
 
 // Stack adalah implementasi stack generik menggunakan slice
-// Digunakan untuk menyimpan data yang dihapus dan bisa di-undo
+// Digunakan untuk menyimpan data yang dihapus dan bisa di-undo; aman dipakai bersamaan oleh handler dan job background
 type Stack[T any] struct {
-	items []T // Slice untuk menyimpan item di dalam stack
+	mu       sync.Mutex
+	items    []T          // Slice untuk menyimpan item di dalam stack
+	lockWait atomic.Int64 // Rata-rata bergerak waktu tunggu lock pada Push (nanodetik), lihat LockWait
 }
 
-// Push menambahkan item baru ke dalam stack
+// Push menambahkan item baru ke dalam stack; waktu tunggu lock-nya ikut diukur untuk load shedding undo
 func (s *Stack[T]) Push(item T) {
+	s.lockTimed()
+	defer s.mu.Unlock()
 	s.items = append(s.items, item)
 }
 
+// lockTimed mengunci stack dan memperbarui rata-rata bergerak waktu tunggu lock (sampel baru berbobot 1/8)
+func (s *Stack[T]) lockTimed() {
+	start := time.Now()
+	s.mu.Lock()
+	wait := int64(time.Since(start))
+	old := s.lockWait.Load()
+	s.lockWait.Store(old + (wait-old)/8) // Tanpa CAS karena hanya ditulis selama mu terkunci
+}
+
+// LockWait mengembalikan rata-rata bergerak waktu tunggu lock yang terakhir diukur
+func (s *Stack[T]) LockWait() time.Duration {
+	return time.Duration(s.lockWait.Load())
+}
+
+// ProbeLockWait mengunci dan melepas stack tanpa mengubah isinya agar LockWait tetap diperbarui saat tidak ada Push
+func (s *Stack[T]) ProbeLockWait() time.Duration {
+	s.lockTimed()
+	s.mu.Unlock()
+	return s.LockWait()
+}
+
 // Pop menghapus item terakhir dari stack dan mengembalikannya
 // Mengembalikan nilai kedua sebagai indikator apakah stack kosong
 func (s *Stack[T]) Pop() (T, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if len(s.items) == 0 {
 		var zero T
 		return zero, false // Jika stack kosong, mengembalikan nilai default dan false
@@ -47,78 +127,700 @@ func (s *Stack[T]) Pop() (T, bool) {
 
 // IsEmpty memeriksa apakah stack kosong
 func (s *Stack[T]) IsEmpty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return len(s.items) == 0
 }
 
+// Len mengembalikan jumlah item yang ada di dalam stack
+func (s *Stack[T]) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.items)
+}
+
+// Items mengembalikan salinan seluruh isi stack, diurutkan dari item terlama (bottom) ke terbaru (top)
+func (s *Stack[T]) Items() []T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]T{}, s.items...)
+}
+
+// Replace mengganti seluruh isi stack; items diurutkan dari item terlama (bottom) ke terbaru (top)
+func (s *Stack[T]) Replace(items []T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = append([]T{}, items...)
+}
+
+// RemoveFunc menghapus semua item yang memenuhi remove dan mengembalikannya (dari terlama ke terbaru)
+func (s *Stack[T]) RemoveFunc(remove func(T) bool) []T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var removed []T
+	kept := s.items[:0]
+	for _, item := range s.items {
+		if remove(item) {
+			removed = append(removed, item)
+			continue
+		}
+		kept = append(kept, item)
+	}
+	s.items = kept
+	return removed
+}
+
+// UpdateFunc mengganti setiap item dengan hasil update, dari terlama ke terbaru; item dengan keep=false dibuang
+// update dipanggil sambil memegang lock sehingga tidak boleh melakukan operasi lambat seperti query database
+func (s *Stack[T]) UpdateFunc(update func(T) (item T, keep bool)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.items[:0]
+	for _, item := range s.items {
+		if updated, keep := update(item); keep {
+			kept = append(kept, updated)
+		}
+	}
+	s.items = kept
+}
+
+// SnapshotRange mengembalikan salinan sebagian isi stack, diurutkan dari item terbaru (top) ke terlama
+// offset dihitung dari item teratas; offset di luar jangkauan atau limit <= 0 menghasilkan slice kosong
+func (s *Stack[T]) SnapshotRange(offset, limit int) []T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if offset < 0 {
+		offset = 0 // Offset negatif dianggap mulai dari item teratas
+	}
+	if offset >= len(s.items) || limit <= 0 {
+		return []T{} // Tidak ada item pada rentang yang diminta
+	}
+	end := offset + limit
+	if end > len(s.items) {
+		end = len(s.items) // Batasi rentang agar tidak melewati dasar stack
+	}
+
+	result := make([]T, 0, end-offset)
+	for i := offset; i < end; i++ {
+		result = append(result, s.items[len(s.items)-1-i]) // Ambil dari top ke bawah (newest-first)
+	}
+	return result
+}
+
+// maxDecisionNoteLength adalah panjang maksimum catatan keputusan (approve/disapprove)
+const maxDecisionNoteLength = 1000
+
+// Batas default dan maksimum jumlah item per halaman pada daftar undo
+const (
+	defaultUndoListLimit = 20
+	maxUndoListLimit     = 100
+)
+
+// maxListLimit adalah jumlah maksimum retur per halaman pada GET /retur?limit=
+const maxListLimit = 500
+
 // Variabel global untuk koneksi database dan stack yang menyimpan data yang dihapus
 var (
 	db           *gorm.DB          // Koneksi ke database
-	deletedStack Stack[Retur]      // Stack untuk menyimpan data retur yang dihapus
+	deletedStack Stack[undoEntry]  // Stack untuk menyimpan data retur yang dihapus
 	deletedIDs   []int             // Menyimpan ID barang yang dihapus untuk reuse ID
 )
 
-// initDB menginisialisasi koneksi ke database MySQL dan melakukan migrasi tabel Retur
+// idMu melindungi deletedIDs dan lastAllocatedID; hanya bagian alokasi ID yang diserialisasi,
+// validasi dan insert ke database tetap berjalan bersamaan
+var (
+	idMu            sync.Mutex
+	lastAllocatedID int // ID terbesar yang sudah dialokasikan proses ini, agar create bersamaan tidak mendapat ID yang sama
+)
+
+// deletedIDCount mengembalikan jumlah ID di pool deletedIDs
+func deletedIDCount() int {
+	idMu.Lock()
+	defer idMu.Unlock()
+	return len(deletedIDs)
+}
+
+// initDB menginisialisasi koneksi ke database MySQL dan melakukan migrasi (atau verifikasi) tabel aplikasi
 func initDB() {
-	var err error
-	dsn := "root:@tcp(127.0.0.1:3306)/retur_db?charset=utf8mb4&parseTime=True&loc=Local" // Data Source Name untuk koneksi MySQL
-	db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{}) // Membuka koneksi ke database
+	dsn, err := databaseDSN()
+	if err != nil {
+		panic("Failed to configure database TLS: " + err.Error()) // Keluar jika CA tidak bisa dibaca atau DSN tidak valid
+	}
+	db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{
+		NowFunc: func() time.Time { return clock.Now().Local() }, // Timestamp otomatis GORM (created_at) mengikuti Clock aplikasi
+	}) // Membuka koneksi ke database menggunakan DSN dari konfigurasi
 	if err != nil {
 		panic("Failed to connect to database: " + err.Error()) // Keluar jika koneksi gagal
 	}
-	db.AutoMigrate(&Retur{}) // Melakukan migrasi tabel Retur di database
+	registerBreakerCallbacks(db) // Laporkan hasil setiap operasi ke circuit breaker database
+	registerWriteCallbacks(db)   // Catat waktu tulis database terakhir yang berhasil
+	if err := migrateSchema(); err != nil {
+		panic("Failed to migrate database schema: " + err.Error()) // Keluar jika migrasi gagal atau skema belum lengkap
+	}
+	if err := runBackfills(); err != nil {
+		panic("Failed to backfill columns: " + err.Error()) // Keluar jika backfill kolom baru gagal
+	}
+	if err := webhookSubs.Reload(); err != nil {
+		panic("Failed to load webhook subscriptions: " + err.Error()) // Keluar jika langganan webhook gagal dimuat
+	}
+	if err := seedReasons(); err != nil {
+		panic("Failed to seed reasons: " + err.Error()) // Keluar jika kode alasan awal gagal dibuat
+	}
+	if err := activeReasons.Reload(); err != nil {
+		panic("Failed to load reasons: " + err.Error()) // Keluar jika kode alasan gagal dimuat
+	}
+	if err := loadUndoStack(); err != nil {
+		panic("Failed to load undo stack: " + err.Error()) // Keluar jika entry undo yang tersimpan gagal dimuat
+	}
+}
+
+// withTransaction menjalankan fn di dalam transaksi GORM: commit jika fn berhasil, rollback jika fn gagal
+// Jika fn panic, transaksi di-rollback lalu panic diteruskan agar recoveryMiddleware tetap mencatatnya
+func withTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	tx := db.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return tx.Error // Gagal memulai transaksi
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback() // Jangan biarkan transaksi menggantung saat panic
+			log.Printf("transaction rolled back after panic: %v", p)
+			panic(p)
+		}
+	}()
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
 }
 
 // respondJSON mengirimkan response JSON dengan status dan payload yang diberikan
 func respondJSON(w http.ResponseWriter, status int, payload interface{}) {
 	w.Header().Set("Content-Type", "application/json") // Menetapkan header response sebagai JSON
 	w.WriteHeader(status)                             // Menulis status HTTP
-	json.NewEncoder(w).Encode(payload)                // Menyandikan payload menjadi JSON dan mengirimkan response
+	encoder := json.NewEncoder(w)
+	if wantsPrettyJSON(w) {
+		encoder.SetIndent("", "  ") // Indentasi dua spasi jika diminta (?pretty=true)
+	}
+	if renamed, err := applyNamingProfile(payload, namingProfileFor(w)); err == nil {
+		payload = renamed // Petakan nama field sesuai profil penamaan (misalnya english_camel)
+	}
+	encoder.Encode(payload) // Menyandikan payload menjadi JSON dan mengirimkan response
+}
+
+// wantsPrettyJSON memeriksa apakah response untuk request ini harus diformat dengan indentasi
+func wantsPrettyJSON(w http.ResponseWriter) bool {
+	opts := responseOptionsFrom(w)
+	return opts != nil && opts.pretty
 }
 
 // handleError mengirimkan pesan error dalam format JSON
+// Jika client meminta problem+json, error dikirim dalam format RFC 7807
 func handleError(w http.ResponseWriter, status int, message string) {
+	if wantsProblemJSON(w) {
+		respondProblem(w, status, message, nil)
+		return
+	}
 	respondJSON(w, status, map[string]string{"error": message}) // Mengirimkan pesan error dalam bentuk JSON
 }
 
-// getReturs adalah handler untuk mengambil semua data retur dari database
+// decodeJSON membaca body JSON request ke dst dan mengirimkan error 400 jika gagal
+// Body kosong dibedakan dari JSON yang rusak agar client tahu bahwa body belum dikirim
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	if r.Body == nil || r.Body == http.NoBody {
+		handleError(w, http.StatusBadRequest, "Request body required") // Tidak ada body sama sekali
+		return false
+	}
+	decoder := json.NewDecoder(r.Body)
+	if strictJSONFor(r) {
+		decoder.DisallowUnknownFields() // Mode strict: field yang tidak dikenal ditolak
+	}
+	err := decoder.Decode(dst)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, io.EOF):
+		handleError(w, http.StatusBadRequest, "Request body required") // Body kosong (EOF pada pembacaan pertama)
+	case strings.HasPrefix(err.Error(), "json: unknown field"):
+		handleError(w, http.StatusBadRequest, "Invalid input: "+strings.TrimPrefix(err.Error(), "json: ")) // Sebutkan field yang tidak dikenal
+	default:
+		handleError(w, http.StatusBadRequest, "Invalid input") // Jika input tidak valid, kirimkan error
+	}
+	return false
+}
+
+// strictJSONFor menentukan apakah body request harus di-decode secara strict (menolak field tidak dikenal)
+// Override per route (berdasarkan nama route mux) diutamakan, selain itu memakai pengaturan global
+func strictJSONFor(r *http.Request) bool {
+	if route := mux.CurrentRoute(r); route != nil {
+		if strict, ok := cfg.StrictJSONRoutes[route.GetName()]; ok {
+			return strict
+		}
+	}
+	return cfg.StrictJSON
+}
+
+// decodeOptionalJSON seperti decodeJSON, tetapi body kosong diperbolehkan dan dst dibiarkan bernilai default
+func decodeOptionalJSON(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return true // Tidak ada body, gunakan nilai default
+	}
+	return decodeJSON(w, r, dst)
+}
+
+// queryInt membaca parameter query bertipe integer non-negatif, mengembalikan def jika parameter kosong
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return def, nil // Gunakan nilai default jika parameter tidak dikirim
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid %s", name) // Parameter harus berupa bilangan bulat non-negatif
+	}
+	return value, nil
+}
+
+// parseTimeParam membaca parameter waktu dengan format tanggal (2006-01-02) atau RFC3339
+func parseTimeParam(raw string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", raw, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
+
+// streamReturs menulis hasil query retur sebagai array JSON baris per baris menggunakan cursor database
+// Memori tetap kecil berapa pun jumlah datanya karena setiap baris langsung di-encode setelah dibaca
+// Jika terjadi error di tengah stream, array tetap ditutup dan error dilaporkan lewat trailer X-Stream-Error
+// Jika ndjson bernilai true, setiap retur ditulis sebagai satu baris JSON tanpa pembungkus array
+// deleted (boleh nil) disisipkan di antara baris query sesuai urutan ?sort, dipakai untuk ?include_deleted=true
+// Jika redact bernilai true, details sensitif dan field PII disamarkan (lihat concealRetur), dipakai untuk pembaca non-admin
+func streamReturs(w http.ResponseWriter, query *gorm.DB, ndjson, redact bool, deleted *deletedMerge) {
+	rows, err := query.Rows()
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to retrieve returns") // Jika gagal mengambil data, kirim error
+		return
+	}
+	defer rows.Close()
+
+	contentType, open, separator, closing := "application/json", "[", ",", "]"
+	if ndjson {
+		contentType, open, separator, closing = ndjsonContentType, "", "", "" // Encoder sudah menambahkan newline setelah setiap objek
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Trailer", "X-Stream-Error") // Trailer untuk melaporkan error setelah body mulai dikirim
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	if wantsPrettyJSON(w) && !ndjson {
+		encoder.SetIndent("", "  ") // Format setiap baris dengan indentasi jika diminta
+	}
+	naming := namingProfileFor(w)
+	io.WriteString(w, open)
+	count := 0
+	emit := func(retur Retur) error {
+		applySLA(&retur) // Hitung sla_deadline dan overdue
+		applyPriority(&retur)
+		if redact {
+			concealRetur(&retur)
+		}
+		if count > 0 {
+			io.WriteString(w, separator)
+		}
+		count++
+		payload, err := applyNamingProfile(retur, naming) // Petakan nama field sesuai profil penamaan
+		if err != nil {
+			return err
+		}
+		return encoder.Encode(payload) // Gagal jika client kemungkinan sudah memutus koneksi
+	}
+	var streamErr error
+	for rows.Next() {
+		var retur Retur
+		if streamErr = query.ScanRows(rows, &retur); streamErr != nil {
+			break // Hentikan stream jika baris gagal dibaca
+		}
+		if streamErr = deleted.emitBefore(retur, emit); streamErr != nil {
+			break
+		}
+		if streamErr = emit(retur); streamErr != nil {
+			break
+		}
+	}
+	if streamErr == nil {
+		streamErr = rows.Err() // Periksa error dari cursor setelah iterasi selesai
+	}
+	if streamErr == nil {
+		streamErr = deleted.emitRest(emit)
+	}
+	io.WriteString(w, closing) // Selalu tutup array agar response tetap JSON yang valid
+
+	if streamErr != nil {
+		w.Header().Set("X-Stream-Error", "truncated: "+streamErr.Error()) // Tandai bahwa hasil terpotong
+		log.Printf("streaming returns failed: %v", streamErr)
+	}
+}
+
+// getReturs adalah handler untuk mengambil semua data retur dari database secara streaming
+// Mendukung filter status, pengembalian, q, from/to, dan overdue (lihat listFilters) serta ?sort (lihat listOrder)
+// Jika ?limit dikirim, hasil dipaginasi dengan ?offset: header X-Has-More dihitung dari limit+1 baris tanpa COUNT,
+// dan X-Total-Count hanya dihitung jika ?with_total=true; ?page (mulai dari 1) bisa dipakai sebagai pengganti offset
+// Dengan ?envelope=true (selalu di bawah /v1), halaman dikirim sebagai {"data": [...], "meta": {...}} (lihat pageMeta) dengan limit default 50
+// Dengan ?format=ndjson atau Accept: application/x-ndjson, hasil dikirim sebagai satu objek JSON per baris
+// Field PII dan details sensitif (RETUR_ENCRYPTED_DETAILS) disamarkan untuk non-admin pada semua format (lihat maskRetur)
+// Dengan ?include_deleted=true, retur di stack undo yang cocok dengan filter ikut dikirim sesuai ?sort dengan deleted dan deleted_at
 func getReturs(w http.ResponseWriter, r *http.Request) {
+	filters, err := listFilters(r)
+	if err != nil {
+		handleError(w, http.StatusBadRequest, err.Error()) // Parameter filter tidak valid
+		return
+	}
+	order, err := listOrder(r)
+	if err != nil {
+		handleError(w, http.StatusBadRequest, err.Error()) // Kolom sort tidak dikenal
+		return
+	}
+	query := db.WithContext(r.Context()).Model(&Retur{}).Scopes(filters).Order(order) // Kolom sort ditambah id sebagai tie-breaker agar hasil konsisten
+	var deleted *deletedMerge
+	if includeDeleted(r) {
+		if r.URL.Query().Get("limit") != "" || wantsEnvelope(r) {
+			handleError(w, http.StatusBadRequest, "include_deleted cannot be combined with limit or envelope") // Retur yang dihapus dibaca dari stack undo sehingga tidak bisa dipaginasi bersama
+			return
+		}
+		if deleted, err = deletedReturs(r); err != nil {
+			handleError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	envelope := wantsEnvelope(r)
+	if envelope && wantsNDJSON(r) {
+		handleError(w, http.StatusBadRequest, "envelope cannot be combined with ndjson") // Envelope adalah satu dokumen JSON, bukan stream baris
+		return
+	}
+	redact := !isAdmin(r) // Details sensitif dan PII hanya dikirim utuh kepada admin
+	if r.URL.Query().Get("limit") == "" && !envelope {
+		streamReturs(w, query, wantsNDJSON(r), redact, deleted) // Tanpa limit, kirim semua retur secara streaming
+		return
+	}
+
+	defaultLimit := 0
+	if envelope {
+		defaultLimit = defaultEnvelopeLimit // Envelope selalu dipaginasi
+	}
+	limit, err := queryInt(r, "limit", defaultLimit)
+	if err != nil || limit == 0 {
+		handleError(w, http.StatusBadRequest, "Invalid limit") // Limit harus berupa angka positif
+		return
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit // Batasi jumlah retur per halaman
+	}
+	offset, err := pageOffset(r, limit)
+	if err != nil {
+		handleError(w, http.StatusBadRequest, "Invalid offset or page") // Offset harus berupa angka non-negatif, page mulai dari 1
+		return
+	}
+	var total int64
+	if r.URL.Query().Get("with_total") == "true" || envelope {
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			handleError(w, http.StatusInternalServerError, "Failed to count returns")
+			return
+		}
+		w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10)) // Total hanya dihitung jika diminta karena COUNT mahal
+	}
+
 	var returs []Retur
-	if err := db.Find(&returs).Error; err != nil {
-		handleError(w, http.StatusInternalServerError, "Failed to retrieve returns") // Jika gagal mengambil data, kirim error
+	if err := query.Offset(offset).Limit(limit + 1).Find(&returs).Error; err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to retrieve returns")
+		return
+	}
+	hasMore := len(returs) > limit // Baris tambahan berarti masih ada halaman berikutnya
+	if hasMore {
+		returs = returs[:limit]
+	}
+	for i := range returs {
+		applySLA(&returs[i]) // Hitung sla_deadline dan overdue
+		applyPriority(&returs[i])
+	}
+	maskReturs(r, returs)
+	w.Header().Set("X-Has-More", strconv.FormatBool(hasMore))
+	if wantsNDJSON(r) {
+		naming := namingProfileFor(w)
+		items := make([]interface{}, 0, len(returs))
+		for _, retur := range returs {
+			payload, err := applyNamingProfile(retur, naming) // Petakan nama field sesuai profil penamaan
+			if err != nil {
+				handleError(w, http.StatusInternalServerError, "Failed to encode returns")
+				return
+			}
+			items = append(items, payload)
+		}
+		writeNDJSON(w, items) // Satu retur per baris untuk pipeline ETL
 		return
 	}
-	respondJSON(w, http.StatusOK, returs) // Kirimkan data retur dalam format JSON
+	if envelope {
+		respondEnvelope(w, returs, newPageMeta(offset, limit, total))
+		return
+	}
+	respondJSON(w, http.StatusOK, returs)
+}
+
+// peekNextReturID menghitung ID yang akan dipakai oleh createRetur berikutnya tanpa mengubah state
+// Nilai kedua bernilai true jika ID tersebut berasal dari deletedIDs (ID hasil reuse)
+func peekNextReturID() (int, bool) {
+	dbMax := maxReturID() // Query di luar idMu agar create lain tidak ikut menunggu round-trip database
+	idMu.Lock()
+	defer idMu.Unlock()
+	return nextReturIDLocked(dbMax)
+}
+
+// maxReturID membaca ID retur terbesar di database (termasuk retur yang di-soft delete), 0 jika tabel kosong atau query gagal
+// Berupa variabel seperti clock agar test alokasi ID bisa berjalan tanpa database
+var maxReturID = func() int {
+	var id int
+	db.Unscoped().Model(&Retur{}).Select("COALESCE(MAX(id), 0)").Scan(&id) // Termasuk baris soft delete yang masih memakai ID-nya
+	return id
+}
+
+// nextReturIDLocked adalah isi peekNextReturID; pemanggil wajib memegang idMu
+// dbMax boleh sudah basi karena dibaca sebelum idMu diambil: setiap ID yang dialokasikan proses ini sejak itu
+// tercatat di lastAllocatedID, sehingga max(dbMax, lastAllocatedID)+1 tetap tidak pernah dipakai dua kali
+func nextReturIDLocked(dbMax int) (int, bool) {
+	// Jika ada ID yang tersedia dari deletedIDs, ID terakhir yang dihapus akan digunakan kembali
+	if len(deletedIDs) > 0 {
+		return deletedIDs[len(deletedIDs)-1], true
+	}
+	return max(dbMax, lastAllocatedID) + 1, false // ID yang sudah dialokasikan tetapi belum di-insert tidak dipakai lagi
+}
+
+// allocateReturID menentukan ID untuk retur baru dan mengambil ID tersebut dari deletedIDs jika hasil reuse
+// Alokasi diserialisasi dengan idMu sehingga dua create bersamaan tidak pernah mendapat ID yang sama;
+// max(id) dibaca sebelum lock sehingga yang diserialisasi hanya perhitungan di memori
+func allocateReturID() int {
+	dbMax := maxReturID()
+	idMu.Lock()
+	defer idMu.Unlock()
+	id, reused := nextReturIDLocked(dbMax)
+	if reused {
+		deletedIDs = deletedIDs[:len(deletedIDs)-1] // Hapus ID tersebut dari deletedIDs
+		idPoolReuses.Add(1)
+	} else {
+		lastAllocatedID = id
+	}
+	return id
+}
+
+// nextReturIDHandler adalah handler untuk melihat ID yang akan diberikan pada pembuatan retur berikutnya
+// Endpoint ini hanya membaca state; ID bisa saja berubah jika ada create/delete lain sebelum create dipanggil
+func nextReturIDHandler(w http.ResponseWriter, r *http.Request) {
+	id, reused := peekNextReturID()
+	source := "max_id"
+	if reused {
+		source = "reused" // ID berasal dari pool deletedIDs
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"next_id":     id,
+		"source":      source,
+		"predictable": true, // ID ditentukan aplikasi, bukan auto-increment database
+	})
 }
 
 // createRetur adalah handler untuk membuat data retur baru di database
+// Status selalu "Dalam Proses", kecuali admin mengimpor data historis dengan ?import=true (lihat RETUR_ALLOW_IMPORT_STATUS)
+// Jika RETUR_AUTO_APPROVE_ON_CREATE=true, retur tanpa item yang cocok dengan RETUR_AUTO_APPROVE_RULES langsung disetujui oleh "auto-approve"
+// Dengan ?upsert=true, retur dengan order_id dan barang yang sama diperbarui (200) alih-alih dibuat ulang (201)
+// Jika RETUR_RETURN_WINDOW diatur, order_date wajib dan pembelian yang lebih lama dari batas tersebut ditolak dengan 422
 func createRetur(w http.ResponseWriter, r *http.Request) {
 	var newRetur Retur
-	if err := json.NewDecoder(r.Body).Decode(&newRetur); err != nil {
-		handleError(w, http.StatusBadRequest, "Invalid input") // Jika input tidak valid, kirimkan error
+	if !decodeJSON(w, r, &newRetur) {
+		return // Body kosong atau JSON tidak valid, error sudah dikirim
+	}
+	importStatus := importStatusAllowed(r) && newRetur.Status != "" // Mode impor: status historis dari client dipertahankan
+	upsert := upsertRequested(r)                                    // Mode upsert: (order_id, barang) sebagai natural key
+	if upsert && importStatus {
+		handleError(w, http.StatusBadRequest, "upsert cannot be combined with import")
 		return
 	}
-
-	// Jika ada ID yang tersedia dari deletedIDs, gunakan kembali ID tersebut
-	if len(deletedIDs) > 0 {
-		newRetur.ID = deletedIDs[len(deletedIDs)-1] // Menggunakan ID yang telah dihapus sebelumnya
-		deletedIDs = deletedIDs[:len(deletedIDs)-1]  // Hapus ID tersebut dari deletedIDs
-	} else {
-		var lastRetur Retur
-		if err := db.Order("id desc").First(&lastRetur).Error; err == nil {
-			newRetur.ID = lastRetur.ID + 1 // Jika ada retur sebelumnya, ID baru adalah ID terakhir + 1
-		} else {
-			newRetur.ID = 1 // Jika belum ada retur, mulai dengan ID 1
+	errs := validateRetur(&newRetur)
+	if importStatus && !validStatus(newRetur.Status) {
+		errs = append(errs, fieldError{Field: "status", Message: "must be 'Dalam Proses', '" + statusAwaitingSecondApproval + "', 'Disetujui', '" + statusPartiallyApproved + "' or 'Tidak Disetujui'"})
+	}
+	if !importStatus {
+		errs = append(errs, validateOrderDate(&newRetur)...) // Data historis hasil impor tidak dibatasi RETUR_RETURN_WINDOW
+	}
+	if upsert {
+		errs = append(errs, validateUpsertKey(&newRetur)...)
+	}
+	if len(errs) > 0 {
+		respondValidationErrors(w, errs) // Jika ada field yang tidak valid, kirimkan daftar error
+		return
+	}
+	if upsert {
+		if updateExistingForUpsert(w, r, newRetur) {
+			return // Retur lama diperbarui (200) atau ditolak, response sudah dikirim
 		}
 	}
 
-	newRetur.Status = "Dalam Proses" // Set status default menjadi "Dalam Proses"
-	if err := db.Create(&newRetur).Error; err != nil {
-		handleError(w, http.StatusInternalServerError, "Failed to create return") // Jika gagal membuat retur, kirimkan error
+	newRetur.ID = allocateReturID() // Tentukan ID baru (reuse ID yang dihapus atau ID terakhir + 1)
+	newRetur.CanonicalBarang = canonicalBarang(newRetur.Barang) // Nama kanonik selalu dihitung server, nilai dari client diabaikan
+
+	if !importStatus {
+		newRetur.Status = "Dalam Proses" // Set status default menjadi "Dalam Proses"
+	}
+	for i := range newRetur.Items {
+		newRetur.Items[i].ID = 0                  // ID item selalu diberikan database
+		newRetur.Items[i].Status = "Dalam Proses" // Setiap item dimulai dari status awal
+		newRetur.Items[i].Pengembalian = ""
+	}
+	pengembalian, autoApproved := "", false
+	if cfg.AutoApproveOnCreate && !importStatus {
+		pengembalian, autoApproved = autoApproveMatch(newRetur) // Hanya jika RETUR_AUTO_APPROVE_ON_CREATE=true
+	}
+	if autoApproved {
+		applyAutoApprove(&newRetur, pengembalian) // Cocok dengan RETUR_AUTO_APPROVE_RULES, langsung disetujui
+	}
+	err := withTransaction(r.Context(), func(tx *gorm.DB) error {
+		if err := consumeTenantQuota(tx, r); err != nil {
+			return err // Kuota create bulanan tenant habis
+		}
+		if err := clearTombstone(tx, newRetur.ID); err != nil {
+			return err // ID hasil reuse tidak lagi dianggap sudah dibuang
+		}
+		if err := purgeSoftDeleted(tx, []int{newRetur.ID}); err != nil {
+			return err // Baris soft delete pemilik lama ID dibuang; undo-nya di-insert ulang dengan ID baru
+		}
+		insert := tx.Create(&newRetur).Error
+		if upsert {
+			insert = insertUpsert(tx, &newRetur) // Unique index natural_key menangani upsert bersamaan
+		}
+		if insert != nil {
+			return insert
+		}
+		if err := tx.Preload("Items").First(&newRetur, newRetur.ID).Error; err != nil {
+			return err // Baca ulang agar response berisi nilai yang benar-benar tersimpan (created_at, default kolom)
+		}
+		if autoApproved {
+			if err := tx.Create(&ReturHistory{ReturID: newRetur.ID, FromStatus: "Dalam Proses", ToStatus: newRetur.Status, Actor: autoApproveActor, Note: newRetur.DecisionNote}).Error; err != nil {
+				return err
+			}
+		}
+		return recordAudit(tx, "create", actorFromRequest(r), Retur{}, newRetur)
+	})
+	if errors.Is(err, errTenantQuota) {
+		handleError(w, http.StatusForbidden, "Monthly create quota exhausted for this tenant") // Kuota create bulanan tenant habis
 		return
 	}
+	if errors.Is(err, errUpsertExists) {
+		releaseIDs([]int{newRetur.ID}) // ID yang tidak terpakai kembali ke pool
+		if updateExistingForUpsert(w, r, newRetur) {
+			return // Upsert lain menyisipkan natural key yang sama sejak pengecekan pertama
+		}
+		handleError(w, http.StatusConflict, "Return was modified concurrently, try again")
+		return
+	}
+	if isDuplicateKey(err) {
+		releaseIDs([]int{newRetur.ID})
+		handleError(w, http.StatusConflict, "A return for this order_id and barang already exists; use ?upsert=true to update it")
+		return
+	}
+	if err != nil {
+		respondSaveError(w, err, "Failed to create return") // 422 jika melanggar Validate, selain itu 500
+		return
+	}
+	w.Header().Set("Location", urlFor(fmt.Sprintf("/retur/%d", newRetur.ID))) // Lokasi resource baru (mengikuti base path)
+	applySLA(&newRetur) // Hitung sla_deadline dan overdue seperti pada GET
+	publishEvent(ReturCreated, newRetur, r)
+	if autoApproved {
+		publishEvent(ReturApproved, newRetur, r) // Webhook menerima keputusan otomatis seperti persetujuan biasa
+	}
+	maskRetur(r, &newRetur)
 	respondJSON(w, http.StatusCreated, newRetur) // Kirimkan retur yang baru dibuat dalam format JSON
 }
 
+// cloneReturHandler adalah handler untuk membuat retur baru dengan menyalin data dari retur yang sudah ada
+// Hanya data pengajuan (barang, alasan, customer) yang disalin; ID, status, dan data keputusan dimulai dari awal
+func cloneReturHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"]) // Convert ID dari string ke integer
+	if err != nil {
+		handleError(w, http.StatusBadRequest, "Invalid ID format") // Jika format ID salah, kirimkan error
+		return
+	}
+
+	var source Retur
+	if err := db.WithContext(r.Context()).First(&source, id).Error; err != nil {
+		handleError(w, http.StatusNotFound, "Return not found") // Jika retur sumber tidak ditemukan, kirimkan error
+		return
+	}
+
+	clone := Retur{
+		ID:              allocateReturID(), // ID baru (reuse ID yang dihapus atau ID terakhir + 1)
+		Barang:          source.Barang,
+		CanonicalBarang: canonicalBarang(source.Barang), // Dihitung ulang dengan katalog yang berlaku saat ini
+		Alasan:          source.Alasan,
+		ReasonCode:      source.ReasonCode,
+		CustomerID:      source.CustomerID,
+		OrderDate:       source.OrderDate,
+		Status:          "Dalam Proses", // Retur hasil clone selalu dimulai dari status awal
+	}
+	err = withTransaction(r.Context(), func(tx *gorm.DB) error {
+		if err := consumeTenantQuota(tx, r); err != nil {
+			return err // Clone juga menghabiskan kuota create tenant
+		}
+		if err := clearTombstone(tx, clone.ID); err != nil {
+			return err
+		}
+		if err := purgeSoftDeleted(tx, []int{clone.ID}); err != nil {
+			return err
+		}
+		if err := tx.Create(&clone).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&clone, clone.ID).Error; err != nil {
+			return err // Baca ulang agar response berisi nilai yang benar-benar tersimpan
+		}
+		return recordAudit(tx, "create", actorFromRequest(r), Retur{}, clone)
+	})
+	if errors.Is(err, errTenantQuota) {
+		handleError(w, http.StatusForbidden, "Monthly create quota exhausted for this tenant") // Kuota create bulanan tenant habis
+		return
+	}
+	if err != nil {
+		respondSaveError(w, err, "Failed to clone return") // Jika gagal membuat retur, kirimkan error
+		return
+	}
+	w.Header().Set("Location", urlFor(fmt.Sprintf("/retur/%d", clone.ID))) // Lokasi resource baru (mengikuti base path)
+	applySLA(&clone)
+	publishEvent(ReturCreated, clone, r)
+	maskRetur(r, &clone)
+	respondJSON(w, http.StatusCreated, clone) // Kirimkan retur hasil clone
+}
+
+// getReturHandler adalah handler untuk mengambil satu retur berdasarkan ID
+// ID yang tidak ditemukan tetapi memiliki tombstone (lihat PurgedRetur) dijawab 410 Gone
+func getReturHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"]) // Convert ID dari string ke integer
+	if err != nil {
+		handleError(w, http.StatusBadRequest, "Invalid ID format") // Jika format ID salah, kirimkan error
+		return
+	}
+
+	var retur Retur
+	if err := db.WithContext(r.Context()).Preload("Items").First(&retur, id).Error; err != nil {
+		if wasPurged(r.Context(), id) {
+			handleError(w, http.StatusGone, "Return was permanently purged") // Pernah ada, tetapi sudah dibuang dari stack undo
+			return
+		}
+		handleError(w, http.StatusNotFound, "Return not found") // Jika retur tidak ditemukan, kirimkan error
+		return
+	}
+	w.Header().Set("ETag", returETag(retur)) // Dikirim kembali lewat If-Match saat PATCH
+	applySLA(&retur)                         // Hitung sla_deadline dan overdue
+	applyPriority(&retur)                    // Hitung skor prioritas antrean kerja
+	maskRetur(r, &retur)                     // Details sensitif hanya plaintext untuk admin
+	respondJSON(w, http.StatusOK, retur)     // Kirimkan retur dalam format JSON
+}
+
 // approveReturHandler adalah handler untuk menyetujui retur dengan ID tertentu
 func approveReturHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)              // Ambil parameter dari URL
@@ -129,33 +831,102 @@ func approveReturHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var input struct {
-		Pengembalian string `json:"pengembalian"` // Menyimpan input pengembalian (barang/uang)
+		Pengembalian string `json:"pengembalian"`  // Menyimpan input pengembalian (barang/uang)
+		RefundAmount int64  `json:"refund_amount"` // Jumlah refund dalam minor units (khusus uang)
+		Currency     string `json:"currency"`      // Kode mata uang refund, default dari konfigurasi
+		Note         string `json:"note"`          // Catatan opsional untuk persetujuan
+		Details      map[string]string `json:"details"` // Data tambahan sesuai jenis pengembalian, misalnya rekening untuk uang
 	}
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		handleError(w, http.StatusBadRequest, "Invalid input") // Jika input tidak valid, kirimkan error
-		return
+	if !decodeJSON(w, r, &input) {
+		return // Body kosong atau JSON tidak valid, error sudah dikirim
 	}
 
-	if input.Pengembalian != "barang" && input.Pengembalian != "uang" {
+	if !validPengembalian(input.Pengembalian) {
 		handleError(w, http.StatusBadRequest, "Pengembalian must be 'barang' or 'uang'") // Validasi nilai pengembalian
 		return
 	}
+	input.Note = strings.TrimSpace(input.Note)
+	if utf8.RuneCountInString(input.Note) > maxDecisionNoteLength {
+		handleError(w, http.StatusBadRequest, fmt.Sprintf("note must be at most %d characters", maxDecisionNoteLength)) // Catatan terlalu panjang
+		return
+	}
+
+	var currency string
+	if input.Pengembalian == "uang" {
+		if input.RefundAmount < 0 {
+			handleError(w, http.StatusBadRequest, "refund_amount must not be negative") // Jumlah refund tidak boleh negatif
+			return
+		}
+		var ok bool
+		if currency, ok = refundCurrency(input.Currency); !ok {
+			handleError(w, http.StatusBadRequest, fmt.Sprintf("Unknown currency code '%s'", input.Currency)) // Kode mata uang tidak dikenal
+			return
+		}
+	} else {
+		input.RefundAmount = 0 // Pengembalian barang tidak memiliki nilai refund
+	}
 
 	var retur Retur
-	if err := db.First(&retur, id).Error; err != nil {
+	if err := db.WithContext(r.Context()).First(&retur, id).Error; err != nil {
 		handleError(w, http.StatusNotFound, "Return not found") // Jika retur tidak ditemukan, kirimkan error
 		return
 	}
 
+	if !decidableStatus(retur.Status) {
+		handleError(w, http.StatusConflict, "Return has already been decided ("+retur.Status+")") // Gunakan reset admin untuk membuka kembali
+		return
+	}
+
+	details := mergeDetails(retur.Details, input.Details) // Details yang sudah tersimpan (misalnya dari customer) tetap dipakai
+	if errs := validateDetails(input.Pengembalian, details); len(errs) > 0 {
+		respondValidationErrors(w, errs) // Field details yang diwajibkan kebijakan belum lengkap
+		return
+	}
+
+	secondApproval := retur.Status == statusAwaitingSecondApproval
+	if secondApproval {
+		if err := checkSecondApproval(retur, r, input.Pengembalian, input.RefundAmount, currency); err != nil {
+			handleError(w, http.StatusConflict, err.Error()) // Approver sama atau nilai refund berbeda dari persetujuan pertama
+			return
+		}
+	}
+
+	before := retur
+	retur.Details = details
 	retur.Pengembalian = input.Pengembalian // Set pengembalian sesuai input
+	retur.RefundAmount = input.RefundAmount // Simpan jumlah refund dalam minor units
+	retur.Currency = currency               // Simpan mata uang refund (kosong untuk barang)
 	retur.Status = "Disetujui"              // Set status menjadi "Disetujui"
-	if err := db.Save(&retur).Error; err != nil {
-		handleError(w, http.StatusInternalServerError, "Failed to update return") // Jika gagal memperbarui, kirimkan error
+	retur.DecisionNote = input.Note         // Catatan persetujuan (opsional)
+	event := ReturApproved
+	if !secondApproval && requiresSecondApproval(input.Pengembalian, input.RefundAmount) {
+		retur.Status = statusAwaitingSecondApproval    // Refund bernilai tinggi menunggu approver kedua
+		retur.FirstApprovedBy = approverFromRequest(r) // Approver kedua harus pengguna yang berbeda
+		event = ReturFirstApproved
+	} else {
+		markDecided(&retur, r)
+	}
+	err = saveDecision(r.Context(), before, &retur, approverFromRequest(r), retur.DecisionNote)
+	if errors.Is(err, errApprovalConflict) {
+		handleError(w, http.StatusConflict, "Return was decided concurrently") // Status berubah setelah dibaca
 		return
 	}
+	if err != nil {
+		respondSaveError(w, err, "Failed to update return") // Jika gagal memperbarui, kirimkan error
+		return
+	}
+	publishEvent(event, retur, r)        // Kabarkan perubahan status ke subscriber (webhook, audit, metrik)
+	maskRetur(r, &retur)
 	respondJSON(w, http.StatusOK, retur) // Kirimkan retur yang sudah disetujui dalam format JSON
 }
 
+// markDecided mencatat siapa dan kapan keputusan (setuju/tolak) diberikan pada retur
+func markDecided(retur *Retur, r *http.Request) {
+	now := clock.Now()
+	retur.DecidedBy = approverFromRequest(r) // Approver terautentikasi yang memberi keputusan
+	retur.DecidedAt = &now                   // Waktu keputusan diberikan
+}
+
 // disapproveReturHandler adalah handler untuk menolak retur dengan ID tertentu
 func disapproveReturHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)              // Ambil parameter dari URL
@@ -165,21 +936,57 @@ func disapproveReturHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var input struct {
+		Note string `json:"note"` // Alasan penolakan, wajib jika RETUR_REQUIRE_REJECTION_NOTE=true
+	}
+	if !decodeOptionalJSON(w, r, &input) {
+		return // JSON tidak valid, error sudah dikirim
+	}
+	input.Note = strings.TrimSpace(input.Note)
+	if input.Note == "" && cfg.RequireRejectionNote {
+		handleError(w, http.StatusBadRequest, "note is required when disapproving a return") // Penolakan wajib disertai alasan
+		return
+	}
+	if utf8.RuneCountInString(input.Note) > maxDecisionNoteLength {
+		handleError(w, http.StatusBadRequest, fmt.Sprintf("note must be at most %d characters", maxDecisionNoteLength)) // Catatan terlalu panjang
+		return
+	}
+
 	var retur Retur
-	if err := db.First(&retur, id).Error; err != nil {
+	if err := db.WithContext(r.Context()).First(&retur, id).Error; err != nil {
 		handleError(w, http.StatusNotFound, "Return not found") // Jika retur tidak ditemukan, kirimkan error
 		return
 	}
 
+	if !decidableStatus(retur.Status) {
+		handleError(w, http.StatusConflict, "Return has already been decided ("+retur.Status+")") // Gunakan reset admin untuk membuka kembali
+		return
+	}
+
+	before := retur
 	retur.Status = "Tidak Disetujui" // Set status menjadi "Tidak Disetujui"
-	if err := db.Save(&retur).Error; err != nil {
-		handleError(w, http.StatusInternalServerError, "Failed to update return") // Jika gagal memperbarui, kirimkan error
+	retur.DecisionNote = input.Note  // Simpan alasan penolakan
+	retur.RefundAmount = 0           // Retur yang ditolak tidak memiliki refund (misalnya penolakan oleh approver kedua)
+	retur.Currency = ""
+	retur.FirstApprovedBy = ""
+	markDecided(&retur, r)
+	err = saveDecision(r.Context(), before, &retur, retur.DecidedBy, retur.DecisionNote)
+	if errors.Is(err, errApprovalConflict) {
+		handleError(w, http.StatusConflict, "Return was decided concurrently") // Status berubah setelah dibaca
 		return
 	}
-	respondJSON(w, http.StatusOK, retur) // Kirimkan retur yang sudah ditolak dalam format JSON
+	if err != nil {
+		respondSaveError(w, err, "Failed to update return") // Jika gagal memperbarui, kirimkan error
+		return
+	}
+	publishEvent(ReturDisapproved, retur, r) // Kabarkan perubahan status ke subscriber (webhook, audit, metrik)
+	maskRetur(r, &retur)
+	respondJSON(w, http.StatusOK, retur)     // Kirimkan retur yang sudah ditolak dalam format JSON
 }
 
-// deleteReturHandler adalah handler untuk menghapus retur dengan ID tertentu
+// deleteReturHandler adalah handler untuk menghapus retur dengan ID tertentu (soft delete, dipulihkan lewat stack undo)
+// Retur yang disetujui dalam RETUR_DELETE_GRACE terakhir ditolak dengan 409 kecuali admin mengirim header override
+// Saat waktu tunggu lock stack undo melewati RETUR_UNDO_SHED_LOCK_WAIT, retur dihapus tanpa entry undo (lihat undoshed.go)
 func deleteReturHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)              // Ambil parameter dari URL
 	id, err := strconv.Atoi(vars["id"]) // Convert ID dari string ke integer
@@ -188,48 +995,298 @@ func deleteReturHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var input struct {
+		Reason string `json:"reason"` // Alasan penghapusan (opsional), ditampilkan di daftar retur yang dihapus
+	}
+	if !decodeOptionalJSON(w, r, &input) {
+		return // JSON tidak valid, error sudah dikirim
+	}
+	input.Reason = strings.TrimSpace(input.Reason)
+	if utf8.RuneCountInString(input.Reason) > maxDecisionNoteLength {
+		handleError(w, http.StatusBadRequest, fmt.Sprintf("reason must be at most %d characters", maxDecisionNoteLength)) // Alasan terlalu panjang
+		return
+	}
+
 	var retur Retur
-	if err := db.First(&retur, id).Error; err != nil {
+	if err := db.WithContext(r.Context()).Preload("Items").First(&retur, id).Error; err != nil {
 		handleError(w, http.StatusNotFound, "Return not found") // Jika retur tidak ditemukan, kirimkan error
 		return
 	}
+	if blocked := deleteGraceBlocked(r, []Retur{retur}); len(blocked) > 0 {
+		respondDeleteGrace(w, blocked) // Retur baru saja disetujui (RETUR_DELETE_GRACE)
+		return
+	}
 
-	deletedIDs = append(deletedIDs, retur.ID) // Simpan ID yang dihapus untuk reuse
-	deletedStack.Push(retur)                  // Push data yang dihapus ke stack
-	if err := db.Delete(&retur).Error; err != nil {
+	updateUndoShedding()
+	entry := undoEntry{Returs: []Retur{retur}, DeletedAt: clock.Now(), Reason: input.Reason}
+	var skipped bool
+	err = withTransaction(r.Context(), func(tx *gorm.DB) error {
+		if err := softDeleteReturs(tx, []int{retur.ID}, entry.DeletedAt); err != nil {
+			return err // Item tetap tersimpan bersama baris retur sampai undo atau purge
+		}
+		var err error
+		if skipped, err = skipUndo(tx, entry); err != nil {
+			return err
+		}
+		if !skipped { // Saat load shedding (RETUR_UNDO_SHED_LOCK_WAIT) retur tidak bisa di-undo
+			if err := persistUndoEntry(tx, &entry); err != nil {
+				return err // Snapshot undo ikut tersimpan agar tetap bisa di-undo setelah restart
+			}
+		}
+		return recordAudit(tx, "delete", actorFromRequest(r), retur, Retur{})
+	})
+	if err != nil {
 		handleError(w, http.StatusInternalServerError, "Failed to delete return") // Jika gagal menghapus, kirimkan error
 		return
 	}
+	if skipped {
+		logSkippedUndo(entry) // ID sudah ber-tombstone dan tidak masuk pool reuse
+	} else {
+		addDeletedID(retur.ID)   // Simpan ID yang dihapus untuk reuse
+		deletedStack.Push(entry) // Push data yang dihapus (beserta item-nya) ke stack
+	}
+	publishEvent(ReturDeleted, retur, r)
 	respondJSON(w, http.StatusOK, map[string]string{"message": fmt.Sprintf("Return with ID %d deleted", id)}) // Kirimkan pesan bahwa retur telah dihapus
 }
 
 // undoDeleteReturHandler adalah handler untuk mengembalikan data retur yang terakhir dihapus
 func undoDeleteReturHandler(w http.ResponseWriter, r *http.Request) {
-	if deletedStack.IsEmpty() {
+	entry, ok := deletedStack.Pop() // Pop entry terakhir yang dihapus dari stack; cek dan ambil dalam satu langkah agar undo bersamaan tidak mendapat entry kosong
+	if !ok {
 		handleError(w, http.StatusBadRequest, "No returns to undo") // Jika tidak ada retur yang dihapus, kirimkan error
 		return
 	}
+	if entry.expired(clock.Now()) {
+		deletedStack.Push(entry) // Dibuang lewat purgeExpiredUndo agar tetap diarsipkan lebih dulu
+		if _, err := purgeExpiredUndo(); err != nil {
+			log.Printf("purging expired undo entries failed: %v", err) // Entry tetap di stack sampai arsip berhasil ditulis
+		}
+		handleError(w, http.StatusGone, "The last deleted return is too old to be restored") // Melewati RETUR_MAX_UNDO_AGE
+		return
+	}
+	restored := cloneReturs(entry.Returs)        // Entry di stack tetap memakai ID asli jika restore gagal
+	reserved := reserveRestoredIDs(entry.Returs) // ID asli tidak boleh dialokasikan createRetur selama restore berjalan
+	var reassigned map[int]int
+	err := withTransaction(r.Context(), func(tx *gorm.DB) error {
+		undeleted, pending, err := undeleteReturs(tx, restored) // Baris yang masih di-soft delete cukup dikembalikan
+		if err != nil {
+			return err
+		}
+		if reassigned, err = reassignOccupiedIDs(tx, pending); err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			if err := tx.Create(&pending).Error; err != nil {
+				return err // Retur tanpa baris soft delete di-insert ulang dari snapshot
+			}
+		}
+		restored = append(undeleted, pending...)
+		if err := deleteUndoRecords(tx, entry); err != nil {
+			return err // Entry yang sudah di-restore tidak boleh dimuat lagi setelah restart
+		}
+		for _, retur := range restored {
+			if err := recordAudit(tx, "restore", actorFromRequest(r), Retur{}, retur); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		releaseIDs(reserved)                                 // ID asli kembali ke pool karena retur belum dikembalikan
+		deletedStack.Push(entry)                             // Kembalikan entry ke stack agar bisa dicoba lagi
+		respondSaveError(w, err, "Failed to restore return") // Jika gagal mengembalikan retur, kirimkan error
+		return
+	}
+	entry.Returs = restored
+	for _, retur := range entry.Returs {
+		publishEvent(ReturRestored, retur, r)
+	}
+	if len(reassigned) > 0 {
+		pairs := make([]string, 0, len(reassigned))
+		for oldID, newID := range reassigned {
+			pairs = append(pairs, fmt.Sprintf("%d=%d", oldID, newID))
+		}
+		sort.Strings(pairs)
+		w.Header().Set("X-Reassigned-IDs", strings.Join(pairs, ",")) // ID lama=ID baru untuk retur yang dikembalikan dengan ID baru
+	}
+
+	maskReturs(r, entry.Returs)
+	if len(entry.Returs) == 1 {
+		respondJSON(w, http.StatusOK, entry.Returs[0]) // Kirimkan retur yang sudah dikembalikan dalam format JSON
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"returs": entry.Returs, "count": len(entry.Returs), "reassigned": reassigned}) // Penghapusan massal dikembalikan sekaligus
+}
+
+// reserveRestoredIDs mengambil ID retur yang akan dikembalikan dari deletedIDs sebelum restore ditulis, sehingga
+// createRetur yang berjalan bersamaan tidak mengalokasikan ID yang sama; lastAllocatedID juga dinaikkan agar
+// ID "terakhir + 1" tidak jatuh pada ID yang sedang dikembalikan. Mengembalikan ID yang benar-benar diambil dari pool
+func reserveRestoredIDs(returs []Retur) []int {
+	restoring := make(map[int]bool, len(returs))
+	for _, retur := range returs {
+		restoring[retur.ID] = true
+	}
+	idMu.Lock()
+	defer idMu.Unlock()
+	var reserved []int
+	kept := deletedIDs[:0]
+	for _, id := range deletedIDs {
+		if restoring[id] {
+			reserved = append(reserved, id)
+			continue
+		}
+		kept = append(kept, id) // Pertahankan ID yang tidak ikut dikembalikan
+	}
+	deletedIDs = kept
+	for id := range restoring {
+		lastAllocatedID = max(lastAllocatedID, id)
+	}
+	return reserved
+}
+
+// releaseIDs mengembalikan ID yang sudah diambil tetapi tidak jadi dipakai (restore gagal, upsert yang ternyata update) ke pool
+func releaseIDs(ids []int) {
+	idMu.Lock()
+	defer idMu.Unlock()
+	deletedIDs = append(deletedIDs, ids...)
+}
+
+// reassignOccupiedIDs memberi ID baru pada retur yang ID aslinya sudah dipakai retur lain (karena ID reuse)
+// sehingga undo tidak gagal karena bentrok; mengembalikan pemetaan ID lama ke ID baru
+// ID baru selalu di atas ID terbesar (bukan dari deletedIDs) agar tidak bentrok dengan retur lain di entry yang sama
+// Dijalankan di dalam transaksi restore; query berjalan tanpa memegang idMu, lalu ID baru diambil di atas
+// max(id) dan lastAllocatedID dengan idMu sehingga tidak bentrok dengan createRetur yang berjalan bersamaan
+func reassignOccupiedIDs(tx *gorm.DB, returs []Retur) (map[int]int, error) {
+	if len(returs) == 0 {
+		return map[int]int{}, nil // Semua retur dikembalikan dari baris soft delete-nya
+	}
+	ids := make([]int, 0, len(returs))
+	maxID := 0
+	for _, retur := range returs {
+		ids = append(ids, retur.ID)
+		maxID = max(maxID, retur.ID)
+	}
+	var occupied []int
+	if err := tx.Unscoped().Model(&Retur{}).Where("id IN ?", ids).Pluck("id", &occupied).Error; err != nil { // Baris soft delete retur lain juga memakai ID
+		return nil, err
+	}
+	reassigned := map[int]int{}
+	if len(occupied) == 0 {
+		return reassigned, nil
+	}
+	var dbMax int
+	if err := tx.Unscoped().Model(&Retur{}).Select("COALESCE(MAX(id), 0)").Scan(&dbMax).Error; err != nil {
+		return nil, err
+	}
+	taken := make(map[int]bool, len(occupied))
+	for _, id := range occupied {
+		taken[id] = true
+	}
+	idMu.Lock()
+	defer idMu.Unlock()
+	next := max(maxID, dbMax, lastAllocatedID) + 1
+	for i := range returs {
+		if !taken[returs[i].ID] {
+			continue
+		}
+		reassigned[returs[i].ID] = next
+		returs[i].ID = next
+		for j := range returs[i].Items {
+			returs[i].Items[j].ReturID = next // Item ikut pindah ke ID retur yang baru
+		}
+		lastAllocatedID = next
+		next++
+	}
+	return reassigned, nil
+}
 
-	item, _ := deletedStack.Pop() // Pop item terakhir yang dihapus dari stack
-	if err := db.Create(&item).Error; err != nil {
-		handleError(w, http.StatusInternalServerError, "Failed to restore return") // Jika gagal mengembalikan retur, kirimkan error
+// cloneReturs menyalin retur beserta item-nya agar perubahan ID saat restore tidak mengubah entry di stack undo
+func cloneReturs(returs []Retur) []Retur {
+	clones := make([]Retur, len(returs))
+	for i, retur := range returs {
+		retur.Items = append([]ReturItem(nil), retur.Items...)
+		clones[i] = retur
+	}
+	return clones
+}
+
+// listUndoHandler adalah handler untuk melihat daftar retur yang bisa di-undo dengan dukungan offset/limit (atau page)
+// dan ?envelope=true untuk format {"data", "meta"} yang sama dengan GET /retur
+// Item diurutkan dari yang terakhir dihapus (yang akan dikembalikan lebih dulu oleh undo) dan berisi alasan penghapusan jika ada
+func listUndoHandler(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryInt(r, "limit", defaultUndoListLimit)
+	if err != nil || limit == 0 {
+		handleError(w, http.StatusBadRequest, "Invalid limit") // Limit harus berupa angka positif
+		return
+	}
+	if limit > maxUndoListLimit {
+		limit = maxUndoListLimit // Batasi jumlah item agar response tidak terlalu besar
+	}
+	offset, err := pageOffset(r, limit)
+	if err != nil {
+		handleError(w, http.StatusBadRequest, "Invalid offset or page") // Offset harus berupa angka non-negatif, page mulai dari 1
+		return
+	}
+
+	if wantsEnvelope(r) {
+		respondEnvelope(w, maskEntries(r, deletedStack.SnapshotRange(offset, limit)), newPageMeta(offset, limit, int64(deletedStack.Len())))
 		return
 	}
-	respondJSON(w, http.StatusOK, item) // Kirimkan retur yang sudah dikembalikan dalam format JSON
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"items":  maskEntries(r, deletedStack.SnapshotRange(offset, limit)), // Item pada halaman yang diminta, data sensitif disamarkan untuk non-admin
+		"offset": offset,
+		"limit":  limit,
+		"total":  deletedStack.Len(), // Total item di dalam stack undo
+	})
 }
 
 // main adalah fungsi utama untuk menjalankan server
 func main() {
+	cfg = loadConfig() // Memuat konfigurasi dari environment variable
+	if _, ok := normalizeCurrency(cfg.DefaultCurrency); !ok {
+		panic("Unknown RETUR_DEFAULT_CURRENCY: " + cfg.DefaultCurrency) // Hentikan aplikasi jika mata uang default tidak valid
+	}
+	if !validNamingProfile(cfg.FieldNaming) {
+		panic("Unknown RETUR_FIELD_NAMING: " + cfg.FieldNaming) // Hentikan aplikasi jika profil penamaan tidak dikenal
+	}
+	if _, _, ok := parseSort(cfg.DefaultSort); !ok {
+		panic("Unknown RETUR_DEFAULT_SORT: " + cfg.DefaultSort) // Hentikan aplikasi agar GET /retur tanpa ?sort tidak selalu 400
+	}
+	if !validPIIMode(cfg.PIIMode) {
+		panic("Unknown RETUR_PII_MODE: " + cfg.PIIMode) // Hentikan aplikasi agar PII tidak tercatat mentah karena salah ketik
+	}
+	dbBreaker = newCircuitBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown) // Circuit breaker untuk operasi database
+	heavySem = semaphore.NewWeighted(int64(cfg.HeavyConcurrency))            // Batas request ekspor/laporan yang berjalan bersamaan
+	if err := initDetailsCipher(); err != nil {
+		panic("Failed to configure details encryption: " + err.Error()) // Hentikan aplikasi jika RETUR_DETAILS_KEY tidak valid
+	}
+	if err := loadRequestSchemas(); err != nil {
+		panic("Failed to compile request schemas: " + err.Error()) // Hentikan aplikasi jika schema yang di-embed tidak valid
+	}
 	initDB() // Inisialisasi koneksi database
 
-	r := mux.NewRouter() // Membuat router baru
-	// Menentukan endpoint dan handler yang sesuai
-	r.HandleFunc("/retur", getReturs).Methods("GET")       // Endpoint untuk mengambil semua retur
-	r.HandleFunc("/retur", createRetur).Methods("POST")    // Endpoint untuk membuat retur baru
-	r.HandleFunc("/retur/{id}/approve", approveReturHandler).Methods("POST") // Endpoint untuk menyetujui retur
-	r.HandleFunc("/retur/{id}/disapprove", disapproveReturHandler).Methods("POST") // Endpoint untuk menolak retur
-	r.HandleFunc("/retur/{id}/delete", deleteReturHandler).Methods("DELETE") // Endpoint untuk menghapus retur
-	r.HandleFunc("/retur/undo", undoDeleteReturHandler).Methods("POST") // Endpoint untuk mengembalikan retur yang dihapus
+	notify = newNotifier(cfg.WebhookURL, cfg.NotifyQueueSize) // Menjalankan worker notifikasi webhook
+	registerEventSubscribers()                                // Menghubungkan webhook, audit log, dan metrik ke event bus
+
+	r := newRouter() // Membuat router dengan semua endpoint (di bawah RETUR_BASE_PATH jika diatur)
+	server := &http.Server{Addr: cfg.Addr, Handler: r}
 
-	http.ListenAndServe(":8080", r) // Menjalankan server di port 8080
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM) // Berhenti saat menerima sinyal
+	defer stop()
+	startBackgroundJobs(ctx) // Menjalankan job background (misalnya pembersihan undo kedaluwarsa)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("server failed: %v", err) // Menjalankan server pada alamat dari konfigurasi (default :8080)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("server shutdown: %v", err) // Request yang masih berjalan melewati batas waktu
+	}
+	delivered, dropped := notify.Shutdown(cfg.NotifyDrainTimeout) // Kirim sisa notifikasi sebelum keluar
+	log.Printf("notifications delivered=%d dropped=%d", delivered, dropped)
 }
\ No newline at end of file
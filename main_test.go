@@ -0,0 +1,511 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+	"gorm.io/gorm"
+)
+
+// TestMain menyiapkan state global yang biasanya dibuat di main(): konfigurasi default, circuit breaker,
+// semaphore request berat, dan schema request; database hanya dibuka oleh testDB
+func TestMain(m *testing.M) {
+	cfg = loadConfig()
+	dbBreaker = newCircuitBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown)
+	heavySem = semaphore.NewWeighted(int64(cfg.HeavyConcurrency))
+	if err := initDetailsCipher(); err != nil {
+		panic(err)
+	}
+	if err := loadRequestSchemas(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// withConfig mengubah cfg untuk satu test dan mengembalikannya setelah test selesai
+func withConfig(tb testing.TB, change func(*Config)) {
+	tb.Helper()
+	saved := cfg
+	change(&cfg)
+	tb.Cleanup(func() { cfg = saved })
+}
+
+// testDBOnce membuka database test sekali untuk seluruh paket
+var testDBOnce sync.Once
+
+// testDB membuka database dari RETUR_TEST_DSN (dilewati jika tidak diatur) dan mengosongkan semua tabel aplikasi
+// serta state undo di memori sehingga setiap test mulai dari keadaan yang sama
+// Database test harus khusus untuk test karena isinya dihapus
+func testDB(tb testing.TB) {
+	tb.Helper()
+	dsn := os.Getenv("RETUR_TEST_DSN")
+	if dsn == "" {
+		tb.Skip("RETUR_TEST_DSN not set")
+	}
+	testDBOnce.Do(func() {
+		cfg.DSN = dsn
+		initDB()
+	})
+	all := db.Session(&gorm.Session{AllowGlobalUpdate: true})
+	for _, model := range schemaModels {
+		if _, ok := model.(*Retur); ok {
+			continue // Dihapus paling akhir karena retur_items menunjuk ke returs
+		}
+		if err := all.Delete(model).Error; err != nil {
+			tb.Fatalf("clean %T: %v", model, err)
+		}
+	}
+	if err := all.Unscoped().Delete(&Retur{}).Error; err != nil { // Termasuk baris soft delete
+		tb.Fatalf("clean returs: %v", err)
+	}
+	deletedStack.Replace(nil)
+	idMu.Lock()
+	deletedIDs, lastAllocatedID = nil, 0
+	idMu.Unlock()
+}
+
+// seedReturs menyimpan retur langsung ke database (tanpa handler) dan mengembalikannya dengan ID yang terisi
+func seedReturs(tb testing.TB, returs ...Retur) []Retur {
+	tb.Helper()
+	for i := range returs {
+		if returs[i].Status == "" {
+			returs[i].Status = "Dalam Proses"
+		}
+		if err := db.Create(&returs[i]).Error; err != nil {
+			tb.Fatalf("seed retur: %v", err)
+		}
+	}
+	return returs
+}
+
+// serve mengirim request ke router aplikasi dan mengembalikan hasil rekamannya
+func serve(req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, req)
+	return rec
+}
+
+// jsonRequest membuat request dengan body JSON dari payload
+func jsonRequest(tb testing.TB, method, target string, payload interface{}) *http.Request {
+	tb.Helper()
+	body, err := json.Marshal(payload)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	req := httptest.NewRequest(method, target, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+// decodeBody membaca body JSON response ke dst
+func decodeBody(tb testing.TB, rec *httptest.ResponseRecorder, dst interface{}) {
+	tb.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), dst); err != nil {
+		tb.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+}
+
+// testAdminToken adalah token admin yang dipasang oleh asAdmin
+const testAdminToken = "test-admin-token"
+
+// asAdmin mengaktifkan token admin untuk satu test dan menambahkannya ke request
+func asAdmin(tb testing.TB, req *http.Request) *http.Request {
+	tb.Helper()
+	if cfg.AdminToken != testAdminToken {
+		withConfig(tb, func(c *Config) { c.AdminToken = testAdminToken })
+	}
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	return req
+}
+
+// useUndoState mengganti isi stack undo dan pool deletedIDs di memori selama satu test
+func useUndoState(tb testing.TB, entries []undoEntry, ids []int) {
+	tb.Helper()
+	savedEntries := deletedStack.Items()
+	idMu.Lock()
+	savedIDs := deletedIDs
+	deletedIDs = append([]int(nil), ids...)
+	idMu.Unlock()
+	deletedStack.Replace(entries)
+	tb.Cleanup(func() {
+		deletedStack.Replace(savedEntries)
+		idMu.Lock()
+		deletedIDs = savedIDs
+		idMu.Unlock()
+	})
+}
+
+// createViaAPI membuat retur lewat POST /retur dan mengembalikan response-nya
+func createViaAPI(tb testing.TB, payload map[string]interface{}) Retur {
+	tb.Helper()
+	rec := serve(jsonRequest(tb, http.MethodPost, "/retur", payload))
+	if rec.Code != http.StatusCreated {
+		tb.Fatalf("create: status %d: %s", rec.Code, rec.Body.String())
+	}
+	var created Retur
+	decodeBody(tb, rec, &created)
+	return created
+}
+
+func TestEmptyBodyRequiresBody(t *testing.T) {
+	useApprovers(t, map[string]string{"tok-budi": "budi"})
+	requests := map[string]func() *http.Request{
+		"create": func() *http.Request { return httptest.NewRequest(http.MethodPost, "/retur", nil) },
+		"create with empty stream": func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/retur", io.MultiReader()) // Content-Length tidak diketahui, EOF pada pembacaan pertama
+		},
+		"approve": func() *http.Request {
+			return asApprover(httptest.NewRequest(http.MethodPost, "/retur/1/approve", nil), "tok-budi")
+		},
+	}
+	for name, build := range requests {
+		req := build()
+		req.Header.Set("Content-Type", "application/json")
+		rec := serve(req)
+		var body struct {
+			Error string `json:"error"`
+		}
+		decodeBody(t, rec, &body)
+		if rec.Code != http.StatusBadRequest || body.Error != "Request body required" {
+			t.Errorf("%s: %d %q, want 400 with error envelope \"Request body required\"", name, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestStrictAndLenientJSON(t *testing.T) {
+	payload := map[string]interface{}{"barang": "Jam", "alasan": "mati", "source_system": "erp"} // source_system bukan field Retur
+	validate := func() *httptest.ResponseRecorder {
+		return serve(jsonRequest(t, http.MethodPost, "/retur/validate", payload))
+	}
+
+	withConfig(t, func(c *Config) { c.StrictJSON, c.StrictJSONRoutes = false, nil })
+	if rec := validate(); rec.Code != http.StatusOK {
+		t.Fatalf("lenient: %d %s, want unknown field ignored", rec.Code, rec.Body.String())
+	}
+
+	cfg.StrictJSON = true
+	rec := validate()
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "source_system") {
+		t.Fatalf("strict: %d %s, want 400 naming the unknown field", rec.Code, rec.Body.String())
+	}
+
+	cfg.StrictJSONRoutes = map[string]bool{"validateRetur": false} // Override per route mengalahkan pengaturan global
+	if rec := validate(); rec.Code != http.StatusOK {
+		t.Fatalf("lenient route override: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestListUndoPagination(t *testing.T) {
+	entries := make([]undoEntry, 5)
+	for i := range entries {
+		entries[i] = undoEntry{Returs: []Retur{{ID: i + 1}}}
+	}
+	useUndoState(t, entries, nil)
+	tests := []struct {
+		query string
+		ids   []int
+	}{
+		{"?limit=2", []int{5, 4}},
+		{"?limit=2&offset=4", []int{1}},
+		{"?limit=2&offset=5", nil}, // Offset di luar jangkauan menghasilkan daftar kosong
+		{"?limit=2&page=2", []int{3, 2}},
+	}
+	for _, tt := range tests {
+		rec := serve(httptest.NewRequest(http.MethodGet, "/retur/undo"+tt.query, nil))
+		var body struct {
+			Items []undoEntry `json:"items"`
+			Total int         `json:"total"`
+		}
+		decodeBody(t, rec, &body)
+		var ids []int
+		for _, item := range body.Items {
+			ids = append(ids, item.Returs[0].ID)
+		}
+		if rec.Code != http.StatusOK || body.Total != 5 || !slices.Equal(ids, tt.ids) || body.Items == nil {
+			t.Errorf("%s: status %d ids %v total %d", tt.query, rec.Code, ids, body.Total)
+		}
+	}
+	for _, query := range []string{"?limit=0", "?offset=-1", "?page=0", "?limit=abc"} {
+		if rec := serve(httptest.NewRequest(http.MethodGet, "/retur/undo"+query, nil)); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+func TestWithTransactionRollsBackOnPanic(t *testing.T) {
+	testDB(t)
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("panic was swallowed")
+			}
+		}()
+		withTransaction(context.Background(), func(tx *gorm.DB) error {
+			tx.Create(&Retur{ID: 77, Barang: "Kemeja", Alasan: "sobek", Status: "Dalam Proses"})
+			panic("boom")
+		})
+	}()
+	var count int64
+	db.Model(&Retur{}).Where("id = ?", 77).Count(&count)
+	if count != 0 {
+		t.Fatal("insert before the panic was committed")
+	}
+	err := withTransaction(context.Background(), func(tx *gorm.DB) error {
+		tx.Create(&Retur{ID: 78, Barang: "Kemeja", Alasan: "sobek", Status: "Dalam Proses"})
+		return errors.New("fail")
+	})
+	db.Model(&Retur{}).Where("id = ?", 78).Count(&count)
+	if err == nil || count != 0 {
+		t.Fatalf("error did not roll back: err=%v rows=%d", err, count)
+	}
+}
+
+func TestCreateEchoesServerDefaults(t *testing.T) {
+	testDB(t)
+	rec := serve(jsonRequest(t, http.MethodPost, "/retur", map[string]interface{}{"barang": "Sepatu", "alasan": "ukuran salah", "status": "Disetujui"}))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	var created Retur
+	decodeBody(t, rec, &created)
+	if created.ID == 0 || created.Status != "Dalam Proses" || created.CreatedAt.IsZero() {
+		t.Fatalf("created = %+v", created)
+	}
+	if rec.Header().Get("Location") != fmt.Sprintf("/retur/%d", created.ID) {
+		t.Fatalf("Location = %q", rec.Header().Get("Location"))
+	}
+	var stored Retur
+	db.First(&stored, created.ID)
+	if !stored.CreatedAt.Equal(created.CreatedAt) {
+		t.Fatalf("response created_at %v differs from stored %v", created.CreatedAt, stored.CreatedAt)
+	}
+}
+
+func TestListPaginationHasMoreWithoutCount(t *testing.T) {
+	testDB(t)
+	for range 3 {
+		seedReturs(t, Retur{Barang: "Buku", Alasan: "rusak"})
+	}
+	rec := serve(httptest.NewRequest(http.MethodGet, "/retur?limit=2", nil))
+	if rec.Header().Get("X-Has-More") != "true" || rec.Header().Get("X-Total-Count") != "" {
+		t.Fatalf("first page headers %v", rec.Header())
+	}
+	rec = serve(httptest.NewRequest(http.MethodGet, "/retur?limit=2&offset=2&with_total=true", nil))
+	var page []Retur
+	decodeBody(t, rec, &page)
+	if rec.Header().Get("X-Has-More") != "false" || rec.Header().Get("X-Total-Count") != "3" || len(page) != 1 {
+		t.Fatalf("last page: %d items, headers %v", len(page), rec.Header())
+	}
+}
+
+func TestDeleteUndoRoundTrip(t *testing.T) {
+	testDB(t)
+	created := createViaAPI(t, map[string]interface{}{"barang": "Jam", "alasan": "mati"})
+	del := jsonRequest(t, http.MethodDelete, fmt.Sprintf("/retur/%d/delete", created.ID), map[string]string{"reason": "salah input"})
+	if rec := serve(del); rec.Code != http.StatusOK {
+		t.Fatalf("delete: %d %s", rec.Code, rec.Body.String())
+	}
+	var deleted struct {
+		Items []undoEntry `json:"items"`
+	}
+	decodeBody(t, serve(httptest.NewRequest(http.MethodGet, "/retur/deleted", nil)), &deleted)
+	if len(deleted.Items) != 1 || deleted.Items[0].Reason != "salah input" {
+		t.Fatalf("deleted list = %+v", deleted.Items)
+	}
+
+	// Restart: stack undo dimuat ulang dari undo_entries
+	deletedStack.Replace(nil)
+	if err := loadUndoStack(); err != nil || deletedStack.Len() != 1 {
+		t.Fatalf("reload: %v, %d entries", err, deletedStack.Len())
+	}
+
+	rec := serve(httptest.NewRequest(http.MethodPost, "/retur/undo", nil))
+	var restored Retur
+	decodeBody(t, rec, &restored)
+	if rec.Code != http.StatusOK || restored.ID != created.ID {
+		t.Fatalf("undo: %d %s", rec.Code, rec.Body.String())
+	}
+	var records int64
+	db.Model(&UndoRecord{}).Count(&records)
+	if records != 0 || deletedStack.Len() != 0 {
+		t.Fatalf("undo left %d records and %d stack entries", records, deletedStack.Len())
+	}
+}
+
+func TestUndoRestoresUnderNewIDWhenTaken(t *testing.T) {
+	testDB(t)
+	created := createViaAPI(t, map[string]interface{}{"barang": "Kaos", "alasan": "luntur"})
+	serve(httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/retur/%d/delete", created.ID), nil))
+	if reused := createViaAPI(t, map[string]interface{}{"barang": "Lain", "alasan": "manual"}); reused.ID != created.ID { // ID dipakai lagi dari pool
+		t.Fatalf("create reused id %d, want %d", reused.ID, created.ID)
+	}
+
+	rec := serve(httptest.NewRequest(http.MethodPost, "/retur/undo", nil))
+	var restored Retur
+	decodeBody(t, rec, &restored)
+	if rec.Code != http.StatusOK || restored.ID == created.ID || restored.Barang != "Kaos" {
+		t.Fatalf("undo: %d %+v", rec.Code, restored)
+	}
+	if want := fmt.Sprintf("%d=%d", created.ID, restored.ID); rec.Header().Get("X-Reassigned-IDs") != want {
+		t.Fatalf("X-Reassigned-IDs = %q, want %q", rec.Header().Get("X-Reassigned-IDs"), want)
+	}
+}
+
+func TestBulkDeleteRejectsUnknownStatus(t *testing.T) {
+	rec := serve(asAdmin(t, httptest.NewRequest(http.MethodDelete, "/retur?status=Tidak+Disetujuii&confirm=true", nil)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown status: %d %s, want 400", rec.Code, rec.Body.String())
+	}
+}
+
+func TestBulkDeleteSoftDeletesAndUndoRestores(t *testing.T) {
+	testDB(t)
+	seeded := seedReturs(t,
+		Retur{Barang: "Tas", Alasan: "robek", Status: "Tidak Disetujui", Items: []ReturItem{{SKU: "TAS-1", Quantity: 1, Status: "Dalam Proses"}}},
+		Retur{Barang: "Dompet", Alasan: "pudar", Status: "Tidak Disetujui"},
+		Retur{Barang: "Sabuk", Alasan: "kepanjangan"})
+
+	rec := serve(asAdmin(t, httptest.NewRequest(http.MethodDelete, "/retur?status=Tidak+Disetujui&confirm=true", nil)))
+	var result struct {
+		Count int `json:"count"`
+	}
+	decodeBody(t, rec, &result)
+	if rec.Code != http.StatusOK || result.Count != 2 {
+		t.Fatalf("bulk delete: %d %s", rec.Code, rec.Body.String())
+	}
+	var active, removed int64
+	db.Model(&Retur{}).Count(&active)
+	db.Unscoped().Model(&Retur{}).Where("deleted_at IS NOT NULL").Count(&removed)
+	if active != 1 || removed != 2 {
+		t.Fatalf("after bulk delete: %d active, %d soft-deleted rows", active, removed)
+	}
+	if rec := serve(httptest.NewRequest(http.MethodGet, fmt.Sprintf("/retur/%d", seeded[0].ID), nil)); rec.Code != http.StatusNotFound {
+		t.Fatalf("GET soft-deleted retur: %d, want 404", rec.Code)
+	}
+
+	rec = serve(httptest.NewRequest(http.MethodPost, "/retur/undo", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("undo: %d %s", rec.Code, rec.Body.String())
+	}
+	var restored Retur
+	if err := db.Preload("Items").First(&restored, seeded[0].ID).Error; err != nil || len(restored.Items) != 1 {
+		t.Fatalf("restored retur: %v %+v", err, restored)
+	}
+	db.Unscoped().Model(&Retur{}).Where("deleted_at IS NOT NULL").Count(&removed)
+	if removed != 0 {
+		t.Fatalf("%d rows still soft-deleted after undo", removed)
+	}
+}
+
+func TestUndoOnEmptyStack(t *testing.T) {
+	useUndoState(t, nil, nil)
+	if rec := serve(httptest.NewRequest(http.MethodPost, "/retur/undo", nil)); rec.Code != http.StatusBadRequest {
+		t.Fatalf("undo on empty stack: %d %s, want 400", rec.Code, rec.Body.String())
+	}
+}
+
+func TestUndoRejectsExpiredEntries(t *testing.T) {
+	testDB(t)
+	fake := useFakeClock(t, time.Now())
+	withConfig(t, func(c *Config) { c.MaxUndoAge = time.Hour })
+	created := createViaAPI(t, map[string]interface{}{"barang": "Payung", "alasan": "patah"})
+	serve(httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/retur/%d/delete", created.ID), nil))
+
+	fake.Advance(2 * time.Hour)
+	if rec := serve(httptest.NewRequest(http.MethodPost, "/retur/undo", nil)); rec.Code != http.StatusGone {
+		t.Fatalf("undo of expired entry: %d", rec.Code)
+	}
+	if deletedStack.Len() != 0 {
+		t.Fatal("expired entry not purged")
+	}
+	if rec := serve(httptest.NewRequest(http.MethodGet, fmt.Sprintf("/retur/%d", created.ID), nil)); rec.Code != http.StatusGone {
+		t.Fatalf("GET purged id: %d, want 410", rec.Code)
+	}
+}
+
+func TestUndoWithIdempotencyKeyRestoresOnce(t *testing.T) {
+	testDB(t)
+	withConfig(t, func(c *Config) { c.UndoIdempotencyTTL = time.Minute })
+	first := createViaAPI(t, map[string]interface{}{"barang": "Sendal", "alasan": "putus"})
+	second := createViaAPI(t, map[string]interface{}{"barang": "Topi", "alasan": "kebesaran"})
+	serve(httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/retur/%d/delete", first.ID), nil))
+	serve(httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/retur/%d/delete", second.ID), nil))
+
+	undo := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/retur/undo", nil)
+		req.Header.Set("Idempotency-Key", "undo-1")
+		return serve(req)
+	}
+	a, b := undo(), undo()
+	if a.Code != http.StatusOK || b.Body.String() != a.Body.String() || b.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("retry was not replayed: %d %s / %s", a.Code, a.Body.String(), b.Body.String())
+	}
+	if deletedStack.Len() != 1 {
+		t.Fatalf("retry restored another entry: %d left", deletedStack.Len())
+	}
+}
+
+// seedBenchReturs mengisi database dengan 1000 retur untuk benchmark daftar retur
+func seedBenchReturs(b *testing.B) {
+	b.Helper()
+	testDB(b)
+	returs := make([]Retur, 1000)
+	for i := range returs {
+		returs[i] = Retur{Barang: fmt.Sprintf("Barang %d", i), Alasan: "rusak"}
+	}
+	seedReturs(b, returs...)
+}
+
+// BenchmarkStreamReturs mengukur GET /retur tanpa limit, yang menulis hasil query baris demi baris
+func BenchmarkStreamReturs(b *testing.B) {
+	seedBenchReturs(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if rec := serve(httptest.NewRequest(http.MethodGet, "/retur", nil)); rec.Code != http.StatusOK {
+			b.Fatalf("list: %d", rec.Code)
+		}
+	}
+}
+
+// BenchmarkSliceReturs adalah pembanding untuk BenchmarkStreamReturs: semua retur dimuat ke slice lalu di-encode
+// sekaligus, seperti getReturs sebelum streaming, dan dijalankan di belakang middleware yang sama
+func BenchmarkSliceReturs(b *testing.B) {
+	seedBenchReturs(b)
+	router := newRouter()
+	router.HandleFunc("/bench/retur-slice", func(w http.ResponseWriter, r *http.Request) {
+		var returs []Retur
+		if err := db.WithContext(r.Context()).Order("id").Find(&returs).Error; err != nil {
+			handleError(w, http.StatusInternalServerError, "Failed to retrieve returns")
+			return
+		}
+		for i := range returs {
+			applySLA(&returs[i])
+			applyPriority(&returs[i])
+		}
+		maskReturs(r, returs)
+		respondJSON(w, http.StatusOK, returs)
+	})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bench/retur-slice", nil))
+		if rec.Code != http.StatusOK {
+			b.Fatalf("list: %d", rec.Code)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// metric adalah satu nilai metrik yang ditampilkan di endpoint /metrics dalam format teks Prometheus
+type metric struct {
+	Name  string         // Nama metrik, misalnya retur_id_pool_evictions_total
+	Help  string         // Penjelasan singkat metrik
+	Type  string         // Jenis metrik Prometheus: counter atau gauge
+	Value func() float64 // Fungsi yang membaca nilai terkini
+
+	Samples func() map[string]float64 // Untuk metrik berlabel: label (misalnya type="x") ke nilai; menggantikan Value
+}
+
+// Counter yang diperbarui oleh handler dan dibaca oleh endpoint /metrics
+var (
+	idPoolEvictions atomic.Int64 // Jumlah ID yang dibuang dari pool karena melewati batas RETUR_ID_POOL_CAP
+	idPoolDeletes   atomic.Int64 // Jumlah ID retur yang dihapus dan masuk ke pool sejak aplikasi berjalan
+	idPoolReuses    atomic.Int64 // Jumlah retur baru yang memakai ulang ID dari pool
+)
+
+// registeredMetrics adalah daftar semua metrik yang diekspos aplikasi
+var registeredMetrics = []metric{
+	{Name: "retur_id_pool_size", Help: "Number of deleted IDs waiting to be reused.", Type: "gauge", Value: func() float64 { return float64(deletedIDCount()) }},
+	{Name: "retur_id_pool_evictions_total", Help: "Reusable IDs evicted because the pool exceeded its cap.", Type: "counter", Value: func() float64 { return float64(idPoolEvictions.Load()) }},
+	{Name: "retur_id_pool_deletes_total", Help: "Deleted IDs added to the reuse pool.", Type: "counter", Value: func() float64 { return float64(idPoolDeletes.Load()) }},
+	{Name: "retur_id_pool_reuses_total", Help: "New returns that reused an ID from the pool.", Type: "counter", Value: func() float64 { return float64(idPoolReuses.Load()) }},
+	{Name: "retur_undo_stack_depth", Help: "Number of entries on the undo stack.", Type: "gauge", Value: func() float64 { return float64(deletedStack.Len()) }},
+	{Name: "retur_undo_shedding", Help: "Whether deletes currently skip the undo stack because of load (1) or not (0).", Type: "gauge", Value: func() float64 {
+		if undoShedding.Load() {
+			return 1
+		}
+		return 0
+	}},
+	{Name: "retur_undo_lock_wait_seconds", Help: "Moving average of the time deletes wait for the undo stack lock.", Type: "gauge", Value: func() float64 { return deletedStack.LockWait().Seconds() }},
+	{Name: "retur_undo_skipped_total", Help: "Deleted returns that were not pushed to the undo stack due to load shedding.", Type: "counter", Value: func() float64 { return float64(undoSkipped.Load()) }},
+	{Name: "retur_db_breaker_open", Help: "Whether the database circuit breaker is open (1) or half-open (0.5).", Type: "gauge", Value: func() float64 {
+		switch dbBreaker.State() {
+		case breakerOpen:
+			return 1
+		case breakerHalfOpen:
+			return 0.5
+		}
+		return 0
+	}},
+	{Name: "retur_db_breaker_trips_total", Help: "Times the database circuit breaker opened.", Type: "counter", Value: func() float64 { return float64(dbBreaker.trips.Load()) }},
+	{Name: "retur_db_breaker_short_circuits_total", Help: "Requests rejected with 503 while the breaker was open.", Type: "counter", Value: func() float64 { return float64(dbBreaker.shortCircuits.Load()) }},
+	{Name: "retur_db_last_write_timestamp_seconds", Help: "Unix time of the last successful database write.", Type: "gauge", Value: func() float64 {
+		return float64(lastWriteSuccess.Load()) / 1e9
+	}},
+	{Name: "retur_slow_requests_total", Help: "Requests slower than the configured slow-request threshold.", Type: "counter", Value: func() float64 { return float64(slowRequests.Load()) }},
+	{Name: "retur_events_total", Help: "Events published on the event bus by type.", Type: "counter", Samples: func() map[string]float64 {
+		eventCounts.Lock()
+		defer eventCounts.Unlock()
+		samples := make(map[string]float64, len(eventCounts.byType))
+		for eventType, count := range eventCounts.byType {
+			samples[fmt.Sprintf("type=%q", eventType)] = float64(count)
+		}
+		return samples
+	}},
+}
+
+// metricsHandler adalah handler yang menampilkan semua metrik dalam format teks Prometheus
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	var b strings.Builder
+	for _, m := range registeredMetrics {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", m.Name, m.Help, m.Name, m.Type)
+		if m.Samples == nil {
+			fmt.Fprintf(&b, "%s %g\n", m.Name, m.Value())
+			continue
+		}
+		samples := m.Samples()
+		labels := make([]string, 0, len(samples))
+		for label := range samples {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels) // Urutan label stabil agar output mudah dibandingkan
+		for _, label := range labels {
+			fmt.Fprintf(&b, "%s{%s} %g\n", m.Name, label, samples[label])
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(b.String()))
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log"
+	"mime"
+	"net/http"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// adminTokenFromRequest mengambil token admin dari header Authorization (Bearer) atau X-Admin-Token
+func adminTokenFromRequest(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return r.Header.Get("X-Admin-Token")
+}
+
+// isAdmin memeriksa apakah request membawa token admin yang valid dari IP yang diizinkan RETUR_ADMIN_ALLOWLIST
+// Dipakai juga di luar requireAdmin (misalnya ekspor mentah tanpa penyamaran PII), jadi allowlist ikut diperiksa di sini
+func isAdmin(r *http.Request) bool {
+	if cfg.AdminToken == "" || !adminIPAllowed(r) {
+		return false // Admin nonaktif jika token tidak dikonfigurasi
+	}
+	token := adminTokenFromRequest(r)
+	return subtle.ConstantTimeCompare([]byte(token), []byte(cfg.AdminToken)) == 1 // Bandingkan dengan waktu konstan
+}
+
+// requireAdmin membungkus handler sehingga hanya bisa diakses dengan token admin yang valid
+// IP client diperiksa terhadap RETUR_ADMIN_ALLOWLIST lebih dulu, sebelum token
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !adminIPAllowed(r) {
+			handleError(w, http.StatusForbidden, "Admin access is not allowed from this address") // IP di luar allowlist
+			return
+		}
+		if cfg.AdminToken == "" {
+			handleError(w, http.StatusForbidden, "Admin access is not configured") // Tolak jika admin belum dikonfigurasi
+			return
+		}
+		if !isAdmin(r) {
+			handleError(w, http.StatusUnauthorized, "Admin token required") // Token tidak ada atau tidak valid
+			return
+		}
+		next(w, r)
+	}
+}
+
+// adminMiddleware adalah bentuk middleware dari requireAdmin untuk dipasang pada subrouter admin
+func adminMiddleware(next http.Handler) http.Handler {
+	return requireAdmin(next.ServeHTTP)
+}
+
+// actorFromRequest mengambil identitas pengguna dari header X-Actor untuk keperluan pencatatan
+func actorFromRequest(r *http.Request) string {
+	if actor := strings.TrimSpace(r.Header.Get("X-Actor")); actor != "" {
+		return actor
+	}
+	return "anonymous" // Pengguna tanpa identitas
+}
+
+// responseOptionsWriter membawa preferensi format response milik request ke helper respondJSON
+type responseOptionsWriter struct {
+	http.ResponseWriter
+	pretty  bool   // Gunakan indentasi dua spasi pada JSON
+	naming  string // Profil penamaan field JSON (default atau english_camel)
+	problem bool   // Kirim error dalam format RFC 7807 (application/problem+json)
+}
+
+// Unwrap mengembalikan ResponseWriter asli (dipakai http.ResponseController dan pencarian opsi)
+func (w *responseOptionsWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// responseOptionsFrom mencari responseOptionsWriter di rantai ResponseWriter, nil jika tidak ada
+func responseOptionsFrom(w http.ResponseWriter) *responseOptionsWriter {
+	for w != nil {
+		if opts, ok := w.(*responseOptionsWriter); ok {
+			return opts
+		}
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		w = unwrapper.Unwrap()
+	}
+	return nil
+}
+
+// responseOptionsMiddleware membaca preferensi format response (?pretty=true atau RETUR_PRETTY_JSON)
+// serta profil penamaan field dari header X-Field-Naming (default dari RETUR_FIELD_NAMING)
+// dan format error RFC 7807 dari header Accept: application/problem+json (default dari RETUR_PROBLEM_JSON)
+func responseOptionsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pretty := cfg.PrettyJSON
+		if value := r.URL.Query().Get("pretty"); value != "" {
+			pretty = value == "true" // Parameter query mengalahkan konfigurasi global
+		}
+		opts := &responseOptionsWriter{ResponseWriter: w, pretty: pretty, naming: cfg.FieldNaming}
+		opts.problem = cfg.ProblemJSON || acceptsMediaType(r, problemContentType)
+		if naming := r.Header.Get("X-Field-Naming"); naming != "" {
+			if !validNamingProfile(naming) {
+				handleError(opts, http.StatusBadRequest, "Unknown X-Field-Naming profile") // Profil tidak dikenal
+				return
+			}
+			opts.naming = naming // Header mengalahkan konfigurasi global
+		}
+		next.ServeHTTP(opts, r)
+	})
+}
+
+// requireJSONContentType memastikan request POST/PUT/PATCH yang membawa body menggunakan Content-Type application/json
+// Suffix parameter seperti "; charset=utf-8" tetap diterima; request tanpa body (misalnya undo) tidak diperiksa
+// PATCH juga menerima application/merge-patch+json (RFC 7396)
+func requireJSONContentType(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := mux.CurrentRoute(r); route != nil && attachmentRoutes[route.GetName()] {
+			next.ServeHTTP(w, r) // Upload lampiran menerima body file mentah
+			return
+		}
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+			if r.ContentLength != 0 { // -1 berarti panjang tidak diketahui (chunked) dan tetap diperiksa
+				mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+				if err != nil || (mediaType != "application/json" && !(r.Method == http.MethodPatch && mediaType == mergePatchContentType)) {
+					handleError(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json") // Tolak sebelum decode
+					return
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// recoveryMiddleware menangkap panic dari handler, mencatat stack trace, dan mengirimkan error 500
+func recoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if p := recover(); p != nil {
+				log.Printf("panic serving %s %s actor=%s: %v\n%s", r.Method, r.URL.Path, logActor(r), p, debug.Stack())
+				handleError(w, http.StatusInternalServerError, "Internal server error") // Response generik, detail hanya di log
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// parseNamedTokens membaca daftar "nama=token" dipisahkan koma (RETUR_APPROVER_TOKENS, RETUR_TENANT_KEYS) dan memetakan token ke nama
+func parseNamedTokens(raw string) map[string]string {
+	tokens := make(map[string]string)
+	for _, item := range strings.Split(raw, ",") {
+		name, token, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok || strings.TrimSpace(name) == "" || strings.TrimSpace(token) == "" {
+			continue
+		}
+		tokens[strings.TrimSpace(token)] = strings.TrimSpace(name)
+	}
+	return tokens
+}
+
+// tokenNames mengembalikan nama yang dikonfigurasi (tanpa token) untuk ditampilkan di /config
+func tokenNames(tokens map[string]string) []string {
+	names := make([]string, 0, len(tokens))
+	for _, name := range tokens {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// authenticatedActor mengembalikan identitas dari kredensial request: approver atau admin (lihat authenticatedApprover),
+// atau tenant dari API key RETUR_TENANT_KEYS; header X-Actor tidak pernah dipakai sebagai bukti identitas
+func authenticatedActor(r *http.Request) (string, bool) {
+	if name, ok := authenticatedApprover(r); ok {
+		return name, true
+	}
+	if tenant := tenantFromRequest(r); tenant != anonymousTenant {
+		return "tenant:" + tenant, true
+	}
+	return "", false
+}
+
+// requireAuthenticated membungkus handler yang menulis data besar atau mahal (upload lampiran, job ekspor) sehingga
+// hanya bisa dipanggil dengan kredensial yang valid
+func requireAuthenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.AdminToken == "" && len(cfg.ApproverTokens) == 0 && len(cfg.TenantKeys) == 0 {
+			handleError(w, http.StatusForbidden, "Authentication is not configured") // Belum ada kredensial yang bisa dipakai
+			return
+		}
+		if _, ok := authenticatedActor(r); !ok {
+			handleError(w, http.StatusUnauthorized, "Authentication required")
+			return
+		}
+		next(w, r)
+	}
+}
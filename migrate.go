@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// columnBackfill mendeskripsikan kolom baru yang perlu diisi nilai default untuk baris lama setelah AutoMigrate
+type columnBackfill struct {
+	Table  string             // Nama tabel
+	Column string             // Nama kolom yang diisi jika masih NULL
+	Value  func() interface{} // Nilai default untuk baris lama
+}
+
+// pendingBackfills adalah daftar kolom yang diisi secara bertahap setiap kali aplikasi dijalankan
+// Backfill hanya menyentuh baris yang masih NULL, sehingga aman dijalankan berulang kali
+var pendingBackfills = []columnBackfill{
+	{Table: "returs", Column: "created_at", Value: func() interface{} { return clock.Now() }},
+}
+
+// backfillColumn mengisi kolom yang masih NULL per batch (UPDATE ... LIMIT) dengan jeda antar batch
+// Cara ini menghindari satu UPDATE besar yang mengunci seluruh tabel pada data berukuran besar
+func backfillColumn(b columnBackfill, batchSize int, pause time.Duration) (int64, error) {
+	statement := fmt.Sprintf("UPDATE `%s` SET `%s` = ? WHERE `%s` IS NULL LIMIT ?", b.Table, b.Column, b.Column)
+	value := b.Value()
+	var total int64
+	for {
+		result := db.Exec(statement, value, batchSize)
+		if result.Error != nil {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+		if result.RowsAffected < int64(batchSize) {
+			return total, nil // Batch terakhir sudah diproses
+		}
+		time.Sleep(pause) // Beri jeda agar query lain tetap mendapat giliran
+	}
+}
+
+// runBackfills menjalankan semua backfill yang terdaftar setelah AutoMigrate
+func runBackfills() error {
+	for _, b := range pendingBackfills {
+		updated, err := backfillColumn(b, cfg.BackfillBatchSize, cfg.BackfillPause)
+		if err != nil {
+			return fmt.Errorf("backfill %s.%s: %w", b.Table, b.Column, err)
+		}
+		if updated > 0 {
+			log.Printf("backfilled %d rows in %s.%s", updated, b.Table, b.Column)
+		}
+	}
+	sealed, err := sealWebhookSecrets()
+	if err != nil {
+		return fmt.Errorf("encrypt webhook secrets: %w", err)
+	}
+	if sealed > 0 {
+		log.Printf("encrypted %d plaintext webhook secrets", sealed)
+	}
+	updated, err := backfillNaturalKeys(cfg.BackfillBatchSize, cfg.BackfillPause)
+	if err != nil {
+		return fmt.Errorf("backfill returs.natural_key: %w", err)
+	}
+	if updated > 0 {
+		log.Printf("backfilled %d rows in returs.natural_key", updated)
+	}
+	return nil
+}
+
+// schemaModels adalah semua model yang tabelnya dikelola aplikasi
+var schemaModels = []interface{}{&Retur{}, &ReturItem{}, &ReturHistory{}, &AuditLog{}, &Webhook{}, &Reason{}, &UndoRecord{}, &Attachment{}, &TenantQuota{}, &PurgedRetur{}, &ExportJob{}}
+
+// migrateSchema menjalankan AutoMigrate jika RETUR_AUTO_MIGRATE=true (default); jika tidak, skema hanya diverifikasi
+// Di production skema sebaiknya diubah lewat migration tooling, sehingga aplikasi cukup gagal cepat jika skema belum lengkap
+func migrateSchema() error {
+	if cfg.AutoMigrate {
+		if err := db.AutoMigrate(schemaModels...); err != nil {
+			return err
+		}
+		return upgradeItemsConstraint()
+	}
+	return verifySchema()
+}
+
+// itemsConstraint adalah nama foreign key retur_items.retur_id yang dibuat GORM untuk relasi Retur.Items
+const itemsConstraint = "fk_returs_items"
+
+// upgradeItemsConstraint membuat ulang foreign key retur_items yang dibuat sebelum relasi memakai ON DELETE CASCADE
+// AutoMigrate hanya membuat constraint yang belum ada, tidak mengubah aturan constraint yang sudah ada
+func upgradeItemsConstraint() error {
+	var rule string
+	err := db.Raw(`SELECT DELETE_RULE FROM information_schema.REFERENTIAL_CONSTRAINTS
+		WHERE CONSTRAINT_SCHEMA = DATABASE() AND CONSTRAINT_NAME = ?`, itemsConstraint).Scan(&rule).Error
+	if err != nil || rule == "" || rule == "CASCADE" {
+		return err
+	}
+	migrator := db.Migrator()
+	if err := migrator.DropConstraint(&Retur{}, itemsConstraint); err != nil {
+		return fmt.Errorf("drop %s: %w", itemsConstraint, err)
+	}
+	if err := migrator.CreateConstraint(&Retur{}, "Items"); err != nil {
+		return fmt.Errorf("create %s: %w", itemsConstraint, err)
+	}
+	log.Printf("recreated %s with ON DELETE CASCADE", itemsConstraint)
+	return nil
+}
+
+// verifySchema memeriksa bahwa setiap tabel dan kolom yang dibutuhkan model sudah ada di database
+func verifySchema() error {
+	migrator := db.Migrator()
+	var missing []string
+	for _, model := range schemaModels {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		table := stmt.Schema.Table
+		if !migrator.HasTable(model) {
+			missing = append(missing, "table "+table)
+			continue
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" {
+				continue // Field yang tidak disimpan (gorm:"-") atau relasi
+			}
+			if !migrator.HasColumn(model, field.DBName) {
+				missing = append(missing, "column "+table+"."+field.DBName)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("schema is incomplete (RETUR_AUTO_MIGRATE=false): missing %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestVerifySchemaReportsMissingColumns(t *testing.T) {
+	testDB(t)
+	withConfig(t, func(c *Config) { c.AutoMigrate = false })
+	if err := migrateSchema(); err != nil {
+		t.Fatalf("verify migrated schema: %v", err)
+	}
+
+	if err := db.Migrator().DropColumn(&PurgedRetur{}, "purged_at"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := db.AutoMigrate(&PurgedRetur{}); err != nil {
+			t.Errorf("restore purged_at: %v", err)
+		}
+	})
+	err := migrateSchema()
+	if err == nil || !strings.Contains(err.Error(), ".purged_at") {
+		t.Fatalf("verify without purged_at: %v", err)
+	}
+
+	cfg.AutoMigrate = true
+	if err := migrateSchema(); err != nil {
+		t.Fatalf("auto migrate: %v", err)
+	}
+	if !db.Migrator().HasColumn(&PurgedRetur{}, "purged_at") {
+		t.Fatal("auto migrate did not restore purged_at")
+	}
+}
+
+func TestBackfillColumnInBatchesAndResume(t *testing.T) {
+	testDB(t)
+	seeded := seedReturs(t,
+		Retur{Barang: "A", Alasan: "x"}, Retur{Barang: "B", Alasan: "x"}, Retur{Barang: "C", Alasan: "x"},
+		Retur{Barang: "D", Alasan: "x"}, Retur{Barang: "E", Alasan: "x"})
+	setNull := func(returs ...Retur) {
+		t.Helper()
+		ids := make([]int, len(returs))
+		for i, retur := range returs {
+			ids[i] = retur.ID
+		}
+		if err := db.Exec("UPDATE returs SET created_at = NULL WHERE id IN ?", ids).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	countWhere := func(query string, args ...interface{}) int64 {
+		var n int64
+		db.Model(&Retur{}).Where(query, args...).Count(&n)
+		return n
+	}
+	var statements int
+	if err := db.Callback().Raw().After("gorm:raw").Register("test:count_backfill", func(tx *gorm.DB) {
+		if strings.HasPrefix(tx.Statement.SQL.String(), "UPDATE `returs` SET `created_at`") {
+			statements++
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Callback().Raw().Remove("test:count_backfill") })
+
+	setNull(seeded...)
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	backfill := columnBackfill{Table: "returs", Column: "created_at", Value: func() interface{} { return first }}
+	updated, err := backfillColumn(backfill, 2, 0)
+	if err != nil || updated != 5 || statements != 3 { // 2 + 2 + 1 baris
+		t.Fatalf("backfill: %d rows in %d statements, err %v; want 5 rows in 3 batches", updated, statements, err)
+	}
+	if n := countWhere("created_at IS NULL"); n != 0 {
+		t.Fatalf("%d rows still NULL", n)
+	}
+
+	// Dijalankan ulang (restart berikutnya atau setelah backfill terputus): hanya baris yang masih NULL yang diisi
+	setNull(seeded[1], seeded[3])
+	second := first.Add(24 * time.Hour)
+	backfill.Value = func() interface{} { return second }
+	if updated, err := backfillColumn(backfill, 2, 0); err != nil || updated != 2 {
+		t.Fatalf("resume: %d rows, err %v; want 2", updated, err)
+	}
+	if kept, resumed := countWhere("created_at = ?", first), countWhere("created_at = ?", second); kept != 3 || resumed != 2 {
+		t.Fatalf("after resume: %d rows kept the first value, %d got the second; want 3 and 2", kept, resumed)
+	}
+}
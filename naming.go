@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Profil penamaan field JSON yang didukung untuk response
+const (
+	namingDefault      = "default"       // Tag JSON bawaan (snake_case dengan istilah Indonesia)
+	namingEnglishCamel = "english_camel" // camelCase dengan istilah bahasa Inggris untuk client lama/partner
+)
+
+// englishFieldNames memetakan istilah Indonesia pada tag JSON ke istilah bahasa Inggris
+// Key yang tidak ada di sini hanya diubah dari snake_case ke camelCase
+var englishFieldNames = map[string]string{
+	"barang":       "item",
+	"alasan":       "reason",
+	"pengembalian": "refundType",
+	"retur":        "return",
+	"returs":       "returns",
+	"retur_id":     "returnId",
+}
+
+// validNamingProfile memeriksa apakah nama profil penamaan dikenal
+func validNamingProfile(profile string) bool {
+	return profile == namingDefault || profile == namingEnglishCamel
+}
+
+// snakeToCamel mengubah nama snake_case menjadi camelCase, misalnya created_at menjadi createdAt
+func snakeToCamel(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// renameKeys mengganti nama semua key object JSON secara rekursif sesuai profil english_camel
+func renameKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		renamed := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if english, ok := englishFieldNames[key]; ok {
+				key = english
+			} else {
+				key = snakeToCamel(key)
+			}
+			renamed[key] = renameKeys(item)
+		}
+		return renamed
+	case []interface{}:
+		for i, item := range v {
+			v[i] = renameKeys(item)
+		}
+		return v
+	}
+	return value
+}
+
+// applyNamingProfile mengubah payload ke bentuk yang sesuai profil penamaan sebelum di-encode
+// Model yang tersimpan tidak berubah; hanya representasi JSON pada response yang dipetakan ulang
+func applyNamingProfile(payload interface{}, profile string) (interface{}, error) {
+	if profile != namingEnglishCamel {
+		return payload, nil
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber() // Pertahankan angka besar (misalnya refund_amount) tanpa kehilangan presisi
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return renameKeys(generic), nil
+}
+
+// namingProfileFor mengembalikan profil penamaan untuk response ini
+func namingProfileFor(w http.ResponseWriter) string {
+	if opts := responseOptionsFrom(w); opts != nil && opts.naming != "" {
+		return opts.naming
+	}
+	return namingDefault
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// undoListKeys mengambil key object retur pertama dari response GET /retur/undo
+func undoListKeys(tb testing.TB, rec *httptest.ResponseRecorder, itemsKey, retursKey string) map[string]json.RawMessage {
+	tb.Helper()
+	var body map[string]json.RawMessage
+	decodeBody(tb, rec, &body)
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(body[itemsKey], &items); err != nil || len(items) != 1 {
+		tb.Fatalf("%s missing in %s: %v", itemsKey, rec.Body.String(), err)
+	}
+	var returs []map[string]json.RawMessage
+	if err := json.Unmarshal(items[0][retursKey], &returs); err != nil || len(returs) != 1 {
+		tb.Fatalf("%s missing in %s: %v", retursKey, rec.Body.String(), err)
+	}
+	return returs[0]
+}
+
+func TestNamingProfiles(t *testing.T) {
+	entry := undoEntry{DeletedAt: time.Now(), Returs: []Retur{{ID: 1, Barang: "Jam", Alasan: "mati", Pengembalian: "uang", CustomerID: "C-1", CreatedAt: time.Now()}}}
+	useUndoState(t, []undoEntry{entry}, nil)
+	list := func(profile string) *httptest.ResponseRecorder {
+		req := asAdmin(t, httptest.NewRequest(http.MethodGet, "/retur/undo", nil)) // Admin agar customer_id tidak disamarkan
+		if profile != "" {
+			req.Header.Set("X-Field-Naming", profile)
+		}
+		return serve(req)
+	}
+
+	tests := []struct {
+		profile              string
+		itemsKey, retursKey  string
+		present, notExpected []string
+	}{
+		{"", "items", "returs", []string{"barang", "alasan", "pengembalian", "customer_id", "created_at"}, []string{"item", "createdAt"}},
+		{namingEnglishCamel, "items", "returns", []string{"item", "reason", "refundType", "customerId", "createdAt"}, []string{"barang", "created_at"}},
+	}
+	for _, tt := range tests {
+		keys := undoListKeys(t, list(tt.profile), tt.itemsKey, tt.retursKey)
+		for _, key := range tt.present {
+			if _, ok := keys[key]; !ok {
+				t.Errorf("profile %q: key %s missing from %v", tt.profile, key, keys)
+			}
+		}
+		for _, key := range tt.notExpected {
+			if _, ok := keys[key]; ok {
+				t.Errorf("profile %q: unexpected key %s", tt.profile, key)
+			}
+		}
+	}
+
+	if rec := list("kebab"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown profile: %d, want 400", rec.Code)
+	}
+}
+
+func TestSnakeToCamel(t *testing.T) {
+	for input, want := range map[string]string{"created_at": "createdAt", "sla_deadline": "slaDeadline", "id": "id", "first_approved_by": "firstApprovedBy"} {
+		if got := snakeToCamel(input); got != want {
+			t.Errorf("snakeToCamel(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ndjsonContentType adalah content type untuk newline-delimited JSON (satu objek JSON per baris)
+const ndjsonContentType = "application/x-ndjson"
+
+// wantsNDJSON memeriksa apakah client meminta NDJSON lewat ?format=ndjson atau header Accept: application/x-ndjson
+func wantsNDJSON(r *http.Request) bool {
+	if r.URL.Query().Get("format") == "ndjson" {
+		return true
+	}
+	return acceptsMediaType(r, ndjsonContentType)
+}
+
+// writeNDJSON mengirim setiap item sebagai satu baris JSON; indentasi (?pretty=true) diabaikan agar format baris tetap valid
+func writeNDJSON(w http.ResponseWriter, items []interface{}) {
+	w.Header().Set("Content-Type", ndjsonContentType)
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+	for _, item := range items {
+		if err := encoder.Encode(item); err != nil {
+			return // Client kemungkinan sudah memutus koneksi
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWantsNDJSON(t *testing.T) {
+	tests := []struct {
+		target, accept string
+		want           bool
+	}{
+		{"/retur?format=ndjson", "", true},
+		{"/retur", "application/x-ndjson", true},
+		{"/retur", "application/json", false},
+		{"/retur?format=json", "", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		if got := wantsNDJSON(req); got != tt.want {
+			t.Errorf("%s Accept %q: %v, want %v", tt.target, tt.accept, got, tt.want)
+		}
+	}
+}
+
+// readNDJSON membaca setiap baris response NDJSON sebagai Retur
+func readNDJSON(tb testing.TB, rec *httptest.ResponseRecorder) []Retur {
+	tb.Helper()
+	if rec.Header().Get("Content-Type") != ndjsonContentType {
+		tb.Fatalf("content type %q: %s", rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	var returs []Retur
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var retur Retur
+		if err := json.Unmarshal(scanner.Bytes(), &retur); err != nil {
+			tb.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		returs = append(returs, retur)
+	}
+	return returs
+}
+
+func TestNonAdminReadsRedactPII(t *testing.T) {
+	testDB(t)
+	withConfig(t, func(c *Config) {
+		c.PIIFields = []string{"customer_id"}
+		c.PIIMode = piiMask
+	})
+	seeded := seedReturs(t, Retur{Barang: "A", Alasan: "x", CustomerID: "CUST-001"}, Retur{Barang: "B", Alasan: "x", CustomerID: "CUST-002"})
+
+	for _, target := range []string{"/retur?format=ndjson", "/retur?format=ndjson&limit=10"} { // Streaming dan halaman
+		masked := readNDJSON(t, serve(httptest.NewRequest(http.MethodGet, target, nil)))
+		if len(masked) != 2 || masked[0].CustomerID != "******01" || masked[1].CustomerID != "******02" {
+			t.Fatalf("%s non-admin: %+v", target, masked)
+		}
+		raw := readNDJSON(t, serve(asAdmin(t, httptest.NewRequest(http.MethodGet, target, nil))))
+		if len(raw) != 2 || raw[0].CustomerID != "CUST-001" {
+			t.Fatalf("%s admin: %+v", target, raw)
+		}
+	}
+	// JSON biasa disamarkan dengan aturan yang sama, sehingga PII tidak bisa dilihat hanya dengan tidak meminta NDJSON
+	for _, target := range []string{"/retur", "/retur?limit=10", "/retur?envelope=true"} {
+		rec := serve(httptest.NewRequest(http.MethodGet, target, nil))
+		if body := rec.Body.String(); rec.Code != http.StatusOK || strings.Contains(body, "CUST-00") {
+			t.Fatalf("%s non-admin: %d %s", target, rec.Code, body)
+		}
+	}
+	var single, raw Retur
+	target := fmt.Sprintf("/retur/%d", seeded[0].ID)
+	decodeBody(t, serve(httptest.NewRequest(http.MethodGet, target, nil)), &single)
+	decodeBody(t, serve(asAdmin(t, httptest.NewRequest(http.MethodGet, target, nil))), &raw)
+	if single.CustomerID != "******01" || raw.CustomerID != "CUST-001" {
+		t.Fatalf("GET %s: non-admin %q, admin %q", target, single.CustomerID, raw.CustomerID)
+	}
+	if rec := serve(httptest.NewRequest(http.MethodGet, "/retur?format=ndjson&envelope=true", nil)); rec.Code != http.StatusBadRequest {
+		t.Fatalf("ndjson with envelope: %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// notification adalah pesan perubahan status retur yang dikirim ke webhook secara asynchronous
+type notification struct {
+	Event string    `json:"event"` // Jenis perubahan, misalnya "retur.approved"
+	Retur Retur     `json:"retur"` // Data retur setelah perubahan
+	At    time.Time `json:"at"`    // Waktu perubahan terjadi
+
+	url     string      // URL tujuan notifikasi ini
+	secret  string      // Kunci HMAC untuk tanda tangan, kosong berarti tanpa tanda tangan
+	public  bool        // Tujuan dari langganan Webhook, dikirim lewat publicClient yang menolak alamat internal
+	payload interface{} // Body pengganti untuk kejadian yang bukan perubahan retur (alert lonjakan), nil berarti notifikasi ini sendiri
+	subject string      // Keterangan untuk log, misalnya "retur 12"
+}
+
+// notifier mengirim notifikasi ke webhook melalui antrian channel dan satu worker di background
+// Setiap kejadian dikirim ke RETUR_WEBHOOK_URL (jika diatur) dan ke semua langganan Webhook aktif yang cocok
+type notifier struct {
+	url    string            // URL webhook dari konfigurasi, kosong berarti hanya langganan di database
+	client *http.Client      // HTTP client dengan timeout untuk URL dari konfigurasi (dipercaya operator)
+	public *http.Client      // HTTP client untuk langganan Webhook, hanya boleh ke alamat publik (lihat publicDialer)
+	queue  chan notification // Antrian notifikasi yang menunggu dikirim
+
+	mu     sync.RWMutex // Melindungi flag closed terhadap enqueue yang berjalan bersamaan
+	closed bool         // Bernilai true setelah intake ditutup saat shutdown
+
+	done      chan struct{} // Ditutup saat worker selesai menguras antrian
+	abort     chan struct{} // Ditutup jika batas waktu drain terlewati
+	delivered atomic.Int64  // Jumlah notifikasi yang berhasil dikirim
+	dropped   atomic.Int64  // Jumlah notifikasi yang gagal atau dibuang
+}
+
+// notify adalah notifier aktif yang dipakai oleh handler
+var notify *notifier
+
+// newNotifier membuat notifier dengan kapasitas antrian tertentu dan langsung menjalankan worker-nya
+func newNotifier(url string, queueSize int) *notifier {
+	n := &notifier{
+		url:    url,
+		client: &http.Client{Timeout: 5 * time.Second},
+		public: &http.Client{Timeout: 5 * time.Second, Transport: &http.Transport{DialContext: publicDialer.DialContext}}, // Tanpa proxy agar alamat tujuan benar-benar diperiksa
+		queue:  make(chan notification, queueSize),
+		done:   make(chan struct{}),
+		abort:  make(chan struct{}),
+	}
+	go n.run()
+	return n
+}
+
+// Enqueue memasukkan notifikasi untuk setiap tujuan ke antrian tanpa blocking; notifikasi dibuang jika antrian penuh atau sudah ditutup
+// Mengembalikan jumlah tujuan yang masuk antrian dan yang dibuang
+func (n *notifier) Enqueue(event string, retur Retur) (queued, dropped int) {
+	if n == nil {
+		return 0, 0 // Notifikasi nonaktif
+	}
+	retur.Details = maskSensitiveDetails(retur.Details) // Subscriber webhook bukan pembaca berwenang, nomor rekening tidak dikirim sebagai plaintext
+	var targets []notification
+	at := clock.Now()
+	subject := fmt.Sprintf("retur %d", retur.ID)
+	if n.url != "" {
+		targets = append(targets, notification{Event: event, Retur: retur, At: at, url: n.url, subject: subject})
+	}
+	for _, h := range webhookSubs.Matching(event) {
+		targets = append(targets, notification{Event: event, Retur: retur, At: at, url: h.URL, secret: h.Secret, public: true, subject: subject})
+	}
+	return n.push(targets)
+}
+
+// EnqueueSpike memasukkan alert lonjakan retur ke antrian untuk RETUR_SPIKE_WEBHOOK_URL (ditandatangani dengan
+// RETUR_SPIKE_WEBHOOK_SECRET) dan untuk langganan Webhook yang memilih retur.spike; dikirim lewat worker yang sama
+func (n *notifier) EnqueueSpike(alert spikeAlert) (queued, dropped int) {
+	if n == nil {
+		return 0, 0 // Notifikasi nonaktif
+	}
+	var targets []notification
+	subject := fmt.Sprintf("barang %q", alert.Barang)
+	if cfg.SpikeWebhookURL != "" {
+		targets = append(targets, notification{Event: alert.Event, At: alert.At, url: cfg.SpikeWebhookURL, secret: cfg.SpikeWebhookSecret, payload: alert, subject: subject})
+	}
+	for _, h := range webhookSubs.Matching(alert.Event) {
+		targets = append(targets, notification{Event: alert.Event, At: alert.At, url: h.URL, secret: h.Secret, public: true, payload: alert, subject: subject})
+	}
+	return n.push(targets)
+}
+
+// push memasukkan notifikasi ke antrian tanpa blocking; notifikasi dibuang jika antrian penuh atau sudah ditutup
+func (n *notifier) push(targets []notification) (queued, dropped int) {
+	if len(targets) == 0 {
+		return 0, 0 // Tidak ada tujuan untuk kejadian ini
+	}
+
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	if n.closed {
+		n.dropped.Add(int64(len(targets))) // Intake sudah ditutup karena aplikasi sedang berhenti
+		return 0, len(targets)
+	}
+	for _, item := range targets {
+		select {
+		case n.queue <- item:
+			queued++
+		default:
+			n.dropped.Add(1) // Antrian penuh, jangan sampai handler ikut tertahan
+			dropped++
+			log.Printf("notification queue full, dropped %s for %s", item.Event, item.subject)
+		}
+	}
+	return queued, dropped
+}
+
+// run adalah worker yang mengirim notifikasi satu per satu sampai antrian ditutup
+func (n *notifier) run() {
+	defer close(n.done)
+	for item := range n.queue {
+		select {
+		case <-n.abort:
+			n.dropped.Add(1) // Batas waktu drain terlewati, sisa antrian dibuang
+			continue
+		default:
+		}
+		if err := n.deliver(item); err != nil {
+			n.dropped.Add(1)
+			log.Printf("notification %s for %s failed: %v", item.Event, item.subject, err)
+			continue
+		}
+		n.delivered.Add(1)
+	}
+}
+
+// deliver mengirim satu notifikasi (atau payload penggantinya) ke webhook sebagai JSON
+func (n *notifier) deliver(item notification) error {
+	var payload interface{} = item
+	if item.payload != nil {
+		payload = item.payload
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, item.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if item.secret != "" {
+		mac := hmac.New(sha256.New, []byte(item.secret))
+		mac.Write(body)
+		req.Header.Set("X-Retur-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil))) // Penerima bisa memverifikasi asal notifikasi
+	}
+	client := n.client
+	if item.public {
+		client = n.public
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return &webhookStatusError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// webhookStatusError menandakan webhook membalas dengan status selain 2xx
+type webhookStatusError struct {
+	StatusCode int
+}
+
+func (e *webhookStatusError) Error() string {
+	return fmt.Sprintf("webhook responded with status %d", e.StatusCode)
+}
+
+// Shutdown menutup intake, menguras antrian dengan batas waktu, lalu melaporkan jumlah terkirim dan dibuang
+func (n *notifier) Shutdown(timeout time.Duration) (delivered, dropped int64) {
+	n.mu.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue) // Worker akan berhenti setelah antrian kosong
+	}
+	n.mu.Unlock()
+
+	select {
+	case <-n.done:
+	case <-time.After(timeout):
+		close(n.abort) // Buang sisa antrian agar shutdown tidak menggantung
+		<-n.done
+	}
+	return n.delivered.Load(), n.dropped.Load()
+}
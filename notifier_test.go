@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNotifierShutdownDeliversQueued(t *testing.T) {
+	var received atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+	}))
+	defer server.Close()
+
+	n := newNotifier(server.URL, 10)
+	for id := 1; id <= 3; id++ {
+		if queued, _ := n.Enqueue(string(ReturApproved), Retur{ID: id}); queued != 1 {
+			t.Fatalf("enqueue retur %d: queued %d", id, queued)
+		}
+	}
+	delivered, dropped := n.Shutdown(5 * time.Second)
+	if delivered != 3 || dropped != 0 || received.Load() != 3 {
+		t.Fatalf("shutdown: delivered %d, dropped %d, received %d; want all 3 delivered", delivered, dropped, received.Load())
+	}
+	if queued, dropped := n.Enqueue(string(ReturApproved), Retur{ID: 4}); queued != 0 || dropped != 1 {
+		t.Fatalf("enqueue after shutdown: queued %d, dropped %d; want intake closed", queued, dropped)
+	}
+}
+
+func TestNotifierShutdownDropsAfterTimeout(t *testing.T) {
+	started, release := make(chan struct{}, 3), make(chan struct{})
+	var received atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		started <- struct{}{}
+		<-release // Webhook lambat: notifikasi pertama tertahan sampai batas waktu drain terlewati
+	}))
+	defer server.Close()
+
+	n := newNotifier(server.URL, 10)
+	for id := 1; id <= 3; id++ {
+		n.Enqueue(string(ReturApproved), Retur{ID: id})
+	}
+	<-started // Notifikasi pertama sudah dikirim sebelum shutdown dimulai
+	go func() {
+		<-n.abort
+		close(release) // Pengiriman yang sedang berjalan selesai setelah drain dibatalkan
+	}()
+	delivered, dropped := n.Shutdown(50 * time.Millisecond)
+	if delivered != 1 || dropped != 2 || received.Load() != 1 {
+		t.Fatalf("shutdown after timeout: delivered %d, dropped %d, received %d; want 1 in-flight delivered and 2 dropped", delivered, dropped, received.Load())
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+// orphanSource adalah tabel sub-resource yang barisnya menunjuk ke retur lewat kolom retur_id
+// retur_items sudah dilindungi foreign key ON DELETE CASCADE (relasi Items), tetapi baris lama dari sebelum constraint dibuat bisa tetap yatim;
+// retur_histories sengaja tanpa foreign key agar riwayat tetap ada selama retur yang dihapus masih bisa di-undo
+// Audit log tidak termasuk karena memang harus tetap ada setelah retur dihapus
+type orphanSource struct {
+	Name  string                                          // Nama yang ditampilkan di laporan
+	Model interface{}                                     // Model GORM tabel sub-resource
+	Files func(tx *gorm.DB, ids []uint) ([]string, error) // Path file di disk milik baris tersebut, nil jika tidak ada
+}
+
+// orphanSources adalah semua sub-resource yang diperiksa oleh /retur/admin/orphans
+var orphanSources = []orphanSource{
+	{Name: "items", Model: &ReturItem{}},
+	{Name: "history", Model: &ReturHistory{}},
+	{Name: "attachments", Model: &Attachment{}, Files: attachmentPaths},
+}
+
+// orphanScope memilih baris yang retur induknya sudah tidak ada dan tidak bisa dikembalikan lewat undo
+func orphanScope(q *gorm.DB) *gorm.DB {
+	q = q.Where("retur_id NOT IN (SELECT id FROM returs)")
+	if pending := undoPendingIDs(); len(pending) > 0 {
+		q = q.Where("retur_id NOT IN ?", pending) // Retur di stack undo masih bisa kembali beserta sub-resource-nya
+	}
+	return q
+}
+
+// undoPendingIDs mengumpulkan ID semua retur yang masih ada di stack undo
+func undoPendingIDs() []int {
+	return entryReturIDs(deletedStack.Items()...)
+}
+
+// orphansReportHandler adalah handler admin untuk menghitung baris sub-resource yang retur induknya sudah tidak ada
+func orphansReportHandler(w http.ResponseWriter, r *http.Request) {
+	counts := make(map[string]int64, len(orphanSources))
+	var total int64
+	for _, source := range orphanSources {
+		var count int64
+		if err := db.WithContext(r.Context()).Model(source.Model).Scopes(orphanScope).Count(&count).Error; err != nil {
+			handleError(w, http.StatusInternalServerError, "Failed to count orphaned "+source.Name)
+			return
+		}
+		counts[source.Name] = count
+		total += count
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"orphans": counts, "total": total})
+}
+
+// cleanupOrphansHandler adalah handler admin untuk menghapus baris sub-resource yatim per batch (?batch_size, default RETUR_BACKFILL_BATCH_SIZE)
+// Setiap batch dihapus dalam transaksi terpisah agar tabel tidak terkunci lama
+func cleanupOrphansHandler(w http.ResponseWriter, r *http.Request) {
+	batchSize, err := queryInt(r, "batch_size", cfg.BackfillBatchSize)
+	if err != nil || batchSize == 0 {
+		handleError(w, http.StatusBadRequest, "Invalid batch_size")
+		return
+	}
+
+	deleted := make(map[string]int64, len(orphanSources))
+	for _, source := range orphanSources {
+		for {
+			var ids []uint
+			if err := db.WithContext(r.Context()).Model(source.Model).Scopes(orphanScope).
+				Order("id").Limit(batchSize).Pluck("id", &ids).Error; err != nil {
+				handleError(w, http.StatusInternalServerError, "Failed to scan orphaned "+source.Name)
+				return
+			}
+			if len(ids) == 0 {
+				break
+			}
+			var affected int64
+			var files []string
+			err := withTransaction(r.Context(), func(tx *gorm.DB) error {
+				if source.Files != nil {
+					var err error
+					if files, err = source.Files(tx, ids); err != nil {
+						return err
+					}
+				}
+				result := tx.Delete(source.Model, ids)
+				affected = result.RowsAffected
+				return result.Error
+			})
+			if err != nil {
+				handleError(w, http.StatusInternalServerError, "Failed to delete orphaned "+source.Name)
+				return
+			}
+			removeAttachmentFiles(files) // File dihapus setelah commit agar rollback tidak meninggalkan metadata tanpa file
+			deleted[source.Name] += affected
+		}
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"deleted": deleted})
+}
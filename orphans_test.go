@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestOrphansReportAndCleanup(t *testing.T) {
+	testDB(t)
+	live := seedReturs(t, Retur{Barang: "A", Alasan: "x"})[0]
+	useUndoState(t, []undoEntry{{Returs: []Retur{{ID: 500}}}}, nil)
+	db.Create(&[]ReturHistory{
+		{ReturID: live.ID, ToStatus: "Dalam Proses"},
+		{ReturID: 500, ToStatus: "Dalam Proses"}, // Masih bisa kembali lewat undo
+		{ReturID: 600, ToStatus: "Dalam Proses"}, // Yatim
+		{ReturID: 600, ToStatus: "Disetujui"},    // Yatim
+	})
+	// Baris lama dari sebelum foreign key dibuat; SET berlaku per koneksi sehingga dijalankan di satu transaksi
+	err := db.Transaction(func(tx *gorm.DB) error {
+		tx.Exec("SET FOREIGN_KEY_CHECKS = 0")
+		defer tx.Exec("SET FOREIGN_KEY_CHECKS = 1")
+		return tx.Create(&ReturItem{ReturID: 601, SKU: "SKU-1", Quantity: 1, Status: "Dalam Proses"}).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var report struct {
+		Orphans map[string]int64 `json:"orphans"`
+		Total   int64            `json:"total"`
+	}
+	decodeBody(t, serve(asAdmin(t, httptest.NewRequest(http.MethodGet, "/retur/admin/orphans", nil))), &report)
+	if report.Orphans["history"] != 2 || report.Orphans["items"] != 1 || report.Total != 3 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	rec := serve(asAdmin(t, httptest.NewRequest(http.MethodPost, "/retur/admin/orphans/cleanup?batch_size=1", nil)))
+	var cleanup struct {
+		Deleted map[string]int64 `json:"deleted"`
+	}
+	decodeBody(t, rec, &cleanup)
+	if rec.Code != http.StatusOK || cleanup.Deleted["history"] != 2 || cleanup.Deleted["items"] != 1 {
+		t.Fatalf("cleanup: %d %+v", rec.Code, cleanup)
+	}
+	var remaining int64
+	db.Model(&ReturHistory{}).Count(&remaining)
+	if remaining != 2 {
+		t.Fatalf("%d history rows left, want 2 (live and undo-pending)", remaining)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import "net/http"
+
+// statusOverview adalah jumlah retur untuk satu status beserta beberapa retur terbaru
+type statusOverview struct {
+	Count  int64   `json:"count"`
+	Recent []Retur `json:"recent"` // Retur terbaru pada status ini, maksimum RETUR_OVERVIEW_RECENT
+}
+
+// overviewHandler adalah handler untuk tampilan kanban dashboard: jumlah retur per status dan retur terbarunya
+// Dihitung dengan satu query GROUP BY status ditambah satu query kecil (LIMIT) per status yang ada
+func overviewHandler(w http.ResponseWriter, r *http.Request) {
+	var counts []struct {
+		Status string
+		Count  int64
+	}
+	if err := db.WithContext(r.Context()).Model(&Retur{}).Select("status, COUNT(*) AS count").Group("status").Scan(&counts).Error; err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to count returns")
+		return
+	}
+
+	overview := make(map[string]statusOverview, len(counts))
+	for _, row := range counts {
+		recent := []Retur{}
+		if err := db.WithContext(r.Context()).Where("status = ?", row.Status).
+			Order("created_at desc, id desc").Limit(cfg.OverviewRecent).Find(&recent).Error; err != nil {
+			handleError(w, http.StatusInternalServerError, "Failed to retrieve recent returns")
+			return
+		}
+		for i := range recent {
+			applySLA(&recent[i]) // Hitung sla_deadline dan overdue
+		}
+		maskReturs(r, recent)
+		overview[row.Status] = statusOverview{Count: row.Count, Recent: recent}
+	}
+	respondJSON(w, http.StatusOK, overview)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOverviewCountsAndRecent(t *testing.T) {
+	testDB(t)
+	withConfig(t, func(c *Config) { c.OverviewRecent = 3 })
+	base := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	want := map[string]int{"Dalam Proses": 5, "Disetujui": 2, "Tidak Disetujui": 1}
+	newest := map[string]int{}
+	i := 0
+	for status, count := range want {
+		for range count {
+			i++
+			retur := seedReturs(t, Retur{Barang: "Barang", Alasan: "Rusak", Status: status, CreatedAt: base.Add(time.Duration(i) * time.Hour)})[0]
+			newest[status] = retur.ID // Retur yang di-seed terakhir adalah yang terbaru
+		}
+	}
+
+	rec := serve(httptest.NewRequest(http.MethodGet, "/retur/overview", nil))
+	var overview map[string]statusOverview
+	decodeBody(t, rec, &overview)
+	if rec.Code != http.StatusOK || len(overview) != len(want) {
+		t.Fatalf("overview: %d %+v", rec.Code, overview)
+	}
+	for status, count := range want {
+		bucket := overview[status]
+		if bucket.Count != int64(count) {
+			t.Errorf("%s: count %d, want %d", status, bucket.Count, count)
+		}
+		if len(bucket.Recent) != min(count, 3) {
+			t.Errorf("%s: %d recent returns, want %d", status, len(bucket.Recent), min(count, 3))
+		}
+		for j, retur := range bucket.Recent {
+			if retur.Status != status {
+				t.Errorf("%s bucket contains retur %d with status %q", status, retur.ID, retur.Status)
+			}
+			if j > 0 && retur.CreatedAt.After(bucket.Recent[j-1].CreatedAt) {
+				t.Errorf("%s: recent returns not newest first", status)
+			}
+		}
+		if len(bucket.Recent) > 0 && bucket.Recent[0].ID != newest[status] {
+			t.Errorf("%s: first recent retur %d, want newest %d", status, bucket.Recent[0].ID, newest[status])
+		}
+	}
+}
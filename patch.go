@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// mergePatchContentType adalah content type JSON merge-patch (RFC 7396)
+const mergePatchContentType = "application/merge-patch+json"
+
+// patchField menerapkan satu field body PATCH ke retur; null hanya dikirim pada mode merge-patch dan berarti hapus nilai
+type patchField func(retur *Retur, raw json.RawMessage, null bool) error
+
+// patchString membuat patchField untuk field string; required berarti field tidak boleh di-null-kan
+func patchString(required bool, set func(retur *Retur, value string)) patchField {
+	return func(retur *Retur, raw json.RawMessage, null bool) error {
+		if null {
+			if required {
+				return errPatchRequired
+			}
+			set(retur, "")
+			return nil
+		}
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return errPatchType
+		}
+		set(retur, value)
+		return nil
+	}
+}
+
+// errPatchRequired dan errPatchType adalah masalah validasi untuk satu field PATCH; nama field ditambahkan oleh patchReturHandler
+var (
+	errPatchRequired = errors.New("is required and cannot be null")
+	errPatchType     = errors.New("has an invalid type")
+)
+
+// patchableFields adalah field retur yang boleh diubah lewat PATCH; status dan data keputusan diubah lewat endpoint khusus
+var patchableFields = map[string]patchField{
+	"barang": patchString(true, func(retur *Retur, value string) {
+		retur.Barang = value
+		retur.CanonicalBarang = canonicalBarang(value) // Nama kanonik mengikuti Barang yang baru
+	}),
+	"alasan":       patchString(true, func(retur *Retur, value string) { retur.Alasan = value }),
+	"reason_code":  patchString(false, func(retur *Retur, value string) { retur.ReasonCode = value }),
+	"customer_id":  patchString(false, func(retur *Retur, value string) { retur.CustomerID = value }),
+	"pengembalian": patchString(false, func(retur *Retur, value string) { retur.Pengembalian = value }),
+	"assigned_to":  patchString(false, func(retur *Retur, value string) { retur.AssignedTo = value }),
+	"details":      patchDetails,
+}
+
+// openOnlyFields adalah field PATCH yang hanya boleh diubah selama retur masih "Dalam Proses", karena keputusan
+// (metode pengembalian dan details seperti rekening tujuan) sudah dinilai approver berdasarkan nilai tersebut
+var openOnlyFields = map[string]bool{"pengembalian": true, "details": true}
+
+// errPatchStale menandai PATCH yang ditolak karena retur berubah sejak ETag pada If-Match dibaca
+var errPatchStale = errors.New("return was modified concurrently")
+
+// returETag menghitung ETag retur dari field yang tersimpan (tanpa item dan field hasil hitungan seperti SLA dan prioritas)
+// Dikirim oleh GET /retur/{id} dan wajib dikirim kembali lewat If-Match saat PATCH
+func returETag(retur Retur) string {
+	retur.Items = nil
+	retur.SLADeadline, retur.Overdue, retur.Priority = nil, false, 0
+	data, _ := json.Marshal(retur)
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// readOnlyFields adalah field retur yang dikenal tetapi tidak boleh diubah lewat PATCH, sehingga selalu ditolak
+var readOnlyFields = map[string]bool{
+	"id": true, "canonical_barang": true, "status": true, "refund_amount": true, "currency": true, "decided_by": true, "decided_at": true,
+	"decision_note": true, "first_approved_by": true, "order_id": true, "order_date": true, "tags": true, "created_at": true, "items": true, "sla_deadline": true, "overdue": true,
+}
+
+// patchDetails menerapkan details: null menghapus semua details, dan pada mode merge-patch key bernilai null dihapus
+// sedangkan key lain ditimpa (RFC 7396); pada JSON biasa key bernilai null diabaikan
+func patchDetails(retur *Retur, raw json.RawMessage, null bool) error {
+	if null {
+		retur.Details = nil
+		return nil
+	}
+	var incoming map[string]*string
+	if err := json.Unmarshal(raw, &incoming); err != nil {
+		return errPatchType
+	}
+	merged := make(map[string]string, len(retur.Details)+len(incoming))
+	for key, value := range retur.Details {
+		merged[key] = value
+	}
+	for key, value := range incoming {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+		merged[key] = *value
+	}
+	retur.Details = merged // Map baru agar salinan retur sebelum perubahan tidak ikut berubah
+	return nil
+}
+
+// patchReturHandler adalah handler untuk mengubah sebagian field retur
+// Dengan Content-Type application/merge-patch+json (RFC 7396), field bernilai null dikosongkan dan field yang tidak
+// dikirim tidak berubah; dengan application/json, null diperlakukan sama dengan field yang tidak dikirim
+// Field wajib (barang, alasan) tidak boleh di-null-kan dan hasil akhirnya divalidasi dengan aturan yang sama seperti create
+// Header If-Match berisi ETag dari GET /retur/{id} wajib dikirim agar perubahan tidak menimpa update lain (412 jika berbeda)
+func patchReturHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"]) // Convert ID dari string ke integer
+	if err != nil {
+		handleError(w, http.StatusBadRequest, "Invalid ID format") // Jika format ID salah, kirimkan error
+		return
+	}
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		handleError(w, http.StatusPreconditionRequired, "If-Match header is required") // Ambil ETag dari GET /retur/{id}
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	mergePatch := mediaType == mergePatchContentType
+
+	var patch map[string]json.RawMessage
+	if !decodeJSON(w, r, &patch) {
+		return // Body kosong atau JSON tidak valid, error sudah dikirim
+	}
+	if patch == nil {
+		handleError(w, http.StatusBadRequest, "Patch document must be a JSON object")
+		return
+	}
+
+	var retur Retur
+	if err := db.WithContext(r.Context()).Preload("Items").First(&retur, id).Error; err != nil {
+		handleError(w, http.StatusNotFound, "Return not found") // Jika retur tidak ditemukan, kirimkan error
+		return
+	}
+	if !etagMatches(ifMatch, returETag(retur)) {
+		handleError(w, http.StatusPreconditionFailed, "Return was modified; fetch it again") // ETag lama
+		return
+	}
+	before := retur
+
+	names := make([]string, 0, len(patch))
+	for name := range patch {
+		names = append(names, name)
+	}
+	sort.Strings(names) // Urutan error deterministik
+	var errs []fieldError
+	for _, name := range names {
+		raw := patch[name]
+		apply, ok := patchableFields[name]
+		if !ok {
+			if strictJSONFor(r) || readOnlyFields[name] {
+				errs = append(errs, fieldError{Field: name, Message: "cannot be changed with PATCH"})
+			}
+			continue // Field tidak dikenal diabaikan pada mode lenient
+		}
+		if openOnlyFields[name] && retur.Status != "Dalam Proses" {
+			errs = append(errs, fieldError{Field: name, Message: "can only be changed while the return is Dalam Proses"})
+			continue
+		}
+		null := bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+		if null && !mergePatch {
+			continue // JSON biasa: null sama dengan tidak dikirim
+		}
+		if err := apply(&retur, raw, null); err != nil {
+			errs = append(errs, fieldError{Field: name, Message: err.Error()})
+		}
+	}
+	if len(errs) == 0 {
+		errs = validateRetur(&retur)
+	}
+	if len(errs) > 0 {
+		respondValidationErrors(w, errs)
+		return
+	}
+
+	err = withTransaction(r.Context(), func(tx *gorm.DB) error {
+		var current Retur
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, id).Error; err != nil {
+			return err
+		}
+		if returETag(current) != returETag(before) {
+			return errPatchStale // Ada update lain di antara pembacaan dan penyimpanan
+		}
+		if err := tx.Omit("Items").Save(&retur).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, "update", actorFromRequest(r), before, retur)
+	})
+	if isDuplicateKey(err) {
+		handleError(w, http.StatusConflict, "A return for this order_id and barang already exists") // natural_key bentrok karena barang berubah
+		return
+	}
+	if errors.Is(err, errPatchStale) {
+		handleError(w, http.StatusPreconditionFailed, "Return was modified; fetch it again")
+		return
+	}
+	if err != nil {
+		respondSaveError(w, err, "Failed to update return") // 422 jika hasil patch melanggar Validate
+		return
+	}
+	w.Header().Set("ETag", returETag(retur)) // ETag baru untuk PATCH berikutnya
+	applySLA(&retur)
+	publishEvent(ReturUpdated, retur, r)
+	maskRetur(r, &retur)
+	respondJSON(w, http.StatusOK, retur)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPatchRequiresIfMatch(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPatch, "/retur/1", strings.NewReader(`{"alasan":null}`))
+	req.Header.Set("Content-Type", mergePatchContentType)
+	if rec := serve(req); rec.Code != http.StatusPreconditionRequired {
+		t.Fatalf("PATCH without If-Match: %d, want 428", rec.Code)
+	}
+}
+
+func TestPatchAbsentKeepsNullClears(t *testing.T) {
+	testDB(t)
+	seeded := seedReturs(t, Retur{Barang: "Kipas", Alasan: "Mati", CustomerID: "C-1", AssignedTo: "budi",
+		Details: map[string]string{"bank_name": "BCA", "note": "hubungi sore"}})[0]
+	path := fmt.Sprintf("/retur/%d", seeded.ID)
+	get := serve(asAdmin(t, httptest.NewRequest(http.MethodGet, path, nil)))
+	etag := get.Header().Get("ETag")
+	if get.Code != http.StatusOK || etag == "" {
+		t.Fatalf("GET: %d, ETag %q", get.Code, etag)
+	}
+	patch := func(contentType, body string) (int, Retur) {
+		t.Helper()
+		req := asAdmin(t, httptest.NewRequest(http.MethodPatch, path, strings.NewReader(body)))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("If-Match", etag)
+		rec := serve(req)
+		var retur Retur
+		decodeBody(t, rec, &retur)
+		if rec.Code == http.StatusOK {
+			etag = rec.Header().Get("ETag")
+		}
+		return rec.Code, retur
+	}
+
+	// Merge-patch: null mengosongkan, field yang tidak dikirim tetap
+	code, retur := patch(mergePatchContentType, `{"customer_id":null,"alasan":"Baling patah","details":{"note":null}}`)
+	if code != http.StatusOK || retur.CustomerID != "" || retur.Alasan != "Baling patah" || retur.AssignedTo != "budi" ||
+		!maps.Equal(retur.Details, map[string]string{"bank_name": "BCA"}) {
+		t.Fatalf("merge-patch: %d %+v", code, retur)
+	}
+
+	// JSON biasa: null sama dengan tidak dikirim
+	code, retur = patch("application/json", `{"assigned_to":null,"alasan":"Motor mati"}`)
+	if code != http.StatusOK || retur.AssignedTo != "budi" || retur.Alasan != "Motor mati" {
+		t.Fatalf("plain JSON patch: %d %+v", code, retur)
+	}
+
+	// Field wajib tidak boleh di-null-kan
+	req := asAdmin(t, httptest.NewRequest(http.MethodPatch, path, strings.NewReader(`{"barang":null}`)))
+	req.Header.Set("Content-Type", mergePatchContentType)
+	req.Header.Set("If-Match", etag)
+	rec := serve(req)
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), `"field":"barang"`) {
+		t.Fatalf("null barang: %d %s", rec.Code, rec.Body)
+	}
+
+	var stored Retur
+	if err := db.First(&stored, seeded.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Barang != "Kipas" || stored.CustomerID != "" || stored.AssignedTo != "budi" || stored.Alasan != "Motor mati" {
+		t.Fatalf("stored = %+v", stored)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Mode penyamaran field PII (RETUR_PII_MODE)
+const (
+	piiHash = "hash" // Diganti hash stabil sehingga baris milik customer yang sama tetap bisa dikelompokkan
+	piiMask = "mask" // Hanya dua karakter terakhir yang ditampilkan
+	piiOff  = "off"  // Nilai asli, tanpa penyamaran
+)
+
+// validPIIMode memeriksa apakah RETUR_PII_MODE dikenal
+func validPIIMode(mode string) bool {
+	return mode == piiHash || mode == piiMask || mode == piiOff
+}
+
+// redactPII menyamarkan nilai field yang terdaftar di RETUR_PII_FIELDS sesuai RETUR_PII_MODE
+// Dipakai untuk log dan semua response non-admin; field lain dan nilai kosong dikembalikan apa adanya
+func redactPII(field, value string) string {
+	if value == "" || cfg.PIIMode == piiOff || !slices.Contains(cfg.PIIFields, field) {
+		return value
+	}
+	if cfg.PIIMode == piiMask {
+		return maskTail(value, 2)
+	}
+	mac := hmac.New(sha256.New, []byte(cfg.PIIHashKey)) // Tanpa key, hash ID berpola (CUST-001) mudah ditebak dengan enumerasi
+	mac.Write([]byte(value))
+	return "h:" + hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// maskTail mengganti semua karakter dengan "*" kecuali keep karakter terakhir; nilai sependek keep disamarkan seluruhnya
+func maskTail(value string, keep int) string {
+	runes := []rune(value)
+	if len(runes) <= keep {
+		return strings.Repeat("*", len(runes))
+	}
+	return strings.Repeat("*", len(runes)-keep) + string(runes[len(runes)-keep:])
+}
+
+// redactRetur menyamarkan field PII retur untuk setiap serialisasi non-admin (lihat concealRetur), dengan nama field sama seperti kolom CSV
+func redactRetur(retur *Retur) {
+	retur.CustomerID = redactPII("customer_id", retur.CustomerID)
+	retur.DecidedBy = redactPII("decided_by", retur.DecidedBy)
+	retur.FirstApprovedBy = redactPII("first_approved_by", retur.FirstApprovedBy)
+	retur.AssignedTo = redactPII("assigned_to", retur.AssignedTo)
+}
+
+// logActor mengambil actor request untuk dicatat di log, disamarkan jika "actor" terdaftar di RETUR_PII_FIELDS
+func logActor(r *http.Request) string {
+	return redactPII("actor", actorFromRequest(r))
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRedactPIIModes(t *testing.T) {
+	withConfig(t, func(c *Config) {
+		c.PIIFields = []string{"customer_id"}
+		c.PIIHashKey = "test-key"
+	})
+	tests := []struct {
+		mode, field, value, want string
+	}{
+		{piiMask, "customer_id", "CUST-001", "******01"},
+		{piiMask, "customer_id", "ab", "**"},
+		{piiMask, "customer_id", "", ""},
+		{piiMask, "barang", "Sepatu", "Sepatu"}, // Field yang tidak terdaftar tidak disamarkan
+		{piiOff, "customer_id", "CUST-001", "CUST-001"},
+	}
+	for _, tt := range tests {
+		cfg.PIIMode = tt.mode
+		if got := redactPII(tt.field, tt.value); got != tt.want {
+			t.Errorf("%s %s=%q: got %q, want %q", tt.mode, tt.field, tt.value, got, tt.want)
+		}
+	}
+
+	cfg.PIIMode = piiHash
+	first, again, other := redactPII("customer_id", "CUST-001"), redactPII("customer_id", "CUST-001"), redactPII("customer_id", "CUST-002")
+	if !strings.HasPrefix(first, "h:") || first != again || first == other || strings.Contains(first, "CUST") {
+		t.Fatalf("hash: %q %q %q", first, again, other)
+	}
+	cfg.PIIHashKey = "other-key"
+	if redactPII("customer_id", "CUST-001") == first {
+		t.Fatal("hash does not depend on RETUR_PII_HASH_KEY")
+	}
+}
+
+func TestRedactReturMasksOnlyConfiguredFields(t *testing.T) {
+	withConfig(t, func(c *Config) {
+		c.PIIFields = []string{"customer_id", "assigned_to"}
+		c.PIIMode = piiMask
+	})
+	retur := Retur{CustomerID: "CUST-001", AssignedTo: "budi", DecidedBy: "siti", Barang: "Tas"}
+	redactRetur(&retur)
+	if retur.CustomerID != "******01" || retur.AssignedTo != "**di" || retur.DecidedBy != "siti" || retur.Barang != "Tas" {
+		t.Fatalf("redacted = %+v", retur)
+	}
+}
+
+func TestLogEventMasksCustomerID(t *testing.T) {
+	withConfig(t, func(c *Config) {
+		c.PIIFields = []string{"customer_id", "actor"}
+		c.PIIMode = piiMask
+	})
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	logEvent(Event{Type: ReturCreated, Retur: Retur{ID: 7, CustomerID: "CUST-001", Status: "Dalam Proses"}, Actor: "budi"})
+	line := out.String()
+	if strings.Contains(line, "CUST-001") || !strings.Contains(line, "customer=******01") || !strings.Contains(line, "actor=**di") {
+		t.Fatalf("log line %q", line)
+	}
+
+	cfg.PIIFields = nil
+	out.Reset()
+	logEvent(Event{Type: ReturCreated, Retur: Retur{ID: 7, CustomerID: "CUST-001"}, Actor: "budi"})
+	if !strings.Contains(out.String(), "customer=CUST-001") {
+		t.Fatalf("unlisted field masked: %q", out.String())
+	}
+}
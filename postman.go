@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// postmanSampleBodies adalah contoh body request per nama route untuk koleksi Postman
+var postmanSampleBodies = map[string]interface{}{
+	"patchRetur":      map[string]interface{}{"alasan": "layar retak", "assigned_to": nil},
+	"assignReturs":    map[string]interface{}{"ids": []int{1, 2}, "agent": "agent-1"},
+	"createRetur":     map[string]interface{}{"barang": "laptop", "alasan": "rusak", "reason_code": "DAMAGED", "customer_id": "CUST-001", "order_id": "ORD-1001", "order_date": "2024-01-15T10:00:00Z"},
+	"validateRetur":   map[string]interface{}{"barang": "laptop", "alasan": "rusak"},
+	"approveRetur":    map[string]interface{}{"pengembalian": "uang", "refund_amount": 15000000, "currency": "IDR", "note": "sesuai kebijakan", "details": map[string]string{"bank_name": "BCA", "account_number": "1234567890"}},
+	"bulkApprove":     map[string]interface{}{"ids": []int{1, 2, 3}, "pengembalian": "barang", "note": "sesuai kebijakan"},
+	"addReturTags":    map[string]interface{}{"tags": []string{"fraud-review"}},
+	"bulkTag":         map[string]interface{}{"ids": []int{1, 2, 3}, "tags": []string{"vip-customer"}},
+	"disapproveRetur": map[string]interface{}{"note": "barang tidak sesuai"},
+	"approveItem":     map[string]interface{}{"pengembalian": "barang"},
+	"batchGetRetur":   map[string]interface{}{"ids": []int{1, 2, 3}},
+
+	"createReason":        map[string]interface{}{"code": "WRONG_SIZE", "label": "Ukuran tidak sesuai"},
+	"updateReason":        map[string]interface{}{"active": false},
+	"createWebhook":       map[string]interface{}{"url": "https://example.com/hooks/retur", "secret": "change-me", "events": []string{"retur.approved"}},
+	"backfillReasonCodes": map[string]interface{}{"mapping": map[string]string{"rusak": "DAMAGED", "salah kirim": "WRONG_ITEM"}},
+}
+
+// postmanAdminRoutes adalah route di luar /retur/admin yang juga membutuhkan token admin
+// Route keputusan (requireApprover) juga menerima token admin, sehingga ikut dikirim dengan header yang sama
+var postmanAdminRoutes = map[string]bool{"resetDecision": true, "returAudit": true, "renotifyRetur": true, "status": true, "config": true,
+	"approveRetur": true, "bulkApprove": true, "disapproveRetur": true, "approveItem": true, "disapproveItem": true,
+	"bulkDeleteRetur": true, "uploadAttachment": true, "createExportJob": true, "listExportJobs": true, "listWebhooks": true}
+
+// pathVariablePattern mencocokkan variabel path mux seperti {id} atau {id:[0-9]+}
+var pathVariablePattern = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// postmanHandler membuat handler yang menghasilkan koleksi Postman v2.1 dari tabel route router
+// Koleksi selalu mengikuti route yang terdaftar karena dibangun dengan menelusuri router saat diminta
+func postmanHandler(router *mux.Router) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var items []map[string]interface{}
+		router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+			template, err := route.GetPathTemplate()
+			if err != nil {
+				return nil
+			}
+			methods, err := route.GetMethods()
+			if err != nil {
+				return nil // Prefix subrouter tidak memiliki method, lewati
+			}
+			name := route.GetName()
+
+			var variables []map[string]string
+			path := pathVariablePattern.ReplaceAllStringFunc(template, func(match string) string {
+				key := pathVariablePattern.FindStringSubmatch(match)[1]
+				variables = append(variables, map[string]string{"key": key, "value": "1"})
+				return ":" + key // Format variabel path Postman
+			})
+			headers := []map[string]string{}
+			if strings.HasPrefix(template, cfg.BasePath+"/retur/admin") || postmanAdminRoutes[name] {
+				headers = append(headers, map[string]string{"key": "Authorization", "value": "Bearer {{adminToken}}"})
+			}
+			if name == "patchRetur" {
+				headers = append(headers, map[string]string{"key": "If-Match", "value": "{{returETag}}"}) // ETag dari GET /retur/{id}
+			}
+
+			for _, method := range methods {
+				request := map[string]interface{}{
+					"method": method,
+					"header": headers,
+					"url": map[string]interface{}{
+						"raw":      "{{baseUrl}}" + path,
+						"host":     []string{"{{baseUrl}}"},
+						"path":     strings.Split(strings.TrimPrefix(path, "/"), "/"),
+						"variable": variables,
+					},
+				}
+				if sample, ok := postmanSampleBodies[name]; ok && method != http.MethodGet {
+					body, _ := json.MarshalIndent(sample, "", "  ")
+					request["header"] = append(headers, map[string]string{"key": "Content-Type", "value": "application/json"})
+					request["body"] = map[string]interface{}{
+						"mode":    "raw",
+						"raw":     string(body),
+						"options": map[string]interface{}{"raw": map[string]string{"language": "json"}},
+					}
+				}
+				items = append(items, map[string]interface{}{"name": method + " " + template + " (" + name + ")", "request": request})
+			}
+			return nil
+		})
+
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		respondJSON(w, http.StatusOK, map[string]interface{}{
+			"info": map[string]string{
+				"name":   "Retur API",
+				"schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json",
+			},
+			"item": items,
+			"variable": []map[string]string{
+				{"key": "baseUrl", "value": scheme + "://" + r.Host}, // Host yang dipakai client saat mengunduh koleksi
+				{"key": "adminToken", "value": ""},
+			},
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm/clause"
+)
+
+// priorityWeights adalah bobot skor prioritas antrean kerja (RETUR_PRIORITY_WEIGHTS)
+//
+// Skor retur yang masih terbuka ("Dalam Proses" atau menunggu persetujuan kedua) dihitung sebagai:
+//
+//	priority = age * jam sejak created_at
+//	         + refund * refund_amount (minor units)
+//	         + reason * severity reason_code (RETUR_REASON_SEVERITY, default 0)
+//	         + tier * tier customer_id (RETUR_CUSTOMER_TIERS, default 0)
+//
+// Retur yang sudah diputuskan selalu memiliki skor 0. Skor tidak disimpan; ?sort=priority menghitung rumus yang sama di SQL
+type priorityWeights struct {
+	Age    float64 // Bobot per jam umur retur
+	Refund float64 // Bobot per satuan terkecil refund_amount
+	Reason float64 // Bobot per tingkat severity reason_code
+	Tier   float64 // Bobot per tingkat tier customer
+}
+
+// parsePriorityWeights membaca daftar "age=1,refund=0.001,reason=10,tier=10"; bobot yang tidak disebut bernilai 0
+func parsePriorityWeights(raw string) priorityWeights {
+	var weights priorityWeights
+	for _, item := range strings.Split(raw, ",") {
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			continue
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			log.Printf("ignoring priority weight %q: %v", item, err)
+			continue
+		}
+		switch strings.TrimSpace(name) {
+		case "age":
+			weights.Age = weight
+		case "refund":
+			weights.Refund = weight
+		case "reason":
+			weights.Reason = weight
+		case "tier":
+			weights.Tier = weight
+		default:
+			log.Printf("ignoring unknown priority weight %q", name)
+		}
+	}
+	return weights
+}
+
+// openStatuses adalah status retur yang masih berada di antrean kerja sehingga memiliki skor prioritas
+var openStatuses = []string{"Dalam Proses", statusAwaitingSecondApproval}
+
+// priorityScore menghitung skor prioritas retur sesuai rumus pada priorityWeights
+func priorityScore(retur Retur) float64 {
+	if retur.Status != "Dalam Proses" && retur.Status != statusAwaitingSecondApproval {
+		return 0
+	}
+	w := cfg.PriorityWeights
+	score := w.Refund*float64(retur.RefundAmount) +
+		w.Reason*float64(cfg.ReasonSeverity[retur.ReasonCode]) +
+		w.Tier*float64(cfg.CustomerTiers[retur.CustomerID])
+	if !retur.CreatedAt.IsZero() {
+		score += w.Age * clock.Now().Sub(retur.CreatedAt).Hours()
+	}
+	return math.Round(score*100) / 100 // Dua desimal cukup untuk ditampilkan
+}
+
+// applyPriority mengisi priority pada retur seperti applySLA untuk sla_deadline
+func applyPriority(retur *Retur) {
+	retur.Priority = priorityScore(*retur)
+}
+
+// priorityOrder membuat ORDER BY untuk ?sort=priority dengan rumus yang sama seperti priorityScore
+// "-priority" (descending) mengurutkan skor tertinggi lebih dulu (urutan antrean kerja), "priority" sebaliknya; id menjadi tie-breaker
+func priorityOrder(descending bool) clause.OrderBy {
+	w := cfg.PriorityWeights
+	var sql strings.Builder
+	vars := []interface{}{openStatuses, w.Age, clock.Now(), w.Refund}
+	sql.WriteString("CASE WHEN status IN ? THEN ? * TIMESTAMPDIFF(SECOND, created_at, ?) / 3600 + ? * refund_amount")
+	for _, lookup := range []struct {
+		column string
+		weight float64
+		values map[string]int
+	}{{"reason_code", w.Reason, cfg.ReasonSeverity}, {"customer_id", w.Tier, cfg.CustomerTiers}} {
+		if lookup.weight == 0 || len(lookup.values) == 0 {
+			continue
+		}
+		keys := make([]string, 0, len(lookup.values))
+		for key := range lookup.values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys) // Urutan tetap agar query yang sama bisa memakai ulang prepared statement
+		sql.WriteString(" + ? * (CASE " + lookup.column)
+		vars = append(vars, lookup.weight)
+		for _, key := range keys {
+			sql.WriteString(" WHEN ? THEN ?")
+			vars = append(vars, key, lookup.values[key])
+		}
+		sql.WriteString(" ELSE 0 END)")
+	}
+	sql.WriteString(" ELSE 0 END")
+	direction := " asc"
+	if descending {
+		direction = " desc"
+	}
+	sql.WriteString(direction + ", id" + direction)
+	return clause.OrderBy{Expression: clause.Expr{SQL: sql.String(), Vars: vars}}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+func TestParsePriorityWeights(t *testing.T) {
+	got := parsePriorityWeights(" age = 2,refund=0.5,reason=x,unknown=1,tier")
+	if got != (priorityWeights{Age: 2, Refund: 0.5}) {
+		t.Fatalf("weights = %+v", got)
+	}
+}
+
+func TestPriorityScore(t *testing.T) {
+	fake := useFakeClock(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+	withConfig(t, func(c *Config) {
+		c.PriorityWeights = priorityWeights{Age: 1, Refund: 0.001, Reason: 10, Tier: 5}
+		c.ReasonSeverity = map[string]int{"DAMAGED": 3}
+		c.CustomerTiers = map[string]int{"CUST-VIP": 2}
+	})
+	retur := Retur{Status: "Dalam Proses", CreatedAt: fake.Now().Add(-12 * time.Hour), RefundAmount: 5000, ReasonCode: "DAMAGED", CustomerID: "CUST-VIP"}
+	if got := priorityScore(retur); got != 12+5+30+10 {
+		t.Fatalf("score = %v, want 57", got)
+	}
+	retur.Status = statusAwaitingSecondApproval
+	if got := priorityScore(retur); got != 57 {
+		t.Fatalf("awaiting second approval score = %v", got)
+	}
+	retur.Status = "Disetujui"
+	if got := priorityScore(retur); got != 0 {
+		t.Fatalf("decided score = %v, want 0", got)
+	}
+}
+
+func TestPriorityOrderSkipsUnusedLookups(t *testing.T) {
+	withConfig(t, func(c *Config) {
+		c.PriorityWeights = priorityWeights{Age: 1, Reason: 10}
+		c.ReasonSeverity = map[string]int{"WRONG_ITEM": 1, "DAMAGED": 3}
+		c.CustomerTiers = map[string]int{"CUST-VIP": 2} // Bobot tier 0, lookup tidak ditulis
+	})
+	expr := priorityOrder(true).Expression.(clause.Expr)
+	if !strings.Contains(expr.SQL, "(CASE reason_code WHEN ? THEN ? WHEN ? THEN ? ELSE 0 END)") || strings.Contains(expr.SQL, "customer_id") {
+		t.Fatalf("sql = %s", expr.SQL)
+	}
+	if !strings.HasSuffix(expr.SQL, " desc, id desc") {
+		t.Fatalf("sql = %s", expr.SQL)
+	}
+	// Kode diurutkan agar query yang sama selalu menghasilkan SQL dan argumen yang sama
+	if n := len(expr.Vars); n < 4 || expr.Vars[n-4] != "DAMAGED" || expr.Vars[n-2] != "WRONG_ITEM" {
+		t.Fatalf("vars = %v", expr.Vars)
+	}
+	if asc := priorityOrder(false).Expression.(clause.Expr); !strings.HasSuffix(asc.SQL, " asc, id asc") {
+		t.Fatalf("ascending sql = %s", asc.SQL)
+	}
+}
+
+func TestListSortsByPriority(t *testing.T) {
+	testDB(t)
+	now := clock.Now()
+	withConfig(t, func(c *Config) {
+		c.PriorityWeights = priorityWeights{Age: 1, Refund: 0.001, Reason: 10}
+		c.ReasonSeverity = map[string]int{"DAMAGED": 3}
+	})
+	seeded := seedReturs(t,
+		Retur{Barang: "Baru", Alasan: "x", CreatedAt: now.Add(-time.Hour)},                              // 1
+		Retur{Barang: "Lama", Alasan: "x", CreatedAt: now.Add(-48 * time.Hour)},                         // 48
+		Retur{Barang: "Rusak", Alasan: "x", CreatedAt: now.Add(-2 * time.Hour), ReasonCode: "DAMAGED"},  // 32
+		Retur{Barang: "Mahal", Alasan: "x", CreatedAt: now.Add(-time.Hour), RefundAmount: 20000},        // 21
+		Retur{Barang: "Selesai", Alasan: "x", CreatedAt: now.Add(-72 * time.Hour), Status: "Disetujui"}, // 0
+	)
+	want := []int{seeded[1].ID, seeded[2].ID, seeded[3].ID, seeded[0].ID, seeded[4].ID}
+
+	rec := serve(httptest.NewRequest(http.MethodGet, "/retur?sort=-priority", nil))
+	var got []Retur
+	decodeBody(t, rec, &got)
+	var ids []int
+	for _, retur := range got {
+		ids = append(ids, retur.ID)
+	}
+	if rec.Code != http.StatusOK || !slices.Equal(ids, want) {
+		t.Fatalf("order = %v, want %v", ids, want)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i].Priority > got[i-1].Priority {
+			t.Fatalf("priority field %v after %v", got[i].Priority, got[i-1].Priority)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// problemContentType adalah content type untuk error format RFC 7807 (problem details)
+const problemContentType = "application/problem+json"
+
+// problemDetails adalah error dalam format RFC 7807; Errors berisi masalah per field untuk error validasi
+type problemDetails struct {
+	Type   string       `json:"type"`   // URI jenis masalah, "about:blank" karena aplikasi belum mendefinisikan jenis khusus
+	Title  string       `json:"title"`  // Ringkasan sesuai status HTTP
+	Status int          `json:"status"` // Status HTTP yang sama dengan response
+	Detail string       `json:"detail"` // Penjelasan masalah untuk request ini
+	Errors []fieldError `json:"errors,omitempty"`
+}
+
+// acceptsMediaType memeriksa apakah header Accept request memuat mediaType
+func acceptsMediaType(r *http.Request, mediaType string) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if parsed, _, err := mime.ParseMediaType(strings.TrimSpace(accept)); err == nil && parsed == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// wantsProblemJSON memeriksa apakah error untuk request ini dikirim dalam format RFC 7807
+// (header Accept: application/problem+json atau RETUR_PROBLEM_JSON=true)
+func wantsProblemJSON(w http.ResponseWriter) bool {
+	opts := responseOptionsFrom(w)
+	return opts != nil && opts.problem
+}
+
+// respondProblem mengirimkan error dalam format RFC 7807 dengan content type application/problem+json
+// Nama member mengikuti standar sehingga profil penamaan (X-Field-Naming) tidak diterapkan
+func respondProblem(w http.ResponseWriter, status int, detail string, errs []fieldError) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	if wantsPrettyJSON(w) {
+		encoder.SetIndent("", "  ")
+	}
+	encoder.Encode(problemDetails{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Errors: errs,
+	})
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultReasons adalah kode alasan awal yang dimasukkan ke tabel reasons jika tabel masih kosong
+// Kode alasan disimpan di kolom reason_code agar laporan tidak bergantung pada teks bebas Alasan
+var defaultReasons = map[string]string{
+	"DAMAGED":          "Barang rusak atau cacat",
+	"WRONG_ITEM":       "Barang yang dikirim salah",
+	"NOT_AS_DESCRIBED": "Barang tidak sesuai deskripsi",
+	"CHANGED_MIND":     "Customer berubah pikiran",
+	"LATE_DELIVERY":    "Pengiriman terlambat",
+	"OTHER":            "Alasan lain",
+}
+
+// validReasonCode memeriksa apakah kode alasan terdaftar dan aktif di tabel reasons
+func validReasonCode(code string) bool {
+	return activeReasons.Has(code)
+}
+
+// reasonKeyword adalah satu aturan pemetaan kata kunci Alasan ke kode alasan
+type reasonKeyword struct {
+	Keyword string
+	Code    string
+}
+
+// sortedReasonKeywords mengurutkan pemetaan kata kunci: kata kunci terpanjang dicocokkan lebih dulu agar hasilnya deterministik
+func sortedReasonKeywords(mapping map[string]string) []reasonKeyword {
+	keywords := make([]reasonKeyword, 0, len(mapping))
+	for keyword, code := range mapping {
+		keywords = append(keywords, reasonKeyword{Keyword: strings.ToLower(keyword), Code: code})
+	}
+	sort.Slice(keywords, func(i, j int) bool {
+		if len(keywords[i].Keyword) != len(keywords[j].Keyword) {
+			return len(keywords[i].Keyword) > len(keywords[j].Keyword)
+		}
+		return keywords[i].Keyword < keywords[j].Keyword
+	})
+	return keywords
+}
+
+// matchReasonCode mencari kode alasan pertama yang kata kuncinya terdapat di Alasan (tidak peka huruf besar/kecil)
+func matchReasonCode(alasan string, keywords []reasonKeyword) (string, bool) {
+	alasan = strings.ToLower(alasan)
+	for _, k := range keywords {
+		if strings.Contains(alasan, k.Keyword) {
+			return k.Code, true
+		}
+	}
+	return "", false
+}
+
+// backfillReasonCodesHandler adalah handler admin untuk mengisi reason_code retur lama berdasarkan kata kunci di Alasan
+// Hanya retur dengan reason_code kosong yang diubah; setiap batch diproses dalam satu transaksi
+func backfillReasonCodesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Mapping   map[string]string `json:"mapping"`    // Kata kunci ke kode alasan, misalnya {"rusak": "DAMAGED"}
+		BatchSize int               `json:"batch_size"` // Jumlah retur per transaksi, default RETUR_BACKFILL_BATCH_SIZE
+	}
+	if !decodeJSON(w, r, &input) {
+		return // Body kosong atau JSON tidak valid, error sudah dikirim
+	}
+	if len(input.Mapping) == 0 {
+		handleError(w, http.StatusBadRequest, "Mapping is required")
+		return
+	}
+	for keyword, code := range input.Mapping {
+		if strings.TrimSpace(keyword) == "" {
+			handleError(w, http.StatusBadRequest, "Mapping keywords must not be empty")
+			return
+		}
+		if !validReasonCode(code) {
+			handleError(w, http.StatusBadRequest, "Unknown reason code: "+code)
+			return
+		}
+	}
+	batchSize := input.BatchSize
+	if batchSize <= 0 {
+		batchSize = cfg.BackfillBatchSize
+	}
+
+	keywords := sortedReasonKeywords(input.Mapping)
+	assigned := make(map[string]int)
+	scanned, lastID := 0, 0
+	for {
+		var batch []Retur
+		if err := db.WithContext(r.Context()).Select("id", "alasan").
+			Where("id > ? AND (reason_code = '' OR reason_code IS NULL)", lastID).
+			Order("id").Limit(batchSize).Find(&batch).Error; err != nil {
+			handleError(w, http.StatusInternalServerError, "Failed to scan returns")
+			return
+		}
+		if len(batch) == 0 {
+			break
+		}
+		lastID = batch[len(batch)-1].ID // Lanjutkan dari ID terakhir agar retur yang tidak cocok tidak dipindai ulang
+		scanned += len(batch)
+
+		matched := make(map[string][]int) // Kode ke daftar ID retur yang cocok di batch ini
+		for _, retur := range batch {
+			if code, ok := matchReasonCode(retur.Alasan, keywords); ok {
+				matched[code] = append(matched[code], retur.ID)
+			}
+		}
+		updated := make(map[string]int) // Jumlah retur yang benar-benar diubah per kode di batch ini
+		err := withTransaction(r.Context(), func(tx *gorm.DB) error {
+			for code, ids := range matched {
+				var returs []Retur // Baca ulang baris lengkap agar perubahan tercatat di audit log (dan /retur/changes)
+				if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+					Where("id IN ? AND (reason_code = '' OR reason_code IS NULL)", ids).Find(&returs).Error; err != nil {
+					return err
+				}
+				for _, retur := range returs {
+					before := retur
+					retur.ReasonCode = code
+					if err := tx.Model(&retur).Update("reason_code", code).Error; err != nil {
+						return err
+					}
+					if err := recordAudit(tx, "update", actorFromRequest(r), before, retur); err != nil {
+						return err
+					}
+				}
+				updated[code] = len(returs)
+			}
+			return nil
+		})
+		if err != nil {
+			handleError(w, http.StatusInternalServerError, "Failed to assign reason codes")
+			return
+		}
+		for code, count := range updated {
+			assigned[code] += count
+		}
+	}
+
+	total := 0
+	for _, count := range assigned {
+		total += count
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"scanned":   scanned,
+		"assigned":  assigned, // Jumlah retur yang diberi kode, per kode alasan
+		"unmatched": scanned - total,
+	})
+}
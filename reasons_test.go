@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMatchReasonCodePrefersLongestKeyword(t *testing.T) {
+	keywords := sortedReasonKeywords(map[string]string{"rusak": "DAMAGED", "salah": "WRONG_ITEM", "salah kirim": "WRONG_ITEM", "kemasan rusak": "OTHER"})
+	tests := []struct {
+		alasan, want string
+		ok           bool
+	}{
+		{"Layar RUSAK parah", "DAMAGED", true},     // Tidak peka huruf besar/kecil
+		{"Kemasan rusak saat tiba", "OTHER", true}, // Kata kunci terpanjang menang atas "rusak"
+		{"salah kirim warna", "WRONG_ITEM", true},
+		{"tidak suka", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := matchReasonCode(tt.alasan, keywords); got != tt.want || ok != tt.ok {
+			t.Errorf("matchReasonCode(%q) = %q, %t, want %q, %t", tt.alasan, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestBackfillReasonCodes(t *testing.T) {
+	testDB(t)
+	if err := seedReasons(); err != nil {
+		t.Fatal(err)
+	}
+	if err := activeReasons.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	seeded := seedReturs(t,
+		Retur{Barang: "HP", Alasan: "Layar rusak"},
+		Retur{Barang: "Kaos", Alasan: "salah kirim ukuran"},
+		Retur{Barang: "Sepatu", Alasan: "tidak suka modelnya"},
+		Retur{Barang: "Tas", Alasan: "resleting rusak"},
+		Retur{Barang: "Jam", Alasan: "rusak", ReasonCode: "OTHER"}, // Sudah punya kode, tidak boleh ditimpa
+	)
+
+	payload := map[string]interface{}{"mapping": map[string]string{"rusak": "DAMAGED", "salah kirim": "WRONG_ITEM"}, "batch_size": 2}
+	rec := serve(asAdmin(t, jsonRequest(t, http.MethodPost, "/retur/admin/backfill-reason-codes", payload)))
+	var body struct {
+		Scanned   int            `json:"scanned"`
+		Assigned  map[string]int `json:"assigned"`
+		Unmatched int            `json:"unmatched"`
+	}
+	decodeBody(t, rec, &body)
+	if rec.Code != http.StatusOK || body.Scanned != 4 || body.Assigned["DAMAGED"] != 2 || body.Assigned["WRONG_ITEM"] != 1 || body.Unmatched != 1 {
+		t.Fatalf("backfill: %d %+v", rec.Code, body)
+	}
+	want := []string{"DAMAGED", "WRONG_ITEM", "", "DAMAGED", "OTHER"}
+	for i, retur := range seeded {
+		var stored Retur
+		if err := db.First(&stored, retur.ID).Error; err != nil || stored.ReasonCode != want[i] {
+			t.Errorf("retur %q: reason_code %q (err %v), want %q", retur.Alasan, stored.ReasonCode, err, want[i])
+		}
+	}
+
+	payload["mapping"] = map[string]string{"rusak": "NOPE"}
+	if rec := serve(asAdmin(t, jsonRequest(t, http.MethodPost, "/retur/admin/backfill-reason-codes", payload))); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown code: %d, want 400", rec.Code)
+	}
+}
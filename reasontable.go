@@ -0,0 +1,192 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Reason adalah satu kode alasan retur yang bisa dikelola admin tanpa redeploy
+type Reason struct {
+	Code      string    `json:"code" gorm:"primaryKey;size:50"` // Kode yang disimpan di Retur.ReasonCode, misalnya DAMAGED
+	Label     string    `json:"label"`                          // Penjelasan kode untuk ditampilkan
+	Active    bool      `json:"active"`                         // Kode nonaktif tidak bisa dipakai retur baru
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// reasonCodePattern adalah format kode alasan: huruf besar, angka, dan garis bawah
+var reasonCodePattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]{0,49}$`)
+
+// reasonRegistry menyimpan kode alasan aktif di memori agar validasi retur tidak perlu query database
+type reasonRegistry struct {
+	mu    sync.RWMutex
+	codes map[string]bool
+}
+
+// activeReasons adalah daftar kode alasan aktif
+var activeReasons = &reasonRegistry{codes: map[string]bool{}}
+
+// Has memeriksa apakah kode alasan aktif
+func (reg *reasonRegistry) Has(code string) bool {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	return reg.codes[code]
+}
+
+// Reload memuat ulang kode alasan aktif dari database; dipanggil saat startup dan setiap kali tabel reasons berubah
+func (reg *reasonRegistry) Reload() error {
+	var codes []string
+	if err := db.Model(&Reason{}).Where("active = ?", true).Pluck("code", &codes).Error; err != nil {
+		return err
+	}
+	active := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		active[code] = true
+	}
+	reg.mu.Lock()
+	reg.codes = active
+	reg.mu.Unlock()
+	return nil
+}
+
+// seedReasons mengisi tabel reasons dengan defaultReasons jika tabel masih kosong
+func seedReasons() error {
+	var count int64
+	if err := db.Model(&Reason{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil // Tabel sudah dikelola admin, jangan ditimpa
+	}
+	reasons := make([]Reason, 0, len(defaultReasons))
+	for code, label := range defaultReasons {
+		reasons = append(reasons, Reason{Code: code, Label: label, Active: true})
+	}
+	return db.Create(&reasons).Error
+}
+
+// listReasonsHandler adalah handler untuk melihat semua kode alasan; ?active=true hanya menampilkan yang aktif
+func listReasonsHandler(w http.ResponseWriter, r *http.Request) {
+	query := db.Order("code")
+	if r.URL.Query().Get("active") == "true" {
+		query = query.Where("active = ?", true)
+	}
+	var reasons []Reason
+	if err := query.Find(&reasons).Error; err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to list reasons")
+		return
+	}
+	respondJSON(w, http.StatusOK, reasons)
+}
+
+// createReasonHandler adalah handler admin untuk menambahkan kode alasan baru
+func createReasonHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Code   string `json:"code"`
+		Label  string `json:"label"`
+		Active *bool  `json:"active"` // Default true
+	}
+	if !decodeJSON(w, r, &input) {
+		return // Body kosong atau JSON tidak valid, error sudah dikirim
+	}
+	input.Code = strings.TrimSpace(input.Code)
+	var errs []fieldError
+	if !reasonCodePattern.MatchString(input.Code) {
+		errs = append(errs, fieldError{Field: "code", Message: "must be uppercase letters, digits or underscores"})
+	}
+	if strings.TrimSpace(input.Label) == "" {
+		errs = append(errs, fieldError{Field: "label", Message: "is required"})
+	}
+	if len(errs) > 0 {
+		respondValidationErrors(w, errs)
+		return
+	}
+
+	var existing int64
+	db.Model(&Reason{}).Where("code = ?", input.Code).Count(&existing)
+	if existing > 0 {
+		handleError(w, http.StatusConflict, "Reason code already exists")
+		return
+	}
+	reason := Reason{Code: input.Code, Label: strings.TrimSpace(input.Label), Active: input.Active == nil || *input.Active}
+	if err := db.Create(&reason).Error; err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to create reason")
+		return
+	}
+	if err := activeReasons.Reload(); err != nil {
+		handleError(w, http.StatusInternalServerError, "Reason saved but failed to reload reasons")
+		return
+	}
+	w.Header().Set("Location", urlFor("/retur/admin/reasons/"+reason.Code))
+	respondJSON(w, http.StatusCreated, reason)
+}
+
+// updateReasonHandler adalah handler admin untuk mengubah label atau mengaktifkan/menonaktifkan kode alasan
+// Retur lama yang memakai kode nonaktif tidak diubah; hanya retur baru yang ditolak
+func updateReasonHandler(w http.ResponseWriter, r *http.Request) {
+	var reason Reason
+	if err := db.First(&reason, "code = ?", mux.Vars(r)["code"]).Error; err != nil {
+		handleError(w, http.StatusNotFound, "Reason not found")
+		return
+	}
+	var input struct {
+		Label  *string `json:"label"`
+		Active *bool   `json:"active"`
+	}
+	if !decodeJSON(w, r, &input) {
+		return // Body kosong atau JSON tidak valid, error sudah dikirim
+	}
+	if input.Label != nil {
+		if strings.TrimSpace(*input.Label) == "" {
+			respondValidationErrors(w, []fieldError{{Field: "label", Message: "is required"}})
+			return
+		}
+		reason.Label = strings.TrimSpace(*input.Label)
+	}
+	if input.Active != nil {
+		reason.Active = *input.Active
+	}
+	if err := db.Save(&reason).Error; err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to update reason")
+		return
+	}
+	if err := activeReasons.Reload(); err != nil {
+		handleError(w, http.StatusInternalServerError, "Reason saved but failed to reload reasons")
+		return
+	}
+	respondJSON(w, http.StatusOK, reason)
+}
+
+// deleteReasonHandler adalah handler admin untuk menghapus kode alasan yang belum pernah dipakai retur
+// Kode yang sudah dipakai hanya bisa dinonaktifkan agar data lama tetap bermakna
+func deleteReasonHandler(w http.ResponseWriter, r *http.Request) {
+	code := mux.Vars(r)["code"]
+	var used int64
+	if err := db.Model(&Retur{}).Where("reason_code = ?", code).Count(&used).Error; err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to check reason usage")
+		return
+	}
+	if used > 0 {
+		handleError(w, http.StatusConflict, "Reason code is used by existing returns; deactivate it instead")
+		return
+	}
+	result := db.Delete(&Reason{}, "code = ?", code)
+	if result.Error != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to delete reason")
+		return
+	}
+	if result.RowsAffected == 0 {
+		handleError(w, http.StatusNotFound, "Reason not found")
+		return
+	}
+	if err := activeReasons.Reload(); err != nil {
+		handleError(w, http.StatusInternalServerError, "Reason deleted but failed to reload reasons")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// reconcileUndoEntry membuang retur yang ID-nya ada di occupied dari satu entry undo
+// keep bernilai false jika tidak ada retur yang tersisa sehingga entry harus dibuang seluruhnya
+func reconcileUndoEntry(entry undoEntry, occupied map[int]bool) (result undoEntry, dropped []Retur, keep bool) {
+	kept := make([]Retur, 0, len(entry.Returs))
+	for _, retur := range entry.Returs {
+		if occupied[retur.ID] {
+			dropped = append(dropped, retur)
+			continue
+		}
+		kept = append(kept, retur)
+	}
+	entry.Returs = kept
+	return entry, dropped, len(kept) > 0
+}
+
+// reconcileUndoStack membuang retur di stack undo yang ID-nya kembali dimiliki retur aktif (misalnya setelah edit manual
+// di database), karena undo untuk retur tersebut akan gagal dengan duplicate key atau menimpa data lain
+// Entry yang kosong setelah dibersihkan dibuang seluruhnya; perubahan undo_entries ditulis di dalam transaksi tx,
+// sedangkan stack di memori baru diubah oleh applyReconcile setelah transaksi berhasil
+// Pemanggil wajib memegang idMu agar pool dan stack tidak berubah di tengah rekonsiliasi
+// Retur aktif yang ID-nya bentrok dibuat di luar aplikasi (tanpa audit log), sehingga dicatat dengan action "reconcile"
+// agar ikut muncul di /retur/changes
+func reconcileUndoStack(tx *gorm.DB, actor string) (occupied map[int]bool, dropped []int, err error) {
+	occupied, err = occupiedPoolIDs(tx, append(undoPendingIDs(), deletedIDs...))
+	dropped = []int{}
+	if err != nil || len(occupied) == 0 {
+		return occupied, dropped, err
+	}
+	var removed []undoEntry
+	for _, entry := range deletedStack.Items() {
+		result, droppedReturs, keep := reconcileUndoEntry(entry, occupied)
+		if len(droppedReturs) == 0 {
+			continue
+		}
+		for _, retur := range droppedReturs {
+			dropped = append(dropped, retur.ID)
+		}
+		if !keep {
+			removed = append(removed, entry)
+			continue
+		}
+		if entry.RecordID == 0 {
+			continue
+		}
+		if err := tx.Model(&UndoRecord{ID: entry.RecordID}).Select("Returs").Updates(&UndoRecord{Returs: result.Returs}).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := deleteUndoRecords(tx, removed...); err != nil {
+		return nil, nil, err
+	}
+	var live []Retur
+	if err := tx.Where("id IN ?", dropped).Find(&live).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, retur := range live {
+		if err := recordAudit(tx, "reconcile", actor, Retur{}, retur); err != nil {
+			return nil, nil, err
+		}
+	}
+	sort.Ints(dropped)
+	return occupied, dropped, nil
+}
+
+// applyReconcile menerapkan hasil reconcileUndoStack ke stack undo dan pool deletedIDs di memori
+// Pemanggil wajib memegang idMu; mengembalikan ID pool yang dibuang
+func applyReconcile(occupied map[int]bool) []int {
+	deletedStack.UpdateFunc(func(entry undoEntry) (undoEntry, bool) {
+		result, _, keep := reconcileUndoEntry(entry, occupied)
+		return result, keep
+	})
+	return compactDeletedIDsLocked(occupied)
+}
+
+// reconcileHandler adalah handler admin untuk menyelaraskan stack undo dan pool deletedIDs dengan isi database
+// setelah intervensi manual: retur undo yang ID-nya sudah aktif dibuang, dan ID pool yang sudah dipakai atau duplikat dihapus
+func reconcileHandler(w http.ResponseWriter, r *http.Request) {
+	stackBefore, poolBefore := deletedStack.Len(), deletedIDCount()
+	idMu.Lock() // Delete, restore, dan create tidak boleh mengubah pool selama rekonsiliasi
+	var occupied map[int]bool
+	var undoDropped, poolDropped []int
+	err := withTransaction(r.Context(), func(tx *gorm.DB) error {
+		var err error
+		occupied, undoDropped, err = reconcileUndoStack(tx, actorFromRequest(r))
+		return err
+	})
+	if err == nil {
+		poolDropped = applyReconcile(occupied) // Memori baru diubah setelah undo_entries tersimpan
+	}
+	idMu.Unlock()
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to reconcile undo stack")
+		return
+	}
+	if len(undoDropped) > 0 || len(poolDropped) > 0 {
+		log.Printf("reconcile: dropped %d undo returns and %d pool IDs", len(undoDropped), len(poolDropped))
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"undo": map[string]interface{}{
+			"entries_before":  stackBefore,
+			"entries_after":   deletedStack.Len(),
+			"dropped_returns": undoDropped, // ID retur yang tidak lagi bisa di-undo karena sudah aktif
+		},
+		"id_pool": map[string]interface{}{
+			"before":  poolBefore,
+			"after":   deletedIDCount(),
+			"dropped": poolDropped, // ID yang sudah dipakai retur aktif atau tercatat dua kali
+		},
+	})
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestReconcileUndoEntry(t *testing.T) {
+	entry := undoEntry{Returs: []Retur{{ID: 1}, {ID: 2}, {ID: 3}}}
+	result, dropped, keep := reconcileUndoEntry(entry, map[int]bool{2: true})
+	if !keep || len(result.Returs) != 2 || len(dropped) != 1 || dropped[0].ID != 2 {
+		t.Fatalf("result %+v dropped %+v keep %v", result.Returs, dropped, keep)
+	}
+	if len(entry.Returs) != 3 {
+		t.Fatal("original entry modified")
+	}
+	if _, _, keep := reconcileUndoEntry(undoEntry{Returs: []Retur{{ID: 2}}}, map[int]bool{2: true}); keep {
+		t.Fatal("empty entry kept")
+	}
+}
+
+func TestApplyReconcileFromInconsistentState(t *testing.T) {
+	// ID 2 dan 5 sudah aktif lagi di database, ID 3 tercatat dua kali di pool
+	useUndoState(t, []undoEntry{
+		{Returs: []Retur{{ID: 2}, {ID: 3}}},
+		{Returs: []Retur{{ID: 5}}},
+	}, []int{2, 3, 3, 5, 7})
+	idMu.Lock()
+	dropped := applyReconcile(map[int]bool{2: true, 5: true})
+	pool := slices.Clone(deletedIDs)
+	idMu.Unlock()
+	if !slices.Equal(dropped, []int{2, 3, 5}) || !slices.Equal(pool, []int{3, 7}) {
+		t.Fatalf("pool dropped %v, kept %v", dropped, pool)
+	}
+	entries := deletedStack.Items()
+	if len(entries) != 1 || len(entries[0].Returs) != 1 || entries[0].Returs[0].ID != 3 {
+		t.Fatalf("stack = %+v", entries)
+	}
+}
+
+func TestReconcileHandlerAfterManualInsert(t *testing.T) {
+	testDB(t)
+	first := createViaAPI(t, map[string]interface{}{"barang": "Lampu", "alasan": "mati"})
+	second := createViaAPI(t, map[string]interface{}{"barang": "Kipas", "alasan": "berisik"})
+	for _, id := range []int{first.ID, second.ID} {
+		serve(httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/retur/%d/delete", id), nil))
+	}
+	if err := db.Unscoped().Model(&Retur{}).Where("id = ?", second.ID).Update("deleted_at", nil).Error; err != nil {
+		t.Fatal(err) // Retur diaktifkan lagi langsung di database, di luar undo
+	}
+	addDeletedID(first.ID) // Duplikat di pool
+
+	rec := serve(asAdmin(t, httptest.NewRequest(http.MethodPost, "/retur/admin/reconcile", nil)))
+	var body struct {
+		Undo struct {
+			After   int   `json:"entries_after"`
+			Dropped []int `json:"dropped_returns"`
+		} `json:"undo"`
+		Pool struct {
+			After   int   `json:"after"`
+			Dropped []int `json:"dropped"`
+		} `json:"id_pool"`
+	}
+	decodeBody(t, rec, &body)
+	if rec.Code != http.StatusOK || body.Undo.After != 1 || !slices.Equal(body.Undo.Dropped, []int{second.ID}) {
+		t.Fatalf("undo: %d %+v", rec.Code, body.Undo)
+	}
+	if body.Pool.After != 1 || !slices.Equal(body.Pool.Dropped, []int{first.ID, second.ID}) {
+		t.Fatalf("pool: %+v", body.Pool)
+	}
+	var records, audits int64
+	db.Model(&UndoRecord{}).Count(&records)
+	db.Model(&AuditLog{}).Where("action = ?", "reconcile").Count(&audits)
+	if records != 1 || audits != 1 {
+		t.Fatalf("undo records %d, reconcile audits %d", records, audits)
+	}
+
+	// Rekonsiliasi kedua pada keadaan yang sudah konsisten tidak mengubah apa pun
+	decodeBody(t, serve(asAdmin(t, httptest.NewRequest(http.MethodPost, "/retur/admin/reconcile", nil))), &body)
+	if len(body.Undo.Dropped) != 0 || len(body.Pool.Dropped) != 0 {
+		t.Fatalf("second reconcile dropped %v / %v", body.Undo.Dropped, body.Pool.Dropped)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// renotifyTypes memetakan nilai ?type pada /retur/{id}/notify ke jenis kejadian webhook
+var renotifyTypes = map[string]EventType{
+	"approved":    ReturApproved,
+	"disapproved": ReturDisapproved,
+	"reset":       ReturReset,
+}
+
+// renotifyStatus adalah status retur yang harus berlaku agar kejadian boleh dikirim ulang
+// Notifikasi "approved" untuk retur yang sudah di-reset akan menyesatkan sistem hilir
+var renotifyStatus = map[EventType]string{
+	ReturApproved:    "Disetujui",
+	ReturDisapproved: "Tidak Disetujui",
+	ReturReset:       "Dalam Proses",
+}
+
+// renotifyLimiter mencatat waktu pengiriman ulang terakhir per retur dan jenis kejadian
+// Entry yang lebih tua dari RETUR_RENOTIFY_INTERVAL tidak lagi membatasi apa pun dan dibuang saat sweep
+var renotifyLimiter = struct {
+	sync.Mutex
+	last    map[string]time.Time
+	sweptAt time.Time // Waktu sweep terakhir
+}{last: make(map[string]time.Time)}
+
+// allowRenotify memeriksa batas RETUR_RENOTIFY_INTERVAL; mengembalikan sisa waktu tunggu jika belum boleh
+func allowRenotify(key string) (bool, time.Duration) {
+	renotifyLimiter.Lock()
+	defer renotifyLimiter.Unlock()
+	now := clock.Now()
+	if now.Sub(renotifyLimiter.sweptAt) >= cfg.RenotifyInterval {
+		for k, last := range renotifyLimiter.last {
+			if now.Sub(last) >= cfg.RenotifyInterval {
+				delete(renotifyLimiter.last, k) // Paling banyak satu interval entry idle tersimpan
+			}
+		}
+		renotifyLimiter.sweptAt = now
+	}
+	if last, ok := renotifyLimiter.last[key]; ok {
+		if wait := cfg.RenotifyInterval - now.Sub(last); wait > 0 {
+			return false, wait
+		}
+	}
+	renotifyLimiter.last[key] = now
+	return true, 0
+}
+
+// renotifyReturHandler adalah handler admin untuk mengirim ulang notifikasi webhook sebuah retur
+// Dipakai saat sistem hilir melewatkan notifikasi; dibatasi satu kali per RETUR_RENOTIFY_INTERVAL per retur dan jenis
+// ?type harus sesuai status retur saat ini (lihat renotifyStatus), selain itu dijawab 409
+// Kejadian dipublikasikan ulang lewat event bus dengan Redelivery=true sehingga semua subscriber (webhook, log, tail) melihatnya
+func renotifyReturHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"]) // Convert ID dari string ke integer
+	if err != nil {
+		handleError(w, http.StatusBadRequest, "Invalid ID format") // Jika format ID salah, kirimkan error
+		return
+	}
+	notifyType := r.URL.Query().Get("type")
+	eventType, ok := renotifyTypes[notifyType]
+	if !ok {
+		handleError(w, http.StatusBadRequest, "type must be 'approved', 'disapproved' or 'reset'")
+		return
+	}
+
+	var retur Retur
+	if err := db.WithContext(r.Context()).Preload("Items").First(&retur, id).Error; err != nil {
+		handleError(w, http.StatusNotFound, "Return not found") // Jika retur tidak ditemukan, kirimkan error
+		return
+	}
+	if retur.Status != renotifyStatus[eventType] {
+		handleError(w, http.StatusConflict, fmt.Sprintf("Return status (%s) does not match notification type '%s'", retur.Status, notifyType))
+		return
+	}
+
+	if allowed, wait := allowRenotify(strconv.Itoa(id) + "|" + notifyType); !allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		handleError(w, http.StatusTooManyRequests, "Notification was re-sent recently, try again later")
+		return
+	}
+	delivery := &eventDelivery{}
+	bus.Publish(Event{Type: eventType, Retur: retur, Actor: actorFromRequest(r), At: clock.Now(), Redelivery: true, delivery: delivery})
+	log.Printf("renotify %s retur=%d actor=%s queued=%d dropped=%d", eventType, retur.ID, logActor(r), delivery.Queued, delivery.Dropped)
+	respondJSON(w, http.StatusAccepted, map[string]interface{}{
+		"event":   eventType,
+		"queued":  delivery.Queued,  // Jumlah tujuan webhook yang menerima notifikasi di antrian
+		"dropped": delivery.Dropped, // Jumlah tujuan yang gagal masuk antrian (antrian penuh atau sedang shutdown)
+	})
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// resetRenotifyLimiter mengosongkan batas kirim ulang agar test tidak saling memengaruhi
+func resetRenotifyLimiter(t *testing.T) {
+	t.Helper()
+	empty := func() {
+		renotifyLimiter.Lock()
+		renotifyLimiter.last = make(map[string]time.Time)
+		renotifyLimiter.sweptAt = time.Time{}
+		renotifyLimiter.Unlock()
+	}
+	empty()
+	t.Cleanup(empty)
+}
+
+func TestAllowRenotifyInterval(t *testing.T) {
+	withConfig(t, func(c *Config) { c.RenotifyInterval = time.Minute })
+	fake := useFakeClock(t, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	resetRenotifyLimiter(t)
+
+	if ok, _ := allowRenotify("7|approved"); !ok {
+		t.Fatal("first renotify rejected")
+	}
+	fake.Advance(20 * time.Second)
+	if ok, wait := allowRenotify("7|approved"); ok || wait != 40*time.Second {
+		t.Fatalf("second renotify = %t, wait %s, want rejected with 40s", ok, wait)
+	}
+	if ok, _ := allowRenotify("7|reset"); !ok {
+		t.Fatal("other type shares the limit")
+	}
+	fake.Advance(40 * time.Second)
+	if ok, _ := allowRenotify("7|approved"); !ok {
+		t.Fatal("renotify still rejected after the interval")
+	}
+}
+
+func TestRenotifyRepublishesEvent(t *testing.T) {
+	testDB(t)
+	resetRenotifyLimiter(t)
+	retur := seedReturs(t, Retur{Barang: "Kulkas", Alasan: "Rusak", Status: "Disetujui"})[0]
+	var published []Event
+	t.Cleanup(bus.Subscribe(func(e Event) { published = append(published, e) }))
+
+	path := fmt.Sprintf("/retur/%d/notify?type=approved", retur.ID)
+	if rec := serve(asAdmin(t, jsonRequest(t, http.MethodPost, path, nil))); rec.Code != http.StatusAccepted {
+		t.Fatalf("renotify: %d %s", rec.Code, rec.Body)
+	}
+	if len(published) != 1 || published[0].Type != ReturApproved || published[0].Retur.ID != retur.ID || !published[0].Redelivery {
+		t.Fatalf("published %+v, want one ReturApproved redelivery for retur %d", published, retur.ID)
+	}
+
+	if rec := serve(asAdmin(t, jsonRequest(t, http.MethodPost, path, nil))); rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("repeated renotify: %d, Retry-After %q, want 429", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	reset := fmt.Sprintf("/retur/%d/notify?type=reset", retur.ID)
+	if rec := serve(asAdmin(t, jsonRequest(t, http.MethodPost, reset, nil))); rec.Code != http.StatusConflict {
+		t.Fatalf("renotify with mismatched type: %d, want 409", rec.Code)
+	}
+	if len(published) != 1 {
+		t.Fatalf("rejected renotify published %d events in total, want 1", len(published))
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// urlFor menambahkan base path (RETUR_BASE_PATH) ke path aplikasi untuk URL yang dikirim ke client
+func urlFor(path string) string {
+	return cfg.BasePath + path
+}
+
+// newRouter membuat router dengan semua endpoint aplikasi
+// Jika RETUR_BASE_PATH diatur (misalnya /returns-api), semua endpoint dipasang di bawah prefix tersebut
+// Endpoint dengan path statis didaftarkan sebelum /retur/{id} agar tidak tertangkap sebagai ID
+func newRouter() *mux.Router {
+	root := mux.NewRouter()             // Membuat router baru
+	root.Use(recoveryMiddleware)        // Menangkap panic agar server tetap berjalan dan error tercatat
+	root.Use(slowRequestMiddleware)     // Mencatat request yang melewati RETUR_SLOW_REQUEST_THRESHOLD
+	root.Use(compressionMiddleware)     // Kompresi br/gzip sesuai Accept-Encoding (RETUR_COMPRESSION)
+	root.Use(securityHeadersMiddleware) // Header keamanan (nosniff, X-Frame-Options, Referrer-Policy, HSTS)
+	root.Use(responseOptionsMiddleware) // Membaca preferensi format response (misalnya ?pretty=true)
+	root.Use(requireJSONContentType)    // Menolak request tulis yang bukan JSON dengan 415
+	root.Use(tenantRateLimitMiddleware) // Batas request per menit per tenant (RETUR_TENANT_RATE_LIMIT)
+	root.Use(breakerMiddleware)         // Menjawab 503 dengan cepat saat database tidak tersedia
+	root.Use(timeoutMiddleware)         // Batas waktu per route (RETUR_REQUEST_TIMEOUT, RETUR_ROUTE_TIMEOUTS)
+
+	r := root
+	if cfg.BasePath != "" {
+		r = root.PathPrefix(cfg.BasePath).Subrouter() // Semua endpoint berada di bawah base path
+	}
+
+	// Menentukan endpoint dan handler yang sesuai
+	// Setiap route diberi nama agar bisa dikonfigurasi per endpoint (misalnya RETUR_STRICT_JSON_ROUTES)
+	// Route yang membaca body JSON: createRetur, validateRetur, approveRetur, bulkApprove, disapproveRetur, approveItem, batchGetRetur, deleteRetur (opsional), assignReturs, addReturTags, bulkTag, patchRetur,
+	// backfillReasonCodes, createWebhook, importUndoState, createReason, updateReason; semuanya lenient (field tidak dikenal diabaikan) kecuali RETUR_STRICT_JSON=true atau diatur per route
+	r.HandleFunc("/retur", getReturs).Methods("GET").Name("listReturs")                                                                   // Endpoint untuk mengambil semua retur
+	r.HandleFunc("/retur", validateSchema("create_retur", createRetur)).Methods("POST").Name("createRetur")                               // Endpoint untuk membuat retur baru
+	r.HandleFunc("/retur", requireAdmin(bulkDeleteReturHandler)).Methods("DELETE").Name("bulkDeleteRetur")                                // Endpoint untuk menghapus retur berdasarkan filter
+	r.HandleFunc("/retur/undo", dedupRequests(undoDedup, undoDeleteReturHandler)).Methods("POST").Name("undoDelete")                      // Endpoint untuk mengembalikan retur yang dihapus
+	r.HandleFunc("/retur/undo", listUndoHandler).Methods("GET").Name("listUndo")                                                          // Endpoint untuk melihat daftar retur yang bisa di-undo
+	r.HandleFunc("/retur/deleted", listUndoHandler).Methods("GET").Name("listDeleted")                                                    // Alias daftar retur yang dihapus beserta alasannya
+	r.HandleFunc("/retur/count", countRetursHandler).Methods("GET").Name("countReturs")                                                   // Endpoint jumlah retur sesuai filter
+	r.HandleFunc("/retur/sample", sampleReturHandler).Methods("GET").Name("sampleReturs")                                                 // Endpoint sampel acak retur untuk review QA
+	r.HandleFunc("/retur/approve", dedupRequests(decisionDedup, requireApprover(bulkApproveHandler))).Methods("POST").Name("bulkApprove") // Endpoint untuk menyetujui banyak retur sekaligus
+	r.HandleFunc("/retur/tags", bulkTagHandler).Methods("POST").Name("bulkTag")                                                           // Endpoint untuk menambahkan tag ke banyak retur
+	r.HandleFunc("/retur/assign", assignReturHandler).Methods("POST").Name("assignReturs")                                                // Endpoint untuk menugaskan retur ke agent
+	r.HandleFunc("/retur/changes", changesHandler).Methods("GET").Name("returChanges")                                                    // Endpoint change feed untuk sinkronisasi inkremental
+	r.HandleFunc("/retur/overview", overviewHandler).Methods("GET").Name("overview")                                                      // Endpoint jumlah dan retur terbaru per status
+	r.HandleFunc("/retur/next-id", nextReturIDHandler).Methods("GET").Name("nextReturID")                                                 // Endpoint untuk melihat ID retur berikutnya
+	r.HandleFunc("/retur/batch", batchGetReturHandler).Methods("GET", "POST").Name("batchGetRetur")                                       // Endpoint untuk mengambil banyak retur sekaligus
+	r.HandleFunc("/retur/validate", validateReturHandler).Methods("POST").Name("validateRetur")                                           // Endpoint untuk validasi payload tanpa menyimpan
+	r.HandleFunc("/retur/reasons", listReasonsHandler).Methods("GET").Name("listReasons")                                                 // Endpoint daftar kode alasan
+	r.HandleFunc("/retur/stats", statsHandler).Methods("GET").Name("stats")                                                               // Endpoint ringkasan statistik dengan ETag
+	r.HandleFunc("/retur/stats/timeseries", limitHeavy(1, timeseriesHandler)).Methods("GET").Name("statsTimeseries")                      // Endpoint data grafik refund per interval
+	r.HandleFunc("/retur/stats/latency", limitHeavy(1, latencyStatsHandler)).Methods("GET").Name("statsLatency")                          // Endpoint lama waktu sampai keputusan
+	r.HandleFunc("/retur/stats/by-barang", limitHeavy(1, statsByBarangHandler)).Methods("GET").Name("statsByBarang")                      // Endpoint jumlah retur per barang
+	r.HandleFunc("/retur/export", limitHeavy(2, exportRetursHandler)).Methods("GET").Name("exportReturs")                                 // Endpoint ekspor CSV retur dengan kolom yang bisa dipilih (?columns)
+	r.HandleFunc("/retur/export", requireAuthenticated(createExportJobHandler)).Methods("POST").Name("createExportJob")                   // Membuat job ekspor CSV async, hasilnya diunduh lewat link bertanda tangan
+	r.HandleFunc("/retur/export/jobs", requireAdmin(listExportJobsHandler)).Methods("GET").Name("listExportJobs")                         // Daftar job ekspor async (khusus admin)
+	r.HandleFunc("/retur/export/jobs/{job}", exportJobStatusHandler).Methods("GET").Name("exportJobStatus")                               // Status job ekspor async
+	r.HandleFunc("/retur/export/jobs/{job}/download", downloadExportHandler).Methods("GET").Name("downloadExport")                        // Unduh hasil ekspor (link bertanda tangan, mendukung Range)
+	r.HandleFunc("/retur/export/by-customer", limitHeavy(2, exportByCustomerHandler)).Methods("GET").Name("exportByCustomer")             // Endpoint ekspor CSV rekap per customer
+
+	admin := r.PathPrefix("/retur/admin").Subrouter() // Endpoint pemeliharaan, semuanya wajib token admin
+	admin.Use(adminMiddleware)
+	admin.HandleFunc("/id-pool", idPoolReportHandler).Methods("GET").Name("idPoolReport")                              // Melihat isi pool deletedIDs
+	admin.HandleFunc("/id-pool/stats", idPoolStatsHandler).Methods("GET").Name("idPoolStats")                          // Mengukur efektivitas reuse ID
+	admin.HandleFunc("/reconcile", reconcileHandler).Methods("POST").Name("reconcile")                                 // Menyelaraskan stack undo dan pool deletedIDs dengan database setelah edit manual
+	admin.HandleFunc("/id-pool/compact", compactIDPoolHandler).Methods("POST").Name("compactIDPool")                   // Membersihkan pool deletedIDs
+	admin.HandleFunc("/undo-state", exportUndoStateHandler).Methods("GET").Name("exportUndoState")                     // Mengekspor stack undo dan deletedIDs
+	admin.HandleFunc("/undo-state", importUndoStateHandler).Methods("POST").Name("importUndoState")                    // Memuat stack undo dan deletedIDs hasil ekspor
+	admin.HandleFunc("/reasons", createReasonHandler).Methods("POST").Name("createReason")                             // Menambahkan kode alasan
+	admin.HandleFunc("/reasons/{code}", updateReasonHandler).Methods("PUT").Name("updateReason")                       // Mengubah atau menonaktifkan kode alasan
+	admin.HandleFunc("/reasons/{code}", deleteReasonHandler).Methods("DELETE").Name("deleteReason")                    // Menghapus kode alasan yang belum dipakai
+	admin.HandleFunc("/webhooks", listWebhooksHandler).Methods("GET").Name("listWebhooks")                             // Melihat langganan webhook
+	admin.HandleFunc("/webhooks", createWebhookHandler).Methods("POST").Name("createWebhook")                          // Mendaftarkan langganan webhook
+	admin.HandleFunc("/webhooks/{id}", deleteWebhookHandler).Methods("DELETE").Name("deleteWebhook")                   // Menghapus langganan webhook
+	admin.HandleFunc("/backfill-reason-codes", backfillReasonCodesHandler).Methods("POST").Name("backfillReasonCodes") // Mengisi reason_code retur lama dari kata kunci Alasan
+	admin.HandleFunc("/orphans", orphansReportHandler).Methods("GET").Name("orphansReport")                            // Menghitung sub-resource yang retur induknya sudah tidak ada
+	admin.HandleFunc("/orphans/cleanup", cleanupOrphansHandler).Methods("POST").Name("cleanupOrphans")                 // Menghapus sub-resource yatim per batch
+	admin.HandleFunc("/apply-autoapprove", applyAutoApproveHandler).Methods("POST").Name("applyAutoApprove")           // Menerapkan aturan auto-approve pada retur yang masih pending
+	admin.HandleFunc("/integrity", integrityReportHandler).Methods("GET").Name("integrityReport")                      // Melaporkan retur dengan status yang tidak konsisten
+	admin.HandleFunc("/integrity/fix", fixIntegrityHandler).Methods("POST").Name("fixIntegrity")                       // Memperbaiki inkonsistensi yang jelas secara otomatis
+	admin.HandleFunc("/tail", tailHandler).Methods("GET").Name("adminTail")                                            // Stream SSE semua kejadian retur untuk debugging
+	admin.HandleFunc("/jobs/{name}/pause", setJobPausedHandler(true)).Methods("POST").Name("pauseJob")                 // Menjeda job background
+	admin.HandleFunc("/jobs/{name}/resume", setJobPausedHandler(false)).Methods("POST").Name("resumeJob")              // Melanjutkan job background yang dijeda
+
+	r.HandleFunc("/retur/{id}", patchReturHandler).Methods("PATCH").Name("patchRetur")                                                                                            // Endpoint untuk mengubah sebagian field retur (JSON atau merge-patch)
+	r.HandleFunc("/retur/{id}", getReturHandler).Methods("GET").Name("getRetur")                                                                                                  // Endpoint untuk mengambil satu retur
+	r.HandleFunc("/retur/{id}/notify", requireAdmin(renotifyReturHandler)).Methods("POST").Name("renotifyRetur")                                                                  // Endpoint admin kirim ulang notifikasi
+	r.HandleFunc("/retur/{id}/audit", requireAdmin(returAuditHandler)).Methods("GET").Name("returAudit")                                                                          // Endpoint admin audit log perubahan field
+	r.HandleFunc("/retur/{id}/history", returHistoryHandler).Methods("GET").Name("returHistory")                                                                                  // Endpoint riwayat perubahan status retur
+	r.HandleFunc("/retur/{id}/full", getReturFullHandler).Methods("GET").Name("getReturFull")                                                                                     // Endpoint retur lengkap dengan riwayat dan keputusan
+	r.HandleFunc("/retur/{id}/approve", dedupRequests(decisionDedup, requireApprover(validateSchema("approve_retur", approveReturHandler)))).Methods("POST").Name("approveRetur") // Endpoint untuk menyetujui retur
+	r.HandleFunc("/retur/{id}/disapprove", dedupRequests(decisionDedup, requireApprover(disapproveReturHandler))).Methods("POST").Name("disapproveRetur")                         // Endpoint untuk menolak retur
+	r.HandleFunc("/retur/{id}/reset", requireAdmin(resetDecisionHandler)).Methods("POST").Name("resetDecision")                                                                   // Endpoint admin untuk membuka kembali retur
+	r.HandleFunc("/retur/{id}/clone", cloneReturHandler).Methods("POST").Name("cloneRetur")                                                                                       // Endpoint untuk menyalin retur sebagai template
+	r.HandleFunc("/retur/{id}/items/{itemID}/approve", requireApprover(itemDecisionHandler(true))).Methods("POST").Name("approveItem")                                            // Endpoint untuk menyetujui satu item retur
+	r.HandleFunc("/retur/{id}/items/{itemID}/disapprove", requireApprover(itemDecisionHandler(false))).Methods("POST").Name("disapproveItem")                                     // Endpoint untuk menolak satu item retur
+	r.HandleFunc("/retur/{id}/tags", addReturTagsHandler).Methods("POST").Name("addReturTags")                                                                                    // Endpoint untuk menambahkan tag ke retur
+	r.HandleFunc("/retur/{id}/tags/{tag}", removeReturTagHandler).Methods("DELETE").Name("removeReturTag")                                                                        // Endpoint untuk menghapus tag dari retur
+	r.HandleFunc("/retur/{id}/attachments", requireAuthenticated(uploadAttachmentHandler)).Methods("POST").Name("uploadAttachment")                                               // Endpoint untuk mengunggah lampiran retur (body file mentah)
+	r.HandleFunc("/retur/{id}/attachments", listAttachmentsHandler).Methods("GET").Name("listAttachments")                                                                        // Endpoint untuk melihat daftar lampiran retur
+	r.HandleFunc("/retur/{id}/attachments/{attID}", downloadAttachmentHandler).Methods("GET").Name("downloadAttachment")                                                          // Endpoint untuk mengunduh lampiran (mendukung Range)
+	r.HandleFunc("/retur/{id}/delete", deleteReturHandler).Methods("DELETE").Name("deleteRetur")                                                                                  // Endpoint untuk menghapus retur
+
+	r.HandleFunc("/status", requireAdmin(statusHandler)).Methods("GET").Name("status") // Endpoint diagnostik (khusus admin)
+	r.HandleFunc("/config", requireAdmin(configHandler)).Methods("GET").Name("config") // Endpoint konfigurasi aktif (khusus admin)
+	r.HandleFunc("/metrics", metricsHandler).Methods("GET").Name("metrics")            // Endpoint metrik format Prometheus
+	r.HandleFunc("/postman", postmanHandler(root)).Methods("GET").Name("postman")      // Endpoint koleksi Postman dari tabel route
+
+	// Di bawah /v1, endpoint daftar selalu memakai envelope data/meta; path lama tetap mengirim array demi kompatibilitas
+	// Nama route sama dengan path lama agar konfigurasi per route (timeout, strict JSON) tetap berlaku
+	v1 := r.PathPrefix("/v1").Subrouter()
+	v1.Use(envelopeMiddleware)
+	v1.HandleFunc("/retur", getReturs).Methods("GET").Name("listReturs")
+	v1.HandleFunc("/retur/undo", listUndoHandler).Methods("GET").Name("listUndo")
+	v1.HandleFunc("/retur/deleted", listUndoHandler).Methods("GET").Name("listDeleted")
+	v1.HandleFunc("/retur/changes", changesHandler).Methods("GET").Name("returChanges")
+	v1.HandleFunc("/retur/export/jobs", requireAdmin(listExportJobsHandler)).Methods("GET").Name("listExportJobs")
+	v1.HandleFunc("/retur/admin/webhooks", requireAdmin(listWebhooksHandler)).Methods("GET").Name("listWebhooks")
+	v1.HandleFunc("/retur/{id}/history", returHistoryHandler).Methods("GET").Name("returHistory")
+	v1.HandleFunc("/retur/{id}/audit", requireAdmin(returAuditHandler)).Methods("GET").Name("returAudit")
+	v1.HandleFunc("/retur/{id}/attachments", listAttachmentsHandler).Methods("GET").Name("listAttachments")
+
+	return root
+}
+
+// normalizeBasePath memastikan base path diawali "/" dan tidak diakhiri "/" ("/" atau kosong berarti root)
+func normalizeBasePath(path string) string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return ""
+	}
+	return "/" + path
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoutesUnderBasePath(t *testing.T) {
+	withConfig(t, func(c *Config) { c.BasePath = normalizeBasePath("returns-api/") })
+	useUndoState(t, nil, nil)
+	for _, path := range []string{"/returns-api/retur/undo", "/returns-api/v1/retur/undo"} {
+		if rec := serve(httptest.NewRequest(http.MethodGet, path, nil)); rec.Code != http.StatusOK {
+			t.Errorf("GET %s: %d, want 200", path, rec.Code)
+		}
+	}
+	for _, path := range []string{"/retur/undo", "/v1/retur/undo"} {
+		if rec := serve(httptest.NewRequest(http.MethodGet, path, nil)); rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s without base path: %d, want 404", path, rec.Code)
+		}
+	}
+	if got := urlFor("/retur/7"); got != "/returns-api/retur/7" {
+		t.Errorf("urlFor = %q, want /returns-api/retur/7", got) // Location dan link ikut memakai base path
+	}
+}
+
+func TestNormalizeBasePath(t *testing.T) {
+	for input, want := range map[string]string{"": "", "/": "", "returns-api": "/returns-api", "/returns-api/": "/returns-api", "/a/b/": "/a/b"} {
+		if got := normalizeBasePath(input); got != want {
+			t.Errorf("normalizeBasePath(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
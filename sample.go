@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// sampleReturHandler adalah handler untuk mengambil sampel acak retur sesuai filter (lihat listFilters) untuk review QA
+// ?n menentukan ukuran sampel (default 10, maksimum RETUR_SAMPLE_MAX); ?seed membuat sampel bisa diulang untuk audit
+// Sampel diambil dengan ORDER BY RAND(seed) yang cukup untuk tabel retur berukuran sedang
+func sampleReturHandler(w http.ResponseWriter, r *http.Request) {
+	filters, err := listFilters(r)
+	if err != nil {
+		handleError(w, http.StatusBadRequest, err.Error()) // Parameter filter tidak valid
+		return
+	}
+	n, err := queryInt(r, "n", 10)
+	if err != nil || n == 0 {
+		handleError(w, http.StatusBadRequest, "Invalid n") // Ukuran sampel harus berupa angka positif
+		return
+	}
+	if n > cfg.SampleMax {
+		n = cfg.SampleMax // Batasi ukuran sampel
+	}
+
+	query := db.WithContext(r.Context()).Model(&Retur{}).Scopes(filters)
+	if raw := r.URL.Query().Get("seed"); raw != "" {
+		seed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			handleError(w, http.StatusBadRequest, "Invalid seed") // Seed harus berupa bilangan bulat
+			return
+		}
+		query = query.Order(clauseRand(seed)) // Seed yang sama menghasilkan sampel yang sama selama data tidak berubah
+	} else {
+		query = query.Order("RAND()")
+	}
+
+	returs := []Retur{}
+	if err := query.Limit(n).Find(&returs).Error; err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to sample returns")
+		return
+	}
+	for i := range returs {
+		applySLA(&returs[i]) // Hitung sla_deadline dan overdue
+	}
+	maskReturs(r, returs)
+	respondJSON(w, http.StatusOK, map[string]interface{}{"returs": returs, "count": len(returs)})
+}
+
+// clauseRand membuat ekspresi ORDER BY RAND(seed); seed berupa angka sehingga aman disisipkan langsung
+func clauseRand(seed int64) string {
+	return "RAND(" + strconv.FormatInt(seed, 10) + ")"
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestSampleReturs(t *testing.T) {
+	testDB(t)
+	withConfig(t, func(c *Config) { c.SampleMax = 5 })
+	for i := 0; i < 10; i++ {
+		status := "Disetujui"
+		if i%3 == 0 {
+			status = "Dalam Proses" // 4 retur di luar filter
+		}
+		seedReturs(t, Retur{Barang: "Barang QA", Alasan: "Rusak", Status: status})
+	}
+
+	sample := func(query string) []int {
+		t.Helper()
+		rec := serve(httptest.NewRequest(http.MethodGet, "/retur/sample?"+query, nil))
+		var body struct {
+			Returs []Retur `json:"returs"`
+			Count  int     `json:"count"`
+		}
+		decodeBody(t, rec, &body)
+		if rec.Code != http.StatusOK || body.Count != len(body.Returs) {
+			t.Fatalf("sample %s: %d %+v", query, rec.Code, body)
+		}
+		var ids []int
+		for _, retur := range body.Returs {
+			if retur.Status != "Disetujui" {
+				t.Errorf("sample %s contains retur %d with status %q", query, retur.ID, retur.Status)
+			}
+			ids = append(ids, retur.ID)
+		}
+		return ids
+	}
+
+	first := sample("n=4&status=Disetujui&seed=42")
+	if len(first) != 4 {
+		t.Fatalf("sample size = %d, want 4", len(first))
+	}
+	if again := sample("n=4&status=Disetujui&seed=42"); !slices.Equal(again, first) {
+		t.Fatalf("same seed gave %v then %v", first, again)
+	}
+	if capped := sample("n=100&status=Disetujui"); len(capped) != 5 {
+		t.Fatalf("sample size = %d, want RETUR_SAMPLE_MAX 5", len(capped))
+	}
+	if rec := serve(httptest.NewRequest(http.MethodGet, "/retur/sample?seed=abc", nil)); rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid seed: %d, want 400", rec.Code)
+	}
+}
+
+func TestSampleRejectsInvalidParams(t *testing.T) {
+	for _, query := range []string{"n=0", "n=-1", "n=abc"} {
+		if rec := serve(httptest.NewRequest(http.MethodGet, "/retur/sample?"+query, nil)); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: %d, want 400", query, rec.Code)
+		}
+	}
+}
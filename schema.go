@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// schemaFiles berisi JSON Schema untuk body request yang divalidasi jika RETUR_JSON_SCHEMA=true
+//
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+// schemaLimit adalah satu keyword schema yang nilainya diambil dari konstanta Go saat startup
+type schemaLimit struct {
+	Path    []string    // Lokasi subschema di dalam file, misalnya properties/barang
+	Keyword string      // Keyword JSON Schema, misalnya maxLength
+	Value   interface{} // Nilai dari konstanta yang juga dipakai validateRetur
+}
+
+// schemaLimits adalah batas yang tidak ditulis di file schema agar tidak bisa berbeda dari validasi struct
+// Per nama file schema (tanpa ekstensi); lihat validateRetur, tagPattern, dan maxDecisionNoteLength
+var schemaLimits = map[string][]schemaLimit{
+	"create_retur": {
+		{Path: []string{"properties", "barang"}, Keyword: "maxLength", Value: maxBarangLength},
+		{Path: []string{"properties", "alasan"}, Keyword: "maxLength", Value: maxAlasanLength},
+		{Path: []string{"properties", "customer_id"}, Keyword: "maxLength", Value: maxCustomerIDLength},
+		{Path: []string{"properties", "order_id"}, Keyword: "maxLength", Value: maxOrderIDLength},
+		{Path: []string{"properties", "tags", "items"}, Keyword: "pattern", Value: tagPattern.String()},
+		{Path: []string{"properties", "items", "items", "properties", "sku"}, Keyword: "maxLength", Value: maxBarangLength},
+	},
+	"approve_retur": {
+		{Path: []string{"properties", "note"}, Keyword: "maxLength", Value: maxDecisionNoteLength},
+	},
+}
+
+// applySchemaLimits menyisipkan schemaLimits ke dokumen schema mentah sebelum dikompilasi
+func applySchemaLimits(name string, data []byte) ([]byte, error) {
+	limits := schemaLimits[name]
+	if len(limits) == 0 {
+		return data, nil
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	for _, limit := range limits {
+		node := document
+		for _, key := range limit.Path {
+			child, ok := node[key].(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("schema %s: no subschema at /%s", name, strings.Join(limit.Path, "/"))
+			}
+			node = child
+		}
+		node[limit.Keyword] = limit.Value
+	}
+	return json.Marshal(document)
+}
+
+// requestSchemas adalah schema yang sudah dikompilasi, per nama file (tanpa ekstensi)
+var requestSchemas = map[string]*jsonschema.Schema{}
+
+// loadRequestSchemas mengompilasi semua schema yang di-embed; dipanggil sekali saat startup
+func loadRequestSchemas() error {
+	entries, err := schemaFiles.ReadDir("schemas")
+	if err != nil {
+		return err
+	}
+	compiler := jsonschema.NewCompiler()
+	for _, entry := range entries {
+		data, err := schemaFiles.ReadFile("schemas/" + entry.Name())
+		if err != nil {
+			return err
+		}
+		if data, err = applySchemaLimits(strings.TrimSuffix(entry.Name(), ".json"), data); err != nil {
+			return err
+		}
+		if err := compiler.AddResource(entry.Name(), bytes.NewReader(data)); err != nil {
+			return err
+		}
+	}
+	for _, entry := range entries {
+		schema, err := compiler.Compile(entry.Name())
+		if err != nil {
+			return err
+		}
+		requestSchemas[strings.TrimSuffix(entry.Name(), ".json")] = schema
+	}
+	return nil
+}
+
+// schemaErrors mengubah error validasi schema menjadi daftar fieldError dengan JSON pointer sebagai nama field
+// Hanya penyebab paling dalam yang dilaporkan karena itulah yang menunjuk langsung ke field bermasalah
+func schemaErrors(err *jsonschema.ValidationError) []fieldError {
+	if len(err.Causes) == 0 {
+		field := err.InstanceLocation
+		if field == "" {
+			field = "/" // Masalah pada object root, misalnya field wajib yang tidak ada
+		}
+		return []fieldError{{Field: field, Message: err.Message}}
+	}
+	var errs []fieldError
+	for _, cause := range err.Causes {
+		errs = append(errs, schemaErrors(cause)...)
+	}
+	return errs
+}
+
+// validateSchema membungkus handler sehingga body request divalidasi terhadap JSON Schema bernama name sebelum diproses
+// Hanya aktif jika RETUR_JSON_SCHEMA=true; body kosong atau JSON rusak diteruskan agar decodeJSON melaporkan error biasa
+func validateSchema(name string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		schema := requestSchemas[name]
+		if !cfg.JSONSchema || schema == nil || r.Body == nil {
+			next(w, r)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			handleError(w, http.StatusBadRequest, "Failed to read request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body)) // Kembalikan body agar bisa dibaca lagi oleh handler
+
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber() // Pertahankan angka apa adanya agar aturan integer diperiksa dengan benar
+		var document interface{}
+		if decoder.Decode(&document) != nil {
+			next(w, r)
+			return
+		}
+		var validationErr *jsonschema.ValidationError
+		if err := schema.Validate(document); errors.As(err, &validationErr) {
+			respondValidationErrors(w, schemaErrors(validationErr))
+			return
+		}
+		next(w, r)
+	}
+}
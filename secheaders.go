@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// defaultSecurityHeaders adalah header keamanan bawaan yang dikirim pada setiap response
+var defaultSecurityHeaders = map[string]string{
+	"X-Content-Type-Options": "nosniff",
+	"X-Frame-Options":        "DENY",
+	"Referrer-Policy":        "no-referrer",
+}
+
+// parseSecurityHeaders menggabungkan RETUR_SECURITY_HEADERS ("Header=nilai,Header=") ke header bawaan
+// Nilai kosong menonaktifkan header tersebut, header baru ditambahkan apa adanya
+func parseSecurityHeaders(raw string) map[string]string {
+	headers := make(map[string]string, len(defaultSecurityHeaders))
+	for name, value := range defaultSecurityHeaders {
+		headers[name] = value
+	}
+	for _, item := range strings.Split(raw, ",") {
+		name, value, ok := strings.Cut(item, "=")
+		name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+		if !ok || name == "" {
+			continue
+		}
+		if value = strings.TrimSpace(value); value == "" {
+			delete(headers, name) // Header dinonaktifkan lewat konfigurasi
+			continue
+		}
+		headers[name] = value
+	}
+	return headers
+}
+
+// headerStrings mengubah header keamanan menjadi daftar "Header: nilai" terurut untuk ditampilkan
+func headerStrings(headers map[string]string) []string {
+	result := make([]string, 0, len(headers))
+	for name, value := range headers {
+		result = append(result, name+": "+value)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// requestIsHTTPS memeriksa apakah request datang lewat TLS, langsung atau lewat proxy tepercaya (X-Forwarded-Proto)
+func requestIsHTTPS(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ipInNets(ip, cfg.TrustedProxies) && strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// securityHeadersMiddleware menambahkan header keamanan (RETUR_SECURITY_HEADERS) pada semua response
+// Strict-Transport-Security (RETUR_HSTS) hanya dikirim untuk request HTTPS agar tidak mengunci akses HTTP lokal
+func securityHeadersMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, value := range cfg.SecurityHeaders {
+			w.Header().Set(name, value)
+		}
+		if cfg.HSTS != "" && requestIsHTTPS(r) {
+			w.Header().Set("Strict-Transport-Security", cfg.HSTS)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseSecurityHeaders(t *testing.T) {
+	got := parseSecurityHeaders("x-frame-options=SAMEORIGIN, Referrer-Policy= ,Content-Security-Policy=default-src 'none',invalid")
+	want := map[string]string{"X-Content-Type-Options": "nosniff", "X-Frame-Options": "SAMEORIGIN", "Content-Security-Policy": "default-src 'none'"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("headers = %v, want %v", got, want)
+	}
+}
+
+func TestSecurityHeadersOnResponse(t *testing.T) {
+	withConfig(t, func(c *Config) {
+		c.SecurityHeaders = parseSecurityHeaders("")
+		c.HSTS = "max-age=600"
+		c.TrustedProxies = parseCIDRList("10.0.0.0/8")
+	})
+	get := func(configure func(*http.Request)) http.Header {
+		req := asAdmin(t, httptest.NewRequest(http.MethodGet, "/retur/undo", nil))
+		configure(req)
+		rec := serve(req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET /retur/undo: %d %s", rec.Code, rec.Body)
+		}
+		return rec.Header()
+	}
+
+	header := get(func(*http.Request) {})
+	for name, value := range defaultSecurityHeaders {
+		if got := header.Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+	if got := header.Get("Strict-Transport-Security"); got != "" {
+		t.Errorf("HSTS sent over plain HTTP: %q", got)
+	}
+
+	tests := []struct {
+		name      string
+		configure func(*http.Request)
+		want      string
+	}{
+		{"direct TLS", func(r *http.Request) { r.TLS = &tls.ConnectionState{} }, "max-age=600"},
+		{"trusted proxy", func(r *http.Request) {
+			r.RemoteAddr = "10.1.2.3:5000"
+			r.Header.Set("X-Forwarded-Proto", "https")
+		}, "max-age=600"},
+		{"untrusted proxy", func(r *http.Request) {
+			r.RemoteAddr = "203.0.113.9:5000"
+			r.Header.Set("X-Forwarded-Proto", "https")
+		}, ""},
+	}
+	for _, tt := range tests {
+		if got := get(tt.configure).Get("Strict-Transport-Security"); got != tt.want {
+			t.Errorf("%s: HSTS = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	cfg.SecurityHeaders = parseSecurityHeaders("X-Frame-Options=")
+	if got := get(func(*http.Request) {}).Values("X-Frame-Options"); len(got) != 0 {
+		t.Errorf("disabled X-Frame-Options still sent: %v", got)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// parseSLAConfig membaca daftar "Status=durasi" dipisahkan koma, misalnya "Dalam Proses=48h"; durasi 0 diabaikan
+func parseSLAConfig(raw string) map[string]time.Duration {
+	result := parseDurationMap(raw)
+	for status, duration := range result {
+		if duration == 0 {
+			delete(result, status)
+		}
+	}
+	return result
+}
+
+// applySLA mengisi sla_deadline dan overdue pada retur berdasarkan SLA dari status saat ini
+// Batas waktu dihitung dari created_at ditambah durasi SLA; status tanpa SLA tidak memiliki deadline
+func applySLA(retur *Retur) {
+	sla, ok := cfg.SLA[retur.Status]
+	if !ok || retur.CreatedAt.IsZero() {
+		retur.SLADeadline = nil
+		retur.Overdue = false
+		return
+	}
+	deadline := retur.CreatedAt.Add(sla)
+	retur.SLADeadline = &deadline
+	retur.Overdue = clock.Now().After(deadline) // Lewat deadline berarti melanggar SLA
+}
+
+// overdueScope memfilter retur yang melewati SLA (overdue=true) atau yang tidak (overdue=false)
+func overdueScope(overdue bool) func(*gorm.DB) *gorm.DB {
+	return func(q *gorm.DB) *gorm.DB {
+		now := clock.Now()
+		condition := db.Where("1 = 0") // Tanpa SLA yang dikonfigurasi, tidak ada retur yang overdue
+		for status, sla := range cfg.SLA {
+			condition = condition.Or("status = ? AND created_at < ?", status, now.Add(-sla))
+		}
+		if overdue {
+			return q.Where(condition)
+		}
+		return q.Not(condition)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// slowRequests menghitung request yang melewati RETUR_SLOW_REQUEST_THRESHOLD
+var slowRequests atomic.Int64
+
+// statusRecorder mencatat status response yang dikirim handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	if sr.status == 0 {
+		sr.status = status
+	}
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+func (sr *statusRecorder) Write(p []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK // Write tanpa WriteHeader berarti 200
+	}
+	return sr.ResponseWriter.Write(p)
+}
+
+// Unwrap mengembalikan ResponseWriter asli agar opsi response dan http.ResponseController tetap bekerja
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
+// slowRequestExemptRoutes adalah route streaming jangka panjang yang durasinya tidak mencerminkan latensi
+var slowRequestExemptRoutes = map[string]bool{
+	"adminTail": true,
+}
+
+// slowRequestMiddleware mengukur durasi setiap request dan mencatat peringatan serta menaikkan retur_slow_requests_total
+// jika durasinya melewati RETUR_SLOW_REQUEST_THRESHOLD (default 1s, 0 berarti nonaktif)
+func slowRequestMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if current := mux.CurrentRoute(r); cfg.SlowRequestThreshold <= 0 || (current != nil && slowRequestExemptRoutes[current.GetName()]) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+		elapsed := time.Since(start)
+		if elapsed <= cfg.SlowRequestThreshold {
+			return
+		}
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil && current.GetName() != "" {
+			route = current.GetName()
+		}
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		slowRequests.Add(1)
+		log.Printf("WARN slow request route=%s method=%s actor=%s duration=%s status=%d threshold=%s", route, r.Method, logActor(r), elapsed.Round(time.Millisecond), status, cfg.SlowRequestThreshold)
+	})
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestSlowRequestLogsWarning(t *testing.T) {
+	withConfig(t, func(c *Config) { c.SlowRequestThreshold = 20 * time.Millisecond })
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	router := mux.NewRouter()
+	router.Use(slowRequestMiddleware)
+	router.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(40 * time.Millisecond)
+		w.WriteHeader(http.StatusTeapot)
+	}).Name("slowRoute")
+	router.HandleFunc("/fast", func(w http.ResponseWriter, r *http.Request) {}).Name("fastRoute")
+	router.HandleFunc("/tail", func(w http.ResponseWriter, r *http.Request) { time.Sleep(40 * time.Millisecond) }).Name("adminTail")
+
+	before := slowRequests.Load()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if got := slowRequests.Load() - before; got != 1 {
+		t.Fatalf("slow_requests_total increased by %d, want 1", got)
+	}
+	for _, want := range []string{"WARN slow request", "route=slowRoute", "method=GET", "status=418", "threshold=20ms"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("log %q does not contain %q", logs.String(), want)
+		}
+	}
+
+	logs.Reset()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fast", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/tail", nil)) // Route streaming dikecualikan
+	if got := slowRequests.Load() - before; got != 1 || logs.Len() != 0 {
+		t.Fatalf("fast and exempt requests counted %d times, logged %q", got-1, logs.String())
+	}
+}
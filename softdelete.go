@@ -0,0 +1,68 @@
+package main
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Delete retur adalah soft delete: baris tetap di tabel returs dengan deleted_at terisi (Retur.Removed) sehingga
+// tersembunyi dari semua query GORM, dan item-nya ikut tersembunyi karena selalu dibaca lewat retur induk.
+// Baris yang di-soft delete hidup selama entry undo-nya: undo menghapus deleted_at, sedangkan purge entry undo,
+// load shedding undo, dan reuse ID membuang baris tersebut secara permanen (lihat purgeSoftDeleted)
+
+// softDeleteReturs menandai retur sebagai terhapus pada waktu deletedAt
+// natural_key dikosongkan agar order_id dan barang yang sama bisa dipakai retur baru selama retur ini menunggu undo
+func softDeleteReturs(tx *gorm.DB, ids []int, deletedAt time.Time) error {
+	return tx.Model(&Retur{}).Where("id IN ?", ids).UpdateColumns(map[string]interface{}{"deleted_at": deletedAt, "natural_key": nil}).Error
+}
+
+// undeleteReturs mengembalikan retur yang barisnya masih di-soft delete dengan menghapus deleted_at
+// Baris hanya dipakai jika created_at-nya sama dengan snapshot, karena ID yang sama bisa saja sudah dipakai dan
+// di-soft delete lagi oleh retur lain; retur yang tidak punya baris seperti itu (misalnya dari undo state instance lain)
+// dikembalikan di pending agar di-insert ulang dari snapshot
+func undeleteReturs(tx *gorm.DB, returs []Retur) (undeleted, pending []Retur, err error) {
+	for _, retur := range returs {
+		result := tx.Unscoped().Model(&Retur{}).Where("id = ? AND created_at = ? AND deleted_at IS NOT NULL", retur.ID, retur.CreatedAt).
+			UpdateColumns(map[string]interface{}{"deleted_at": nil, "natural_key": naturalKey(retur.OrderID, retur.Barang)})
+		if result.Error != nil {
+			return nil, nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			pending = append(pending, retur)
+			continue
+		}
+		undeleted = append(undeleted, retur)
+	}
+	return undeleted, pending, nil
+}
+
+// purgeSoftDeleted membuang permanen baris retur yang di-soft delete (beserta item-nya) untuk ID yang diberikan
+// Retur aktif dengan ID yang sama tidak disentuh, sehingga aman dipanggil untuk ID yang sudah dipakai ulang
+func purgeSoftDeleted(tx *gorm.DB, ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var removed []int
+	if err := tx.Unscoped().Model(&Retur{}).Where("id IN ? AND deleted_at IS NOT NULL", ids).Pluck("id", &removed).Error; err != nil {
+		return err
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	if err := tx.Where("retur_id IN ?", removed).Delete(&ReturItem{}).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Delete(&Retur{}, removed).Error
+}
+
+// entryReturIDs mengumpulkan ID semua retur pada entry undo
+func entryReturIDs(entries ...undoEntry) []int {
+	var ids []int
+	for _, entry := range entries {
+		for _, retur := range entry.Returs {
+			ids = append(ids, retur.ID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// spikeAlert adalah payload yang dikirim saat satu barang menerima terlalu banyak retur dalam jendela waktu
+type spikeAlert struct {
+	Event     string    `json:"event"`      // Selalu ReturSpike
+	Barang    string    `json:"barang"`     // Nama barang (kanonik jika cocok dengan katalog)
+	Count     int       `json:"count"`      // Jumlah retur dalam jendela waktu
+	Window    string    `json:"window"`     // Panjang jendela waktu, misalnya 1h0m0s
+	ReturnIDs []int     `json:"return_ids"` // ID retur di dalam jendela, terlama lebih dulu
+	At        time.Time `json:"at"`
+}
+
+// spikeSample adalah satu retur baru yang dihitung oleh spikeMonitor
+type spikeSample struct {
+	id int
+	at time.Time
+}
+
+// spikeMonitor menghitung retur baru per barang dalam jendela bergulir (RETUR_SPIKE_WINDOW) dan mengirim alert
+// jika jumlahnya mencapai RETUR_SPIKE_THRESHOLD; setiap barang paling banyak mendapat satu alert per jendela
+// Barang yang tidak lagi menerima retur dibuang dari map paling lambat satu jendela kemudian (lihat prune)
+type spikeMonitor struct {
+	mu        sync.Mutex
+	samples   map[string][]spikeSample
+	alertedAt map[string]time.Time
+	prunedAt  time.Time // Waktu pembersihan terakhir
+}
+
+// spikes adalah monitor lonjakan retur yang dipakai aplikasi
+var spikes = &spikeMonitor{
+	samples:   make(map[string][]spikeSample),
+	alertedAt: make(map[string]time.Time),
+}
+
+// spikeKey menentukan barang yang dihitung: nama kanonik jika ada, selain itu Barang tanpa beda huruf besar/kecil
+func spikeKey(retur Retur) string {
+	if retur.CanonicalBarang != "" {
+		return retur.CanonicalBarang
+	}
+	return strings.ToLower(strings.TrimSpace(retur.Barang))
+}
+
+// Observe mencatat retur baru dan mengembalikan alert jika barang tersebut baru saja melewati ambang batas
+func (m *spikeMonitor) Observe(retur Retur, now time.Time) (spikeAlert, bool) {
+	if cfg.SpikeThreshold <= 0 || cfg.SpikeWindow <= 0 {
+		return spikeAlert{}, false // Monitor nonaktif
+	}
+	key := spikeKey(retur)
+	cutoff := now.Add(-cfg.SpikeWindow)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if now.Sub(m.prunedAt) >= cfg.SpikeWindow {
+		m.prune(cutoff)
+		m.prunedAt = now
+	}
+	kept := m.samples[key][:0]
+	for _, s := range m.samples[key] {
+		if s.at.After(cutoff) {
+			kept = append(kept, s) // Buang retur yang sudah keluar dari jendela
+		}
+	}
+	kept = append(kept, spikeSample{id: retur.ID, at: now})
+	m.samples[key] = kept
+
+	if len(kept) < cfg.SpikeThreshold {
+		return spikeAlert{}, false
+	}
+	if last, ok := m.alertedAt[key]; ok && now.Sub(last) < cfg.SpikeWindow {
+		return spikeAlert{}, false // Sudah ada alert untuk barang ini di jendela yang sama
+	}
+	m.alertedAt[key] = now
+	ids := make([]int, len(kept))
+	for i, s := range kept {
+		ids[i] = s.id
+	}
+	return spikeAlert{Event: string(ReturSpike), Barang: key, Count: len(kept), Window: cfg.SpikeWindow.String(), ReturnIDs: ids, At: now}, true
+}
+
+// prune membuang barang yang semua sampelnya sudah keluar dari jendela dan alert yang lebih tua dari satu jendela
+// Dipanggil dengan m.mu terkunci
+func (m *spikeMonitor) prune(cutoff time.Time) {
+	for key, samples := range m.samples {
+		if len(samples) == 0 || !samples[len(samples)-1].at.After(cutoff) {
+			delete(m.samples, key) // Sampel terbaru ada di akhir, jadi semua sampel sudah kedaluwarsa
+		}
+	}
+	for key, at := range m.alertedAt {
+		if !at.After(cutoff) {
+			delete(m.alertedAt, key)
+		}
+	}
+}
+
+// Send mencatat alert di log dan mengantrekannya di notifier (lihat EnqueueSpike) sehingga dikirim dengan
+// tanda tangan HMAC, timeout, dan batas antrean yang sama seperti notifikasi perubahan status
+func (m *spikeMonitor) Send(alert spikeAlert) {
+	log.Printf("WARN return spike barang=%q count=%d window=%s ids=%v", alert.Barang, alert.Count, alert.Window, alert.ReturnIDs)
+	notify.EnqueueSpike(alert)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func newTestSpikeMonitor() *spikeMonitor {
+	return &spikeMonitor{samples: make(map[string][]spikeSample), alertedAt: make(map[string]time.Time)}
+}
+
+func TestSpikeMonitorAlertsOncePerWindow(t *testing.T) {
+	withConfig(t, func(c *Config) {
+		c.SpikeThreshold = 3
+		c.SpikeWindow = time.Hour
+	})
+	m := newTestSpikeMonitor()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	observe := func(id int, barang string, offset time.Duration) (spikeAlert, bool) {
+		return m.Observe(Retur{ID: id, Barang: barang}, start.Add(offset))
+	}
+	observe(1, "Sepatu", 0)
+	observe(2, "sepatu ", 10*time.Minute) // Huruf besar/kecil dan spasi tidak membedakan barang
+	if _, ok := observe(3, "Tas", 20*time.Minute); ok {
+		t.Fatal("alert for a different barang")
+	}
+	alert, ok := observe(4, "SEPATU", 30*time.Minute)
+	if !ok || alert.Barang != "sepatu" || alert.Count != 3 || !slices.Equal(alert.ReturnIDs, []int{1, 2, 4}) || alert.Event != string(ReturSpike) {
+		t.Fatalf("alert = %+v, %v", alert, ok)
+	}
+	if _, ok := observe(5, "Sepatu", 40*time.Minute); ok {
+		t.Fatal("second alert within the same window")
+	}
+
+	// Setelah satu jendela, sampel lama keluar sehingga hitungan mulai lagi dari retur yang masih di jendela
+	if _, ok := observe(6, "Sepatu", 90*time.Minute); ok {
+		t.Fatal("alert with only two samples in the window")
+	}
+	if alert, ok := observe(7, "Sepatu", 95*time.Minute); !ok || !slices.Equal(alert.ReturnIDs, []int{5, 6, 7}) {
+		t.Fatalf("alert in next window = %+v, %v", alert, ok)
+	}
+}
+
+func TestSpikeMonitorPrunesIdleBarang(t *testing.T) {
+	withConfig(t, func(c *Config) {
+		c.SpikeThreshold = 2
+		c.SpikeWindow = time.Hour
+	})
+	m := newTestSpikeMonitor()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.Observe(Retur{ID: 1, Barang: "Tas"}, start)
+	m.Observe(Retur{ID: 2, Barang: "Tas"}, start) // Memicu alert
+	m.Observe(Retur{ID: 3, CanonicalBarang: "iPhone 13", Barang: "ip13"}, start.Add(30*time.Minute))
+	m.Observe(Retur{ID: 4, Barang: "Jam"}, start.Add(2*time.Hour))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.samples["tas"]; ok {
+		t.Error("idle barang not pruned")
+	}
+	if _, ok := m.samples["iPhone 13"]; ok {
+		t.Error("idle canonical barang not pruned")
+	}
+	if _, ok := m.alertedAt["tas"]; ok {
+		t.Error("old alert not pruned")
+	}
+	if len(m.samples["jam"]) != 1 {
+		t.Errorf("samples = %v", m.samples)
+	}
+}
+
+func TestSpikeMonitorDisabled(t *testing.T) {
+	withConfig(t, func(c *Config) { c.SpikeThreshold = 0 })
+	m := newTestSpikeMonitor()
+	for i := range 5 {
+		if _, ok := m.Observe(Retur{ID: i, Barang: "Tas"}, time.Now()); ok {
+			t.Fatal("alert while disabled")
+		}
+	}
+	if len(m.samples) != 0 {
+		t.Fatal("disabled monitor kept samples")
+	}
+}
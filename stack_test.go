@@ -0,0 +1,107 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newIntStack membuat stack berisi 1..n dengan n di top
+func newIntStack(n int) *Stack[int] {
+	var s Stack[int]
+	for i := 1; i <= n; i++ {
+		s.Push(i)
+	}
+	return &s
+}
+
+func TestSnapshotRangeNewestFirst(t *testing.T) {
+	s := newIntStack(5)
+	tests := []struct {
+		offset, limit int
+		want          []int
+	}{
+		{0, 2, []int{5, 4}},
+		{2, 2, []int{3, 2}},
+		{3, 10, []int{2, 1}}, // limit melewati dasar stack
+		{0, 5, []int{5, 4, 3, 2, 1}},
+		{-3, 1, []int{5}}, // offset negatif dianggap 0
+	}
+	for _, tt := range tests {
+		if got := s.SnapshotRange(tt.offset, tt.limit); !slices.Equal(got, tt.want) {
+			t.Errorf("SnapshotRange(%d, %d) = %v, want %v", tt.offset, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestSnapshotRangeOutOfRange(t *testing.T) {
+	s := newIntStack(3)
+	for _, tt := range []struct{ offset, limit int }{{3, 1}, {100, 10}, {0, 0}, {1, -1}} {
+		got := s.SnapshotRange(tt.offset, tt.limit)
+		if got == nil || len(got) != 0 {
+			t.Errorf("SnapshotRange(%d, %d) = %#v, want empty non-nil slice", tt.offset, tt.limit, got)
+		}
+	}
+	var empty Stack[int]
+	if got := empty.SnapshotRange(0, 10); len(got) != 0 {
+		t.Errorf("empty stack SnapshotRange = %v", got)
+	}
+}
+
+func TestSnapshotRangeIsACopy(t *testing.T) {
+	s := newIntStack(2)
+	got := s.SnapshotRange(0, 2)
+	got[0] = 99
+	if top, _ := s.Pop(); top != 2 {
+		t.Fatalf("snapshot aliased the stack: top = %d", top)
+	}
+}
+
+func TestStackRemoveAndUpdateFunc(t *testing.T) {
+	s := newIntStack(6)
+	removed := s.RemoveFunc(func(v int) bool { return v%2 == 0 })
+	if !slices.Equal(removed, []int{2, 4, 6}) || !slices.Equal(s.Items(), []int{1, 3, 5}) {
+		t.Fatalf("RemoveFunc removed %v, kept %v", removed, s.Items())
+	}
+	s.UpdateFunc(func(v int) (int, bool) { return v * 10, v != 3 })
+	if !slices.Equal(s.Items(), []int{10, 50}) {
+		t.Fatalf("UpdateFunc kept %v", s.Items())
+	}
+}
+
+func TestStackConcurrentPushPop(t *testing.T) {
+	var s Stack[int]
+	var wg sync.WaitGroup
+	for i := range 100 {
+		wg.Add(2)
+		go func() { defer wg.Done(); s.Push(i) }()
+		go func() { defer wg.Done(); s.SnapshotRange(0, 10) }()
+	}
+	wg.Wait()
+	if s.Len() != 100 {
+		t.Fatalf("Len = %d, want 100", s.Len())
+	}
+}
+
+func TestUndoSheddingFollowsLockWait(t *testing.T) {
+	withConfig(t, func(c *Config) { c.UndoShedLockWait = time.Millisecond })
+	saved := deletedStack.lockWait.Load()
+	t.Cleanup(func() {
+		deletedStack.lockWait.Store(saved)
+		undoShedding.Store(false)
+	})
+
+	deletedStack.lockWait.Store(int64(10 * time.Millisecond)) // Antrean lock panjang yang baru saja diukur
+	updateUndoShedding()
+	if !undoShedding.Load() {
+		t.Fatal("shedding not enabled above RETUR_UNDO_SHED_LOCK_WAIT")
+	}
+	for range 100 {
+		updateUndoShedding() // Setiap probe tanpa antrean menurunkan rata-rata bergerak
+		if !undoShedding.Load() {
+			return
+		}
+	}
+	t.Fatalf("shedding still enabled, lock wait %s", deletedStack.LockWait())
+}
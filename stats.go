@@ -0,0 +1,200 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"time"
+)
+
+// maxTimeseriesBuckets membatasi jumlah bucket agar query rentang yang sangat panjang tidak berat
+const maxTimeseriesBuckets = 400
+
+// timeseriesBucket adalah satu titik data pada grafik refund harian/mingguan/bulanan
+type timeseriesBucket struct {
+	Date          string `json:"date"`           // Tanggal awal bucket (YYYY-MM-DD)
+	ApprovedCount int64  `json:"approved_count"` // Jumlah retur yang disetujui pada bucket ini
+	RefundTotal   int64  `json:"refund_total"`   // Total refund dalam minor units untuk mata uang yang diminta
+}
+
+// bucketStart mengembalikan awal bucket untuk waktu t sesuai interval (day, week dimulai Senin, month)
+func bucketStart(t time.Time, interval string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch interval {
+	case "week":
+		offset := (int(day.Weekday()) + 6) % 7 // Jumlah hari sejak hari Senin
+		return day.AddDate(0, 0, -offset)
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	}
+	return day
+}
+
+// nextBucket mengembalikan awal bucket berikutnya setelah start
+func nextBucket(start time.Time, interval string) time.Time {
+	switch interval {
+	case "week":
+		return start.AddDate(0, 0, 7)
+	case "month":
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// timeseriesHandler adalah handler untuk data grafik jumlah approval dan total refund per interval waktu
+// Data dihitung per hari dengan query GROUP BY, lalu digabung ke interval yang diminta dan bucket kosong diisi nol
+func timeseriesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	interval := query.Get("interval")
+	if interval == "" {
+		interval = "day"
+	}
+	if interval != "day" && interval != "week" && interval != "month" {
+		handleError(w, http.StatusBadRequest, "interval must be 'day', 'week' or 'month'") // Validasi interval
+		return
+	}
+	currency, ok := normalizeCurrency(query.Get("currency"))
+	if query.Get("currency") == "" {
+		currency, ok = normalizeCurrency(cfg.DefaultCurrency) // Gunakan mata uang default jika tidak dikirim
+	}
+	if !ok {
+		handleError(w, http.StatusBadRequest, "Unknown currency code") // Kode mata uang tidak dikenal
+		return
+	}
+
+	to := clock.Now()
+	if raw := query.Get("to"); raw != "" {
+		var err error
+		if to, err = parseTimeParam(raw); err != nil {
+			handleError(w, http.StatusBadRequest, "Invalid to, use YYYY-MM-DD or RFC3339") // Format waktu tidak valid
+			return
+		}
+	}
+	from := to.AddDate(0, 0, -30) // Default 30 hari terakhir
+	if raw := query.Get("from"); raw != "" {
+		var err error
+		if from, err = parseTimeParam(raw); err != nil {
+			handleError(w, http.StatusBadRequest, "Invalid from, use YYYY-MM-DD or RFC3339") // Format waktu tidak valid
+			return
+		}
+	}
+	if from.After(to) {
+		handleError(w, http.StatusBadRequest, "from must not be after to") // Rentang waktu terbalik
+		return
+	}
+
+	// Siapkan semua bucket terlebih dahulu agar hari tanpa aktivitas tetap muncul dengan nilai nol
+	var buckets []*timeseriesBucket
+	index := make(map[string]*timeseriesBucket)
+	for start := bucketStart(from, interval); !start.After(to); start = nextBucket(start, interval) {
+		if len(buckets) >= maxTimeseriesBuckets {
+			handleError(w, http.StatusBadRequest, "Range too large for the requested interval") // Terlalu banyak bucket
+			return
+		}
+		bucket := &timeseriesBucket{Date: start.Format("2006-01-02")}
+		buckets = append(buckets, bucket)
+		index[bucket.Date] = bucket
+	}
+
+	var rows []struct {
+		Day           time.Time
+		ApprovedCount int64
+		RefundTotal   int64
+	}
+	err := db.WithContext(r.Context()).Model(&Retur{}).
+		Select("DATE(decided_at) AS day, COUNT(*) AS approved_count, COALESCE(SUM(CASE WHEN currency = ? THEN refund_amount ELSE 0 END), 0) AS refund_total", currency).
+		Where("status = ? AND decided_at >= ? AND decided_at < ?", "Disetujui", bucketStart(from, "day"), bucketStart(to, "day").AddDate(0, 0, 1)).
+		Group("DATE(decided_at)").
+		Scan(&rows).Error
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to compute timeseries") // Jika query gagal, kirimkan error
+		return
+	}
+	for _, row := range rows {
+		if bucket, ok := index[bucketStart(row.Day, interval).Format("2006-01-02")]; ok {
+			bucket.ApprovedCount += row.ApprovedCount // Gabungkan data harian ke bucket interval
+			bucket.RefundTotal += row.RefundTotal
+		}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"interval": interval,
+		"currency": currency,
+		"buckets":  buckets,
+	})
+}
+
+// barangStats adalah jumlah retur untuk satu barang beserta rinciannya per kode alasan dan status
+type barangStats struct {
+	Barang   string           `json:"barang"`
+	Count    int64            `json:"count"`     // Total retur untuk barang ini
+	ByReason map[string]int64 `json:"by_reason"` // Jumlah per reason_code; kosong berarti belum diberi kode
+	ByStatus map[string]int64 `json:"by_status"` // Jumlah per status retur
+}
+
+// statsByBarangHandler adalah handler untuk jumlah retur per barang pada rentang waktu pembuatan from..to (opsional)
+// Dihitung dengan satu query GROUP BY barang, reason_code, status lalu digabung per barang,
+// dengan nama kanonik dari katalog (canonical_barang) dipakai jika ada agar varian penulisan tergabung,
+// diurutkan dari jumlah terbanyak dan dibatasi ?limit (default 20, maksimum maxListLimit)
+func statsByBarangHandler(w http.ResponseWriter, r *http.Request) {
+	limit, err := queryInt(r, "limit", 20)
+	if err != nil || limit == 0 {
+		handleError(w, http.StatusBadRequest, "Invalid limit") // Limit harus berupa angka positif
+		return
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	scope := db.WithContext(r.Context()).Model(&Retur{}).
+		Select("COALESCE(NULLIF(canonical_barang, ''), barang) AS barang, reason_code, status, COUNT(*) AS count").
+		Group("COALESCE(NULLIF(canonical_barang, ''), barang), reason_code, status")
+	for _, param := range []struct {
+		name, op string
+	}{{"from", ">="}, {"to", "<"}} {
+		raw := r.URL.Query().Get(param.name)
+		if raw == "" {
+			continue
+		}
+		t, err := parseTimeParam(raw)
+		if err != nil {
+			handleError(w, http.StatusBadRequest, "Invalid "+param.name+", use YYYY-MM-DD or RFC3339") // Format waktu tidak valid
+			return
+		}
+		scope = scope.Where("created_at "+param.op+" ?", t) // Batasi berdasarkan waktu retur dibuat
+	}
+
+	var rows []struct {
+		Barang     string
+		ReasonCode string
+		Status     string
+		Count      int64
+	}
+	if err := scope.Scan(&rows).Error; err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to compute stats by barang") // Jika query gagal, kirimkan error
+		return
+	}
+
+	index := make(map[string]*barangStats)
+	result := []*barangStats{}
+	for _, row := range rows {
+		stats, ok := index[row.Barang]
+		if !ok {
+			stats = &barangStats{Barang: row.Barang, ByReason: map[string]int64{}, ByStatus: map[string]int64{}}
+			index[row.Barang] = stats
+			result = append(result, stats)
+		}
+		stats.Count += row.Count
+		stats.ByReason[row.ReasonCode] += row.Count
+		stats.ByStatus[row.Status] += row.Count
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Barang < result[j].Barang // Urutan stabil untuk jumlah yang sama
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"barang": result})
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBucketStartAndNext(t *testing.T) {
+	at := time.Date(2024, 2, 29, 15, 30, 0, 0, time.UTC) // Kamis
+	tests := []struct {
+		interval    string
+		start, next string
+	}{
+		{"day", "2024-02-29", "2024-03-01"},
+		{"week", "2024-02-26", "2024-03-04"}, // Minggu dimulai hari Senin
+		{"month", "2024-02-01", "2024-03-01"},
+	}
+	for _, tt := range tests {
+		start := bucketStart(at, tt.interval)
+		if got := start.Format("2006-01-02"); got != tt.start {
+			t.Errorf("%s start = %s, want %s", tt.interval, got, tt.start)
+		}
+		if got := nextBucket(start, tt.interval).Format("2006-01-02"); got != tt.next {
+			t.Errorf("%s next = %s, want %s", tt.interval, got, tt.next)
+		}
+	}
+	sunday := time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC)
+	if got := bucketStart(sunday, "week").Format("2006-01-02"); got != "2024-02-26" {
+		t.Errorf("week of Sunday starts %s", got)
+	}
+}
+
+func TestStatsByBarangGroupsCanonicalNames(t *testing.T) {
+	testDB(t)
+	seedReturs(t,
+		Retur{Barang: "iphone13", CanonicalBarang: "iPhone 13", Alasan: "x", ReasonCode: "DAMAGED"},
+		Retur{Barang: "IPHONE 13", CanonicalBarang: "iPhone 13", Alasan: "x", ReasonCode: "DAMAGED", Status: "Disetujui"},
+		Retur{Barang: "Kabel", Alasan: "x"},
+		Retur{Barang: "Charger", Alasan: "x"},
+	)
+	var body struct {
+		Barang []barangStats `json:"barang"`
+	}
+	rec := serve(httptest.NewRequest(http.MethodGet, "/retur/stats/by-barang?limit=2", nil))
+	decodeBody(t, rec, &body)
+	if rec.Code != http.StatusOK || len(body.Barang) != 2 {
+		t.Fatalf("stats: %d %+v", rec.Code, body.Barang)
+	}
+	top := body.Barang[0]
+	if top.Barang != "iPhone 13" || top.Count != 2 || top.ByReason["DAMAGED"] != 2 || top.ByStatus["Disetujui"] != 1 {
+		t.Fatalf("top = %+v", top)
+	}
+	if body.Barang[1].Barang != "Charger" { // Jumlah sama diurutkan berdasarkan nama
+		t.Fatalf("second = %+v", body.Barang[1])
+	}
+	if rec := serve(httptest.NewRequest(http.MethodGet, "/retur/stats/by-barang?from=kemarin", nil)); rec.Code != http.StatusBadRequest {
+		t.Fatalf("invalid from: %d", rec.Code)
+	}
+}
+
+func TestStatsETagRevalidation(t *testing.T) {
+	testDB(t)
+	t.Cleanup(bus.Subscribe(invalidateStats))
+	cachedStats.Invalidate() // Jangan memakai cache dari test lain
+	t.Cleanup(cachedStats.Invalidate)
+	seedReturs(t, Retur{Barang: "Kabel", Alasan: "x"})
+
+	first := serve(httptest.NewRequest(http.MethodGet, "/retur/stats", nil))
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("first poll: %d, ETag %q", first.Code, etag)
+	}
+	poll := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/retur/stats", nil)
+		req.Header.Set("If-None-Match", etag)
+		return serve(req)
+	}
+	if rec := poll(); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Fatalf("unchanged stats: %d %q, want 304 without body", rec.Code, rec.Body.String())
+	}
+
+	createViaAPI(t, map[string]interface{}{"barang": "Charger", "alasan": "panas"})
+	rec := poll()
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == "" || rec.Header().Get("ETag") == etag {
+		t.Fatalf("after write: %d, ETag %q (was %q), want 200 with a new ETag", rec.Code, rec.Header().Get("ETag"), etag)
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{`W/"abc"`, true},
+		{`"abc"`, true},          // Perbandingan weak: prefix W/ diabaikan
+		{`"xyz", W/"abc"`, true}, // Salah satu dari beberapa ETag
+		{`*`, true},
+		{`"xyz"`, false},
+		{``, false},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, `W/"abc"`); got != tt.want {
+			t.Errorf("etagMatches(%q) = %t, want %t", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// statsSnapshot adalah ringkasan jumlah retur per status dan total refund per mata uang untuk dashboard
+type statsSnapshot struct {
+	Total       int64            `json:"total"`        // Jumlah seluruh retur
+	ByStatus    map[string]int64 `json:"by_status"`    // Jumlah retur per status
+	RefundTotal map[string]int64 `json:"refund_total"` // Total refund retur yang disetujui per mata uang (minor units)
+}
+
+// statsCache menyimpan hasil /retur/stats terakhir beserta ETag-nya
+// Cache dikosongkan setiap ada perubahan retur (lewat event bus) atau setelah RETUR_STATS_CACHE_TTL
+type statsCache struct {
+	mu         sync.Mutex
+	snapshot   *statsSnapshot
+	etag       string
+	computedAt time.Time
+}
+
+// cachedStats adalah cache untuk endpoint /retur/stats
+var cachedStats statsCache
+
+// Invalidate mengosongkan cache agar request berikutnya menghitung ulang statistik
+func (c *statsCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.snapshot = nil
+}
+
+// Get mengembalikan statistik dan ETag dari cache, menghitung ulang jika cache kosong atau kedaluwarsa
+func (c *statsCache) Get() (*statsSnapshot, string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.snapshot != nil && clock.Now().Sub(c.computedAt) < cfg.StatsCacheTTL {
+		return c.snapshot, c.etag, nil
+	}
+	snapshot, err := computeStats()
+	if err != nil {
+		return nil, "", err
+	}
+	body, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(body)
+	c.snapshot = snapshot
+	c.etag = `W/"` + hex.EncodeToString(sum[:16]) + `"` // Weak ETag karena format response bisa berbeda (pretty, naming)
+	c.computedAt = clock.Now()
+	return c.snapshot, c.etag, nil
+}
+
+// computeStats menghitung statistik retur langsung dari database
+func computeStats() (*statsSnapshot, error) {
+	snapshot := &statsSnapshot{ByStatus: map[string]int64{}, RefundTotal: map[string]int64{}}
+
+	var byStatus []struct {
+		Status string
+		Count  int64
+	}
+	if err := db.Model(&Retur{}).Select("status, COUNT(*) AS count").Group("status").Scan(&byStatus).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range byStatus {
+		snapshot.ByStatus[row.Status] = row.Count
+		snapshot.Total += row.Count
+	}
+
+	var refunds []struct {
+		Currency string
+		Total    int64
+	}
+	if err := db.Model(&Retur{}).Select("currency, SUM(refund_amount) AS total").
+		Where("status = ? AND refund_amount > 0", "Disetujui").Group("currency").Scan(&refunds).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range refunds {
+		snapshot.RefundTotal[row.Currency] = row.Total
+	}
+	return snapshot, nil
+}
+
+// statsHandler adalah handler untuk ringkasan statistik retur
+// Mendukung If-None-Match: jika statistik tidak berubah sejak ETag terakhir, response 304 tanpa body
+func statsHandler(w http.ResponseWriter, r *http.Request) {
+	snapshot, etag, err := cachedStats.Get()
+	if err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to compute stats")
+		return
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache") // Client boleh menyimpan response tetapi wajib revalidasi dengan ETag
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	respondJSON(w, http.StatusOK, snapshot)
+}
+
+// etagMatches memeriksa apakah header If-None-Match berisi etag (atau "*")
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// startTime mencatat waktu aplikasi mulai berjalan untuk perhitungan uptime
+var startTime = clock.Now()
+
+// statusHandler adalah handler untuk menampilkan snapshot diagnostik aplikasi (database, undo, uptime, konfigurasi)
+// Endpoint ini dilindungi admin karena menampilkan kondisi internal aplikasi
+func statusHandler(w http.ResponseWriter, r *http.Request) {
+	dbStatus := map[string]interface{}{"status": "ok"}
+	sqlDB, err := db.DB() // Ambil koneksi database/sql di balik GORM
+	if err != nil {
+		dbStatus["status"] = "error"
+		dbStatus["error"] = err.Error()
+	} else {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second) // Batasi waktu ping agar status tetap responsif
+		defer cancel()
+		if err := sqlDB.PingContext(ctx); err != nil {
+			dbStatus["status"] = "unreachable"
+			dbStatus["error"] = err.Error()
+		}
+		stats := sqlDB.Stats()
+		dbStatus["open_connections"] = stats.OpenConnections
+		dbStatus["in_use"] = stats.InUse
+		dbStatus["idle"] = stats.Idle
+		dbStatus["wait_count"] = stats.WaitCount
+		dbStatus["max_open_connections"] = stats.MaxOpenConnections
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"database": dbStatus,
+		"breaker":  dbBreaker.Snapshot(), // Kondisi circuit breaker database
+		"writes":   writeHealth(),        // Waktu tulis database terakhir yang berhasil dan gagal
+		"undo": map[string]interface{}{
+			"stack_depth":       deletedStack.Len(), // Jumlah retur yang bisa di-undo
+			"reusable_ids":      deletedIDCount(),   // Jumlah ID yang menunggu untuk digunakan ulang
+			"id_pool_cap":       cfg.IDPoolCap,      // 0 berarti tanpa batas
+			"id_pool_evictions": idPoolEvictions.Load(),
+			"max_age":           cfg.MaxUndoAge.String(), // 0 berarti tanpa batas umur
+			"shedding":          undoShedding.Load(),     // true jika delete sedang tidak masuk stack undo karena beban tinggi
+			"skipped":           undoSkipped.Load(),
+			"lock_wait":         deletedStack.LockWait().String(), // Rata-rata waktu tunggu lock stack undo (RETUR_UNDO_SHED_LOCK_WAIT)
+		},
+		"jobs":           jobsStatus(), // Kondisi job background
+		"uptime_seconds": int64(clock.Now().Sub(startTime).Seconds()),
+		"started_at":     startTime.UTC(),
+		"config":         cfg.Redacted(), // Konfigurasi aktif dengan nilai rahasia disamarkan
+	})
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// tagPattern adalah format tag yang diterima: huruf kecil, angka, "-", "_" atau ":", maksimal 50 karakter
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_:-]{0,49}$`)
+
+// normalizeTags merapikan daftar tag dari client (trim, huruf kecil) dan mengembalikan masalah validasi per tag
+func normalizeTags(raw []string) ([]string, []fieldError) {
+	var tags []string
+	var errs []fieldError
+	for i, tag := range raw {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !tagPattern.MatchString(tag) {
+			errs = append(errs, fieldError{Field: fmt.Sprintf("tags[%d]", i), Message: "must match " + tagPattern.String()})
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	if len(tags) == 0 && len(errs) == 0 {
+		errs = append(errs, fieldError{Field: "tags", Message: "is required"})
+	}
+	return tags, errs
+}
+
+// addTags menambahkan tag yang belum ada ke retur dan mengembalikan false jika hasilnya melewati RETUR_MAX_TAGS
+func addTags(retur *Retur, tags []string) bool {
+	merged := append([]string{}, retur.Tags...)
+	for _, tag := range tags {
+		if !containsTag(merged, tag) {
+			merged = append(merged, tag)
+		}
+	}
+	if len(merged) > cfg.MaxTagsPerRetur {
+		return false
+	}
+	sort.Strings(merged) // Urutan tetap agar audit log tidak mencatat perubahan urutan saja
+	retur.Tags = merged
+	return true
+}
+
+// errTooManyTags menandai penambahan tag yang akan melewati RETUR_MAX_TAGS
+var errTooManyTags = errors.New("too many tags")
+
+// containsTag memeriksa apakah tag sudah ada di daftar
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// saveTags menyimpan kolom tags retur beserta audit log di dalam transaksi tx
+func saveTags(tx *gorm.DB, actor string, before, retur Retur) error {
+	if err := tx.Model(&retur).Select("tags").Updates(&retur).Error; err != nil {
+		return err
+	}
+	return recordAudit(tx, "update", actor, before, retur)
+}
+
+// addReturTagsHandler adalah handler untuk menambahkan tag ke satu retur (POST /retur/{id}/tags {"tags": [...]})
+// Tag yang sudah ada diabaikan; jumlah tag per retur dibatasi RETUR_MAX_TAGS
+func addReturTagsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"]) // Convert ID dari string ke integer
+	if err != nil {
+		handleError(w, http.StatusBadRequest, "Invalid ID format") // Jika format ID salah, kirimkan error
+		return
+	}
+	var input struct {
+		Tags []string `json:"tags"` // Tag yang ditambahkan, misalnya ["fraud-review"]
+	}
+	if !decodeJSON(w, r, &input) {
+		return // Body kosong atau JSON tidak valid, error sudah dikirim
+	}
+	tags, errs := normalizeTags(input.Tags)
+	if len(errs) > 0 {
+		respondValidationErrors(w, errs)
+		return
+	}
+
+	var retur Retur
+	err = withTransaction(r.Context(), func(tx *gorm.DB) error {
+		// Dibaca ulang dengan FOR UPDATE agar dua request tag bersamaan tidak saling menimpa kolom tags
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&retur, id).Error; err != nil {
+			return err
+		}
+		before := retur
+		if !addTags(&retur, tags) {
+			return errTooManyTags
+		}
+		return saveTags(tx, actorFromRequest(r), before, retur)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		handleError(w, http.StatusNotFound, "Return not found") // Jika retur tidak ditemukan, kirimkan error
+		return
+	}
+	if errors.Is(err, errTooManyTags) {
+		respondValidationErrors(w, []fieldError{{Field: "tags", Message: fmt.Sprintf("must contain at most %d tags per return", cfg.MaxTagsPerRetur)}})
+		return
+	}
+	if err != nil {
+		respondSaveError(w, err, "Failed to update tags")
+		return
+	}
+	applySLA(&retur)
+	publishEvent(ReturUpdated, retur, r)
+	maskRetur(r, &retur)
+	respondJSON(w, http.StatusOK, retur)
+}
+
+// removeReturTagHandler adalah handler untuk menghapus satu tag dari retur (DELETE /retur/{id}/tags/{tag})
+// Menghapus tag yang tidak ada tetap berhasil agar request bisa diulang dengan aman
+func removeReturTagHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"]) // Convert ID dari string ke integer
+	if err != nil {
+		handleError(w, http.StatusBadRequest, "Invalid ID format") // Jika format ID salah, kirimkan error
+		return
+	}
+	tag := strings.ToLower(mux.Vars(r)["tag"])
+
+	var retur Retur
+	removed := false
+	err = withTransaction(r.Context(), func(tx *gorm.DB) error {
+		// Dibaca ulang dengan FOR UPDATE agar penghapusan tag tidak menimpa tag yang baru ditambahkan request lain
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&retur, id).Error; err != nil {
+			return err
+		}
+		if !containsTag(retur.Tags, tag) {
+			return nil
+		}
+		before := retur
+		kept := []string{}
+		for _, t := range retur.Tags {
+			if t != tag {
+				kept = append(kept, t)
+			}
+		}
+		retur.Tags = kept
+		removed = true
+		return saveTags(tx, actorFromRequest(r), before, retur)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		handleError(w, http.StatusNotFound, "Return not found") // Jika retur tidak ditemukan, kirimkan error
+		return
+	}
+	if err != nil {
+		respondSaveError(w, err, "Failed to update tags")
+		return
+	}
+	if removed {
+		publishEvent(ReturUpdated, retur, r)
+	}
+	applySLA(&retur)
+	maskRetur(r, &retur)
+	respondJSON(w, http.StatusOK, retur)
+}
+
+// bulkTagHandler adalah handler untuk menambahkan tag yang sama ke banyak retur dalam satu transaksi
+// ID yang tidak ditemukan dilaporkan di not_found, dan retur yang akan melewati RETUR_MAX_TAGS dilewati (skipped)
+func bulkTagHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		IDs  []int    `json:"ids"`  // Daftar ID retur yang diberi tag
+		Tags []string `json:"tags"` // Tag yang ditambahkan ke semua retur
+	}
+	if !decodeJSON(w, r, &input) {
+		return // Body kosong atau JSON tidak valid, error sudah dikirim
+	}
+	if len(input.IDs) == 0 {
+		handleError(w, http.StatusBadRequest, "At least one id is required") // Daftar ID tidak boleh kosong
+		return
+	}
+	if len(input.IDs) > cfg.BatchMaxIDs {
+		handleError(w, http.StatusBadRequest, fmt.Sprintf("At most %d ids per request", cfg.BatchMaxIDs)) // Batasi jumlah ID per request
+		return
+	}
+	tags, errs := normalizeTags(input.Tags)
+	if len(errs) > 0 {
+		respondValidationErrors(w, errs)
+		return
+	}
+
+	var returs []Retur
+	tagged, skipped := []int{}, []int{}
+	var changed []Retur
+	err := withTransaction(r.Context(), func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", input.IDs).Order("id").Find(&returs).Error; err != nil {
+			return err
+		}
+		for _, retur := range returs {
+			before := retur
+			if !addTags(&retur, tags) {
+				skipped = append(skipped, retur.ID) // Jumlah tag akan melewati RETUR_MAX_TAGS
+				continue
+			}
+			if err := saveTags(tx, actorFromRequest(r), before, retur); err != nil {
+				return err
+			}
+			tagged = append(tagged, retur.ID)
+			changed = append(changed, retur)
+		}
+		return nil
+	})
+	if err != nil {
+		respondSaveError(w, err, "Failed to update tags")
+		return
+	}
+	for _, retur := range changed {
+		publishEvent(ReturUpdated, retur, r)
+	}
+
+	found := make(map[int]bool, len(returs))
+	for _, retur := range returs {
+		found[retur.ID] = true
+	}
+	notFound := []int{}
+	for _, id := range input.IDs {
+		if !found[id] {
+			notFound = append(notFound, id)
+			found[id] = true // Hindari duplikat pada daftar not_found
+		}
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"tags": tags, "tagged": tagged, "skipped": skipped, "not_found": notFound})
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tags, errs := normalizeTags([]string{" Fraud-Review ", "vip:gold", "bad tag", ""})
+	if !slices.Equal(tags, []string{"fraud-review", "vip:gold"}) {
+		t.Fatalf("tags = %v", tags)
+	}
+	if len(errs) != 2 || errs[0].Field != "tags[2]" || errs[1].Field != "tags[3]" {
+		t.Fatalf("errors = %+v", errs)
+	}
+	if _, errs := normalizeTags(nil); len(errs) != 1 || errs[0].Field != "tags" {
+		t.Fatalf("empty list errors = %+v", errs)
+	}
+}
+
+func TestAddTagsDeduplicatesAndEnforcesLimit(t *testing.T) {
+	withConfig(t, func(c *Config) { c.MaxTagsPerRetur = 3 })
+	retur := Retur{Tags: []string{"vip"}}
+	if !addTags(&retur, []string{"urgent", "vip"}) || !slices.Equal(retur.Tags, []string{"urgent", "vip"}) {
+		t.Fatalf("tags = %v", retur.Tags)
+	}
+	if addTags(&retur, []string{"a", "b"}) {
+		t.Fatal("limit of 3 tags exceeded")
+	}
+	if !slices.Equal(retur.Tags, []string{"urgent", "vip"}) {
+		t.Fatalf("rejected add modified tags: %v", retur.Tags)
+	}
+}
+
+func TestTagHandlersRoundTrip(t *testing.T) {
+	testDB(t)
+	withConfig(t, func(c *Config) { c.MaxTagsPerRetur = 2 })
+	seeded := seedReturs(t, Retur{Barang: "Tas", Alasan: "robek"}, Retur{Barang: "Dompet", Alasan: "warna", Tags: []string{"a", "b"}})
+	path := fmt.Sprintf("/retur/%d/tags", seeded[0].ID)
+
+	var retur Retur
+	rec := serve(jsonRequest(t, http.MethodPost, path, map[string][]string{"tags": {"VIP"}}))
+	decodeBody(t, rec, &retur)
+	if rec.Code != http.StatusOK || !slices.Equal(retur.Tags, []string{"vip"}) {
+		t.Fatalf("add: %d %v", rec.Code, retur.Tags)
+	}
+	for range 2 { // Menghapus tag yang sudah tidak ada tetap berhasil
+		rec = serve(httptest.NewRequest(http.MethodDelete, path+"/vip", nil))
+		decodeBody(t, rec, &retur)
+		if rec.Code != http.StatusOK || len(retur.Tags) != 0 {
+			t.Fatalf("remove: %d %v", rec.Code, retur.Tags)
+		}
+	}
+
+	var bulk struct {
+		Tagged   []int `json:"tagged"`
+		Skipped  []int `json:"skipped"`
+		NotFound []int `json:"not_found"`
+	}
+	ids := []int{seeded[0].ID, seeded[1].ID, 9999, 9999}
+	rec = serve(jsonRequest(t, http.MethodPost, "/retur/tags", map[string]interface{}{"ids": ids, "tags": []string{"new"}}))
+	decodeBody(t, rec, &bulk)
+	if !slices.Equal(bulk.Tagged, []int{seeded[0].ID}) || !slices.Equal(bulk.Skipped, []int{seeded[1].ID}) || !slices.Equal(bulk.NotFound, []int{9999}) {
+		t.Fatalf("bulk: %d %+v", rec.Code, bulk)
+	}
+}
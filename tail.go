@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// tailEvent adalah ringkasan satu kejadian yang dikirim ke stream tail admin
+type tailEvent struct {
+	Type   EventType `json:"type"`   // Jenis kejadian, misalnya retur.created atau retur.deleted
+	ID     int       `json:"id"`     // ID retur
+	Status string    `json:"status"` // Status retur setelah kejadian
+	Actor  string    `json:"actor"`  // Pengguna yang memicu kejadian
+	At     time.Time `json:"at"`     // Waktu kejadian
+
+	Redelivery bool `json:"redelivery,omitempty"` // Kirim ulang manual lewat POST /retur/{id}/notify
+}
+
+// tailHeartbeat adalah jeda komentar SSE agar proxy tidak menutup koneksi yang sedang sepi
+const tailHeartbeat = 15 * time.Second
+
+// tailHandler adalah handler admin yang mengirim setiap kejadian pada event bus sebagai Server-Sent Events
+// Setiap koneksi mendapat buffer sendiri (RETUR_TAIL_BUFFER); jika client terlalu lambat, kejadian dibuang alih-alih
+// menahan handler lain, dan jumlah yang dibuang dilaporkan lewat event "dropped"
+// Subscriber dilepas dari bus saat client memutus koneksi; actor disamarkan seperti di log (RETUR_PII_FIELDS)
+func tailHandler(w http.ResponseWriter, r *http.Request) {
+	controller := http.NewResponseController(w)
+	events := make(chan tailEvent, cfg.TailBuffer)
+	var dropped atomic.Int64
+	unsubscribe := bus.Subscribe(func(e Event) {
+		select {
+		case events <- tailEvent{Type: e.Type, ID: e.Retur.ID, Status: e.Retur.Status, Actor: redactPII("actor", e.Actor), At: e.At, Redelivery: e.Redelivery}:
+		default:
+			dropped.Add(1) // Buffer penuh, jangan memblokir publisher
+		}
+	})
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Matikan buffering pada proxy nginx
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": tail started\n\n")
+	if err := controller.Flush(); err != nil {
+		return // ResponseWriter tidak mendukung streaming
+	}
+
+	heartbeat := time.NewTicker(tailHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return // Client memutus koneksi
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": ping\n\n")
+		case event := <-events:
+			if lost := dropped.Swap(0); lost > 0 {
+				fmt.Fprintf(w, "event: dropped\ndata: {\"count\":%d}\n\n", lost)
+			}
+			data, _ := json.Marshal(event)
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+		}
+		if err == nil {
+			err = controller.Flush()
+		}
+		if err != nil {
+			return // Gagal menulis, koneksi kemungkinan sudah putus
+		}
+	}
+}
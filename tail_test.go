@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// subscriberCount mengembalikan jumlah subscriber yang terdaftar di event bus
+func subscriberCount() int {
+	bus.mu.RLock()
+	defer bus.mu.RUnlock()
+	return len(bus.subscribers)
+}
+
+func TestTailStreamsEventsAndUnsubscribes(t *testing.T) {
+	withConfig(t, func(c *Config) {
+		c.AdminToken = testAdminToken
+		c.PIIFields = []string{"actor"}
+		c.PIIMode = piiMask
+	})
+	server := httptest.NewServer(newRouter())
+	t.Cleanup(server.Close)
+	before := subscriberCount()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/retur/admin/tail", nil)
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("tail: %d %v", resp.StatusCode, resp.Header)
+	}
+	lines := bufio.NewScanner(resp.Body)
+	if !lines.Scan() || lines.Text() != ": tail started" {
+		t.Fatalf("first line %q", lines.Text())
+	}
+
+	bus.Publish(Event{Type: ReturCreated, Retur: Retur{ID: 7, Status: "Dalam Proses"}, Actor: "budi", At: time.Now()})
+	var eventLine, dataLine string
+	for lines.Scan() {
+		if line := lines.Text(); strings.HasPrefix(line, "event: ") {
+			eventLine = line
+		} else if strings.HasPrefix(line, "data: ") {
+			dataLine = line
+			break
+		}
+	}
+	var event tailEvent
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(dataLine, "data: ")), &event); err != nil {
+		t.Fatalf("data %q: %v", dataLine, err)
+	}
+	if eventLine != "event: "+string(ReturCreated) || event.ID != 7 || event.Actor != "**di" {
+		t.Fatalf("got %q %+v", eventLine, event)
+	}
+
+	resp.Body.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for subscriberCount() != before {
+		if time.Now().After(deadline) {
+			t.Fatalf("subscriber not removed after disconnect: %d, want %d", subscriberCount(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTailRequiresAdmin(t *testing.T) {
+	withConfig(t, func(c *Config) { c.AdminToken = testAdminToken })
+	if rec := serve(httptest.NewRequest(http.MethodGet, "/retur/admin/tail", nil)); rec.Code == http.StatusOK {
+		t.Fatal("tail served without admin token")
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// anonymousTenant adalah bucket rate limit bersama untuk request tanpa API key tenant yang valid
+// Tanpa bucket ini, klien cukup mengirim key acak (atau tanpa key) untuk lolos dari batas per tenant
+const anonymousTenant = ""
+
+// tenantFromRequest mencari tenant milik API key di header RETUR_TENANT_HEADER (RETUR_TENANT_KEYS)
+// Nilai header tidak pernah dipakai langsung sebagai nama tenant; key yang tidak dikenal berarti request tanpa tenant
+func tenantFromRequest(r *http.Request) string {
+	if cfg.TenantHeader == "" {
+		return anonymousTenant
+	}
+	key := strings.TrimSpace(r.Header.Get(cfg.TenantHeader))
+	if key == "" {
+		return anonymousTenant
+	}
+	for candidate, tenant := range cfg.TenantKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(candidate)) == 1 {
+			return tenant
+		}
+	}
+	return anonymousTenant
+}
+
+// parseIntMap membaca daftar "nama=angka" dipisahkan koma, misalnya "acme=600,globex=60"; nilai negatif diabaikan
+func parseIntMap(raw string) map[string]int {
+	result := make(map[string]int)
+	for _, item := range strings.Split(raw, ",") {
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n < 0 {
+			continue
+		}
+		result[strings.TrimSpace(name)] = n
+	}
+	return result
+}
+
+// tenantLimit mengembalikan nilai override per tenant jika ada, selain itu nilai default; 0 berarti tanpa batas
+func tenantLimit(overrides map[string]int, def int, tenant string) int {
+	if limit, ok := overrides[tenant]; ok {
+		return limit
+	}
+	return def
+}
+
+// tenantBucket adalah token bucket satu tenant: kapasitas sama dengan limit per menit dan terisi ulang secara merata
+type tenantBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// maxTenantBuckets adalah jumlah bucket sebelum bucket tenant yang sudah lama tidak aktif dibersihkan
+const maxTenantBuckets = 10000
+
+// tenantBuckets menyimpan token bucket per tenant
+var tenantBuckets = struct {
+	sync.Mutex
+	byTenant map[string]*tenantBucket
+}{byTenant: make(map[string]*tenantBucket)}
+
+// takeTenantToken mengambil satu token dari bucket tenant; jika habis, mengembalikan lama menunggu sampai token berikutnya
+func takeTenantToken(tenant string, perMinute int, now time.Time) (bool, time.Duration) {
+	tenantBuckets.Lock()
+	defer tenantBuckets.Unlock()
+	capacity := float64(perMinute)
+	bucket, ok := tenantBuckets.byTenant[tenant]
+	if !ok && len(tenantBuckets.byTenant) >= maxTenantBuckets {
+		for name, b := range tenantBuckets.byTenant {
+			if now.Sub(b.last) >= time.Minute {
+				delete(tenantBuckets.byTenant, name) // Bucket yang sudah penuh kembali sama dengan bucket baru
+			}
+		}
+	}
+	if !ok {
+		bucket = &tenantBucket{tokens: capacity, last: now}
+		tenantBuckets.byTenant[tenant] = bucket
+	}
+	refill := now.Sub(bucket.last).Minutes() * capacity
+	bucket.tokens = math.Min(capacity, bucket.tokens+refill)
+	bucket.last = now
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / capacity * float64(time.Minute))
+		return false, wait
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// tenantRateLimitMiddleware membatasi jumlah request per menit untuk setiap tenant secara terpisah
+// (RETUR_TENANT_RATE_LIMIT, override per tenant lewat RETUR_TENANT_RATE_LIMITS) dan menjawab 429 jika terlewati
+// Request tanpa tenant berbagi satu bucket dengan limit RETUR_TENANT_RATE_LIMIT
+func tenantRateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant := tenantFromRequest(r)
+		limit := cfg.TenantRateLimit
+		if tenant != anonymousTenant {
+			limit = tenantLimit(cfg.TenantRateLimits, cfg.TenantRateLimit, tenant)
+		}
+		if cfg.TenantHeader == "" || limit <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if ok, wait := takeTenantToken(tenant, limit, time.Now()); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			handleError(w, http.StatusTooManyRequests, "Tenant rate limit exceeded, try again later")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// TenantQuota mencatat jumlah retur yang dibuat tenant dalam satu periode bulanan (YYYY-MM)
+// Periode baru otomatis dimulai dari nol karena memakai baris baru
+type TenantQuota struct {
+	Tenant  string `json:"tenant" gorm:"primaryKey;size:100"`
+	Period  string `json:"period" gorm:"primaryKey;size:7"` // Bulan kuota, misalnya 2024-01
+	Creates int    `json:"creates"`                         // Jumlah retur yang sudah dibuat pada periode ini
+}
+
+// errTenantQuota menandai pembuatan retur yang ditolak karena kuota bulanan tenant sudah habis
+var errTenantQuota = errors.New("monthly create quota exhausted for this tenant")
+
+// consumeTenantQuota menaikkan pemakaian kuota create bulanan tenant di dalam transaksi tx, atau mengembalikan
+// errTenantQuota jika kuota (RETUR_TENANT_CREATE_QUOTA, override lewat RETUR_TENANT_CREATE_QUOTAS) sudah habis
+// Baris kuota dikunci (SELECT ... FOR UPDATE) agar create bersamaan tidak melewati kuota
+func consumeTenantQuota(tx *gorm.DB, r *http.Request) error {
+	tenant := tenantFromRequest(r)
+	quota := tenantLimit(cfg.TenantCreateQuotas, cfg.TenantCreateQuota, tenant)
+	if tenant == anonymousTenant || quota <= 0 {
+		return nil
+	}
+	usage := TenantQuota{Tenant: tenant, Period: clock.Now().UTC().Format("2006-01")}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&usage).Error; err != nil {
+		return err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&usage, "tenant = ? AND period = ?", usage.Tenant, usage.Period).Error; err != nil {
+		return err
+	}
+	if usage.Creates >= quota {
+		return errTenantQuota
+	}
+	return tx.Model(&usage).Update("creates", gorm.Expr("creates + 1")).Error
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// useTenantBuckets mengosongkan token bucket tenant selama satu test
+func useTenantBuckets(tb testing.TB) {
+	tb.Helper()
+	tenantBuckets.Lock()
+	saved := tenantBuckets.byTenant
+	tenantBuckets.byTenant = make(map[string]*tenantBucket)
+	tenantBuckets.Unlock()
+	tb.Cleanup(func() {
+		tenantBuckets.Lock()
+		tenantBuckets.byTenant = saved
+		tenantBuckets.Unlock()
+	})
+}
+
+func TestTenantFromRequestUsesKnownKeysOnly(t *testing.T) {
+	withConfig(t, func(c *Config) {
+		c.TenantHeader = "X-Tenant-ID"
+		c.TenantKeys = map[string]string{"key-acme": "acme"}
+	})
+	for key, want := range map[string]string{"key-acme": "acme", " key-acme ": "acme", "acme": anonymousTenant, "": anonymousTenant} {
+		req := httptest.NewRequest(http.MethodGet, "/retur", nil)
+		req.Header.Set("X-Tenant-ID", key)
+		if got := tenantFromRequest(req); got != want {
+			t.Errorf("key %q: tenant %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestParseIntMapAndTenantLimit(t *testing.T) {
+	limits := parseIntMap(" acme = 600,globex=-1,bad,initech=x,umbrella=0")
+	if len(limits) != 2 || limits["acme"] != 600 || limits["umbrella"] != 0 {
+		t.Fatalf("limits = %v", limits)
+	}
+	if tenantLimit(limits, 60, "acme") != 600 || tenantLimit(limits, 60, "umbrella") != 0 || tenantLimit(limits, 60, "other") != 60 {
+		t.Fatal("unexpected tenant limit")
+	}
+}
+
+func TestTakeTenantTokenRefills(t *testing.T) {
+	useTenantBuckets(t)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 2 {
+		if ok, _ := takeTenantToken("acme", 2, now); !ok {
+			t.Fatalf("request %d rejected within limit", i+1)
+		}
+	}
+	ok, wait := takeTenantToken("acme", 2, now)
+	if ok || wait != 30*time.Second {
+		t.Fatalf("third request: ok=%v wait=%v, want rejected with 30s wait", ok, wait)
+	}
+	if ok, _ := takeTenantToken("globex", 2, now); !ok {
+		t.Fatal("buckets are not separate per tenant")
+	}
+	if ok, _ := takeTenantToken("acme", 2, now.Add(30*time.Second)); !ok {
+		t.Fatal("token not refilled after 30s")
+	}
+}
+
+func TestTenantRateLimitMiddlewareSharesAnonymousBucket(t *testing.T) {
+	useTenantBuckets(t)
+	withConfig(t, func(c *Config) {
+		c.TenantHeader = "X-Tenant-ID"
+		c.TenantKeys = map[string]string{"key-acme": "acme"}
+		c.TenantRateLimit = 1
+		c.TenantRateLimits = map[string]int{"acme": 0} // acme tanpa batas
+	})
+	send := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/retur/undo", nil)
+		req.Header.Set("X-Tenant-ID", key)
+		return serve(req)
+	}
+	if rec := send("random-1"); rec.Code != http.StatusOK {
+		t.Fatalf("first anonymous request: %d", rec.Code)
+	}
+	rec := send("random-2") // Key acak tidak mendapat bucket baru
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("second anonymous request: %d %v", rec.Code, rec.Header())
+	}
+	for range 3 {
+		if rec := send("key-acme"); rec.Code != http.StatusOK {
+			t.Fatalf("unlimited tenant rejected: %d", rec.Code)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// returDecision adalah ringkasan keputusan terakhir pada retur
+type returDecision struct {
+	Status       string     `json:"status"`
+	Pengembalian string     `json:"pengembalian"`
+	RefundAmount int64      `json:"refund_amount"`
+	Currency     string     `json:"currency"`
+	DecidedBy    string     `json:"decided_by"`
+	DecidedAt    *time.Time `json:"decided_at"`
+	Note         string     `json:"note"`
+}
+
+// returTimeline adalah seluruh data satu retur dalam satu dokumen untuk tampilan detail
+type returTimeline struct {
+	Retur       Retur          `json:"retur"`       // Data retur beserta item-nya
+	History     []ReturHistory `json:"history"`     // Riwayat perubahan status, dari yang terlama
+	Attachments []Attachment   `json:"attachments"` // Metadata lampiran retur, dari yang terlama
+	Decision    *returDecision `json:"decision"`    // Keputusan terakhir, null jika retur belum diputuskan
+}
+
+// getReturFullHandler adalah handler untuk mengambil retur beserta item, riwayat status, dan keputusannya sekaligus
+// Data diambil dengan jumlah query tetap (retur, item, riwayat, lampiran) tanpa N+1
+func getReturFullHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"]) // Convert ID dari string ke integer
+	if err != nil {
+		handleError(w, http.StatusBadRequest, "Invalid ID format") // Jika format ID salah, kirimkan error
+		return
+	}
+
+	var retur Retur
+	if err := db.WithContext(r.Context()).Preload("Items").First(&retur, id).Error; err != nil {
+		handleError(w, http.StatusNotFound, "Return not found") // Jika retur tidak ditemukan, kirimkan error
+		return
+	}
+	applySLA(&retur)
+	maskRetur(r, &retur)
+
+	history := []ReturHistory{}
+	// Riwayat dibatasi sejak retur dibuat agar riwayat milik retur lama dengan ID yang sama (ID reuse) tidak ikut tampil
+	if err := db.WithContext(r.Context()).Where("retur_id = ? AND created_at >= ?", retur.ID, retur.CreatedAt).Order("created_at, id").Find(&history).Error; err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to retrieve return history")
+		return
+	}
+
+	attachments := []Attachment{}
+	// Lampiran juga dibatasi sejak retur dibuat dengan alasan yang sama seperti riwayat
+	if err := db.WithContext(r.Context()).Where("retur_id = ? AND created_at >= ?", retur.ID, retur.CreatedAt).Order("id").Find(&attachments).Error; err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to retrieve return attachments")
+		return
+	}
+
+	timeline := returTimeline{Retur: retur, History: history, Attachments: attachments}
+	if retur.DecidedAt != nil {
+		timeline.Decision = &returDecision{
+			Status:       retur.Status,
+			Pengembalian: retur.Pengembalian,
+			RefundAmount: retur.RefundAmount,
+			Currency:     retur.Currency,
+			DecidedBy:    retur.DecidedBy,
+			DecidedAt:    retur.DecidedAt,
+			Note:         retur.DecisionNote,
+		}
+	}
+	respondJSON(w, http.StatusOK, timeline)
+}
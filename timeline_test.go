@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReturTimelineIncludesOnlyCurrentLifetime(t *testing.T) {
+	testDB(t)
+	created := createViaAPI(t, map[string]interface{}{"barang": "Rice cooker", "alasan": "tidak panas"})
+	// Riwayat retur lama dengan ID yang sama (ID reuse) tidak boleh ikut tampil
+	db.Create(&ReturHistory{ReturID: created.ID, FromStatus: "Dalam Proses", ToStatus: "Disetujui", Actor: "lama", CreatedAt: created.CreatedAt.Add(-time.Hour)})
+
+	path := fmt.Sprintf("/retur/%d", created.ID)
+	var timeline returTimeline
+	rec := serve(httptest.NewRequest(http.MethodGet, path+"/full", nil))
+	decodeBody(t, rec, &timeline)
+	if rec.Code != http.StatusOK || timeline.Decision != nil || len(timeline.History) != 0 || timeline.Attachments == nil {
+		t.Fatalf("pending timeline: %d %+v", rec.Code, timeline)
+	}
+
+	approve := jsonRequest(t, http.MethodPost, path+"/approve", map[string]interface{}{"pengembalian": "barang", "note": "oke"})
+	if rec := serve(asAdmin(t, approve)); rec.Code != http.StatusOK {
+		t.Fatalf("approve: %d %s", rec.Code, rec.Body.String())
+	}
+	decodeBody(t, serve(httptest.NewRequest(http.MethodGet, path+"/full", nil)), &timeline)
+	if len(timeline.History) != 1 || timeline.History[0].ToStatus != "Disetujui" {
+		t.Fatalf("history = %+v", timeline.History)
+	}
+	if d := timeline.Decision; d == nil || d.Status != "Disetujui" || d.DecidedBy != "admin" || d.Note != "oke" || d.DecidedAt == nil {
+		t.Fatalf("decision = %+v", timeline.Decision)
+	}
+	if rec := serve(httptest.NewRequest(http.MethodGet, "/retur/9999/full", nil)); rec.Code != http.StatusNotFound {
+		t.Fatalf("missing return: %d", rec.Code)
+	}
+}
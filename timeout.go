@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// routeTimeout mengembalikan batas waktu untuk route: RETUR_ROUTE_TIMEOUTS jika diatur, selain itu RETUR_REQUEST_TIMEOUT
+func routeTimeout(r *http.Request) time.Duration {
+	if route := mux.CurrentRoute(r); route != nil {
+		if timeout, ok := cfg.RouteTimeouts[route.GetName()]; ok {
+			return timeout
+		}
+	}
+	return cfg.RequestTimeout
+}
+
+// timeoutWriter mengganti response 5xx menjadi 503 (seperti http.TimeoutHandler) jika penyebabnya batas waktu request terlewati
+type timeoutWriter struct {
+	http.ResponseWriter
+	ctx      context.Context
+	timedOut bool // Response asli sudah diganti dengan 503
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	if status >= http.StatusInternalServerError && errors.Is(tw.ctx.Err(), context.DeadlineExceeded) {
+		tw.timedOut = true
+		tw.ResponseWriter.Header().Set("Content-Type", "application/json")
+		tw.ResponseWriter.WriteHeader(http.StatusServiceUnavailable)
+		tw.ResponseWriter.Write([]byte(`{"error":"Request timed out"}` + "\n"))
+		return
+	}
+	tw.ResponseWriter.WriteHeader(status)
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	if tw.timedOut {
+		return len(p), nil // Body error asli dibuang, client sudah menerima pesan timeout
+	}
+	return tw.ResponseWriter.Write(p)
+}
+
+// Unwrap mengembalikan ResponseWriter asli agar opsi response dan http.ResponseController tetap bekerja
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+// timeoutMiddleware memasang batas waktu pada context request sesuai route yang cocok
+// Query database yang memakai r.Context() dibatalkan saat batas waktu terlewati dan client menerima 503
+// Default: RETUR_REQUEST_TIMEOUT=10s untuk semua route; listReturs=1m, exportReturs=2m, exportByCustomer=2m dan statsTimeseries=30s
+// (RETUR_ROUTE_TIMEOUTS) karena streaming daftar retur dan laporan memang butuh waktu lebih lama
+func timeoutMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeout := routeTimeout(r)
+		if timeout <= 0 {
+			next.ServeHTTP(w, r) // Batas waktu dinonaktifkan untuk route ini
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		next.ServeHTTP(&timeoutWriter{ResponseWriter: w, ctx: ctx}, r.WithContext(ctx))
+	})
+}
+
+// parseDurationMap membaca daftar "nama=durasi" dipisahkan koma, misalnya "exportByCustomer=2m"
+// Durasi 0 diperbolehkan untuk menonaktifkan batas waktu pada route tertentu
+func parseDurationMap(raw string) map[string]time.Duration {
+	result := make(map[string]time.Duration)
+	for _, item := range strings.Split(raw, ",") {
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			continue
+		}
+		duration, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || duration < 0 {
+			continue // Lewati durasi yang tidak valid
+		}
+		result[strings.TrimSpace(name)] = duration
+	}
+	return result
+}
+
+// durationStrings mengubah map durasi menjadi teks untuk ditampilkan
+func durationStrings(durations map[string]time.Duration) map[string]string {
+	result := make(map[string]string, len(durations))
+	for name, duration := range durations {
+		result[name] = duration.String()
+	}
+	return result
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestTimeoutMiddlewareTurns5xxInto503(t *testing.T) {
+	withConfig(t, func(c *Config) {
+		c.RequestTimeout = time.Minute
+		c.RouteTimeouts = parseDurationMap("slow=20ms, bogus=abc, off=0")
+	})
+	r := mux.NewRouter()
+	r.Use(timeoutMiddleware)
+	wait := func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		handleError(w, http.StatusInternalServerError, "Failed to retrieve returns")
+	}
+	r.HandleFunc("/slow", wait).Name("slow")
+	r.HandleFunc("/fast", func(w http.ResponseWriter, r *http.Request) {
+		handleError(w, http.StatusInternalServerError, "boom")
+	}).Name("fast")
+	r.HandleFunc("/off", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); ok {
+			t.Error("route with timeout 0 has a deadline")
+		}
+	}).Name("off")
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != `{"error":"Request timed out"}`+"\n" {
+		t.Fatalf("slow route: %d %q", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("error before the deadline became %d", rec.Code)
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/off", nil))
+	if _, ok := cfg.RouteTimeouts["bogus"]; ok {
+		t.Fatal("invalid duration parsed")
+	}
+}
+
+func TestRouteTimeoutsFromConfig(t *testing.T) {
+	defaults := loadConfig()
+	timeoutFor := func(method, path string) (string, time.Duration) {
+		t.Helper()
+		var match mux.RouteMatch
+		if !newRouter().Match(httptest.NewRequest(method, path, nil), &match) {
+			t.Fatalf("%s %s does not match a route", method, path)
+		}
+		name := match.Route.GetName()
+		if timeout, ok := defaults.RouteTimeouts[name]; ok {
+			return name, timeout
+		}
+		return name, defaults.RequestTimeout
+	}
+	createRoute, createTimeout := timeoutFor(http.MethodPost, "/retur")
+	exportRoute, exportTimeout := timeoutFor(http.MethodGet, "/retur/export")
+	if exportTimeout < 4*createTimeout {
+		t.Fatalf("default export timeout %s is not well above the create timeout %s", exportTimeout, createTimeout)
+	}
+
+	// Batas waktu bawaan diperkecil 1000 kali (10s menjadi 10ms) dengan perbandingan yang sama agar test tetap cepat
+	const scale = 1000
+	withConfig(t, func(c *Config) {
+		c.RequestTimeout = defaults.RequestTimeout / scale
+		c.RouteTimeouts = make(map[string]time.Duration, len(defaults.RouteTimeouts))
+		for name, timeout := range defaults.RouteTimeouts {
+			c.RouteTimeouts[name] = timeout / scale
+		}
+	})
+	slow := 2 * createTimeout / scale // Lebih lama dari batas create, jauh di bawah batas ekspor
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(slow):
+			respondJSON(w, http.StatusOK, map[string]string{"status": "done"})
+		case <-r.Context().Done():
+			handleError(w, http.StatusInternalServerError, "Failed to save return") // Query yang dibatalkan oleh context
+		}
+	}
+	r := mux.NewRouter()
+	r.Use(timeoutMiddleware)
+	r.HandleFunc("/retur", handler).Methods(http.MethodPost).Name(createRoute)
+	r.HandleFunc("/retur/export", handler).Methods(http.MethodGet).Name(exportRoute)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/retur/export", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("slow export within its %s timeout: %d %s", exportTimeout/scale, rec.Code, rec.Body)
+	}
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/retur", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != `{"error":"Request timed out"}`+"\n" {
+		t.Fatalf("slow create past its %s timeout: %d %q", createTimeout/scale, rec.Code, rec.Body)
+	}
+}
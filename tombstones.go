@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PurgedRetur adalah tombstone retur yang sudah dibuang permanen dari stack undo; hanya ID dan waktu purge yang disimpan
+// Dipakai GET /retur/{id} untuk menjawab 410 Gone alih-alih 404 bagi ID yang pernah ada
+type PurgedRetur struct {
+	ID       int       `json:"id" gorm:"primaryKey;autoIncrement:false"`
+	PurgedAt time.Time `json:"purged_at" gorm:"index"`
+}
+
+// recordTombstones mencatat tombstone untuk semua retur pada entry undo yang dibuang permanen
+func recordTombstones(tx *gorm.DB, entries []undoEntry) error {
+	now := clock.Now()
+	var tombstones []PurgedRetur
+	for _, entry := range entries {
+		for _, retur := range entry.Returs {
+			tombstones = append(tombstones, PurgedRetur{ID: retur.ID, PurgedAt: now})
+		}
+	}
+	if len(tombstones) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&tombstones).Error
+}
+
+// clearTombstone menghapus tombstone untuk ID yang dipakai lagi oleh retur baru (reuse ID dari pool deletedIDs)
+func clearTombstone(tx *gorm.DB, id int) error {
+	return tx.Delete(&PurgedRetur{}, id).Error
+}
+
+// wasPurged memeriksa apakah ID retur memiliki tombstone; error database dianggap tidak ada tombstone (404 biasa)
+func wasPurged(ctx context.Context, id int) bool {
+	return db.WithContext(ctx).First(&PurgedRetur{}, id).Error == nil
+}
+
+// purgeOldTombstones menghapus tombstone yang lebih lama dari RETUR_TOMBSTONE_RETENTION; 0 berarti disimpan selamanya
+func purgeOldTombstones() (int64, error) {
+	if cfg.TombstoneRetention <= 0 {
+		return 0, nil
+	}
+	result := db.Where("purged_at < ?", clock.Now().Add(-cfg.TombstoneRetention)).Delete(&PurgedRetur{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTombstonesAnswerGoneUntilRetention(t *testing.T) {
+	testDB(t)
+	fake := useFakeClock(t, time.Now())
+	withConfig(t, func(c *Config) { c.TombstoneRetention = 24 * time.Hour })
+	if err := recordTombstones(db, []undoEntry{{Returs: []Retur{{ID: 41}, {ID: 42}}}}); err != nil {
+		t.Fatal(err)
+	}
+	if rec := serve(httptest.NewRequest(http.MethodGet, "/retur/41", nil)); rec.Code != http.StatusGone {
+		t.Fatalf("purged id: %d, want 410", rec.Code)
+	}
+	if rec := serve(httptest.NewRequest(http.MethodGet, "/retur/43", nil)); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown id: %d, want 404", rec.Code)
+	}
+
+	if err := clearTombstone(db, 42); err != nil || wasPurged(context.Background(), 42) {
+		t.Fatalf("tombstone for reused id not cleared: %v", err)
+	}
+	fake.Advance(25 * time.Hour)
+	if removed, err := purgeOldTombstones(); err != nil || removed != 1 || wasPurged(context.Background(), 41) {
+		t.Fatalf("purgeOldTombstones = %d, %v", removed, err)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"log"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+)
+
+// Kondisi load shedding undo: jika rata-rata waktu tunggu lock deletedStack melewati RETUR_UNDO_SHED_LOCK_WAIT,
+// delete baru tidak masuk ke stack undo agar mutex deletedStack tidak menjadi titik antrean
+var (
+	undoShedding atomic.Bool  // Bernilai true selama undo dinonaktifkan karena beban tinggi
+	undoSkipped  atomic.Int64 // Jumlah retur terhapus yang tidak bisa di-undo karena load shedding
+)
+
+// updateUndoShedding menghitung ulang mode shedding dari waktu tunggu lock stack undo; dipanggil di awal setiap delete
+// Selama shedding tidak ada Push yang mengukur lock, jadi lock di-probe agar undo aktif kembali begitu antrean lock surut
+func updateUndoShedding() {
+	if cfg.UndoShedLockWait <= 0 {
+		undoShedding.Store(false) // Load shedding nonaktif
+		return
+	}
+	wait := deletedStack.LockWait()
+	if undoShedding.Load() {
+		wait = deletedStack.ProbeLockWait()
+	}
+	shed := wait > cfg.UndoShedLockWait
+	if undoShedding.Swap(shed) != shed {
+		if shed {
+			log.Printf("undo load shedding enabled: undo stack lock wait %s (threshold %s)", wait, cfg.UndoShedLockWait)
+		} else {
+			log.Printf("undo load shedding disabled: undo stack lock wait %s", wait)
+		}
+	}
+}
+
+// skipUndo memeriksa apakah entry undo untuk delete ini dilewati karena load shedding
+// Jika ya, retur langsung dicatat sebagai tombstone (seperti entry undo yang dibuang) agar GET tetap mengembalikan 410,
+// dan baris soft delete-nya langsung dibuang permanen karena tidak ada undo yang bisa mengembalikannya;
+// ID-nya tidak dikembalikan ke pool reuse sehingga tombstone tidak pernah menunjuk ke retur baru
+func skipUndo(tx *gorm.DB, entry undoEntry) (bool, error) {
+	if !undoShedding.Load() {
+		return false, nil
+	}
+	if err := purgeSoftDeleted(tx, entryReturIDs(entry)); err != nil {
+		return true, err
+	}
+	return true, recordTombstones(tx, []undoEntry{entry})
+}
+
+// logSkippedUndo mencatat retur yang terhapus tanpa entry undo setelah transaksi delete berhasil
+func logSkippedUndo(entry undoEntry) {
+	undoSkipped.Add(int64(len(entry.Returs)))
+	log.Printf("undo skipped for %d deleted return(s) due to load shedding", len(entry.Returs))
+}
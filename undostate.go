@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// undoStateVersion adalah versi format ekspor undo state; naikkan jika strukturnya berubah
+const undoStateVersion = 1
+
+// undoState adalah isi stack undo dan pool deletedIDs dalam bentuk yang bisa dipindahkan antar instance
+type undoState struct {
+	Version    int         `json:"version"`
+	Undo       []undoEntry `json:"undo"`        // Entri undo dari yang terlama ke yang terbaru (top stack di akhir)
+	DeletedIDs []int       `json:"deleted_ids"` // Pool ID yang menunggu reuse, sesuai urutan di memori
+	ExportedAt time.Time   `json:"exported_at"`
+}
+
+// exportUndoStateHandler adalah handler admin untuk mengekspor stack undo dan deletedIDs sebagai JSON
+// Dipakai saat blue-green deploy agar retur yang baru dihapus tetap bisa di-undo di instance baru
+func exportUndoStateHandler(w http.ResponseWriter, r *http.Request) {
+	entries := deletedStack.Items()
+	for i := range entries {
+		returs, err := sealReturs(entries[i].Returs) // Instance tujuan harus memakai RETUR_DETAILS_KEY yang sama
+		if err != nil {
+			handleError(w, http.StatusInternalServerError, "Failed to encrypt undo state")
+			return
+		}
+		entries[i].Returs = returs
+	}
+	idMu.Lock()
+	ids := append([]int{}, deletedIDs...)
+	idMu.Unlock()
+	respondJSON(w, http.StatusOK, undoState{
+		Version:    undoStateVersion,
+		Undo:       entries,
+		DeletedIDs: ids,
+		ExportedAt: clock.Now(),
+	})
+}
+
+// importUndoStateHandler adalah handler admin untuk memuat stack undo dan deletedIDs hasil ekspor
+// Instance tujuan harus masih kosong (belum ada entri undo maupun ID di pool) kecuali ?replace=true
+func importUndoStateHandler(w http.ResponseWriter, r *http.Request) {
+	var state undoState
+	if !decodeJSON(w, r, &state) {
+		return // Body kosong atau JSON tidak valid, error sudah dikirim
+	}
+	if state.Version != undoStateVersion {
+		handleError(w, http.StatusBadRequest, "Unsupported undo state version")
+		return
+	}
+	for _, entry := range state.Undo {
+		if len(entry.Returs) == 0 {
+			handleError(w, http.StatusBadRequest, "Undo entries must contain at least one return")
+			return
+		}
+		if err := openReturs(entry.Returs); err != nil {
+			handleError(w, http.StatusBadRequest, "Undo state contains details that cannot be decrypted") // RETUR_DETAILS_KEY berbeda dengan instance asal
+			return
+		}
+	}
+	if r.URL.Query().Get("replace") != "true" && (!deletedStack.IsEmpty() || deletedIDCount() > 0) {
+		handleError(w, http.StatusConflict, "Undo state is not empty; use ?replace=true to overwrite it")
+		return
+	}
+
+	if err := withTransaction(r.Context(), func(tx *gorm.DB) error {
+		if err := purgeSoftDeleted(tx, undoPendingIDs()); err != nil {
+			return err // Entry lama ditimpa sehingga barisnya tidak bisa di-undo lagi; entry impor di-insert ulang dari snapshot
+		}
+		return replaceUndoRecords(tx, state.Undo)
+	}); err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to persist undo state")
+		return
+	}
+	deletedStack.Replace(state.Undo)
+	idMu.Lock()
+	deletedIDs = append([]int{}, state.DeletedIDs...)
+	idMu.Unlock()
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"undo_entries": deletedStack.Len(),
+		"deleted_ids":  len(state.DeletedIDs),
+	})
+}
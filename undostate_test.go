@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportUndoStateSealsDetails(t *testing.T) {
+	useDetailsKey(t)
+	deletedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	useUndoState(t, []undoEntry{
+		{Returs: []Retur{{ID: 4, Barang: "Tas", Details: map[string]string{"account_number": "9876543210"}}}, DeletedAt: deletedAt},
+		{Returs: []Retur{{ID: 9, Barang: "Topi"}}, DeletedAt: deletedAt.Add(time.Hour), Reason: "duplikat"},
+	}, []int{4, 9})
+
+	rec := serve(asAdmin(t, httptest.NewRequest(http.MethodGet, "/retur/admin/undo-state", nil)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "9876543210") {
+		t.Fatal("export contains a plaintext account number")
+	}
+	var state undoState
+	decodeBody(t, rec, &state)
+	if state.Version != undoStateVersion || len(state.Undo) != 2 || state.Undo[1].Reason != "duplikat" || !slices.Equal(state.DeletedIDs, []int{4, 9}) {
+		t.Fatalf("state = %+v", state)
+	}
+	if err := openReturs(state.Undo[0].Returs); err != nil || state.Undo[0].Returs[0].Details["account_number"] != "9876543210" {
+		t.Fatalf("exported details cannot be opened with the same key: %v", err)
+	}
+	if live := deletedStack.Items(); live[0].Returs[0].Details["account_number"] != "9876543210" {
+		t.Fatal("export encrypted the live stack")
+	}
+}
+
+func TestImportUndoStateRejectsBadInput(t *testing.T) {
+	useUndoState(t, []undoEntry{{Returs: []Retur{{ID: 1}}}}, nil)
+	tests := []struct {
+		name   string
+		target string
+		state  undoState
+		want   int
+	}{
+		{"version", "/retur/admin/undo-state", undoState{Version: 99}, http.StatusBadRequest},
+		{"empty entry", "/retur/admin/undo-state", undoState{Version: undoStateVersion, Undo: []undoEntry{{}}}, http.StatusBadRequest},
+		{"not empty", "/retur/admin/undo-state", undoState{Version: undoStateVersion, Undo: []undoEntry{{Returs: []Retur{{ID: 2}}}}}, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		rec := serve(asAdmin(t, jsonRequest(t, http.MethodPost, tt.target, tt.state)))
+		if rec.Code != tt.want {
+			t.Errorf("%s: status %d, want %d (%s)", tt.name, rec.Code, tt.want, rec.Body.String())
+		}
+	}
+	if deletedStack.Len() != 1 {
+		t.Fatal("rejected import changed the stack")
+	}
+}
+
+func TestImportUndoStateReplacesAndPersists(t *testing.T) {
+	testDB(t)
+	useUndoState(t, []undoEntry{{Returs: []Retur{{ID: 1}}}}, []int{1})
+	state := undoState{Version: undoStateVersion, Undo: []undoEntry{
+		{Returs: []Retur{{ID: 5, Barang: "Jaket"}}, DeletedAt: clock.Now()},
+	}, DeletedIDs: []int{5}}
+	rec := serve(asAdmin(t, jsonRequest(t, http.MethodPost, "/retur/admin/undo-state?replace=true", state)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	if top, _ := deletedStack.Pop(); top.Returs[0].ID != 5 || deletedIDCount() != 1 {
+		t.Fatalf("stack top %+v, pool %d", top, deletedIDCount())
+	}
+	var stored int64
+	db.Model(&UndoRecord{}).Count(&stored)
+	if stored != 1 {
+		t.Fatalf("undo_entries rows = %d, want 1", stored)
+	}
+}
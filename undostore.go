@@ -0,0 +1,99 @@
+package main
+
+import (
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UndoRecord adalah salinan satu entry undo di tabel undo_entries agar undo tetap bisa dilakukan setelah restart
+// Baris ditulis di transaksi yang sama dengan penghapusan dan dihapus di transaksi yang sama dengan restore
+type UndoRecord struct {
+	ID        uint      `gorm:"primaryKey"`
+	Returs    []Retur   `gorm:"serializer:snapshot;type:longtext"` // Snapshot lengkap retur (beserta item) yang dihapus, details sensitif terenkripsi
+	DeletedAt time.Time `gorm:"index"`                             // Waktu penghapusan, urutan stack saat dimuat ulang
+	Reason    string    `gorm:"type:text"`                         // Alasan penghapusan dari pengguna
+}
+
+// TableName menentukan nama tabel untuk model UndoRecord
+func (UndoRecord) TableName() string {
+	return "undo_entries"
+}
+
+// persistUndoEntry menyimpan entry undo ke tabel undo_entries dan mengisi RecordID pada entry
+func persistUndoEntry(tx *gorm.DB, entry *undoEntry) error {
+	record := UndoRecord{Returs: entry.Returs, DeletedAt: entry.DeletedAt, Reason: entry.Reason}
+	if err := tx.Create(&record).Error; err != nil {
+		return err
+	}
+	entry.RecordID = record.ID
+	return nil
+}
+
+// deleteUndoRecords menghapus baris undo_entries milik entry yang sudah di-restore atau dibuang
+func deleteUndoRecords(tx *gorm.DB, entries ...undoEntry) error {
+	ids := make([]uint, 0, len(entries))
+	for _, entry := range entries {
+		if entry.RecordID != 0 {
+			ids = append(ids, entry.RecordID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return tx.Delete(&UndoRecord{}, ids).Error
+}
+
+// replaceUndoRecords mengganti seluruh isi undo_entries dengan entries (dipakai saat impor undo state)
+// RecordID setiap entry diisi ulang sesuai baris yang baru dibuat
+func replaceUndoRecords(tx *gorm.DB, entries []undoEntry) error {
+	if err := tx.Where("1 = 1").Delete(&UndoRecord{}).Error; err != nil {
+		return err
+	}
+	for i := range entries {
+		if err := persistUndoEntry(tx, &entries[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadUndoStack membangun ulang stack undo di memori dari tabel undo_entries, yang terlama di bawah
+func loadUndoStack() error {
+	var records []UndoRecord
+	if err := db.Order("deleted_at, id").Find(&records).Error; err != nil {
+		return err
+	}
+	entries := make([]undoEntry, 0, len(records))
+	for _, record := range records {
+		entries = append(entries, undoEntry{Returs: record.Returs, DeletedAt: record.DeletedAt, Reason: record.Reason, RecordID: record.ID})
+	}
+	deletedStack.Replace(entries)
+	return nil
+}
+
+// lockUndoRecords mengunci baris undo_entries milik entries (FOR UPDATE) dan mengembalikan entry yang barisnya masih ada
+// Entry tanpa baris (RecordID 0, misalnya hasil impor format lama) hanya ada di memori dan selalu dikembalikan
+func lockUndoRecords(tx *gorm.DB, entries []undoEntry) ([]undoEntry, error) {
+	ids := make([]uint, 0, len(entries))
+	for _, entry := range entries {
+		if entry.RecordID != 0 {
+			ids = append(ids, entry.RecordID)
+		}
+	}
+	var existing []uint
+	if len(ids) > 0 {
+		if err := tx.Model(&UndoRecord{}).Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", ids).Pluck("id", &existing).Error; err != nil {
+			return nil, err
+		}
+	}
+	live := make([]undoEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.RecordID == 0 || slices.Contains(existing, entry.RecordID) {
+			live = append(live, entry)
+		}
+	}
+	return live, nil
+}
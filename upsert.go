@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errUpsertDecided dikembalikan jika retur dengan natural key yang sama sudah diputuskan sehingga tidak boleh ditimpa
+var errUpsertDecided = errors.New("return for this order_id and barang has already been decided")
+
+// errUpsertExists dikembalikan insert upsert yang tidak menulis baris karena natural key sudah dimiliki retur lain
+var errUpsertExists = errors.New("return for this order_id and barang already exists")
+
+// naturalKey menghitung nilai kolom natural_key dari (order_id, barang); nil jika order_id kosong
+// Barang disimpan sebagai text sehingga tidak bisa langsung masuk unique index MySQL, karena itu yang diindeks adalah hash-nya
+func naturalKey(orderID, barang string) *string {
+	if orderID == "" {
+		return nil // NULL boleh muncul berkali-kali di unique index
+	}
+	sum := sha256.Sum256([]byte(orderID + "\x00" + barang))
+	key := hex.EncodeToString(sum[:])
+	return &key
+}
+
+// isDuplicateKey memeriksa apakah err berasal dari pelanggaran unique index MySQL (error 1062)
+func isDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
+
+// insertUpsert menyisipkan retur baru dengan INSERT ... ON DUPLICATE KEY UPDATE id = id pada unique index natural_key
+// Jika natural key sudah ada, tidak ada baris yang ditulis dan errUpsertExists dikembalikan; unique index menjamin dua
+// upsert bersamaan tidak menghasilkan dua retur, tanpa kunci di dalam proses
+func insertUpsert(tx *gorm.DB, retur *Retur) error {
+	items := retur.Items
+	result := tx.Omit("Items").Clauses(clause.OnConflict{DoNothing: true}).Create(retur)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errUpsertExists
+	}
+	for i := range items {
+		items[i].ReturID = retur.ID
+	}
+	if len(items) > 0 {
+		if err := tx.Create(&items).Error; err != nil {
+			return err
+		}
+	}
+	retur.Items = items
+	return nil
+}
+
+// upsertRequested memeriksa apakah client meminta create dengan natural key (order_id, barang) lewat ?upsert=true
+func upsertRequested(r *http.Request) bool {
+	return r.URL.Query().Get("upsert") == "true"
+}
+
+// validateUpsertKey memeriksa bahwa natural key (order_id, barang) terisi pada mode upsert
+func validateUpsertKey(retur *Retur) []fieldError {
+	if retur.OrderID == "" {
+		return []fieldError{{Field: "order_id", Message: "is required when upsert=true"}}
+	}
+	return nil // barang sudah diwajibkan oleh validateRetur
+}
+
+// updateExistingForUpsert memperbarui retur dengan order_id dan barang yang sama jika sudah ada, lalu mengirim 200
+// Hanya data pengajuan yang ditimpa (alasan, reason_code, customer_id, order_date, details, tags); status, keputusan, dan item tidak diubah.
+// Retur yang sudah diputuskan ditolak dengan 409. Mengembalikan false jika belum ada retur yang cocok sehingga createRetur lanjut insert
+func updateExistingForUpsert(w http.ResponseWriter, r *http.Request, input Retur) bool {
+	var existing Retur
+	found := false
+	err := withTransaction(r.Context(), func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Items").
+			Where("natural_key = ?", *naturalKey(input.OrderID, input.Barang)).First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+		if existing.Status != "Dalam Proses" {
+			return errUpsertDecided
+		}
+		before := existing
+		existing.Alasan = input.Alasan
+		existing.ReasonCode = input.ReasonCode
+		existing.CustomerID = input.CustomerID
+		existing.OrderDate = input.OrderDate
+		existing.Details = input.Details
+		existing.Tags = input.Tags
+		if err := tx.Model(&existing).Select("Alasan", "ReasonCode", "CustomerID", "OrderDate", "Details", "Tags").Updates(&existing).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, "update", actorFromRequest(r), before, existing)
+	})
+	if errors.Is(err, errUpsertDecided) {
+		handleError(w, http.StatusConflict, "A return for this order_id and barang has already been decided")
+		return true
+	}
+	if err != nil {
+		respondSaveError(w, err, "Failed to upsert return") // 422 jika melanggar Validate, selain itu 500
+		return true
+	}
+	if !found {
+		return false
+	}
+	applySLA(&existing)
+	publishEvent(ReturUpdated, existing, r)
+	maskRetur(r, &existing)
+	respondJSON(w, http.StatusOK, existing) // 200 menandakan retur lama diperbarui, bukan dibuat
+	return true
+}
+
+// backfillNaturalKeys mengisi natural_key untuk retur lama yang dibuat sebelum kolom tersebut ada, per batch
+// SHA2 di MySQL menghasilkan hex yang sama dengan naturalKey; UPDATE IGNORE melewati retur lama dengan (order_id, barang)
+// yang sama sehingga hanya retur pertama yang menjadi target upsert
+// Batch dipilih dengan cursor ID agar baris yang dilewati IGNORE tidak dipindai ulang
+func backfillNaturalKeys(batchSize int, pause time.Duration) (int64, error) {
+	statement := "UPDATE IGNORE `returs` SET `natural_key` = SHA2(CONCAT(`order_id`, CHAR(0), `barang`), 256) WHERE `id` IN ?"
+	var total int64
+	lastID := 0
+	for {
+		var ids []int
+		if err := db.Model(&Retur{}).Where("id > ? AND natural_key IS NULL AND order_id <> ''", lastID).
+			Order("id").Limit(batchSize).Pluck("id", &ids).Error; err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			return total, nil
+		}
+		result := db.Exec(statement, ids)
+		if result.Error != nil {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+		lastID = ids[len(ids)-1]
+		time.Sleep(pause)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestNaturalKey(t *testing.T) {
+	if naturalKey("", "Sepatu") != nil {
+		t.Fatal("empty order_id must not have a natural key")
+	}
+	a, b := naturalKey("ORD-1", "Sepatu"), naturalKey("ORD-1", "Sepatu")
+	if a == nil || *a != *b || len(*a) != 64 {
+		t.Fatalf("natural key not stable: %v %v", a, b)
+	}
+	// Pemisah NUL mencegah pasangan berbeda menghasilkan gabungan yang sama
+	if *naturalKey("ORD-1", "2Sepatu") == *naturalKey("ORD-12", "Sepatu") {
+		t.Fatal("different (order_id, barang) pairs share a key")
+	}
+}
+
+func TestUpsertRequiresOrderID(t *testing.T) {
+	rec := serve(jsonRequest(t, http.MethodPost, "/retur?upsert=true", map[string]string{"barang": "Sepatu", "alasan": "rusak"}))
+	var body struct {
+		Errors []fieldError `json:"errors"`
+	}
+	decodeBody(t, rec, &body)
+	if rec.Code != http.StatusUnprocessableEntity || len(body.Errors) != 1 || body.Errors[0].Field != "order_id" {
+		t.Fatalf("status %d errors %+v", rec.Code, body.Errors)
+	}
+}
+
+func TestUpsertUpdatesPendingAndRejectsDecided(t *testing.T) {
+	testDB(t)
+	payload := map[string]interface{}{"barang": "Sepatu", "alasan": "rusak", "order_id": "ORD-9"}
+	created := serve(jsonRequest(t, http.MethodPost, "/retur?upsert=true", payload))
+	var first Retur
+	decodeBody(t, created, &first)
+	if created.Code != http.StatusCreated {
+		t.Fatalf("first upsert: %d %s", created.Code, created.Body.String())
+	}
+
+	payload["alasan"] = "ukuran salah"
+	updated := serve(jsonRequest(t, http.MethodPost, "/retur?upsert=true", payload))
+	var second Retur
+	decodeBody(t, updated, &second)
+	if updated.Code != http.StatusOK || second.ID != first.ID || second.Alasan != "ukuran salah" {
+		t.Fatalf("second upsert: %d %+v", updated.Code, second)
+	}
+	if rec := serve(jsonRequest(t, http.MethodPost, "/retur", payload)); rec.Code != http.StatusConflict {
+		t.Fatalf("plain create with same natural key: %d, want 409", rec.Code)
+	}
+
+	db.Model(&Retur{}).Where("id = ?", first.ID).Update("status", "Disetujui")
+	if rec := serve(jsonRequest(t, http.MethodPost, "/retur?upsert=true", payload)); rec.Code != http.StatusConflict {
+		t.Fatalf("upsert of decided return: %d, want 409", rec.Code)
+	}
+	var count int64
+	db.Model(&Retur{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("%d returns for one natural key", count)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Batas panjang field sesuai ukuran kolom varchar di tabel returs
+const (
+	maxBarangLength     = 100
+	maxAlasanLength     = 100
+	maxCustomerIDLength = 100
+	maxOrderIDLength    = 100
+)
+
+// fieldError menjelaskan satu masalah validasi pada field tertentu
+type fieldError struct {
+	Field   string `json:"field"`   // Nama field JSON yang bermasalah
+	Message string `json:"message"` // Penjelasan masalahnya
+}
+
+// validPengembalian memeriksa jenis pengembalian yang dikenal: barang (tukar barang) atau uang (refund)
+func validPengembalian(pengembalian string) bool {
+	return pengembalian == "barang" || pengembalian == "uang"
+}
+
+// validStatus memeriksa status retur yang dikenal
+func validStatus(status string) bool {
+	return status == "Dalam Proses" || status == statusAwaitingSecondApproval || status == "Disetujui" || status == statusPartiallyApproved || status == "Tidak Disetujui"
+}
+
+// importStatusAllowed menentukan apakah status dari client dipakai saat create: hanya jika RETUR_ALLOW_IMPORT_STATUS aktif,
+// request memakai ?import=true, dan berasal dari admin; endpoint publik tetap selalu memulai dari "Dalam Proses"
+func importStatusAllowed(r *http.Request) bool {
+	return cfg.AllowImportStatus && r.URL.Query().Get("import") == "true" && isAdmin(r)
+}
+
+// validateRetur memeriksa data retur baru dan mengembalikan semua masalah validasi yang ditemukan
+// Semua field diperiksa sekaligus (tidak berhenti di error pertama) agar client bisa memperbaiki semuanya dalam satu kali kirim
+// Fungsi ini dipakai bersama oleh createRetur dan endpoint /retur/validate agar aturannya tidak berbeda
+func validateRetur(retur *Retur) []fieldError {
+	var errs []fieldError
+	checkText := func(field, value string, max int, required bool) {
+		switch {
+		case required && strings.TrimSpace(value) == "":
+			errs = append(errs, fieldError{Field: field, Message: "is required"})
+		case utf8.RuneCountInString(value) > max:
+			errs = append(errs, fieldError{Field: field, Message: fmt.Sprintf("must be at most %d characters", max)})
+		}
+	}
+	checkText("barang", retur.Barang, maxBarangLength, true)
+	checkText("alasan", retur.Alasan, maxAlasanLength, true)
+	checkText("customer_id", retur.CustomerID, maxCustomerIDLength, false)
+	checkText("order_id", retur.OrderID, maxOrderIDLength, false)
+	if retur.Pengembalian != "" && !validPengembalian(retur.Pengembalian) {
+		errs = append(errs, fieldError{Field: "pengembalian", Message: "must be 'barang' or 'uang'"}) // Preferensi pengembalian dari customer (opsional)
+	}
+	if retur.ReasonCode != "" && !validReasonCode(retur.ReasonCode) {
+		errs = append(errs, fieldError{Field: "reason_code", Message: "is not a known reason code"})
+	}
+	for i, tag := range retur.Tags {
+		if !tagPattern.MatchString(tag) {
+			errs = append(errs, fieldError{Field: fmt.Sprintf("tags[%d]", i), Message: "must match " + tagPattern.String()})
+		}
+	}
+	if len(retur.Tags) > cfg.MaxTagsPerRetur {
+		errs = append(errs, fieldError{Field: "tags", Message: fmt.Sprintf("must contain at most %d tags per return", cfg.MaxTagsPerRetur)})
+	}
+	for i, item := range retur.Items {
+		checkText(fmt.Sprintf("items[%d].sku", i), item.SKU, maxBarangLength, true)
+		if item.Quantity <= 0 {
+			errs = append(errs, fieldError{Field: fmt.Sprintf("items[%d].quantity", i), Message: "must be greater than 0"})
+		}
+	}
+	return errs
+}
+
+// validateOrderDate memeriksa order_date terhadap batas waktu pengembalian (RETUR_RETURN_WINDOW); 0 berarti nonaktif
+// Jika aktif, order_date wajib diisi, tidak boleh di masa depan, dan tidak boleh lebih lama dari batas waktu tersebut
+func validateOrderDate(retur *Retur) []fieldError {
+	if cfg.ReturnWindow <= 0 {
+		return nil
+	}
+	now := clock.Now()
+	switch {
+	case retur.OrderDate == nil:
+		return []fieldError{{Field: "order_date", Message: "is required"}}
+	case retur.OrderDate.After(now):
+		return []fieldError{{Field: "order_date", Message: "must not be in the future"}}
+	case now.Sub(*retur.OrderDate) > cfg.ReturnWindow:
+		return []fieldError{{Field: "order_date", Message: fmt.Sprintf("is outside the return window of %s (returns must be created by %s)",
+			cfg.ReturnWindow, retur.OrderDate.Add(cfg.ReturnWindow).Format(time.RFC3339))}}
+	}
+	return nil
+}
+
+// respondValidationErrors mengirimkan daftar masalah validasi dengan status 422
+func respondValidationErrors(w http.ResponseWriter, errs []fieldError) {
+	if wantsProblemJSON(w) {
+		respondProblem(w, http.StatusUnprocessableEntity, "Request validation failed", errs) // Masalah per field di member errors
+		return
+	}
+	respondJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{"valid": false, "errors": errs})
+}
+
+// validateReturHandler adalah handler untuk memvalidasi payload retur tanpa menyimpannya ke database
+func validateReturHandler(w http.ResponseWriter, r *http.Request) {
+	var retur Retur
+	if !decodeJSON(w, r, &retur) {
+		return // Body kosong atau JSON tidak valid, error sudah dikirim
+	}
+	if errs := append(validateRetur(&retur), validateOrderDate(&retur)...); len(errs) > 0 {
+		respondValidationErrors(w, errs) // Kirim semua masalah validasi sekaligus
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]bool{"valid": true})
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fieldNames mengambil nama field dari daftar error validasi
+func fieldNames(errs []fieldError) []string {
+	names := make([]string, len(errs))
+	for i, err := range errs {
+		names[i] = err.Field
+	}
+	return names
+}
+
+func TestValidateReturReportsAllFailures(t *testing.T) {
+	retur := Retur{
+		Alasan:       strings.Repeat("a", maxAlasanLength+1),
+		Pengembalian: "kredit",
+		Tags:         []string{"ok", "Not Valid"},
+		Items:        []ReturItem{{SKU: "", Quantity: 0}},
+	}
+	got := strings.Join(fieldNames(validateRetur(&retur)), ",")
+	want := "barang,alasan,pengembalian,tags[1],items[0].sku,items[0].quantity"
+	if got != want {
+		t.Fatalf("fields = %s, want %s", got, want)
+	}
+}
+
+func TestValidateReturCountsCharactersNotBytes(t *testing.T) {
+	retur := Retur{Barang: strings.Repeat("é", maxBarangLength), Alasan: "rusak"} // 200 byte, 100 karakter
+	if errs := validateRetur(&retur); len(errs) != 0 {
+		t.Fatalf("multibyte barang at the limit rejected: %v", errs)
+	}
+}
+
+func TestValidateEndpointReturns422WithFieldList(t *testing.T) {
+	rec := serve(jsonRequest(t, http.MethodPost, "/retur/validate", map[string]interface{}{"pengembalian": "kredit"}))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", rec.Code)
+	}
+	var body struct {
+		Valid  bool         `json:"valid"`
+		Errors []fieldError `json:"errors"`
+	}
+	decodeBody(t, rec, &body)
+	if body.Valid || strings.Join(fieldNames(body.Errors), ",") != "barang,alasan,pengembalian" {
+		t.Fatalf("body = %+v", body)
+	}
+}
+
+func TestValidationErrorsAsProblemJSON(t *testing.T) {
+	req := jsonRequest(t, http.MethodPost, "/retur/validate", map[string]string{})
+	req.Header.Set("Accept", problemContentType)
+	rec := serve(req)
+	if rec.Code != http.StatusUnprocessableEntity || rec.Header().Get("Content-Type") != problemContentType {
+		t.Fatalf("status %d content type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var problem problemDetails
+	decodeBody(t, rec, &problem)
+	if problem.Status != http.StatusUnprocessableEntity || problem.Type != "about:blank" || len(problem.Errors) != 2 {
+		t.Fatalf("problem = %+v", problem)
+	}
+}
+
+func TestProblemJSONForPlainErrorsWhenConfigured(t *testing.T) {
+	withConfig(t, func(c *Config) { c.ProblemJSON = true })
+	rec := serve(jsonRequest(t, http.MethodPost, "/retur/abc/approve", map[string]string{}))
+	if rec.Header().Get("Content-Type") != problemContentType {
+		t.Fatalf("content type %q, want %s", rec.Header().Get("Content-Type"), problemContentType)
+	}
+}
+
+func TestImportStatusMustBeKnown(t *testing.T) {
+	withConfig(t, func(c *Config) {
+		c.AllowImportStatus = true
+		c.AdminToken = "admin-secret"
+	})
+	req := jsonRequest(t, http.MethodPost, "/retur?import=true", map[string]string{"barang": "Sepatu", "alasan": "rusak", "status": "Selesai"})
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	rec := serve(req)
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), `"status"`) {
+		t.Fatalf("status %d body %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCreateRejectsSchemaViolations(t *testing.T) {
+	withConfig(t, func(c *Config) { c.JSONSchema = true })
+	rec := serve(jsonRequest(t, http.MethodPost, "/retur", map[string]interface{}{
+		"barang": strings.Repeat("x", maxBarangLength+1),
+		"alasan": "rusak",
+		"tags":   []string{"UPPER"},
+	}))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", rec.Code)
+	}
+	var body struct {
+		Errors []fieldError `json:"errors"`
+	}
+	decodeBody(t, rec, &body)
+	got := strings.Join(fieldNames(body.Errors), ",")
+	if !strings.Contains(got, "/barang") || !strings.Contains(got, "/tags/0") {
+		t.Fatalf("schema errors = %v", body.Errors)
+	}
+}
+
+func TestSchemaLimitsMatchGoConstants(t *testing.T) {
+	for name, limits := range schemaLimits {
+		data, err := schemaFiles.ReadFile("schemas/" + name + ".json")
+		if err != nil {
+			t.Fatalf("schema %s: %v", name, err)
+		}
+		if _, err := applySchemaLimits(name, data); err != nil {
+			t.Fatalf("schema %s: %v", name, err)
+		}
+		if len(limits) == 0 {
+			t.Fatalf("schema %s has no limits", name)
+		}
+	}
+	if _, err := applySchemaLimits("create_retur", []byte(`{"properties": {}}`)); err == nil {
+		t.Fatal("missing subschema not reported")
+	}
+}
+
+func TestValidateOrderDateReturnWindow(t *testing.T) {
+	fake := useFakeClock(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+	withConfig(t, func(c *Config) { c.ReturnWindow = 30 * 24 * time.Hour })
+	at := func(d time.Duration) *time.Time { v := fake.Now().Add(d); return &v }
+	tests := []struct {
+		orderDate *time.Time
+		wantError bool
+	}{
+		{nil, true},
+		{at(time.Hour), true},             // Masa depan
+		{at(-31 * 24 * time.Hour), true},  // Di luar batas
+		{at(-29 * 24 * time.Hour), false}, // Di dalam batas
+	}
+	for _, tt := range tests {
+		if errs := validateOrderDate(&Retur{OrderDate: tt.orderDate}); (len(errs) > 0) != tt.wantError {
+			t.Errorf("order_date %v: errors %v", tt.orderDate, errs)
+		}
+	}
+}
+
+func TestRefundCurrency(t *testing.T) {
+	withConfig(t, func(c *Config) { c.DefaultCurrency = "EUR" })
+	tests := []struct {
+		input, want string
+		ok          bool
+	}{
+		{"", "EUR", true},      // Mata uang default dipakai jika tidak dikirim
+		{"USD", "USD", true},   // Kode ISO 4217 diterima apa adanya
+		{" jpy ", "JPY", true}, // Huruf kecil dan spasi dinormalisasi
+		{"XYZ", "XYZ", false},  // Kode tidak dikenal
+	}
+	for _, tt := range tests {
+		if got, ok := refundCurrency(tt.input); got != tt.want || ok != tt.ok {
+			t.Errorf("refundCurrency(%q) = %q, %t, want %q, %t", tt.input, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestApproveRejectsUnknownCurrency(t *testing.T) {
+	useApprovers(t, map[string]string{"tok-budi": "budi"})
+	req := jsonRequest(t, http.MethodPost, "/retur/1/approve", map[string]interface{}{"pengembalian": "uang", "refund_amount": 1000, "currency": "rupiah"})
+	if rec := serve(asApprover(req, "tok-budi")); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "rupiah") {
+		t.Fatalf("unknown currency: %d %s, want 400", rec.Code, rec.Body.String())
+	}
+}
+
+func TestDecisionNoteLengthCountsCharacters(t *testing.T) {
+	withConfig(t, func(c *Config) { c.AdminToken = "admin-secret" })
+	req := jsonRequest(t, http.MethodPost, "/retur/1/disapprove", map[string]string{"note": strings.Repeat("ü", maxDecisionNoteLength+1)})
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	if rec := serve(req); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "note must be at most") {
+		t.Fatalf("status %d body %s", rec.Code, rec.Body.String())
+	}
+}
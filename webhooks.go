@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm/schema"
+)
+
+// webhookEvents adalah kejadian yang bisa dilanggan oleh webhook (perubahan status retur)
+var webhookEvents = map[EventType]bool{
+	ReturApproved:    true,
+	ReturDisapproved: true,
+	ReturReset:       true,
+	ReturSpike:       true,
+}
+
+// Webhook adalah langganan webhook yang disimpan di database dan bisa diubah tanpa redeploy
+type Webhook struct {
+	ID        uint      `json:"id"`
+	URL       string    `json:"url"`                                  // URL tujuan (http atau https)
+	Secret    string    `json:"-" gorm:"serializer:secret;type:text"` // Kunci HMAC untuk header X-Retur-Signature, disimpan terenkripsi dan tidak pernah ditampilkan
+	Events    []string  `json:"events" gorm:"serializer:json"`        // Kejadian yang dikirim, kosong berarti semua perubahan status (retur.spike harus dipilih eksplisit)
+	Active    bool      `json:"active"`                               // Langganan nonaktif tidak menerima notifikasi
+	HasSecret bool      `json:"has_secret" gorm:"-"`                  // Bernilai true jika secret diatur (dihitung, tidak disimpan)
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Wants memeriksa apakah webhook berlangganan kejadian tertentu
+func (h Webhook) Wants(event string) bool {
+	if len(h.Events) == 0 {
+		return event != string(ReturSpike) // Alert lonjakan bukan perubahan status, jadi tidak ikut langganan default
+	}
+	for _, e := range h.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookRegistry menyimpan salinan langganan aktif di memori agar pengiriman notifikasi tidak perlu query database
+type webhookRegistry struct {
+	mu       sync.RWMutex
+	webhooks []Webhook
+}
+
+// webhookSubs adalah daftar langganan webhook aktif
+var webhookSubs = &webhookRegistry{}
+
+// Reload memuat ulang langganan aktif dari database; dipanggil saat startup dan setiap kali langganan berubah
+func (reg *webhookRegistry) Reload() error {
+	var webhooks []Webhook
+	if err := db.Where("active = ?", true).Order("id").Find(&webhooks).Error; err != nil {
+		return err
+	}
+	reg.mu.Lock()
+	reg.webhooks = webhooks
+	reg.mu.Unlock()
+	return nil
+}
+
+// Matching mengembalikan langganan aktif yang menerima kejadian tertentu
+func (reg *webhookRegistry) Matching(event string) []Webhook {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	var matched []Webhook
+	for _, h := range reg.webhooks {
+		if h.Wants(event) {
+			matched = append(matched, h)
+		}
+	}
+	return matched
+}
+
+// secretSerializer menyimpan secret webhook terenkripsi dengan cipher yang sama seperti details (RETUR_DETAILS_KEY)
+// Secret HMAC harus bisa dibaca kembali untuk menandatangani notifikasi, jadi dienkripsi alih-alih di-hash;
+// nilai lama tanpa prefix enkripsi tetap terbaca dan dienkripsi ulang oleh sealWebhookSecrets saat startup
+type secretSerializer struct{}
+
+// Scan membaca secret dari database dan mendekripsinya
+func (secretSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var stored string
+	switch value := dbValue.(type) {
+	case nil:
+	case []byte:
+		stored = string(value)
+	case string:
+		stored = value
+	default:
+		return fmt.Errorf("unsupported secret value %T", dbValue)
+	}
+	secret, err := decryptDetail(stored)
+	if err != nil {
+		return err
+	}
+	field.ReflectValueOf(ctx, dst).SetString(secret)
+	return nil
+}
+
+// Value mengenkripsi secret sebelum disimpan; secret tidak pernah ditulis sebagai plaintext
+func (secretSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	secret, _ := fieldValue.(string)
+	if secret == "" {
+		return "", nil
+	}
+	if detailsAEAD == nil {
+		return nil, errSecretNeedsKey
+	}
+	return encryptDetail(secret)
+}
+
+// errSecretNeedsKey menandakan secret webhook tidak bisa disimpan karena RETUR_DETAILS_KEY belum diatur
+var errSecretNeedsKey = errors.New("webhook secret requires RETUR_DETAILS_KEY")
+
+// sealWebhookSecrets mengenkripsi secret webhook lama yang masih tersimpan sebagai plaintext; aman dijalankan berulang
+func sealWebhookSecrets() (int, error) {
+	if detailsAEAD == nil {
+		return 0, nil // Tanpa key, secret lama dibiarkan sampai RETUR_DETAILS_KEY diatur
+	}
+	var rows []struct {
+		ID     uint
+		Secret string
+	}
+	if err := db.Table("webhooks").Select("id", "secret").Where("secret <> '' AND secret NOT LIKE ?", encryptedValuePrefix+"%").Find(&rows).Error; err != nil {
+		return 0, err
+	}
+	for _, row := range rows {
+		sealed, err := encryptDetail(row.Secret)
+		if err != nil {
+			return 0, err
+		}
+		if err := db.Table("webhooks").Where("id = ?", row.ID).Update("secret", sealed).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(rows), nil
+}
+
+// checkWebhookURL memeriksa bahwa URL webhook absolut dengan skema http atau https dan tidak menuju jaringan internal
+// Host di-resolve saat pendaftaran; alamat hasil DNS juga diperiksa lagi saat koneksi dibuat (lihat publicDialer)
+// Mengembalikan pesan field error, kosong jika URL boleh dipakai
+func checkWebhookURL(ctx context.Context, raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return "must be an absolute http or https URL"
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil {
+		if blockedWebhookIP(ip) {
+			return "must not point to a loopback, link-local, or private address"
+		}
+		return ""
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return "host could not be resolved"
+	}
+	for _, addr := range addrs {
+		if blockedWebhookIP(addr.IP) {
+			return "must not point to a loopback, link-local, or private address"
+		}
+	}
+	return ""
+}
+
+// sharedAddressSpace adalah blok CGNAT 100.64.0.0/10 (RFC 6598) yang tidak dicakup net.IP.IsPrivate
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// blockedWebhookIP memeriksa apakah IP tidak boleh dituju webhook langganan: loopback, link-local (termasuk metadata
+// cloud 169.254.169.254), privat, CGNAT, unspecified, atau multicast
+func blockedWebhookIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() || sharedAddressSpace.Contains(ip)
+}
+
+// errBlockedWebhookAddress menandakan koneksi webhook ditolak karena alamat tujuan termasuk jaringan internal
+var errBlockedWebhookAddress = errors.New("webhook address is not allowed")
+
+// publicDialer adalah dialer untuk webhook langganan yang menolak alamat internal setelah DNS di-resolve,
+// sehingga host yang di-resolve ulang ke alamat internal setelah pendaftaran (DNS rebinding) tetap tertahan
+var publicDialer = &net.Dialer{
+	Timeout: 5 * time.Second,
+	Control: func(network, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if ip := net.ParseIP(host); ip == nil || blockedWebhookIP(ip) {
+			return errBlockedWebhookAddress
+		}
+		return nil
+	},
+}
+
+// listWebhooksHandler adalah handler admin untuk melihat semua langganan webhook
+// Dengan ?envelope=true (selalu di bawah /v1), hasil dipaginasi dalam envelope data/meta (lihat findEnvelopePage)
+func listWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	var webhooks []Webhook
+	query := db.WithContext(r.Context()).Model(&Webhook{}).Order("id")
+	var meta pageMeta
+	if wantsEnvelope(r) {
+		var ok bool
+		if meta, ok = findEnvelopePage(w, r, query, &webhooks); !ok {
+			return
+		}
+	} else if err := query.Find(&webhooks).Error; err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to list webhooks")
+		return
+	}
+	for i := range webhooks {
+		webhooks[i].HasSecret = webhooks[i].Secret != ""
+	}
+	if wantsEnvelope(r) {
+		respondEnvelope(w, webhooks, meta)
+		return
+	}
+	respondJSON(w, http.StatusOK, webhooks)
+}
+
+// createWebhookHandler adalah handler admin untuk mendaftarkan langganan webhook baru
+func createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		URL    string   `json:"url"`
+		Secret string   `json:"secret"`
+		Events []string `json:"events"`
+		Active *bool    `json:"active"` // Default true
+	}
+	if !decodeJSON(w, r, &input) {
+		return // Body kosong atau JSON tidak valid, error sudah dikirim
+	}
+	var errs []fieldError
+	if message := checkWebhookURL(r.Context(), input.URL); message != "" {
+		errs = append(errs, fieldError{Field: "url", Message: message})
+	}
+	if input.Secret != "" && detailsAEAD == nil {
+		errs = append(errs, fieldError{Field: "secret", Message: "requires RETUR_DETAILS_KEY so it can be stored encrypted"})
+	}
+	for i, event := range input.Events {
+		if !webhookEvents[EventType(event)] {
+			errs = append(errs, fieldError{Field: "events[" + strconv.Itoa(i) + "]", Message: "is not a subscribable event"})
+		}
+	}
+	if len(errs) > 0 {
+		respondValidationErrors(w, errs)
+		return
+	}
+
+	webhook := Webhook{URL: input.URL, Secret: input.Secret, Events: input.Events, Active: input.Active == nil || *input.Active}
+	if webhook.Events == nil {
+		webhook.Events = []string{}
+	}
+	if err := db.Create(&webhook).Error; err != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to create webhook")
+		return
+	}
+	if err := webhookSubs.Reload(); err != nil {
+		handleError(w, http.StatusInternalServerError, "Webhook saved but failed to reload subscriptions")
+		return
+	}
+	webhook.HasSecret = webhook.Secret != ""
+	w.Header().Set("Location", urlFor("/retur/admin/webhooks/"+strconv.Itoa(int(webhook.ID))))
+	respondJSON(w, http.StatusCreated, webhook)
+}
+
+// deleteWebhookHandler adalah handler admin untuk menghapus langganan webhook
+func deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		handleError(w, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+	result := db.Delete(&Webhook{}, id)
+	if result.Error != nil {
+		handleError(w, http.StatusInternalServerError, "Failed to delete webhook")
+		return
+	}
+	if result.RowsAffected == 0 {
+		handleError(w, http.StatusNotFound, "Webhook not found")
+		return
+	}
+	if err := webhookSubs.Reload(); err != nil {
+		handleError(w, http.StatusInternalServerError, "Webhook deleted but failed to reload subscriptions")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}